@@ -5,12 +5,16 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
 	"maps"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/codeindex"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/db"
@@ -21,9 +25,13 @@ import (
 	"github.com/charmbracelet/crush/internal/pubsub"
 
 	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/watcher"
 	"github.com/charmbracelet/crush/internal/message"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/rewind"
 	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/shell"
+	"github.com/charmbracelet/crush/internal/transcript"
 )
 
 type App struct {
@@ -31,8 +39,15 @@ type App struct {
 	Messages    message.Service
 	History     history.Service
 	Permissions permission.Service
+	Rewind      rewind.Service
+	CodeIndex   codeindex.Service
 
-	CoderAgent agent.Service
+	// agentMu guards the active agent, which SwitchAgent replaces while the
+	// TUI uses it.
+	agentMu    sync.RWMutex
+	coderAgent agent.Service
+	agentID    string
+	agentSub   context.CancelFunc
 
 	LSPClients map[string]*lsp.Client
 
@@ -42,6 +57,7 @@ type App struct {
 	lspWatcherWG       sync.WaitGroup
 
 	config *config.Config
+	db     *sql.DB
 
 	serviceEventsWG *sync.WaitGroup
 	eventsCtx       context.Context
@@ -61,20 +77,29 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 	files := history.NewService(q, conn)
 	skipPermissionsRequests := cfg.Permissions != nil && cfg.Permissions.SkipRequests
 	allowedTools := []string{}
-	if cfg.Permissions != nil && cfg.Permissions.AllowedTools != nil {
-		allowedTools = cfg.Permissions.AllowedTools
+	var rules permission.Rules
+	if cfg.Permissions != nil {
+		if cfg.Permissions.AllowedTools != nil {
+			allowedTools = cfg.Permissions.AllowedTools
+		}
+		rules = permission.Rules{Allow: cfg.Permissions.Allow, Deny: cfg.Permissions.Deny}
 	}
+	rulesFile := filepath.Join(cfg.Options.DataDirectory, permission.RulesFileName)
 
 	app := &App{
 		Sessions:    sessions,
 		Messages:    messages,
 		History:     files,
-		Permissions: permission.NewPermissionService(cfg.WorkingDir(), skipPermissionsRequests, allowedTools),
+		Permissions: permission.NewPermissionService(cfg.WorkingDir(), skipPermissionsRequests, allowedTools, rules, rulesFile),
+		Rewind:      rewind.NewService(sessions, messages, files),
+		CodeIndex:   codeindex.NewService(q, conn, cfg.WorkingDir(), watcher.Watching),
 		LSPClients:  make(map[string]*lsp.Client),
 
 		globalCtx: ctx,
 
-		config: cfg,
+		config:  cfg,
+		db:      conn,
+		agentID: config.AgentCoder,
 
 		watcherCancelFuncs: csync.NewSlice[context.CancelFunc](),
 
@@ -84,6 +109,7 @@ func New(ctx context.Context, conn *sql.DB, cfg *config.Config) (*App, error) {
 	}
 
 	app.setupEvents()
+	app.setupCodeIndex()
 
 	// Initialize LSP clients in the background.
 	app.initLSPClients(ctx)
@@ -104,56 +130,93 @@ func (app *App) Config() *config.Config {
 	return app.config
 }
 
+// ForkSession copies the session up to the given message into a new
+// session, see transcript.Fork.
+func (app *App) ForkSession(ctx context.Context, sessionID, messageID string) (session.Session, error) {
+	id, err := transcript.Fork(ctx, app.db, sessionID, messageID)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to fork session: %w", err)
+	}
+	return app.Sessions.Get(ctx, id)
+}
+
+// RunOptions configures a non-interactive run.
+type RunOptions struct {
+	// SessionID is the session to send the prompt to. A new session is
+	// created if it is empty.
+	SessionID string
+	// Quiet hides the spinner of the text output format.
+	Quiet        bool
+	OutputFormat OutputFormat
+}
+
 // RunNonInteractive handles the execution flow when a prompt is provided via
-// CLI flag.
-func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool) error {
+// CLI flag. When the run does not complete successfully the returned error is
+// an *ExitError.
+func (app *App) RunNonInteractive(ctx context.Context, prompt string, opts RunOptions) error {
 	slog.Info("Running in non-interactive mode")
 
 	ctx, cancel := context.WithCancel(ctx)
 	defer cancel()
 
+	var sess session.Session
+	var err error
+	if opts.SessionID != "" {
+		sess, err = app.Sessions.Get(ctx, opts.SessionID)
+		if err != nil {
+			return fmt.Errorf("failed to get session %s: %w", opts.SessionID, err)
+		}
+		slog.Info("Resuming session for non-interactive run", "session_id", sess.ID)
+	} else {
+		const maxPromptLengthForTitle = 100
+		titlePrefix := "Non-interactive: "
+		var titleSuffix string
+
+		if len(prompt) > maxPromptLengthForTitle {
+			titleSuffix = prompt[:maxPromptLengthForTitle] + "..."
+		} else {
+			titleSuffix = prompt
+		}
+		title := titlePrefix + titleSuffix
+
+		sess, err = app.Sessions.Create(ctx, title)
+		if err != nil {
+			return fmt.Errorf("failed to create session for non-interactive mode: %w", err)
+		}
+		slog.Info("Created session for non-interactive run", "session_id", sess.ID)
+	}
+
+	// Automatically approve all permission requests for this non-interactive session
+	app.Permissions.AutoApproveSession(sess.ID)
+
+	switch opts.OutputFormat {
+	case OutputFormatJSON, OutputFormatStreamJSON:
+		return app.runStructured(ctx, sess, prompt, opts.OutputFormat, os.Stdout)
+	}
+
 	// Start spinner if not in quiet mode.
 	var spinner *format.Spinner
-	if !quiet {
+	if !opts.Quiet {
 		spinner = format.NewSpinner(ctx, cancel, "Generating")
 		spinner.Start()
 	}
 
 	// Helper function to stop spinner once.
 	stopSpinner := func() {
-		if !quiet && spinner != nil {
+		if !opts.Quiet && spinner != nil {
 			spinner.Stop()
 			spinner = nil
 		}
 	}
 	defer stopSpinner()
 
-	const maxPromptLengthForTitle = 100
-	titlePrefix := "Non-interactive: "
-	var titleSuffix string
-
-	if len(prompt) > maxPromptLengthForTitle {
-		titleSuffix = prompt[:maxPromptLengthForTitle] + "..."
-	} else {
-		titleSuffix = prompt
-	}
-	title := titlePrefix + titleSuffix
-
-	sess, err := app.Sessions.Create(ctx, title)
-	if err != nil {
-		return fmt.Errorf("failed to create session for non-interactive mode: %w", err)
-	}
-	slog.Info("Created session for non-interactive run", "session_id", sess.ID)
-
-	// Automatically approve all permission requests for this non-interactive session
-	app.Permissions.AutoApproveSession(sess.ID)
+	messageEvents := app.Messages.Subscribe(ctx)
 
-	done, err := app.CoderAgent.Run(ctx, sess.ID, prompt)
+	done, err := app.CoderAgent().Run(ctx, sess.ID, prompt)
 	if err != nil {
 		return fmt.Errorf("failed to start agent processing stream: %w", err)
 	}
 
-	messageEvents := app.Messages.Subscribe(ctx)
 	readBts := 0
 
 	for {
@@ -164,9 +227,8 @@ func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool
 			if result.Error != nil {
 				if errors.Is(result.Error, context.Canceled) || errors.Is(result.Error, agent.ErrRequestCancelled) {
 					slog.Info("Non-interactive: agent processing cancelled", "session_id", sess.ID)
-					return nil
 				}
-				return fmt.Errorf("agent processing failed: %w", result.Error)
+				return runError(result, false)
 			}
 
 			msgContent := result.Message.Content().String()
@@ -177,7 +239,13 @@ func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool
 			fmt.Println(msgContent[readBts:])
 
 			slog.Info("Non-interactive: run completed", "session_id", sess.ID)
-			return nil
+			w := newEventWriter(io.Discard, false, sess.ID)
+			if msgs, err := app.Messages.List(context.Background(), sess.ID); err == nil {
+				for _, msg := range msgs {
+					w.message(msg)
+				}
+			}
+			return runError(result, w.toolFailed)
 
 		case event := <-messageEvents:
 			msg := event.Payload
@@ -190,13 +258,13 @@ func (app *App) RunNonInteractive(ctx context.Context, prompt string, quiet bool
 
 		case <-ctx.Done():
 			stopSpinner()
-			return ctx.Err()
+			return &ExitError{Code: ExitCodeCanceled, Err: ctx.Err()}
 		}
 	}
 }
 
 func (app *App) UpdateAgentModel() error {
-	return app.CoderAgent.UpdateModel()
+	return app.CoderAgent().UpdateModel()
 }
 
 func (app *App) setupEvents() {
@@ -209,6 +277,7 @@ func (app *App) setupEvents() {
 	setupSubscriber(ctx, app.serviceEventsWG, "history", app.History.Subscribe, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "mcp", agent.SubscribeMCPEvents, app.events)
 	setupSubscriber(ctx, app.serviceEventsWG, "lsp", SubscribeLSPEvents, app.events)
+	setupSubscriber(ctx, app.serviceEventsWG, "jobs", shell.SubscribeJobEvents, app.events)
 	cleanupFunc := func() {
 		cancel()
 		app.serviceEventsWG.Wait()
@@ -216,6 +285,38 @@ func (app *App) setupEvents() {
 	app.cleanupFuncs = append(app.cleanupFuncs, cleanupFunc)
 }
 
+// setupCodeIndex brings the code index up to date in the background, and
+// keeps it up to date with the files changed in the workspace and by the
+// agent.
+func (app *App) setupCodeIndex() {
+	ctx := app.eventsCtx
+	app.serviceEventsWG.Go(func() {
+		if err := app.CodeIndex.Update(ctx); err != nil && ctx.Err() == nil {
+			slog.Error("Failed to update the code index", "error", err)
+		}
+	})
+	app.serviceEventsWG.Go(func() {
+		files := watcher.SubscribeFileEvents(ctx)
+		edits := app.History.Subscribe(ctx)
+		for {
+			select {
+			case event, ok := <-files:
+				if !ok {
+					return
+				}
+				app.CodeIndex.Changed(event.Payload)
+			case event, ok := <-edits:
+				if !ok {
+					return
+				}
+				app.CodeIndex.Changed(event.Payload.Path)
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
+
 func setupSubscriber[T any](
 	ctx context.Context,
 	wg *sync.WaitGroup,
@@ -252,32 +353,76 @@ func setupSubscriber[T any](
 }
 
 func (app *App) InitCoderAgent() error {
-	coderAgentCfg := app.config.Agents["coder"]
-	if coderAgentCfg.ID == "" {
-		return fmt.Errorf("coder agent configuration is missing")
+	app.agentMu.Lock()
+	defer app.agentMu.Unlock()
+	return app.initAgent(app.agentID)
+}
+
+// initAgent makes the configured agent with the given ID the active agent.
+// The caller must hold agentMu.
+func (app *App) initAgent(agentID string) error {
+	agentCfg, ok := app.config.Agent(agentID)
+	if !ok {
+		return fmt.Errorf("%s agent configuration is missing", agentID)
 	}
-	var err error
-	app.CoderAgent, err = agent.NewAgent(
+	coderAgent, err := agent.NewAgent(
 		app.globalCtx,
-		coderAgentCfg,
+		agentCfg,
 		app.Permissions,
 		app.Sessions,
 		app.Messages,
 		app.History,
 		app.LSPClients,
+		app.CodeIndex,
 	)
 	if err != nil {
-		slog.Error("Failed to create coder agent", "err", err)
+		slog.Error("Failed to create agent", "agent", agentID, "err", err)
 		return err
 	}
+	app.coderAgent = coderAgent
+	app.agentID = agentID
 
-	// Add MCP client cleanup to shutdown process
-	app.cleanupFuncs = append(app.cleanupFuncs, agent.CloseMCPClients)
-
-	setupSubscriber(app.eventsCtx, app.serviceEventsWG, "coderAgent", app.CoderAgent.Subscribe, app.events)
+	if app.agentSub != nil {
+		app.agentSub()
+	} else {
+		// Add MCP client cleanup to shutdown process
+		app.cleanupFuncs = append(app.cleanupFuncs, agent.CloseMCPClients)
+	}
+	var subCtx context.Context
+	subCtx, app.agentSub = context.WithCancel(app.eventsCtx)
+	setupSubscriber(subCtx, app.serviceEventsWG, "coderAgent", coderAgent.Subscribe, app.events)
 	return nil
 }
 
+// CoderAgent returns the active agent, the coder agent unless another one
+// was selected with SwitchAgent. It is nil until InitCoderAgent succeeds.
+func (app *App) CoderAgent() agent.Service {
+	app.agentMu.RLock()
+	defer app.agentMu.RUnlock()
+	return app.coderAgent
+}
+
+// AgentID returns the ID of the active agent.
+func (app *App) AgentID() string {
+	app.agentMu.RLock()
+	defer app.agentMu.RUnlock()
+	return app.agentID
+}
+
+// SwitchAgent replaces the active agent with the configured agent with the
+// given ID.
+func (app *App) SwitchAgent(agentID string) error {
+	if _, ok := app.config.Agent(agentID); !ok {
+		return fmt.Errorf("agent %q not found in config", agentID)
+	}
+	app.agentMu.Lock()
+	defer app.agentMu.Unlock()
+	if app.coderAgent != nil && app.coderAgent.IsBusy() {
+		return agent.ErrSessionBusy
+	}
+	return app.initAgent(agentID)
+}
+
 // Subscribe sends events to the TUI as tea.Msgs.
 func (app *App) Subscribe(program *tea.Program) {
 	defer log.RecoverPanic("app.Subscribe", func() {
@@ -311,14 +456,17 @@ func (app *App) Subscribe(program *tea.Program) {
 
 // Shutdown performs a graceful shutdown of the application.
 func (app *App) Shutdown() {
-	if app.CoderAgent != nil {
-		app.CoderAgent.CancelAll()
+	if coderAgent := app.CoderAgent(); coderAgent != nil {
+		coderAgent.CancelAll()
 	}
 
 	for cancel := range app.watcherCancelFuncs.Seq() {
 		cancel()
 	}
 
+	// Kill all background jobs started by the bash tool.
+	shell.KillBackgroundJobs()
+
 	// Wait for all LSP watchers to finish.
 	app.lspWatcherWG.Wait()
 
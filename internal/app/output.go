@@ -0,0 +1,406 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// OutputFormat is the output format of non-interactive runs.
+type OutputFormat string
+
+const (
+	// OutputFormatText prints the assistant response as it is generated.
+	OutputFormatText OutputFormat = "text"
+	// OutputFormatJSON prints a single JSON object with the result of the
+	// run once it is done.
+	OutputFormatJSON OutputFormat = "json"
+	// OutputFormatStreamJSON prints one JSON event per line while the run
+	// progresses, ending with the result.
+	OutputFormatStreamJSON OutputFormat = "stream-json"
+)
+
+// Exit codes of non-interactive runs.
+const (
+	// ExitCodeError is used when the model or provider failed.
+	ExitCodeError = 1
+	// ExitCodeToolError is used when the run was stopped by a tool call that
+	// was denied, or stopped right after tool calls that failed without the
+	// model finishing its turn.
+	ExitCodeToolError = 2
+	// ExitCodeCanceled is used when the run was canceled.
+	ExitCodeCanceled = 130
+)
+
+// ExitError is returned by RunNonInteractive when a run did not complete
+// successfully, with the exit code the process should use.
+type ExitError struct {
+	Code int
+	Err  error
+}
+
+func (e *ExitError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ExitError) Unwrap() error {
+	return e.Err
+}
+
+// runError maps the final event of a run to the error returned by
+// RunNonInteractive. toolFailed tells whether the last tool results of the
+// run were errors, which only matters if the model didn't end its turn: a
+// model that recovered from a failed tool call completed the run.
+func runError(result agent.AgentEvent, toolFailed bool) error {
+	finishReason := result.Message.FinishReason()
+	switch {
+	case result.Error == nil && finishReason == message.FinishReasonPermissionDenied:
+		return &ExitError{Code: ExitCodeToolError, Err: errors.New("run stopped: permission denied")}
+	case result.Error == nil && toolFailed && finishReason != message.FinishReasonEndTurn:
+		return &ExitError{Code: ExitCodeToolError, Err: errors.New("run stopped after a failed tool call")}
+	case result.Error == nil:
+		return nil
+	case errors.Is(result.Error, context.Canceled) || errors.Is(result.Error, agent.ErrRequestCancelled):
+		return &ExitError{Code: ExitCodeCanceled, Err: agent.ErrRequestCancelled}
+	default:
+		return &ExitError{Code: ExitCodeError, Err: fmt.Errorf("agent processing failed: %w", result.Error)}
+	}
+}
+
+// Events written by the json and stream-json output formats. Every event has
+// a type and the id of the session it belongs to, which is the id of a
+// sub-agent session for the work done by sub-agents.
+type (
+	streamEvent struct {
+		Type      string `json:"type"`
+		SessionID string `json:"session_id"`
+	}
+
+	initEvent struct {
+		streamEvent
+		Model    string `json:"model"`
+		Provider string `json:"provider"`
+	}
+
+	textEvent struct {
+		streamEvent
+		MessageID string `json:"message_id"`
+		Text      string `json:"text"`
+	}
+
+	toolCallEvent struct {
+		streamEvent
+		MessageID  string          `json:"message_id"`
+		ToolCallID string          `json:"tool_call_id"`
+		Name       string          `json:"name"`
+		Input      json.RawMessage `json:"input"`
+	}
+
+	toolResultEvent struct {
+		streamEvent
+		ToolCallID string `json:"tool_call_id"`
+		Name       string `json:"name"`
+		Content    string `json:"content"`
+		IsError    bool   `json:"is_error"`
+	}
+
+	permissionEvent struct {
+		streamEvent
+		ToolCallID string `json:"tool_call_id"`
+		Name       string `json:"name,omitempty"`
+		Granted    bool   `json:"granted"`
+	}
+
+	usageEvent struct {
+		streamEvent
+		PromptTokens     int64   `json:"prompt_tokens"`
+		CompletionTokens int64   `json:"completion_tokens"`
+		Cost             float64 `json:"cost"`
+	}
+
+	warningEvent struct {
+		streamEvent
+		Message string `json:"message"`
+	}
+
+	resultEvent struct {
+		streamEvent
+		FinishReason     string  `json:"finish_reason"`
+		Result           string  `json:"result"`
+		IsError          bool    `json:"is_error"`
+		Error            string  `json:"error,omitempty"`
+		ExitCode         int     `json:"exit_code"`
+		ToolCalls        int     `json:"tool_calls"`
+		ToolErrors       int     `json:"tool_errors"`
+		PromptTokens     int64   `json:"prompt_tokens"`
+		CompletionTokens int64   `json:"completion_tokens"`
+		Cost             float64 `json:"cost"`
+		DurationMS       int64   `json:"duration_ms"`
+	}
+)
+
+// eventWriter turns the message, session, permission and agent events of a
+// run into stream events. Message events only carry the full message, so
+// the writer keeps track of what it already reported.
+type eventWriter struct {
+	enc       *json.Encoder
+	stream    bool
+	sessionID string
+
+	sessions   map[string]bool
+	text       map[string]int
+	reasoning  map[string]int
+	toolNames  map[string]string
+	toolOwner  map[string]string
+	toolCalls  map[string]bool
+	results    map[string]bool
+	toolErrors int
+	// toolFailed is set if the last tool results of the run's session
+	// include an error.
+	toolFailed bool
+	usage      usageEvent
+}
+
+func newEventWriter(w io.Writer, stream bool, sessionID string) *eventWriter {
+	return &eventWriter{
+		enc:       json.NewEncoder(w),
+		stream:    stream,
+		sessionID: sessionID,
+		sessions:  map[string]bool{sessionID: true},
+		text:      make(map[string]int),
+		reasoning: make(map[string]int),
+		toolNames: make(map[string]string),
+		toolOwner: make(map[string]string),
+		toolCalls: make(map[string]bool),
+		results:   make(map[string]bool),
+	}
+}
+
+func (w *eventWriter) write(event any) {
+	if !w.stream {
+		return
+	}
+	if err := w.enc.Encode(event); err != nil {
+		slog.Error("Non-interactive: failed to write event", "error", err)
+	}
+}
+
+func (w *eventWriter) session(s session.Session) {
+	if s.ParentSessionID != "" && w.sessions[s.ParentSessionID] {
+		w.sessions[s.ID] = true
+	}
+	if !w.sessions[s.ID] || s.ParentSessionID != "" {
+		return
+	}
+	if s.PromptTokens == w.usage.PromptTokens && s.CompletionTokens == w.usage.CompletionTokens && s.Cost == w.usage.Cost {
+		return
+	}
+	w.usage = usageEvent{
+		streamEvent:      streamEvent{Type: "usage", SessionID: s.ID},
+		PromptTokens:     s.PromptTokens,
+		CompletionTokens: s.CompletionTokens,
+		Cost:             s.Cost,
+	}
+	w.write(w.usage)
+}
+
+func (w *eventWriter) message(msg message.Message) {
+	if !w.sessions[msg.SessionID] {
+		return
+	}
+	header := func(typ string) streamEvent {
+		return streamEvent{Type: typ, SessionID: msg.SessionID}
+	}
+
+	switch msg.Role {
+	case message.Assistant:
+		// A message that gets shorter was reset to retry the request, start
+		// over with it.
+		if len(msg.ReasoningContent().Thinking) < w.reasoning[msg.ID] || len(msg.Content().Text) < w.text[msg.ID] {
+			w.reasoning[msg.ID] = 0
+			w.text[msg.ID] = 0
+		}
+		if thinking := msg.ReasoningContent().Thinking; len(thinking) > w.reasoning[msg.ID] {
+			w.write(textEvent{
+				streamEvent: header("reasoning"),
+				MessageID:   msg.ID,
+				Text:        thinking[w.reasoning[msg.ID]:],
+			})
+			w.reasoning[msg.ID] = len(thinking)
+		}
+		if text := msg.Content().Text; len(text) > w.text[msg.ID] {
+			w.write(textEvent{
+				streamEvent: header("text"),
+				MessageID:   msg.ID,
+				Text:        text[w.text[msg.ID]:],
+			})
+			w.text[msg.ID] = len(text)
+		}
+		for _, call := range msg.ToolCalls() {
+			w.toolNames[call.ID] = call.Name
+			w.toolOwner[call.ID] = msg.SessionID
+			if !call.Finished || w.toolCalls[call.ID] {
+				continue
+			}
+			w.toolCalls[call.ID] = true
+			input := json.RawMessage(call.Input)
+			if !json.Valid(input) {
+				input, _ = json.Marshal(call.Input)
+			}
+			w.write(toolCallEvent{
+				streamEvent: header("tool_call"),
+				MessageID:   msg.ID,
+				ToolCallID:  call.ID,
+				Name:        call.Name,
+				Input:       input,
+			})
+		}
+	case message.Tool:
+		if msg.SessionID == w.sessionID {
+			w.toolFailed = slices.ContainsFunc(msg.ToolResults(), func(result message.ToolResult) bool {
+				return result.IsError
+			})
+		}
+		for _, result := range msg.ToolResults() {
+			if result.Running || w.results[result.ToolCallID] {
+				continue
+			}
+			w.results[result.ToolCallID] = true
+			if result.IsError {
+				w.toolErrors++
+			}
+			name := result.Name
+			if name == "" {
+				name = w.toolNames[result.ToolCallID]
+			}
+			w.write(toolResultEvent{
+				streamEvent: header("tool_result"),
+				ToolCallID:  result.ToolCallID,
+				Name:        name,
+				Content:     result.Content,
+				IsError:     result.IsError,
+			})
+		}
+	}
+}
+
+func (w *eventWriter) permission(n permission.PermissionNotification) {
+	sessionID, ok := w.toolOwner[n.ToolCallID]
+	if !ok || (!n.Granted && !n.Denied) {
+		// Either not a tool call of this run, or the request is still
+		// waiting for a decision.
+		return
+	}
+	w.write(permissionEvent{
+		streamEvent: streamEvent{Type: "permission", SessionID: sessionID},
+		ToolCallID:  n.ToolCallID,
+		Name:        w.toolNames[n.ToolCallID],
+		Granted:     n.Granted,
+	})
+}
+
+func (w *eventWriter) warning(sessionID string, err error) {
+	w.write(warningEvent{
+		streamEvent: streamEvent{Type: "warning", SessionID: sessionID},
+		Message:     err.Error(),
+	})
+}
+
+// runStructured runs the prompt in the given session, writing the json or
+// stream-json output to out.
+func (app *App) runStructured(ctx context.Context, sess session.Session, prompt string, format OutputFormat, out io.Writer) error {
+	start := time.Now()
+	w := newEventWriter(out, format == OutputFormatStreamJSON, sess.ID)
+
+	// Subscribe before starting the agent so no event is missed.
+	messageEvents := app.Messages.Subscribe(ctx)
+	sessionEvents := app.Sessions.Subscribe(ctx)
+	permissionEvents := app.Permissions.SubscribeNotifications(ctx)
+	coderAgent := app.CoderAgent()
+	agentEvents := coderAgent.Subscribe(ctx)
+
+	ev := initEvent{
+		streamEvent: streamEvent{Type: "init", SessionID: sess.ID},
+		Model:       coderAgent.Model().ID,
+	}
+	if agentCfg, ok := app.config.Agent(app.AgentID()); ok {
+		ev.Provider = app.config.Models[agentCfg.Model].Provider
+	}
+	w.write(ev)
+
+	done, err := coderAgent.Run(ctx, sess.ID, prompt)
+	if err != nil {
+		return fmt.Errorf("failed to start agent processing stream: %w", err)
+	}
+
+	for {
+		select {
+		case result := <-done:
+			// Events may have been dropped by the brokers, catch up with the
+			// stored messages before reporting the result.
+			if msgs, err := app.Messages.List(context.Background(), sess.ID); err == nil {
+				for _, msg := range msgs {
+					w.message(msg)
+				}
+			}
+			if s, err := app.Sessions.Get(context.Background(), sess.ID); err == nil {
+				w.session(s)
+			}
+
+			runErr := runError(result, w.toolFailed)
+			event := resultEvent{
+				streamEvent:      streamEvent{Type: "result", SessionID: sess.ID},
+				FinishReason:     string(result.Message.FinishReason()),
+				Result:           result.Message.Content().Text,
+				ToolCalls:        len(w.toolCalls),
+				ToolErrors:       w.toolErrors,
+				PromptTokens:     w.usage.PromptTokens,
+				CompletionTokens: w.usage.CompletionTokens,
+				Cost:             w.usage.Cost,
+				DurationMS:       time.Since(start).Milliseconds(),
+			}
+			if exitErr := (*ExitError)(nil); errors.As(runErr, &exitErr) {
+				event.IsError = true
+				event.Error = exitErr.Error()
+				event.ExitCode = exitErr.Code
+				if event.FinishReason == "" {
+					event.FinishReason = string(message.FinishReasonError)
+					if exitErr.Code == ExitCodeCanceled {
+						event.FinishReason = string(message.FinishReasonCanceled)
+					}
+				}
+			}
+			if err := json.NewEncoder(out).Encode(event); err != nil {
+				return fmt.Errorf("failed to write result: %w", err)
+			}
+			return runErr
+
+		case event := <-messageEvents:
+			w.message(event.Payload)
+
+		case event := <-sessionEvents:
+			w.session(event.Payload)
+
+		case event := <-permissionEvents:
+			w.permission(event.Payload)
+
+		case event := <-agentEvents:
+			if event.Payload.Type == agent.AgentEventTypeWarning && w.sessions[event.Payload.SessionID] {
+				w.warning(event.Payload.SessionID, event.Payload.Error)
+			}
+
+		case <-ctx.Done():
+			return &ExitError{Code: ExitCodeCanceled, Err: ctx.Err()}
+		}
+	}
+}
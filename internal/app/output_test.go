@@ -0,0 +1,203 @@
+package app
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func readEvents(t *testing.T, buf *bytes.Buffer) []map[string]any {
+	t.Helper()
+	var events []map[string]any
+	scanner := bufio.NewScanner(buf)
+	for scanner.Scan() {
+		var event map[string]any
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &event))
+		events = append(events, event)
+	}
+	return events
+}
+
+func TestEventWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newEventWriter(&buf, true, "s1")
+
+	msg := message.Message{ID: "m1", SessionID: "s1", Role: message.Assistant}
+	msg.AppendContent("Hello")
+	w.message(msg)
+	msg.AppendContent(", world")
+	w.message(msg)
+	msg.AddToolCall(message.ToolCall{ID: "c1", Name: "view", Input: `{"file_path":"main.go"}`})
+	w.message(msg)
+	msg.FinishToolCall("c1")
+	w.message(msg)
+	w.message(msg)
+
+	w.permission(permission.PermissionNotification{ToolCallID: "c1"})
+	w.permission(permission.PermissionNotification{ToolCallID: "c1", Granted: true})
+	w.permission(permission.PermissionNotification{ToolCallID: "other", Granted: true})
+
+	result := message.Message{ID: "m2", SessionID: "s1", Role: message.Tool}
+	result.AddToolResult(message.ToolResult{ToolCallID: "c1", Content: "not found", IsError: true})
+	w.message(result)
+	w.message(result)
+
+	// Sub-agent sessions are reported, unrelated sessions are not.
+	w.session(session.Session{ID: "c1", ParentSessionID: "s1"})
+	w.message(message.Message{ID: "m3", SessionID: "c1", Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "sub"}}})
+	w.message(message.Message{ID: "m4", SessionID: "other", Role: message.Assistant, Parts: []message.ContentPart{message.TextContent{Text: "nope"}}})
+
+	w.session(session.Session{ID: "s1", PromptTokens: 10, CompletionTokens: 5, Cost: 0.1})
+	w.session(session.Session{ID: "s1", PromptTokens: 10, CompletionTokens: 5, Cost: 0.1})
+
+	events := readEvents(t, &buf)
+	types := make([]string, 0, len(events))
+	for _, event := range events {
+		types = append(types, event["type"].(string))
+	}
+	require.Equal(t, []string{"text", "text", "tool_call", "permission", "tool_result", "text", "usage"}, types)
+
+	require.Equal(t, "Hello", events[0]["text"])
+	require.Equal(t, ", world", events[1]["text"])
+	require.Equal(t, map[string]any{"file_path": "main.go"}, events[2]["input"])
+	require.Equal(t, true, events[3]["granted"])
+	require.Equal(t, "view", events[3]["name"])
+	require.Equal(t, "view", events[4]["name"])
+	require.Equal(t, true, events[4]["is_error"])
+	require.Equal(t, "c1", events[5]["session_id"])
+	require.Equal(t, float64(10), events[6]["prompt_tokens"])
+
+	require.Len(t, w.toolCalls, 1)
+	require.Equal(t, 1, w.toolErrors)
+	require.True(t, w.toolFailed)
+
+	// Only the last tool results of the run's session count.
+	result = message.Message{ID: "m5", SessionID: "s1", Role: message.Tool}
+	result.AddToolResult(message.ToolResult{ToolCallID: "c2", Content: "ok"})
+	w.message(result)
+	require.False(t, w.toolFailed)
+	subResult := message.Message{ID: "m6", SessionID: "c1", Role: message.Tool}
+	subResult.AddToolResult(message.ToolResult{ToolCallID: "c3", Content: "failed", IsError: true})
+	w.message(subResult)
+	require.False(t, w.toolFailed)
+}
+
+func TestEventWriter_Retry(t *testing.T) {
+	var buf bytes.Buffer
+	w := newEventWriter(&buf, true, "s1")
+
+	msg := message.Message{ID: "m1", SessionID: "s1", Role: message.Assistant}
+	msg.AppendContent("Partial answer")
+	w.message(msg)
+
+	// The message is reset when the request is retried.
+	msg.Parts = nil
+	msg.AppendContent("Full")
+	w.message(msg)
+
+	events := readEvents(t, &buf)
+	require.Len(t, events, 2)
+	require.Equal(t, "Full", events[1]["text"])
+}
+
+func TestEventWriter_NotStreaming(t *testing.T) {
+	var buf bytes.Buffer
+	w := newEventWriter(&buf, false, "s1")
+
+	msg := message.Message{ID: "m1", SessionID: "s1", Role: message.Assistant}
+	msg.AppendContent("Hello")
+	w.message(msg)
+
+	require.Empty(t, buf.String())
+}
+
+func TestRunError(t *testing.T) {
+	tests := []struct {
+		name       string
+		result     agent.AgentEvent
+		toolFailed bool
+		code       int
+	}{
+		{
+			name:   "success",
+			result: agent.AgentEvent{Message: finishedMessage(message.FinishReasonEndTurn)},
+		},
+		{
+			name:   "permission denied",
+			result: agent.AgentEvent{Message: finishedMessage(message.FinishReasonPermissionDenied)},
+			code:   ExitCodeToolError,
+		},
+		{
+			name:       "recovered from a failed tool call",
+			result:     agent.AgentEvent{Message: finishedMessage(message.FinishReasonEndTurn)},
+			toolFailed: true,
+		},
+		{
+			name:       "stopped after a failed tool call",
+			result:     agent.AgentEvent{Message: finishedMessage(message.FinishReasonMaxTokens)},
+			toolFailed: true,
+			code:       ExitCodeToolError,
+		},
+		{
+			name:   "canceled",
+			result: agent.AgentEvent{Error: context.Canceled},
+			code:   ExitCodeCanceled,
+		},
+		{
+			name:   "request canceled",
+			result: agent.AgentEvent{Error: agent.ErrRequestCancelled},
+			code:   ExitCodeCanceled,
+		},
+		{
+			name:   "provider error",
+			result: agent.AgentEvent{Error: errors.New("rate limited")},
+			code:   ExitCodeError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runError(tt.result, tt.toolFailed)
+			if tt.code == 0 {
+				require.NoError(t, err)
+				return
+			}
+			var exitErr *ExitError
+			require.ErrorAs(t, err, &exitErr)
+			require.Equal(t, tt.code, exitErr.Code)
+		})
+	}
+}
+
+func TestRunError_RecoveredFromToolError(t *testing.T) {
+	w := newEventWriter(io.Discard, false, "s1")
+	call := message.Message{ID: "m1", SessionID: "s1", Role: message.Assistant}
+	call.AddToolCall(message.ToolCall{ID: "c1", Name: "bash", Finished: true})
+	call.AddFinish(message.FinishReasonToolUse, "", "")
+	w.message(call)
+	failed := message.Message{ID: "m2", SessionID: "s1", Role: message.Tool}
+	failed.AddToolResult(message.ToolResult{ToolCallID: "c1", Content: "exit status 1", IsError: true})
+	w.message(failed)
+	answer := finishedMessage(message.FinishReasonEndTurn)
+	answer.ID, answer.SessionID = "m3", "s1"
+	w.message(answer)
+
+	require.Equal(t, 1, w.toolErrors)
+	require.NoError(t, runError(agent.AgentEvent{Message: answer}, w.toolFailed))
+}
+
+func finishedMessage(reason message.FinishReason) message.Message {
+	msg := message.Message{Role: message.Assistant}
+	msg.AddFinish(reason, "", "")
+	return msg
+}
@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/mcpserver"
+	"github.com/charmbracelet/crush/internal/permission"
+	crushserver "github.com/charmbracelet/crush/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var mcpCmd = &cobra.Command{
+	Use:   "mcp",
+	Short: "Use Crush over the Model Context Protocol",
+}
+
+var mcpServeCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve Crush's tools and agent over MCP",
+	Long: `Serve Crush's view, grep, glob, edit, bash and diagnostics tools, and a
+run_agent tool running the coder agent on a prompt, to other agents and editors
+over MCP, on stdio or streamable HTTP.
+
+Tool calls go through the permission rules of the project. As there's no one to
+ask, calls the rules don't allow are denied, unless --yolo is given.
+
+Over HTTP, every request must carry a bearer token: the one set with --token or
+CRUSH_MCP_TOKEN, or else a random one printed on start. Requests must be
+addressed to localhost or a loopback address, and an address without a host
+listens on 127.0.0.1.`,
+	Example: `
+# Serve over stdio, e.g. from an editor's MCP configuration
+crush mcp serve
+
+# Serve over streamable HTTP at http://localhost:8080/mcp, with a given token
+crush mcp serve --http :8080 --token secret
+
+# Only let clients explore the code
+crush mcp serve --mode plan
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("http")
+		token, _ := cmd.Flags().GetString("token")
+
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		sess, err := app.Sessions.Create(cmd.Context(), "MCP server")
+		if err != nil {
+			return fmt.Errorf("failed to create session: %w", err)
+		}
+
+		opts := mcpserver.Options{
+			WorkingDir:  app.Config().WorkingDir(),
+			SessionID:   sess.ID,
+			Permissions: app.Permissions,
+			History:     app.History,
+			LSPClients:  app.LSPClients,
+			Bash: tools.BashOptions{
+				Sandbox: app.Config().Sandbox.ShellSandbox(app.Config().WorkingDir()),
+			},
+			CodeIndex: app.CodeIndex,
+			Sessions:  app.Sessions,
+		}
+		if perms := app.Config().Permissions; perms != nil {
+			opts.Bash.SafeCommands = perms.SafeCommands
+			opts.Bash.BannedCommands = perms.BannedCommands
+		}
+		if app.Config().IsConfigured() {
+			opts.Agent = app.CoderAgent()
+		} else {
+			slog.Warn("No providers configured, not serving the run_agent tool")
+		}
+		server := mcpserver.New(opts)
+
+		if addr != "" {
+			token = cmp.Or(token, os.Getenv("CRUSH_MCP_TOKEN"))
+			generated := token == ""
+			if generated {
+				token = crushserver.GenerateToken()
+			}
+			slog.Info("Serving MCP over HTTP", "addr", addr)
+			if generated {
+				cmd.PrintErrf("Token: %s\n", token)
+			}
+			return server.ServeHTTP(cmd.Context(), addr, token)
+		}
+		return server.ServeStdio(cmd.Context(), os.Stdin, os.Stdout)
+	},
+}
+
+func init() {
+	mcpServeCmd.Flags().String("http", "", "Serve streamable HTTP on this address instead of stdio")
+	mcpServeCmd.Flags().String("token", "", "Bearer token required with every HTTP request (random by default)")
+	mcpServeCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+	mcpServeCmd.Flags().String("mode", string(permission.ModeDefault), "Permission mode: default, or plan to refuse tool calls that change files")
+
+	mcpCmd.AddCommand(mcpServeCmd)
+	rootCmd.AddCommand(mcpCmd)
+}
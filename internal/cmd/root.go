@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -12,6 +13,7 @@ import (
 	"github.com/charmbracelet/crush/internal/app"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/tui"
 	"github.com/charmbracelet/crush/internal/version"
 	"github.com/charmbracelet/fang"
@@ -26,6 +28,10 @@ func init() {
 
 	rootCmd.Flags().BoolP("help", "h", false, "Help")
 	rootCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+	rootCmd.Flags().String("mode", string(permission.ModeDefault), "Permission mode: default, or plan to refuse tool calls that change files")
+	rootCmd.Flags().StringP("session", "s", "", "Resume the session with the given id")
+	rootCmd.Flags().Bool("continue", false, "Resume the most recent session")
+	rootCmd.MarkFlagsMutuallyExclusive("session", "continue")
 
 	rootCmd.AddCommand(runCmd)
 }
@@ -57,6 +63,12 @@ crush run "Explain the use of context in Go"
 
 # Run in dangerous mode (auto-accept all permissions)
 crush -y
+
+# Run in plan mode (refuse tool calls that change files)
+crush --mode plan
+
+# Continue the most recent session
+crush --continue
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		app, err := setupApp(cmd)
@@ -65,9 +77,14 @@ crush -y
 		}
 		defer app.Shutdown()
 
+		sess, err := resumeSession(cmd, app.Sessions)
+		if err != nil {
+			return err
+		}
+
 		// Set up the TUI.
 		program := tea.NewProgram(
-			tui.New(app),
+			tui.New(app, sess),
 			tea.WithAltScreen(),
 			tea.WithContext(cmd.Context()),
 			tea.WithMouseCellMotion(),            // Use cell motion instead of all motion to reduce event flooding
@@ -91,6 +108,10 @@ func Execute() {
 		fang.WithVersion(version.Version),
 		fang.WithNotifySignal(os.Interrupt),
 	); err != nil {
+		var exitErr *app.ExitError
+		if errors.As(err, &exitErr) {
+			os.Exit(exitErr.Code)
+		}
 		os.Exit(1)
 	}
 }
@@ -100,9 +121,15 @@ func Execute() {
 func setupApp(cmd *cobra.Command) (*app.App, error) {
 	debug, _ := cmd.Flags().GetBool("debug")
 	yolo, _ := cmd.Flags().GetBool("yolo")
+	modeName, _ := cmd.Flags().GetString("mode")
 	dataDir, _ := cmd.Flags().GetString("data-dir")
 	ctx := cmd.Context()
 
+	mode, err := permission.ParseMode(modeName)
+	if err != nil {
+		return nil, err
+	}
+
 	cwd, err := ResolveCwd(cmd)
 	if err != nil {
 		return nil, err
@@ -133,6 +160,7 @@ func setupApp(cmd *cobra.Command) (*app.App, error) {
 		slog.Error("Failed to create app instance", "error", err)
 		return nil, err
 	}
+	appInstance.Permissions.SetMode(mode)
 
 	return appInstance, nil
 }
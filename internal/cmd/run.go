@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"strings"
 
+	"github.com/charmbracelet/crush/internal/app"
+	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/spf13/cobra"
 )
 
@@ -22,9 +24,33 @@ echo "What is this code doing?" | crush run
 
 # Run with quiet mode (no spinner)
 crush run -q "Generate a README for this project"
+
+# Run with a custom agent defined in crush.json
+crush run --agent reviewer "Review the changes in the current branch"
+
+# Ask a follow-up question in the most recent session
+crush run --continue "Now add tests for it"
+
+# Explore the code and propose a plan without changing any files
+crush run --mode plan "How would you add caching to the API client?"
+
+# Stream one JSON event per line, e.g. for CI pipelines
+crush run --output-format stream-json "Fix the failing tests"
   `,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		quiet, _ := cmd.Flags().GetBool("quiet")
+		agentID, _ := cmd.Flags().GetString("agent")
+		outputFormat, _ := cmd.Flags().GetString("output-format")
+
+		opts := app.RunOptions{
+			Quiet:        quiet,
+			OutputFormat: app.OutputFormat(outputFormat),
+		}
+		switch opts.OutputFormat {
+		case app.OutputFormatText, app.OutputFormatJSON, app.OutputFormatStreamJSON:
+		default:
+			return fmt.Errorf("invalid output format %q, must be text, json or stream-json", outputFormat)
+		}
 
 		app, err := setupApp(cmd)
 		if err != nil {
@@ -36,6 +62,17 @@ crush run -q "Generate a README for this project"
 			return fmt.Errorf("no providers configured - please run 'crush' to set up a provider interactively")
 		}
 
+		sess, err := resumeSession(cmd, app.Sessions)
+		if err != nil {
+			return err
+		}
+
+		if agentID != "" {
+			if err := app.SwitchAgent(agentID); err != nil {
+				return err
+			}
+		}
+
 		prompt := strings.Join(args, " ")
 
 		prompt, err = MaybePrependStdin(prompt)
@@ -49,10 +86,17 @@ crush run -q "Generate a README for this project"
 		}
 
 		// Run non-interactive flow using the App method
-		return app.RunNonInteractive(cmd.Context(), prompt, quiet)
+		opts.SessionID = sess.ID
+		return app.RunNonInteractive(cmd.Context(), prompt, opts)
 	},
 }
 
 func init() {
 	runCmd.Flags().BoolP("quiet", "q", false, "Hide spinner")
+	runCmd.Flags().StringP("agent", "a", "", "Agent to run the prompt with")
+	runCmd.Flags().StringP("session", "s", "", "Run the prompt in the session with the given id")
+	runCmd.Flags().Bool("continue", false, "Run the prompt in the most recent session")
+	runCmd.Flags().String("output-format", string(app.OutputFormatText), "Output format: text, json or stream-json")
+	runCmd.Flags().String("mode", string(permission.ModeDefault), "Permission mode: default, or plan to refuse tool calls that change files")
+	runCmd.MarkFlagsMutuallyExclusive("session", "continue")
 }
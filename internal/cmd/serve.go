@@ -0,0 +1,80 @@
+package cmd
+
+import (
+	"cmp"
+	"log/slog"
+	"os"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/server"
+	"github.com/spf13/cobra"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve sessions and agent runs over a local HTTP API",
+	Long: `Serve the sessions of the current project and the agent over a local HTTP/JSON
+API, with the session, message, permission and agent events streamed as
+server-sent events, for web UIs and editor plugins to build on.
+
+Permission requests wait for an answer through the API, unless --yolo is given.
+Every request must carry a bearer token: the one set with --token or
+CRUSH_SERVER_TOKEN, or else a random one printed on start. Requests must be
+addressed to localhost or a loopback address.`,
+	Example: `
+# Serve on the default address, with a random token
+crush serve
+
+# Serve on another address, with a given token
+crush serve --addr localhost:9000 --token secret
+
+# Start a session, follow it and send it a prompt
+curl -H "Authorization: Bearer <token>" -X POST localhost:4096/v1/sessions
+curl -H "Authorization: Bearer <token>" -N "localhost:4096/v1/events?session_id=<id>"
+curl -H "Authorization: Bearer <token>" -X POST localhost:4096/v1/sessions/<id>/prompt -d '{"prompt":"Explain main.go"}'
+  `,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		addr, _ := cmd.Flags().GetString("addr")
+		token, _ := cmd.Flags().GetString("token")
+
+		app, err := setupApp(cmd)
+		if err != nil {
+			return err
+		}
+		defer app.Shutdown()
+
+		token = cmp.Or(token, os.Getenv("CRUSH_SERVER_TOKEN"))
+		generated := token == ""
+		if generated {
+			token = server.GenerateToken()
+		}
+		opts := server.Options{
+			Sessions:    app.Sessions,
+			Messages:    app.Messages,
+			Permissions: app.Permissions,
+			Token:       token,
+		}
+		if app.Config().IsConfigured() {
+			opts.Agent = app.CoderAgent()
+		} else {
+			slog.Warn("No providers configured, prompts will be refused")
+		}
+
+		slog.Info("Serving the HTTP API", "addr", addr)
+		cmd.PrintErrf("Serving the Crush API on http://%s\n", addr)
+		if generated {
+			cmd.PrintErrf("Token: %s\n", token)
+		}
+		return server.New(cmd.Context(), opts).ListenAndServe(cmd.Context(), addr)
+	},
+}
+
+func init() {
+	serveCmd.Flags().String("addr", "localhost:4096", "Address to listen on")
+	serveCmd.Flags().String("token", "", "Bearer token required with every request (random by default)")
+	serveCmd.Flags().BoolP("yolo", "y", false, "Automatically accept all permissions (dangerous mode)")
+	serveCmd.Flags().String("mode", string(permission.ModeDefault), "Permission mode: default, or plan to refuse tool calls that change files")
+
+	rootCmd.AddCommand(serveCmd)
+}
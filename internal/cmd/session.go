@@ -0,0 +1,556 @@
+package cmd
+
+import (
+	"bufio"
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/rewind"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/transcript"
+	"github.com/charmbracelet/x/term"
+	"github.com/spf13/cobra"
+)
+
+var sessionCmd = &cobra.Command{
+	Use:     "session",
+	Aliases: []string{"sessions"},
+	Short:   "Manage sessions",
+	Long: `Manage the sessions stored in the crush database of the current project.
+Sessions can be referred to by their full id or by a unique prefix of it.`,
+}
+
+var sessionListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List sessions",
+	Long:    `List the sessions of the current project, most recently updated first.`,
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		store, err := openSessionStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		sessions, err := store.sessions.List(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to list sessions: %w", err)
+		}
+		sortByUpdated(sessions)
+
+		if asJSON {
+			return writeJSON(os.Stdout, sessions)
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTITLE\tMESSAGES\tTOKENS\tCOST\tUPDATED")
+		for _, s := range sessions {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t$%.4f\t%s\n",
+				s.ID,
+				truncate(s.Title, 50),
+				s.MessageCount,
+				s.PromptTokens+s.CompletionTokens,
+				s.Cost,
+				formatUnix(s.UpdatedAt),
+			)
+		}
+		return w.Flush()
+	},
+}
+
+var sessionShowCmd = &cobra.Command{
+	Use:   "show <id>",
+	Short: "Show a session and its conversation",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		asJSON, _ := cmd.Flags().GetBool("json")
+
+		store, err := openSessionStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		sess, err := resolveSession(cmd.Context(), store.sessions, args[0])
+		if err != nil {
+			return err
+		}
+		messages, err := store.messages.List(cmd.Context(), sess.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+
+		if asJSON {
+			return writeSessionJSON(os.Stdout, sess, messages)
+		}
+		writeSession(os.Stdout, sess, messages)
+		return nil
+	},
+}
+
+var sessionRemoveCmd = &cobra.Command{
+	Use:     "rm <id>...",
+	Aliases: []string{"delete"},
+	Short:   "Delete sessions",
+	Long: `Delete sessions together with their messages, sub-agent sessions and
+file history. Asks for confirmation unless --force is given.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		force, _ := cmd.Flags().GetBool("force")
+		ctx := cmd.Context()
+
+		store, err := openSessionStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		var sessions []session.Session
+		for _, id := range args {
+			sess, err := resolveSession(ctx, store.sessions, id)
+			if err != nil {
+				return err
+			}
+			sessions = append(sessions, sess)
+		}
+
+		if !force {
+			if !term.IsTerminal(os.Stdin.Fd()) {
+				return fmt.Errorf("refusing to delete sessions without confirmation, use --force")
+			}
+			for _, sess := range sessions {
+				fmt.Printf("%s  %s\n", sess.ID, sess.Title)
+			}
+			fmt.Printf("Delete %d session(s)? [y/N] ", len(sessions))
+			answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+			if a := strings.ToLower(strings.TrimSpace(answer)); a != "y" && a != "yes" {
+				return nil
+			}
+		}
+
+		for _, sess := range sessions {
+			if err := rewind.DeleteSession(ctx, store.sessions, store.messages, store.files, sess.ID); err != nil {
+				return fmt.Errorf("failed to delete session %s: %w", sess.ID, err)
+			}
+			fmt.Printf("Deleted session %s\n", sess.ID)
+		}
+		return nil
+	},
+}
+
+var sessionRenameCmd = &cobra.Command{
+	Use:   "rename <id> <title>...",
+	Short: "Rename a session",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		title := strings.TrimSpace(strings.Join(args[1:], " "))
+		if title == "" {
+			return fmt.Errorf("title must not be empty")
+		}
+
+		store, err := openSessionStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		sess, err := resolveSession(cmd.Context(), store.sessions, args[0])
+		if err != nil {
+			return err
+		}
+		sess.Title = title
+		if _, err := store.sessions.Save(cmd.Context(), sess); err != nil {
+			return fmt.Errorf("failed to rename session: %w", err)
+		}
+		fmt.Printf("Renamed session %s to %q\n", sess.ID, title)
+		return nil
+	},
+}
+
+var sessionForkCmd = &cobra.Command{
+	Use:   "fork <id>",
+	Short: "Fork a session",
+	Long: `Copy a session into a new session, up to the given message or the whole
+conversation, together with its file history. Both sessions can then be
+continued independently.`,
+	Example: `
+# Fork a session and continue the fork
+crush session fork 5f1c...
+crush run --session <fork id> "Try a different approach"
+
+# Fork a session at one of its messages
+crush session fork 5f1c... --message 9a2b...
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		messageID, _ := cmd.Flags().GetString("message")
+
+		store, err := openSessionStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		sess, err := resolveSession(cmd.Context(), store.sessions, args[0])
+		if err != nil {
+			return err
+		}
+		msgs, err := store.messages.List(cmd.Context(), sess.ID)
+		if err != nil {
+			return fmt.Errorf("failed to list messages: %w", err)
+		}
+		if len(msgs) == 0 {
+			return fmt.Errorf("session %s has no messages to fork", sess.ID)
+		}
+		msg := msgs[len(msgs)-1]
+		if messageID != "" {
+			var matches []message.Message
+			for _, m := range msgs {
+				if strings.HasPrefix(m.ID, messageID) {
+					matches = append(matches, m)
+				}
+			}
+			switch len(matches) {
+			case 0:
+				return fmt.Errorf("no message matching %q in session %s", messageID, sess.ID)
+			case 1:
+				msg = matches[0]
+			default:
+				return fmt.Errorf("message id %q is ambiguous, %d messages match", messageID, len(matches))
+			}
+		}
+
+		id, err := transcript.Fork(cmd.Context(), store.conn, sess.ID, msg.ID)
+		if err != nil {
+			return fmt.Errorf("failed to fork session: %w", err)
+		}
+		fmt.Printf("Forked session %s into %s\n", sess.ID, id)
+		return nil
+	},
+}
+
+var sessionExportCmd = &cobra.Command{
+	Use:   "export <id>",
+	Short: "Export a session",
+	Long: `Export a session, its sub-agent sessions and its file history.
+
+The JSON format is self-contained and can be imported into another project or
+machine with 'crush session import'. The Markdown format renders the
+conversation and its tool calls for reading, e.g. to attach it to a code review.`,
+	Example: `
+# Export a session to a file
+crush session export 5f1c... -o session.json
+
+# Render a session as Markdown
+crush session export 5f1c... --format markdown > session.md
+  `,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		output, _ := cmd.Flags().GetString("output")
+		if format != "json" && format != "markdown" {
+			return fmt.Errorf("invalid format %q, must be json or markdown", format)
+		}
+
+		store, err := openSessionStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		sess, err := resolveSession(cmd.Context(), store.sessions, args[0])
+		if err != nil {
+			return err
+		}
+		t, err := transcript.Export(cmd.Context(), store.q, sess.ID)
+		if err != nil {
+			return err
+		}
+
+		var w io.Writer = os.Stdout
+		if output != "" {
+			f, err := os.Create(output)
+			if err != nil {
+				return fmt.Errorf("failed to create output file: %w", err)
+			}
+			defer f.Close()
+			w = f
+		}
+
+		if format == "markdown" {
+			return transcript.WriteMarkdown(w, t)
+		}
+		return transcript.Write(w, t)
+	},
+}
+
+var sessionImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "Import a session",
+	Long: `Import a session exported with 'crush session export' in JSON format.
+Use - to read it from stdin. The session keeps its original id, so it can only
+be imported once into the same project.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var r io.Reader = os.Stdin
+		if args[0] != "-" {
+			f, err := os.Open(args[0])
+			if err != nil {
+				return fmt.Errorf("failed to open transcript: %w", err)
+			}
+			defer f.Close()
+			r = f
+		}
+
+		t, err := transcript.Read(r)
+		if err != nil {
+			return err
+		}
+
+		store, err := openSessionStore(cmd)
+		if err != nil {
+			return err
+		}
+		defer store.Close()
+
+		if err := transcript.Import(cmd.Context(), store.conn, t); err != nil {
+			return err
+		}
+		root := t.Root()
+		fmt.Printf("Imported session %q (%s)\n", root.Title, root.ID)
+		return nil
+	},
+}
+
+func init() {
+	sessionListCmd.Flags().Bool("json", false, "Output as JSON")
+	sessionShowCmd.Flags().Bool("json", false, "Output as JSON")
+	sessionRemoveCmd.Flags().BoolP("force", "f", false, "Delete without asking for confirmation")
+	sessionForkCmd.Flags().StringP("message", "m", "", "Fork at the message with the given id instead of the last one")
+	sessionExportCmd.Flags().StringP("format", "f", "json", "Output format: json or markdown")
+	sessionExportCmd.Flags().StringP("output", "o", "", "Write to the given file instead of stdout")
+
+	sessionCmd.AddCommand(sessionListCmd)
+	sessionCmd.AddCommand(sessionShowCmd)
+	sessionCmd.AddCommand(sessionRemoveCmd)
+	sessionCmd.AddCommand(sessionRenameCmd)
+	sessionCmd.AddCommand(sessionForkCmd)
+	sessionCmd.AddCommand(sessionExportCmd)
+	sessionCmd.AddCommand(sessionImportCmd)
+
+	rootCmd.AddCommand(sessionCmd)
+}
+
+// sessionStore gives access to the sessions of the current project without
+// starting the rest of the app.
+type sessionStore struct {
+	conn     *sql.DB
+	q        *db.Queries
+	sessions session.Service
+	messages message.Service
+	files    history.Service
+}
+
+func openSessionStore(cmd *cobra.Command) (*sessionStore, error) {
+	debug, _ := cmd.Flags().GetBool("debug")
+	dataDir, _ := cmd.Flags().GetString("data-dir")
+
+	cwd, err := ResolveCwd(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg, err := config.Load(cwd, dataDir, debug)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	if err := createDotCrushDir(cfg.Options.DataDirectory); err != nil {
+		return nil, err
+	}
+
+	conn, err := db.Connect(cmd.Context(), cfg.Options.DataDirectory)
+	if err != nil {
+		return nil, err
+	}
+	q := db.New(conn)
+	return &sessionStore{
+		conn:     conn,
+		q:        q,
+		sessions: session.NewService(q),
+		messages: message.NewService(q),
+		files:    history.NewService(q, conn),
+	}, nil
+}
+
+func (s *sessionStore) Close() error {
+	return s.conn.Close()
+}
+
+// resolveSession finds a top level session by its id or a unique prefix of
+// it.
+func resolveSession(ctx context.Context, sessions session.Service, id string) (session.Session, error) {
+	sess, err := sessions.Get(ctx, id)
+	if err == nil {
+		return sess, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return session.Session{}, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	all, err := sessions.List(ctx)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	var matches []session.Session
+	for _, s := range all {
+		if strings.HasPrefix(s.ID, id) {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return session.Session{}, fmt.Errorf("session %s not found", id)
+	case 1:
+		return matches[0], nil
+	default:
+		return session.Session{}, fmt.Errorf("session id %s is ambiguous, it matches %d sessions", id, len(matches))
+	}
+}
+
+// latestSession returns the most recently updated top level session.
+func latestSession(ctx context.Context, sessions session.Service) (session.Session, error) {
+	all, err := sessions.List(ctx)
+	if err != nil {
+		return session.Session{}, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	if len(all) == 0 {
+		return session.Session{}, fmt.Errorf("no session to continue")
+	}
+	sortByUpdated(all)
+	return all[0], nil
+}
+
+// resumeSession returns the session selected with the --session or
+// --continue flags, or an empty session if a new one should be started.
+func resumeSession(cmd *cobra.Command, sessions session.Service) (session.Session, error) {
+	id, _ := cmd.Flags().GetString("session")
+	cont, _ := cmd.Flags().GetBool("continue")
+	switch {
+	case id != "":
+		return resolveSession(cmd.Context(), sessions, id)
+	case cont:
+		return latestSession(cmd.Context(), sessions)
+	default:
+		return session.Session{}, nil
+	}
+}
+
+func sortByUpdated(sessions []session.Session) {
+	slices.SortStableFunc(sessions, func(a, b session.Session) int {
+		return cmp.Compare(b.UpdatedAt, a.UpdatedAt)
+	})
+}
+
+func writeJSON(w io.Writer, v any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func writeSessionJSON(w io.Writer, sess session.Session, messages []message.Message) error {
+	type messageJSON struct {
+		ID        string          `json:"id"`
+		Role      string          `json:"role"`
+		Model     string          `json:"model,omitempty"`
+		Provider  string          `json:"provider,omitempty"`
+		Parts     json.RawMessage `json:"parts"`
+		CreatedAt int64           `json:"created_at"`
+	}
+	out := struct {
+		session.Session
+		Messages []messageJSON `json:"messages"`
+	}{
+		Session:  sess,
+		Messages: make([]messageJSON, 0, len(messages)),
+	}
+	for _, m := range messages {
+		parts, err := message.MarshalParts(m.Parts)
+		if err != nil {
+			return err
+		}
+		out.Messages = append(out.Messages, messageJSON{
+			ID:        m.ID,
+			Role:      string(m.Role),
+			Model:     m.Model,
+			Provider:  m.Provider,
+			Parts:     parts,
+			CreatedAt: m.CreatedAt,
+		})
+	}
+	return writeJSON(w, out)
+}
+
+func writeSession(w io.Writer, sess session.Session, messages []message.Message) {
+	fmt.Fprintf(w, "Title:     %s\n", sess.Title)
+	fmt.Fprintf(w, "ID:        %s\n", sess.ID)
+	if sess.ForkedFromSessionID != "" {
+		fmt.Fprintf(w, "Forked:    from %s\n", sess.ForkedFromSessionID)
+	}
+	fmt.Fprintf(w, "Created:   %s\n", formatUnix(sess.CreatedAt))
+	fmt.Fprintf(w, "Updated:   %s\n", formatUnix(sess.UpdatedAt))
+	fmt.Fprintf(w, "Messages:  %d\n", sess.MessageCount)
+	fmt.Fprintf(w, "Tokens:    %d in, %d out\n", sess.PromptTokens, sess.CompletionTokens)
+	fmt.Fprintf(w, "Cost:      $%.4f\n", sess.Cost)
+
+	for _, m := range messages {
+		switch m.Role {
+		case message.User:
+			fmt.Fprintf(w, "\n> %s\n", strings.ReplaceAll(strings.TrimSpace(m.Content().Text), "\n", "\n> "))
+		case message.Assistant:
+			if text := strings.TrimSpace(m.Content().Text); text != "" {
+				fmt.Fprintf(w, "\n%s\n", text)
+			}
+			for _, call := range m.ToolCalls() {
+				fmt.Fprintf(w, "  → %s %s\n", call.Name, truncate(call.Input, 80))
+			}
+		case message.Tool:
+			for _, result := range m.ToolResults() {
+				status := "ok"
+				if result.IsError {
+					status = "error"
+				}
+				fmt.Fprintf(w, "  ← %s %s\n", result.Name, status)
+			}
+		}
+	}
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len([]rune(s)) <= n {
+		return s
+	}
+	return string([]rune(s)[:n-1]) + "…"
+}
+
+func formatUnix(unix int64) string {
+	return time.Unix(unix, 0).Format("2006-01-02 15:04")
+}
@@ -0,0 +1,231 @@
+package codeindex
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// maxChunkLines is the size of the longest chunk, longer symbols are split.
+const maxChunkLines = 80
+
+// chunk is a part of a file, usually the definition of a symbol.
+type chunk struct {
+	Symbol    string
+	Kind      string
+	StartLine int // 1-based
+	EndLine   int // inclusive
+	Content   string
+}
+
+var (
+	// definitionRegex matches the lines that define a symbol in most
+	// languages, e.g. "export async function foo" or "pub fn foo".
+	definitionRegex = regexp.MustCompile(`^\s*(?:(?:export|default|pub(?:\([^)]*\))?|public|private|protected|internal|static|abstract|final|async|override|open|data|sealed)\s+)*(function|class|interface|enum|struct|trait|impl|fn|def|func|type|module|object|record|protocol|extension|namespace)\s+([\p{L}_$][\p{L}\p{N}_$]*)`)
+	// assignedFunctionRegex matches functions assigned to variables in
+	// JavaScript and TypeScript, e.g. "const foo = async (a) =>".
+	assignedFunctionRegex = regexp.MustCompile(`^\s*(?:export\s+)?(?:const|let|var)\s+([\p{L}_$][\p{L}\p{N}_$]*)\s*(?::[^=]+)?=\s*(?:async\s+)?(?:function\b|\([^)]*\)\s*(?::[^=]+)?=>|[\p{L}_$][\p{L}\p{N}_$]*\s*=>)`)
+	// headingRegex matches Markdown headings.
+	headingRegex = regexp.MustCompile(`^#{1,6}\s+(.+?)\s*#*$`)
+	// commentRegex matches the comment and annotation lines kept with the
+	// definition that follows them.
+	commentRegex = regexp.MustCompile(`^\s*(?://|#|/\*|\*|--|@|"""|'''|///)`)
+)
+
+// chunkFile splits a file into chunks, one per symbol it defines.
+func chunkFile(path, content string) []chunk {
+	var chunks []chunk
+	switch extension(path) {
+	case ".go":
+		var ok bool
+		if chunks, ok = chunkGo(path, content); !ok {
+			chunks = chunkLines(content, false)
+		}
+	case ".md", ".mdx", ".markdown":
+		chunks = chunkLines(content, true)
+	default:
+		chunks = chunkLines(content, false)
+	}
+
+	var result []chunk
+	for _, c := range chunks {
+		result = append(result, splitChunk(c)...)
+	}
+	return result
+}
+
+// chunkGo splits a Go file at its top-level declarations.
+func chunkGo(path, content string) ([]chunk, bool) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, path, content, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, false
+	}
+	lines := strings.Split(content, "\n")
+	add := func(chunks []chunk, symbol, kind string, doc *ast.CommentGroup, node ast.Node) []chunk {
+		start := fset.Position(node.Pos()).Line
+		if doc != nil {
+			start = fset.Position(doc.Pos()).Line
+		}
+		end := fset.Position(node.End()).Line
+		return append(chunks, chunk{
+			Symbol:    symbol,
+			Kind:      kind,
+			StartLine: start,
+			EndLine:   end,
+			Content:   strings.Join(lines[start-1:end], "\n"),
+		})
+	}
+
+	var chunks []chunk
+	for _, decl := range file.Decls {
+		switch decl := decl.(type) {
+		case *ast.FuncDecl:
+			symbol, kind := decl.Name.Name, "function"
+			if decl.Recv != nil && len(decl.Recv.List) > 0 {
+				symbol, kind = receiverType(decl.Recv.List[0].Type)+"."+symbol, "method"
+			}
+			chunks = add(chunks, symbol, kind, decl.Doc, decl)
+		case *ast.GenDecl:
+			switch decl.Tok {
+			case token.TYPE:
+				if len(decl.Specs) == 1 {
+					chunks = add(chunks, decl.Specs[0].(*ast.TypeSpec).Name.Name, "type", decl.Doc, decl)
+					continue
+				}
+				for _, spec := range decl.Specs {
+					spec := spec.(*ast.TypeSpec)
+					chunks = add(chunks, spec.Name.Name, "type", spec.Doc, spec)
+				}
+			case token.CONST, token.VAR:
+				var names []string
+				for _, spec := range decl.Specs {
+					for _, name := range spec.(*ast.ValueSpec).Names {
+						names = append(names, name.Name)
+					}
+				}
+				symbol := strings.Join(names, ", ")
+				if len(names) > 3 {
+					symbol = strings.Join(names[:3], ", ") + ", …"
+				}
+				chunks = add(chunks, symbol, decl.Tok.String(), decl.Doc, decl)
+			}
+		}
+	}
+	return chunks, true
+}
+
+// receiverType returns the name of the type of a method receiver.
+func receiverType(expr ast.Expr) string {
+	switch expr := expr.(type) {
+	case *ast.StarExpr:
+		return receiverType(expr.X)
+	case *ast.IndexExpr:
+		return receiverType(expr.X)
+	case *ast.IndexListExpr:
+		return receiverType(expr.X)
+	case *ast.Ident:
+		return expr.Name
+	default:
+		return fmt.Sprint(expr)
+	}
+}
+
+// chunkLines splits a file at the lines that look like definitions, or at
+// the headings of a Markdown file. The comments right above a definition
+// belong to its chunk. What comes before the first definition is a chunk of
+// its own.
+func chunkLines(content string, markdown bool) []chunk {
+	lines := strings.Split(content, "\n")
+	type definition struct {
+		line         int // 0-based
+		symbol, kind string
+	}
+	var definitions []definition
+	for i, line := range lines {
+		if markdown {
+			if m := headingRegex.FindStringSubmatch(line); m != nil {
+				definitions = append(definitions, definition{i, m[1], "section"})
+			}
+			continue
+		}
+		if m := definitionRegex.FindStringSubmatch(line); m != nil {
+			definitions = append(definitions, definition{i, m[2], m[1]})
+		} else if m := assignedFunctionRegex.FindStringSubmatch(line); m != nil {
+			definitions = append(definitions, definition{i, m[1], "function"})
+		}
+	}
+
+	// Start the chunks at the comments above the definitions.
+	starts := make([]int, len(definitions))
+	for i, def := range definitions {
+		start := def.line
+		limit := 0
+		if i > 0 {
+			limit = definitions[i-1].line + 1
+		}
+		for !markdown && start > limit && commentRegex.MatchString(lines[start-1]) {
+			start--
+		}
+		starts[i] = start
+	}
+
+	var chunks []chunk
+	addChunk := func(symbol, kind string, start, end int) {
+		text := strings.Join(lines[start:end], "\n")
+		if strings.TrimSpace(text) == "" {
+			return
+		}
+		chunks = append(chunks, chunk{
+			Symbol:    symbol,
+			Kind:      kind,
+			StartLine: start + 1,
+			EndLine:   end,
+			Content:   text,
+		})
+	}
+	first := len(lines)
+	if len(starts) > 0 {
+		first = starts[0]
+	}
+	addChunk("", "file", 0, first)
+	for i, def := range definitions {
+		end := len(lines)
+		if i+1 < len(definitions) {
+			end = starts[i+1]
+		}
+		addChunk(def.symbol, def.kind, starts[i], end)
+	}
+	return chunks
+}
+
+// splitChunk splits a chunk longer than maxChunkLines into parts of the same
+// symbol.
+func splitChunk(c chunk) []chunk {
+	lines := strings.Split(c.Content, "\n")
+	// Trailing blank lines don't need to be shown.
+	for len(lines) > 1 && strings.TrimSpace(lines[len(lines)-1]) == "" {
+		lines = lines[:len(lines)-1]
+	}
+	c.EndLine = c.StartLine + len(lines) - 1
+	if len(lines) <= maxChunkLines {
+		c.Content = strings.Join(lines, "\n")
+		return []chunk{c}
+	}
+
+	var parts []chunk
+	for start := 0; start < len(lines); start += maxChunkLines {
+		end := min(start+maxChunkLines, len(lines))
+		parts = append(parts, chunk{
+			Symbol:    c.Symbol,
+			Kind:      c.Kind,
+			StartLine: c.StartLine + start,
+			EndLine:   c.StartLine + end - 1,
+			Content:   strings.Join(lines[start:end], "\n"),
+		})
+	}
+	return parts
+}
@@ -0,0 +1,411 @@
+// Package codeindex keeps a search index of the source files of the working
+// directory. Files are split into chunks, one per symbol, and the terms of
+// each chunk are stored in the database, so chunks can be ranked against a
+// natural language query with BM25.
+package codeindex
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/charlievieth/fastwalk"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/fsext"
+)
+
+const (
+	// maxFileSize is the size of the largest file that is indexed.
+	maxFileSize = 512 * 1024
+	// symbolBoost is how many times the terms of the symbol and of the
+	// path of a chunk count.
+	symbolBoost = 3
+
+	// The BM25 parameters.
+	k1 = 1.2
+	b  = 0.75
+)
+
+// indexedExtensions are the extensions of the files that are indexed.
+var indexedExtensions = map[string]bool{
+	".go": true, ".py": true, ".js": true, ".jsx": true, ".mjs": true,
+	".cjs": true, ".ts": true, ".tsx": true, ".java": true, ".kt": true,
+	".kts": true, ".scala": true, ".rs": true, ".c": true, ".h": true,
+	".cc": true, ".cpp": true, ".cxx": true, ".hpp": true, ".cs": true,
+	".swift": true, ".m": true, ".rb": true, ".php": true, ".lua": true,
+	".dart": true, ".ex": true, ".exs": true, ".erl": true, ".hs": true,
+	".ml": true, ".clj": true, ".zig": true, ".nim": true, ".sh": true,
+	".bash": true, ".zsh": true, ".fish": true, ".sql": true, ".proto": true,
+	".graphql": true, ".vue": true, ".svelte": true, ".md": true,
+	".mdx": true, ".markdown": true,
+}
+
+// Result is a chunk that matches a query.
+type Result struct {
+	Path      string
+	Symbol    string
+	Kind      string
+	StartLine int
+	EndLine   int
+	Content   string
+	Score     float64
+}
+
+type Service interface {
+	// Update brings the index up to date. It walks the whole working
+	// directory, unless changes to files are being reported with Changed:
+	// then only the first update walks it, and later ones reindex the
+	// reported files.
+	Update(ctx context.Context) error
+	// Changed reports that a file was created, written or removed.
+	Changed(path string)
+	// Search updates the index and returns the chunks that best match
+	// the query, best first.
+	Search(ctx context.Context, query string, limit int) ([]Result, error)
+}
+
+type service struct {
+	q        *db.Queries
+	db       *sql.DB
+	root     string
+	watching func() bool
+
+	// mu serializes updates.
+	mu      sync.Mutex
+	updated bool
+
+	pendingMu sync.Mutex
+	pending   map[string]struct{}
+}
+
+// NewService creates a code index of root. watching reports whether changes
+// to files, including those made outside of Crush, are reported with
+// Changed; it can be nil if they aren't.
+func NewService(q *db.Queries, db *sql.DB, root string, watching func() bool) Service {
+	return &service{
+		q:        q,
+		db:       db,
+		root:     root,
+		watching: watching,
+		pending:  make(map[string]struct{}),
+	}
+}
+
+func (s *service) Changed(path string) {
+	rel, ok := s.relative(path)
+	if !ok {
+		return
+	}
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+	s.pending[rel] = struct{}{}
+}
+
+func (s *service) Update(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pendingMu.Lock()
+	pending := s.pending
+	s.pending = make(map[string]struct{})
+	s.pendingMu.Unlock()
+
+	// Without a watcher, files changed by commands or other programs are
+	// only found by walking again.
+	if !s.updated || s.watching == nil || !s.watching() {
+		if err := s.updateAll(ctx); err != nil {
+			return err
+		}
+		s.updated = true
+		return nil
+	}
+
+	walker := fsext.NewFastGlobWalker(s.root)
+	for rel := range pending {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !indexedExtensions[extension(rel)] {
+			continue
+		}
+		path := filepath.Join(s.root, filepath.FromSlash(rel))
+		info, err := os.Stat(path)
+		if err != nil || !info.Mode().IsRegular() || !indexed(path, info) || walker.ShouldSkip(path) {
+			if err := s.remove(ctx, rel); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := s.index(ctx, rel, info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateAll indexes the files of the working directory that changed since
+// they were indexed, and removes the files that no longer exist.
+func (s *service) updateAll(ctx context.Context) error {
+	files, err := s.q.ListCodeIndexFiles(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list indexed files: %w", err)
+	}
+	known := make(map[string]db.CodeIndexFile, len(files))
+	for _, file := range files {
+		known[file.Path] = file
+	}
+
+	walker := fsext.NewFastGlobWalker(s.root)
+	var mu sync.Mutex
+	found := make(map[string]fs.FileInfo)
+	conf := fastwalk.Config{Follow: true}
+	err = fastwalk.Walk(&conf, s.root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil // Skip files we can't access
+		}
+		if d.IsDir() {
+			if path != s.root && walker.ShouldSkip(path) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if walker.ShouldSkip(path) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() || !indexed(path, info) {
+			return nil
+		}
+		rel, ok := s.relative(path)
+		if !ok {
+			return nil
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		found[rel] = info
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to walk %s: %w", s.root, err)
+	}
+
+	for rel, info := range found {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if file, ok := known[rel]; ok && file.ModTime == info.ModTime().UnixNano() && file.Size == info.Size() {
+			continue
+		}
+		if err := s.index(ctx, rel, info); err != nil {
+			return err
+		}
+	}
+	for rel := range known {
+		if _, ok := found[rel]; !ok {
+			if err := s.remove(ctx, rel); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// index replaces the chunks of a file with the ones of its current content.
+func (s *service) index(ctx context.Context, rel string, info fs.FileInfo) error {
+	content, err := os.ReadFile(filepath.Join(s.root, filepath.FromSlash(rel)))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return s.remove(ctx, rel)
+		}
+		return fmt.Errorf("failed to read %s: %w", rel, err)
+	}
+	var chunks []chunk
+	// Binary files are recorded without chunks, so they aren't read again.
+	if !bytes.Contains(content[:min(len(content), 8000)], []byte{0}) {
+		chunks = chunkFile(rel, string(content))
+	}
+
+	return s.transaction(ctx, func(q *db.Queries) error {
+		if err := deleteChunks(ctx, q, rel); err != nil {
+			return err
+		}
+		pathTerms := terms(rel)
+		for _, c := range chunks {
+			frequencies := make(map[string]int64)
+			length := int64(0)
+			for _, term := range terms(c.Content) {
+				frequencies[term]++
+				length++
+			}
+			for _, term := range append(terms(c.Symbol), pathTerms...) {
+				frequencies[term] += symbolBoost
+				length += symbolBoost
+			}
+			id, err := q.CreateCodeChunk(ctx, db.CreateCodeChunkParams{
+				Path:      rel,
+				Symbol:    c.Symbol,
+				Kind:      c.Kind,
+				StartLine: int64(c.StartLine),
+				EndLine:   int64(c.EndLine),
+				Content:   c.Content,
+				Length:    length,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create chunk: %w", err)
+			}
+			for term, frequency := range frequencies {
+				if err := q.CreateCodeTerm(ctx, db.CreateCodeTermParams{
+					Term:      term,
+					ChunkID:   id,
+					Frequency: frequency,
+				}); err != nil {
+					return fmt.Errorf("failed to create term: %w", err)
+				}
+			}
+		}
+		if err := q.UpsertCodeIndexFile(ctx, db.UpsertCodeIndexFileParams{
+			Path:    rel,
+			ModTime: info.ModTime().UnixNano(),
+			Size:    info.Size(),
+		}); err != nil {
+			return fmt.Errorf("failed to update indexed file: %w", err)
+		}
+		return nil
+	})
+}
+
+// remove removes a file from the index.
+func (s *service) remove(ctx context.Context, rel string) error {
+	return s.transaction(ctx, func(q *db.Queries) error {
+		if err := deleteChunks(ctx, q, rel); err != nil {
+			return err
+		}
+		if err := q.DeleteCodeIndexFile(ctx, rel); err != nil {
+			return fmt.Errorf("failed to delete indexed file: %w", err)
+		}
+		return nil
+	})
+}
+
+func (s *service) transaction(ctx context.Context, fn func(q *db.Queries) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	if err := fn(s.q.WithTx(tx)); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func deleteChunks(ctx context.Context, q *db.Queries, rel string) error {
+	if err := q.DeleteCodeTermsByPath(ctx, rel); err != nil {
+		return fmt.Errorf("failed to delete terms: %w", err)
+	}
+	if err := q.DeleteCodeChunksByPath(ctx, rel); err != nil {
+		return fmt.Errorf("failed to delete chunks: %w", err)
+	}
+	return nil
+}
+
+func (s *service) Search(ctx context.Context, query string, limit int) ([]Result, error) {
+	if err := s.Update(ctx); err != nil {
+		return nil, fmt.Errorf("failed to update the code index: %w", err)
+	}
+
+	queryTerms := slices.Compact(slices.Sorted(slices.Values(terms(query))))
+	if len(queryTerms) == 0 {
+		return nil, nil
+	}
+	stats, err := s.q.GetCodeIndexStats(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index stats: %w", err)
+	}
+	if stats.ChunkCount == 0 {
+		return nil, nil
+	}
+
+	chunkCount := float64(stats.ChunkCount)
+	scores := make(map[int64]float64)
+	for _, term := range queryTerms {
+		postings, err := s.q.ListCodeTermChunks(ctx, term)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %q: %w", term, err)
+		}
+		df := float64(len(postings))
+		idf := math.Log(1 + (chunkCount-df+0.5)/(df+0.5))
+		for _, p := range postings {
+			tf := float64(p.Frequency)
+			norm := 1 - b + b*float64(p.Length)/max(stats.AverageLength, 1)
+			scores[p.ChunkID] += idf * tf * (k1 + 1) / (tf + k1*norm)
+		}
+	}
+
+	ids := make([]int64, 0, len(scores))
+	for id := range scores {
+		ids = append(ids, id)
+	}
+	slices.SortFunc(ids, func(a, b int64) int {
+		if scores[a] != scores[b] {
+			if scores[a] > scores[b] {
+				return -1
+			}
+			return 1
+		}
+		return cmp.Compare(a, b)
+	})
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+	}
+
+	results := make([]Result, 0, len(ids))
+	for _, id := range ids {
+		c, err := s.q.GetCodeChunk(ctx, id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chunk: %w", err)
+		}
+		results = append(results, Result{
+			Path:      filepath.Join(s.root, filepath.FromSlash(c.Path)),
+			Symbol:    c.Symbol,
+			Kind:      c.Kind,
+			StartLine: int(c.StartLine),
+			EndLine:   int(c.EndLine),
+			Content:   c.Content,
+			Score:     scores[id],
+		})
+	}
+	return results, nil
+}
+
+// relative returns the path relative to the working directory, with forward
+// slashes, and reports whether the path is inside of it.
+func (s *service) relative(path string) (string, bool) {
+	rel, err := filepath.Rel(s.root, path)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
+}
+
+// indexed reports whether a file should be indexed.
+func indexed(path string, info fs.FileInfo) bool {
+	return info.Size() <= maxFileSize && indexedExtensions[extension(path)]
+}
+
+func extension(path string) string {
+	return strings.ToLower(filepath.Ext(path))
+}
@@ -0,0 +1,152 @@
+package codeindex
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/stretchr/testify/require"
+)
+
+const serverSource = `package server
+
+// Server handles HTTP requests.
+type Server struct {
+	addr string
+}
+
+// ParseHTTPHeader parses a raw request header line.
+func ParseHTTPHeader(line string) (string, string) {
+	return line, ""
+}
+
+// Listen starts accepting connections.
+func (s *Server) Listen() error {
+	return nil
+}
+`
+
+const retrySource = `// retries a failed upload with exponential backoff
+export async function retryUpload(file) {
+  return backoff(() => upload(file));
+}
+
+const backoff = async (fn) => {
+  return fn();
+};
+`
+
+func TestSearch(t *testing.T) {
+	ctx := t.Context()
+	root := t.TempDir()
+	write(t, root, "server/server.go", serverSource)
+	write(t, root, "web/upload.js", retrySource)
+	write(t, root, "ignored/secret.go", "package ignored\n\nfunc ParseHTTPHeader() {}\n")
+	write(t, root, ".gitignore", "ignored/\n")
+
+	conn, err := db.Connect(ctx, t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	watching := true
+	index := NewService(db.New(conn), conn, root, func() bool { return watching })
+
+	results, err := index.Search(ctx, "where are http headers parsed", 3)
+	require.NoError(t, err)
+	require.NotEmpty(t, results)
+	require.Equal(t, filepath.Join(root, "server", "server.go"), results[0].Path)
+	require.Equal(t, "ParseHTTPHeader", results[0].Symbol)
+	require.Equal(t, "function", results[0].Kind)
+	require.Equal(t, 8, results[0].StartLine)
+	require.Equal(t, 11, results[0].EndLine)
+	for _, result := range results {
+		require.NotContains(t, result.Path, "ignored")
+	}
+
+	results, err = index.Search(ctx, "retry uploads", 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "retryUpload", results[0].Symbol)
+
+	// Changed files are reindexed on the next search.
+	require.NoError(t, os.Remove(filepath.Join(root, "web", "upload.js")))
+	index.Changed(filepath.Join(root, "web", "upload.js"))
+	write(t, root, "web/download.ts", "export function resumeDownload(url: string) {}\n")
+	index.Changed(filepath.Join(root, "web", "download.ts"))
+
+	results, err = index.Search(ctx, "retry uploads", 1)
+	require.NoError(t, err)
+	require.Empty(t, results)
+	results, err = index.Search(ctx, "resume downloads", 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "resumeDownload", results[0].Symbol)
+
+	// A new service only reindexes the files changed since the last run.
+	write(t, root, "server/server.go", serverSource+"\nfunc Shutdown() {}\n")
+	later := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(filepath.Join(root, "server", "server.go"), later, later))
+	index = NewService(db.New(conn), conn, root, func() bool { return watching })
+	results, err = index.Search(ctx, "shutdown", 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "Shutdown", results[0].Symbol)
+	results, err = index.Search(ctx, "resume downloads", 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+
+	// Without a watcher, files changed without being reported are found
+	// by walking again.
+	write(t, root, "web/cache.ts", "export function evictCache() {}\n")
+	results, err = index.Search(ctx, "evict cache", 1)
+	require.NoError(t, err)
+	require.Empty(t, results)
+	watching = false
+	results, err = index.Search(ctx, "evict cache", 1)
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	require.Equal(t, "evictCache", results[0].Symbol)
+}
+
+func TestChunkFile(t *testing.T) {
+	chunks := chunkFile("server.go", serverSource)
+	var symbols []string
+	for _, c := range chunks {
+		symbols = append(symbols, c.Kind+" "+c.Symbol)
+	}
+	require.Equal(t, []string{"type Server", "function ParseHTTPHeader", "method Server.Listen"}, symbols)
+
+	chunks = chunkFile("upload.js", retrySource)
+	require.Len(t, chunks, 2)
+	require.Equal(t, "retryUpload", chunks[0].Symbol)
+	require.Equal(t, 1, chunks[0].StartLine)
+	require.Equal(t, 4, chunks[0].EndLine)
+	require.Equal(t, "backoff", chunks[1].Symbol)
+
+	long := "# Title\n\nintro\n\n## Usage\n"
+	for range maxChunkLines + 10 {
+		long += "line\n"
+	}
+	chunks = chunkFile("README.md", long)
+	require.Len(t, chunks, 3)
+	require.Equal(t, "Title", chunks[0].Symbol)
+	require.Equal(t, "Usage", chunks[1].Symbol)
+	require.Equal(t, 5, chunks[1].StartLine)
+	require.Equal(t, 5+maxChunkLines-1, chunks[1].EndLine)
+	require.Equal(t, "Usage", chunks[2].Symbol)
+}
+
+func TestTerms(t *testing.T) {
+	require.Equal(t, []string{"parse", "http", "header", "parsehttpheader"}, terms("parseHTTPHeader"))
+	require.Equal(t, []string{"retry", "upload", "retryupload"}, terms("retry_uploads"))
+	require.Equal(t, []string{"request", "request", "request"}, terms("the requests requested requesting"))
+	require.Empty(t, terms("a 42 of"))
+}
+
+func write(t *testing.T, root, name, content string) {
+	t.Helper()
+	path := filepath.Join(root, filepath.FromSlash(name))
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+}
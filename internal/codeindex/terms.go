@@ -0,0 +1,98 @@
+package codeindex
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// identifierRegex matches the words and identifiers of source code and text.
+var identifierRegex = regexp.MustCompile(`[\p{L}\p{N}_]+`)
+
+// stopWords are left out of the index and of queries.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "can": true, "do": true, "does": true, "for": true,
+	"from": true, "how": true, "in": true, "is": true, "it": true, "its": true,
+	"of": true, "on": true, "or": true, "that": true, "the": true, "this": true,
+	"to": true, "what": true, "when": true, "where": true, "which": true,
+	"who": true, "why": true, "with": true,
+}
+
+// terms returns the terms of text. Identifiers are split into their words,
+// e.g. "parseHTTPHeader" gives "parse", "http" and "header", and are kept
+// whole as well, so a query can name them.
+func terms(text string) []string {
+	var result []string
+	for _, word := range identifierRegex.FindAllString(text, -1) {
+		parts := splitIdentifier(word)
+		for _, part := range parts {
+			if term, ok := normalize(part); ok {
+				result = append(result, term)
+			}
+		}
+		if len(parts) > 1 {
+			if term, ok := normalize(strings.ReplaceAll(word, "_", "")); ok {
+				result = append(result, term)
+			}
+		}
+	}
+	return result
+}
+
+// splitIdentifier splits an identifier at underscores and case changes.
+func splitIdentifier(word string) []string {
+	var parts []string
+	for _, part := range strings.Split(word, "_") {
+		runes := []rune(part)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			prev, cur := runes[i-1], runes[i]
+			next := rune(0)
+			if i+1 < len(runes) {
+				next = runes[i+1]
+			}
+			// fooBar, HTTPServer and foo2Bar are split before the B and S.
+			if (unicode.IsLower(prev) && unicode.IsUpper(cur)) ||
+				(unicode.IsUpper(prev) && unicode.IsUpper(cur) && unicode.IsLower(next)) ||
+				(unicode.IsDigit(prev) && unicode.IsLetter(cur)) {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			parts = append(parts, string(runes[start:]))
+		}
+	}
+	return parts
+}
+
+// normalize lowercases and stems a word, and reports whether it is a term.
+func normalize(word string) (string, bool) {
+	word = strings.ToLower(word)
+	if len(word) < 2 || stopWords[word] || strings.IndexFunc(word, unicode.IsLetter) < 0 {
+		return "", false
+	}
+	return stem(word), true
+}
+
+// stem removes the common English suffixes of a word, so "requests",
+// "requested" and "requesting" are the same term. It doesn't aim to be
+// correct, only to treat the words of the code and of queries the same.
+func stem(word string) string {
+	switch {
+	case len(word) > 4 && strings.HasSuffix(word, "ies"):
+		return word[:len(word)-3] + "y"
+	case len(word) > 5 && strings.HasSuffix(word, "ing"):
+		return word[:len(word)-3]
+	case len(word) > 4 && strings.HasSuffix(word, "ed"):
+		return word[:len(word)-2]
+	case len(word) > 4 && strings.HasSuffix(word, "sses"):
+		return word[:len(word)-2]
+	case len(word) > 3 && strings.HasSuffix(word, "s") &&
+		!strings.HasSuffix(word, "ss") && !strings.HasSuffix(word, "us") && !strings.HasSuffix(word, "is"):
+		return word[:len(word)-1]
+	default:
+		return word
+	}
+}
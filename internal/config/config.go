@@ -14,6 +14,7 @@ import (
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/env"
+	"github.com/charmbracelet/crush/internal/ollama"
 	"github.com/tidwall/sjson"
 )
 
@@ -62,8 +63,12 @@ type SelectedModel struct {
 	// Overrides the default model configuration.
 	MaxTokens int64 `json:"max_tokens,omitempty" jsonschema:"description=Maximum number of tokens for model responses,minimum=1,maximum=200000,example=4096"`
 
-	// Used by anthropic models that can reason to indicate if the model should think.
-	Think bool `json:"think,omitempty" jsonschema:"description=Enable thinking mode for Anthropic models that support reasoning"`
+	// Used by anthropic and ollama models that can reason to indicate if the model should think.
+	Think bool `json:"think,omitempty" jsonschema:"description=Enable thinking mode for Anthropic and Ollama models that support reasoning"`
+
+	// Models to switch to, in order, when the provider of this one keeps
+	// failing. Their own fallbacks are ignored.
+	Fallbacks []SelectedModel `json:"fallbacks,omitempty" jsonschema:"description=Models to switch to in order when the provider of this model fails with a non-retryable error"`
 }
 
 type ProviderConfig struct {
@@ -74,7 +79,7 @@ type ProviderConfig struct {
 	// The provider's API endpoint.
 	BaseURL string `json:"base_url,omitempty" jsonschema:"description=Base URL for the provider's API,format=uri,example=https://api.openai.com/v1"`
 	// The provider type, e.g. "openai", "anthropic", etc. if empty it defaults to openai.
-	Type catwalk.Type `json:"type,omitempty" jsonschema:"description=Provider type that determines the API format,enum=openai,enum=anthropic,enum=gemini,enum=azure,enum=vertexai,default=openai"`
+	Type catwalk.Type `json:"type,omitempty" jsonschema:"description=Provider type that determines the API format,enum=openai,enum=anthropic,enum=gemini,enum=azure,enum=vertexai,enum=ollama,default=openai"`
 	// The provider's API key.
 	APIKey string `json:"api_key,omitempty" jsonschema:"description=API key for authentication with the provider,example=$OPENAI_API_KEY"`
 	// Marks the provider as disabled.
@@ -91,10 +96,41 @@ type ProviderConfig struct {
 	// Used to pass extra parameters to the provider.
 	ExtraParams map[string]string `json:"-"`
 
+	// The API used by openai providers, defaults to chat completions.
+	API OpenAIAPI `json:"api,omitempty" jsonschema:"description=API used by openai providers: chat_completions or responses for the Responses API with reasoning summaries,enum=chat_completions,enum=responses,default=chat_completions"`
+
+	// Retry policy for rate limits and transient errors.
+	Retry *RetryConfig `json:"retry,omitempty" jsonschema:"description=Retry policy for rate limits and transient errors"`
+
 	// The provider models
 	Models []catwalk.Model `json:"models,omitempty" jsonschema:"description=List of models available from this provider"`
 }
 
+// OpenAIAPI is the API spoken by an openai provider.
+type OpenAIAPI string
+
+const (
+	// OpenAIAPIChatCompletions is the Chat Completions API, supported by most
+	// OpenAI-compatible providers.
+	OpenAIAPIChatCompletions OpenAIAPI = "chat_completions"
+	// OpenAIAPIResponses is the Responses API, which streams reasoning
+	// summaries and carries encrypted reasoning over between requests.
+	OpenAIAPIResponses OpenAIAPI = "responses"
+)
+
+// TypeOllama is the type of providers speaking the native Ollama API. Their
+// models are discovered from the server when none are configured.
+const TypeOllama catwalk.Type = "ollama"
+
+type RetryConfig struct {
+	// Maximum number of retries, 0 uses the default and a negative value disables retries.
+	MaxRetries int `json:"max_retries,omitempty" jsonschema:"description=Maximum number of retries for rate limits and transient errors (-1 disables retries),default=8,example=3"`
+	// Delay before the first retry in milliseconds, doubled on every retry.
+	InitialBackoff int `json:"initial_backoff_ms,omitempty" jsonschema:"description=Delay in milliseconds before the first retry (doubled on every retry),default=2000"`
+	// Upper bound for the delay between retries in milliseconds.
+	MaxBackoff int `json:"max_backoff_ms,omitempty" jsonschema:"description=Maximum delay in milliseconds between retries,default=60000"`
+}
+
 type MCPType string
 
 const (
@@ -134,6 +170,35 @@ type TUIOptions struct {
 type Permissions struct {
 	AllowedTools []string `json:"allowed_tools,omitempty" jsonschema:"description=List of tools that don't require permission prompts,example=bash,example=view"` // Tools that don't require permission prompts
 	SkipRequests bool     `json:"-"`                                                                                                                              // Automatically accept all permissions (YOLO mode)
+	Allow        []string `json:"allow,omitempty" jsonschema:"description=Rules for tool calls that don't require permission prompts: a tool name optionally followed by a colon and a regular expression matching each command of the bash command line or a glob matching the file path,example=bash:go test ./...,example=edit:internal/**"`
+	Deny         []string `json:"deny,omitempty" jsonschema:"description=Rules for tool calls that are always denied; same format as allow and taking precedence over it,example=write:**/*.pem,example=bash:rm -rf .*"`
+	// SafeCommands and BannedCommands are checked against every command of
+	// a bash command line, including pipelines and command substitutions.
+	SafeCommands   []string `json:"safe_commands,omitempty" jsonschema:"description=Commands the bash tool runs without permission prompts when every command of the command line is safe; each is the leading words of a command,example=go test,example=make lint"`
+	BannedCommands []string `json:"banned_commands,omitempty" jsonschema:"description=Programs the bash tool never runs; in addition to the built-in ones,example=docker,example=kubectl"`
+}
+
+// Hook is a command run at a defined point of the agent loop, with the event
+// as JSON on its standard input.
+type Hook struct {
+	Command string `json:"command" jsonschema:"description=Shell command to run,example=gofmt -l -w ."`
+	Matcher string `json:"matcher,omitempty" jsonschema:"description=Regular expression matching the names of the tools the hook runs for (all tools when empty); only used by tool call hooks,example=edit|multiedit|write"`
+	Timeout int    `json:"timeout,omitempty" jsonschema:"description=Timeout in seconds,default=60,example=120"`
+}
+
+type Hooks struct {
+	PreToolCall  []Hook `json:"pre_tool_call,omitempty" jsonschema:"description=Hooks run before a tool call; exiting with code 2 refuses the call with stderr as the reason and printing JSON to stdout replaces the tool input"`
+	PostToolCall []Hook `json:"post_tool_call,omitempty" jsonschema:"description=Hooks run after a tool call; their output is added to the tool result"`
+	PromptSubmit []Hook `json:"prompt_submit,omitempty" jsonschema:"description=Hooks run when a prompt is submitted; their output is added to the prompt and exiting with code 2 blocks it"`
+	TurnEnd      []Hook `json:"turn_end,omitempty" jsonschema:"description=Hooks run when the agent finishes a turn; exiting with code 2 makes the agent continue with stderr as the next prompt (at most 3 times in a row)"`
+}
+
+// Sandbox restricts what the commands run by the bash tool can change.
+type Sandbox struct {
+	Enabled       bool     `json:"enabled,omitempty" jsonschema:"description=Run the commands of the bash tool in a sandbox that can only write to the working directory and the temporary directory and has no network access; in the built-in sandbox without network access, commands not known to be risky don't ask for permission,default=false"`
+	AllowNetwork  bool     `json:"allow_network,omitempty" jsonschema:"description=Let sandboxed commands access the network,default=false"`
+	WritablePaths []string `json:"writable_paths,omitempty" jsonschema:"description=Additional paths sandboxed commands can write to,example=~/.cache/go-build,example=~/go/pkg/mod"`
+	Command       []string `json:"command,omitempty" jsonschema:"description=Wrapper command every sandboxed command is appended to instead of using the built-in sandbox, which needs Linux; {workdir} is replaced with the working directory,example=bwrap,example=--ro-bind,example=/,example=/,example=--bind,example={workdir},example={workdir},example=--unshare-net,example=--"`
 }
 
 type Options struct {
@@ -209,30 +274,58 @@ func (m MCPConfig) ResolvedHeaders() map[string]string {
 }
 
 type Agent struct {
-	ID          string `json:"id,omitempty"`
-	Name        string `json:"name,omitempty"`
-	Description string `json:"description,omitempty"`
-	// This is the id of the system prompt used by the agent
-	Disabled bool `json:"disabled,omitempty"`
+	ID          string `json:"id,omitempty" jsonschema:"description=Unique identifier for the agent,example=reviewer"`
+	Name        string `json:"name,omitempty" jsonschema:"description=Human-readable name for the agent,example=Reviewer"`
+	Description string `json:"description,omitempty" jsonschema:"description=Description of what the agent is for"`
+	// Marks the agent as disabled.
+	Disabled bool `json:"disabled,omitempty" jsonschema:"description=Whether this agent is disabled,default=false"`
 
-	Model SelectedModelType `json:"model" jsonschema:"required,description=The model type to use for this agent,enum=large,enum=small,default=large"`
+	Model SelectedModelType `json:"model,omitempty" jsonschema:"description=The model type to use for this agent,enum=large,enum=small,default=large"`
+
+	// Path to a file containing the system prompt for the agent, relative
+	// to the working directory. If empty the built-in prompt is used.
+	SystemPromptFile string `json:"system_prompt_file,omitempty" jsonschema:"description=Path to a file containing the system prompt for this agent,example=.crush/agents/reviewer.md"`
 
 	// The available tools for the agent
 	//  if this is nil, all tools are available
-	AllowedTools []string `json:"allowed_tools,omitempty"`
+	AllowedTools []string `json:"allowed_tools,omitempty" jsonschema:"description=List of tools available to this agent (all tools when omitted),example=view,example=grep"`
 
 	// this tells us which MCPs are available for this agent
 	//  if this is empty all mcps are available
 	//  the string array is the list of tools from the AllowedMCP the agent has available
 	//  if the string array is nil, all tools from the AllowedMCP are available
-	AllowedMCP map[string][]string `json:"allowed_mcp,omitempty"`
+	AllowedMCP map[string][]string `json:"allowed_mcp,omitempty" jsonschema:"description=MCP servers and their tools available to this agent (all when omitted)"`
 
 	// The list of LSPs that this agent can use
 	//  if this is nil, all LSPs are available
-	AllowedLSP []string `json:"allowed_lsp,omitempty"`
+	AllowedLSP []string `json:"allowed_lsp,omitempty" jsonschema:"description=List of LSP servers available to this agent (all when omitted)"`
 
 	// Overrides the context paths for this agent
-	ContextPaths []string `json:"context_paths,omitempty"`
+	ContextPaths []string `json:"context_paths,omitempty" jsonschema:"description=Context paths for this agent (defaults to the global context paths)"`
+}
+
+type Agents map[string]Agent
+
+// Sorted returns the enabled agents sorted by ID, with the coder agent
+// first.
+func (a Agents) Sorted() []Agent {
+	sorted := make([]Agent, 0, len(a))
+	for _, agent := range a {
+		if agent.Disabled {
+			continue
+		}
+		sorted = append(sorted, agent)
+	}
+	slices.SortFunc(sorted, func(a, b Agent) int {
+		switch {
+		case a.ID == AgentCoder:
+			return -1
+		case b.ID == AgentCoder:
+			return 1
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+	return sorted
 }
 
 // Config holds the configuration for crush.
@@ -253,10 +346,14 @@ type Config struct {
 
 	Permissions *Permissions `json:"permissions,omitempty" jsonschema:"description=Permission settings for tool usage"`
 
+	Agents Agents `json:"agents,omitempty" jsonschema:"description=Agent configurations, merged with the built-in coder and task agents"`
+
+	Hooks *Hooks `json:"hooks,omitempty" jsonschema:"description=Commands run before and after tool calls, when a prompt is submitted and when the agent finishes a turn"`
+
+	Sandbox *Sandbox `json:"sandbox,omitempty" jsonschema:"description=Sandbox for the commands of the bash tool"`
+
 	// Internal
 	workingDir string `json:"-"`
-	// TODO: most likely remove this concept when I come back to it
-	Agents map[string]Agent `json:"-"`
 	// TODO: find a better way to do this this should probably not be part of the config
 	resolver       VariableResolver
 	dataConfigDir  string             `json:"-"`
@@ -336,6 +433,16 @@ func (c *Config) SetCompactMode(enabled bool) error {
 	return c.SetConfigField("options.tui.compact_mode", enabled)
 }
 
+// SetMCPDisabled saves whether an MCP server is disabled, so it's started or
+// not the next time Crush starts.
+func (c *Config) SetMCPDisabled(name string, disabled bool) error {
+	key := "mcp." + strings.ReplaceAll(name, ".", `\.`) + ".disabled"
+	if err := c.SetConfigField(key, disabled); err != nil {
+		return fmt.Errorf("failed to save mcp %s: %w", name, err)
+	}
+	return nil
+}
+
 func (c *Config) Resolve(key string) (string, error) {
 	if c.resolver == nil {
 		return "", fmt.Errorf("no variable resolver configured")
@@ -344,6 +451,10 @@ func (c *Config) Resolve(key string) (string, error) {
 }
 
 func (c *Config) UpdatePreferredModel(modelType SelectedModelType, model SelectedModel) error {
+	// The fallbacks don't depend on the model, keep them when switching.
+	if model.Fallbacks == nil {
+		model.Fallbacks = c.Models[modelType].Fallbacks
+	}
 	c.Models[modelType] = model
 	if err := c.SetConfigField(fmt.Sprintf("models.%s", modelType), model); err != nil {
 		return fmt.Errorf("failed to update preferred model: %w", err)
@@ -415,23 +526,29 @@ func (c *Config) SetProviderAPIKey(providerID, apiKey string) error {
 	return nil
 }
 
+const (
+	AgentCoder = "coder"
+	AgentTask  = "task"
+)
+
 func (c *Config) SetupAgents() {
-	agents := map[string]Agent{
-		"coder": {
-			ID:           "coder",
+	agents := Agents{
+		AgentCoder: {
+			ID:           AgentCoder,
 			Name:         "Coder",
 			Description:  "An agent that helps with executing coding tasks.",
 			Model:        SelectedModelTypeLarge,
 			ContextPaths: c.Options.ContextPaths,
 			// All tools allowed
 		},
-		"task": {
-			ID:           "task",
+		AgentTask: {
+			ID:           AgentTask,
 			Name:         "Task",
 			Description:  "An agent that helps with searching for context and finding implementation details.",
 			Model:        SelectedModelTypeLarge,
 			ContextPaths: c.Options.ContextPaths,
 			AllowedTools: []string{
+				"codesearch",
 				"glob",
 				"grep",
 				"ls",
@@ -443,9 +560,57 @@ func (c *Config) SetupAgents() {
 			AllowedLSP: []string{},
 		},
 	}
+
+	// Merge the user defined agents on top of the built-in ones.
+	for id, userAgent := range c.Agents {
+		agent, builtin := agents[id]
+		if !builtin {
+			agent = Agent{
+				ID:           id,
+				Name:         id,
+				Model:        SelectedModelTypeLarge,
+				ContextPaths: c.Options.ContextPaths,
+			}
+		}
+		if userAgent.Name != "" {
+			agent.Name = userAgent.Name
+		}
+		if userAgent.Description != "" {
+			agent.Description = userAgent.Description
+		}
+		if userAgent.Model != "" {
+			agent.Model = userAgent.Model
+		}
+		if userAgent.SystemPromptFile != "" {
+			agent.SystemPromptFile = userAgent.SystemPromptFile
+		}
+		if userAgent.AllowedTools != nil {
+			agent.AllowedTools = userAgent.AllowedTools
+		}
+		if userAgent.AllowedMCP != nil {
+			agent.AllowedMCP = userAgent.AllowedMCP
+		}
+		if userAgent.AllowedLSP != nil {
+			agent.AllowedLSP = userAgent.AllowedLSP
+		}
+		if userAgent.ContextPaths != nil {
+			agent.ContextPaths = userAgent.ContextPaths
+		}
+		agent.Disabled = userAgent.Disabled && id != AgentCoder && id != AgentTask
+		agents[id] = agent
+	}
 	c.Agents = agents
 }
 
+// Agent returns the enabled agent with the given ID.
+func (c *Config) Agent(id string) (Agent, bool) {
+	agent, ok := c.Agents[id]
+	if !ok || agent.Disabled {
+		return Agent{}, false
+	}
+	return agent, true
+}
+
 func (c *Config) Resolver() VariableResolver {
 	return c.resolver
 }
@@ -476,6 +641,15 @@ func (c *ProviderConfig) TestConnection(resolver VariableResolver) error {
 			baseURL = "https://generativelanguage.googleapis.com"
 		}
 		testURL = baseURL + "/v1beta/models?key=" + url.QueryEscape(apiKey)
+	case TypeOllama:
+		baseURL, _ := resolver.ResolveValue(c.BaseURL)
+		if baseURL == "" {
+			baseURL = ollama.DefaultBaseURL
+		}
+		testURL = strings.TrimSuffix(baseURL, "/") + "/api/tags"
+		if apiKey != "" {
+			headers["Authorization"] = "Bearer " + apiKey
+		}
 	}
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
 	defer cancel()
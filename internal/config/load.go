@@ -17,6 +17,7 @@ import (
 	"github.com/charmbracelet/crush/internal/env"
 	"github.com/charmbracelet/crush/internal/fsext"
 	"github.com/charmbracelet/crush/internal/log"
+	"github.com/charmbracelet/crush/internal/ollama"
 )
 
 const defaultCatwalkURL = "https://catwalk.charm.sh"
@@ -186,6 +187,8 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			ExtraHeaders:       headers,
 			ExtraBody:          config.ExtraBody,
 			ExtraParams:        make(map[string]string),
+			API:                config.API,
+			Retry:              config.Retry,
 			Models:             p.Models,
 		}
 
@@ -264,7 +267,10 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			c.Providers.Del(id)
 			continue
 		}
-		if providerConfig.APIKey == "" {
+		if providerConfig.Type == TypeOllama && providerConfig.BaseURL == "" {
+			providerConfig.BaseURL = ollama.DefaultBaseURL
+		}
+		if providerConfig.APIKey == "" && providerConfig.Type != TypeOllama {
 			slog.Warn("Provider is missing API key, this might be OK for local providers", "provider", id)
 		}
 		if providerConfig.BaseURL == "" {
@@ -272,19 +278,20 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			c.Providers.Del(id)
 			continue
 		}
-		if len(providerConfig.Models) == 0 {
+		// Ollama models are discovered below
+		if len(providerConfig.Models) == 0 && providerConfig.Type != TypeOllama {
 			slog.Warn("Skipping custom provider because the provider has no models", "provider", id)
 			c.Providers.Del(id)
 			continue
 		}
-		if providerConfig.Type != catwalk.TypeOpenAI && providerConfig.Type != catwalk.TypeAnthropic {
+		if providerConfig.Type != catwalk.TypeOpenAI && providerConfig.Type != catwalk.TypeAnthropic && providerConfig.Type != TypeOllama {
 			slog.Warn("Skipping custom provider because the provider type is not supported", "provider", id, "type", providerConfig.Type)
 			c.Providers.Del(id)
 			continue
 		}
 
 		apiKey, err := resolver.ResolveValue(providerConfig.APIKey)
-		if apiKey == "" || err != nil {
+		if (apiKey == "" || err != nil) && providerConfig.Type != TypeOllama {
 			slog.Warn("Provider is missing API key, this might be OK for local providers", "provider", id)
 		}
 		baseURL, err := resolver.ResolveValue(providerConfig.BaseURL)
@@ -294,6 +301,24 @@ func (c *Config) configureProviders(env env.Env, resolver VariableResolver, know
 			continue
 		}
 
+		if providerConfig.Type == TypeOllama {
+			headers := make(map[string]string, len(providerConfig.ExtraHeaders))
+			for key, value := range providerConfig.ExtraHeaders {
+				headers[key], _ = resolver.ResolveValue(value)
+			}
+			models, err := discoverOllamaModels(baseURL, apiKey, headers, providerConfig.Models)
+			if err != nil {
+				slog.Warn("Failed to discover Ollama models", "provider", id, "error", err)
+			} else {
+				providerConfig.Models = models
+			}
+			if len(providerConfig.Models) == 0 {
+				slog.Warn("Skipping custom provider because the provider has no models", "provider", id)
+				c.Providers.Del(id)
+				continue
+			}
+		}
+
 		c.Providers.Set(id, providerConfig)
 	}
 	return nil
@@ -1,8 +1,11 @@
 package config
 
 import (
+	"encoding/json"
 	"io"
 	"log/slog"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
@@ -668,6 +671,76 @@ func TestConfig_configureProvidersCustomProviderValidation(t *testing.T) {
 	})
 }
 
+func TestConfig_configureProvidersOllama(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/tags":
+			w.Write([]byte(`{"models": [{"name": "llama3.2:latest"}, {"name": "qwen3:8b"}]}`))
+		case "/api/show":
+			var req struct{ Model string }
+			json.NewDecoder(r.Body).Decode(&req)
+			switch req.Model {
+			case "llama3.2:latest":
+				w.Write([]byte(`{"model_info": {"general.architecture": "llama", "llama.context_length": 131072}, "capabilities": ["completion", "tools"]}`))
+			case "qwen3:8b":
+				w.Write([]byte(`{"model_info": {"general.architecture": "qwen3", "qwen3.context_length": 40960}, "capabilities": ["completion", "tools", "thinking"]}`))
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	t.Run("discovers the installed models", func(t *testing.T) {
+		cfg := &Config{
+			Providers: csync.NewMapFrom(map[string]ProviderConfig{
+				"ollama": {
+					BaseURL: server.URL,
+					Type:    TypeOllama,
+					Models: []catwalk.Model{{
+						ID:            "qwen3:8b",
+						Name:          "Qwen 3",
+						ContextWindow: 16384,
+					}},
+				},
+			}),
+		}
+		cfg.setDefaults("/tmp", "")
+
+		env := env.NewFromMap(map[string]string{})
+		resolver := NewEnvironmentVariableResolver(env)
+		err := cfg.configureProviders(env, resolver, []catwalk.Provider{})
+		require.NoError(t, err)
+
+		ollamaProvider, exists := cfg.Providers.Get("ollama")
+		require.True(t, exists)
+		require.Equal(t, []catwalk.Model{
+			{ID: "qwen3:8b", Name: "Qwen 3", ContextWindow: 16384},
+			{ID: "llama3.2:latest", Name: "llama3.2:latest", ContextWindow: 131072, DefaultMaxTokens: 8192},
+		}, ollamaProvider.Models)
+	})
+
+	t.Run("unreachable server without models is removed", func(t *testing.T) {
+		cfg := &Config{
+			Providers: csync.NewMapFrom(map[string]ProviderConfig{
+				"ollama": {
+					BaseURL: server.URL + "/missing",
+					Type:    TypeOllama,
+				},
+			}),
+		}
+		cfg.setDefaults("/tmp", "")
+
+		env := env.NewFromMap(map[string]string{})
+		resolver := NewEnvironmentVariableResolver(env)
+		err := cfg.configureProviders(env, resolver, []catwalk.Provider{})
+		require.NoError(t, err)
+
+		_, exists := cfg.Providers.Get("ollama")
+		require.False(t, exists)
+	})
+}
+
 func TestConfig_configureProvidersEnhancedCredentialValidation(t *testing.T) {
 	t.Run("VertexAI provider removed when credentials missing with existing config", func(t *testing.T) {
 		knownProviders := []catwalk.Provider{
@@ -1184,3 +1257,65 @@ func TestConfig_configureSelectedModels(t *testing.T) {
 		require.Equal(t, int64(100), large.MaxTokens)
 	})
 }
+
+func TestConfig_SetupAgents(t *testing.T) {
+	t.Run("builtin agents", func(t *testing.T) {
+		cfg := &Config{}
+		cfg.setDefaults("/tmp", "")
+		cfg.SetupAgents()
+
+		coder, ok := cfg.Agent(AgentCoder)
+		require.True(t, ok)
+		require.Equal(t, SelectedModelTypeLarge, coder.Model)
+		require.Nil(t, coder.AllowedTools)
+
+		task, ok := cfg.Agent(AgentTask)
+		require.True(t, ok)
+		require.Contains(t, task.AllowedTools, "grep")
+	})
+
+	t.Run("user defined agents", func(t *testing.T) {
+		cfg, err := loadFromReaders([]io.Reader{strings.NewReader(`{
+			"agents": {
+				"reviewer": {
+					"name": "Reviewer",
+					"model": "small",
+					"system_prompt_file": "reviewer.md",
+					"allowed_tools": ["view", "grep"]
+				},
+				"coder": {
+					"allowed_tools": ["view"],
+					"disabled": true
+				},
+				"old": {
+					"disabled": true
+				}
+			}
+		}`)})
+		require.NoError(t, err)
+		cfg.setDefaults("/tmp", "")
+		cfg.SetupAgents()
+
+		reviewer, ok := cfg.Agent("reviewer")
+		require.True(t, ok)
+		require.Equal(t, "reviewer", reviewer.ID)
+		require.Equal(t, "Reviewer", reviewer.Name)
+		require.Equal(t, SelectedModelTypeSmall, reviewer.Model)
+		require.Equal(t, "reviewer.md", reviewer.SystemPromptFile)
+		require.Equal(t, []string{"view", "grep"}, reviewer.AllowedTools)
+		require.Equal(t, cfg.Options.ContextPaths, reviewer.ContextPaths)
+
+		// The built-in agents can be customized but not disabled.
+		coder, ok := cfg.Agent(AgentCoder)
+		require.True(t, ok)
+		require.Equal(t, "Coder", coder.Name)
+		require.Equal(t, []string{"view"}, coder.AllowedTools)
+
+		_, ok = cfg.Agent("old")
+		require.False(t, ok)
+
+		sorted := cfg.Agents.Sorted()
+		require.Len(t, sorted, 3)
+		require.Equal(t, AgentCoder, sorted[0].ID)
+	})
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/ollama"
+)
+
+const (
+	ollamaDiscoveryTimeout = 3 * time.Second
+	// Context window assumed for models that don't report theirs.
+	defaultOllamaContextWindow = 8192
+	defaultOllamaMaxTokens     = 8192
+)
+
+// discoverOllamaModels lists the models installed on the Ollama server along
+// with their context window and capabilities. Configured models are kept as
+// they are and take precedence over the discovered ones with the same ID.
+func discoverOllamaModels(baseURL, apiKey string, headers map[string]string, configured []catwalk.Model) ([]catwalk.Model, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ollamaDiscoveryTimeout)
+	defer cancel()
+
+	client := ollama.NewClient(baseURL)
+	client.Headers = make(map[string]string, len(headers)+1)
+	if apiKey != "" {
+		client.Headers["Authorization"] = "Bearer " + apiKey
+	}
+	for k, v := range headers {
+		client.Headers[k] = v
+	}
+
+	installed, err := client.Tags(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	models := make([]catwalk.Model, len(installed))
+	var wg sync.WaitGroup
+	for i, m := range installed {
+		wg.Go(func() {
+			models[i] = ollamaModel(ctx, client, m)
+		})
+	}
+	wg.Wait()
+
+	result := append([]catwalk.Model{}, configured...)
+	seen := make(map[string]bool, len(configured))
+	for _, m := range configured {
+		seen[m.ID] = true
+	}
+	for _, m := range models {
+		if !seen[m.ID] {
+			result = append(result, m)
+		}
+	}
+	return result, nil
+}
+
+func ollamaModel(ctx context.Context, client *ollama.Client, m ollama.Model) catwalk.Model {
+	model := catwalk.Model{
+		ID:               m.Name,
+		Name:             m.Name,
+		ContextWindow:    defaultOllamaContextWindow,
+		DefaultMaxTokens: defaultOllamaMaxTokens / 2,
+	}
+	info, err := client.Show(ctx, m.Name)
+	if err != nil {
+		slog.Warn("Failed to get Ollama model information", "model", m.Name, "error", err)
+		return model
+	}
+	if length := info.ContextLength(); length > 0 {
+		model.ContextWindow = length
+	}
+	model.DefaultMaxTokens = min(model.ContextWindow/2, defaultOllamaMaxTokens)
+	model.CanReason = info.HasCapability(ollama.CapabilityThinking)
+	model.SupportsImages = info.HasCapability(ollama.CapabilityVision)
+	return model
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+// ShellSandbox returns the sandbox for the commands run in the given working
+// directory, or nil if the sandbox isn't enabled. The working directory and
+// the temporary directory are always writable.
+func (s *Sandbox) ShellSandbox(workingDir string) *shell.Sandbox {
+	if s == nil || !s.Enabled {
+		return nil
+	}
+
+	writable := []string{workingDir, os.TempDir()}
+	for _, path := range s.WritablePaths {
+		if rest, ok := strings.CutPrefix(path, "~"); ok {
+			if home, err := os.UserHomeDir(); err == nil {
+				path = home + rest
+			}
+		}
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(workingDir, path)
+		}
+		writable = append(writable, filepath.Clean(path))
+	}
+
+	command := make([]string, len(s.Command))
+	for i, arg := range s.Command {
+		command[i] = strings.ReplaceAll(arg, "{workdir}", workingDir)
+	}
+	return &shell.Sandbox{
+		WritablePaths: writable,
+		AllowNetwork:  s.AllowNetwork,
+		Command:       command,
+	}
+}
@@ -0,0 +1,26 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSandbox_ShellSandbox(t *testing.T) {
+	var disabled *Sandbox
+	require.Nil(t, disabled.ShellSandbox("/project"))
+	require.Nil(t, (&Sandbox{AllowNetwork: true}).ShellSandbox("/project"))
+
+	home, err := os.UserHomeDir()
+	require.NoError(t, err)
+	sandbox := (&Sandbox{
+		Enabled:       true,
+		WritablePaths: []string{"~/.cache", "build/../out", "/var/cache"},
+		Command:       []string{"wrap", "--bind", "{workdir}"},
+	}).ShellSandbox("/project")
+	require.Equal(t, []string{"/project", os.TempDir(), filepath.Join(home, ".cache"), "/project/out", "/var/cache"}, sandbox.WritablePaths)
+	require.Equal(t, []string{"wrap", "--bind", "/project"}, sandbox.Command)
+	require.False(t, sandbox.AllowNetwork)
+}
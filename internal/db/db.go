@@ -24,6 +24,12 @@ func New(db DBTX) *Queries {
 func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	q := Queries{db: db}
 	var err error
+	if q.createCodeChunkStmt, err = db.PrepareContext(ctx, createCodeChunk); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateCodeChunk: %w", err)
+	}
+	if q.createCodeTermStmt, err = db.PrepareContext(ctx, createCodeTerm); err != nil {
+		return nil, fmt.Errorf("error preparing query CreateCodeTerm: %w", err)
+	}
 	if q.createFileStmt, err = db.PrepareContext(ctx, createFile); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateFile: %w", err)
 	}
@@ -33,6 +39,15 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.createSessionStmt, err = db.PrepareContext(ctx, createSession); err != nil {
 		return nil, fmt.Errorf("error preparing query CreateSession: %w", err)
 	}
+	if q.deleteCodeChunksByPathStmt, err = db.PrepareContext(ctx, deleteCodeChunksByPath); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteCodeChunksByPath: %w", err)
+	}
+	if q.deleteCodeIndexFileStmt, err = db.PrepareContext(ctx, deleteCodeIndexFile); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteCodeIndexFile: %w", err)
+	}
+	if q.deleteCodeTermsByPathStmt, err = db.PrepareContext(ctx, deleteCodeTermsByPath); err != nil {
+		return nil, fmt.Errorf("error preparing query DeleteCodeTermsByPath: %w", err)
+	}
 	if q.deleteFileStmt, err = db.PrepareContext(ctx, deleteFile); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteFile: %w", err)
 	}
@@ -48,6 +63,12 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.deleteSessionMessagesStmt, err = db.PrepareContext(ctx, deleteSessionMessages); err != nil {
 		return nil, fmt.Errorf("error preparing query DeleteSessionMessages: %w", err)
 	}
+	if q.getCodeChunkStmt, err = db.PrepareContext(ctx, getCodeChunk); err != nil {
+		return nil, fmt.Errorf("error preparing query GetCodeChunk: %w", err)
+	}
+	if q.getCodeIndexStatsStmt, err = db.PrepareContext(ctx, getCodeIndexStats); err != nil {
+		return nil, fmt.Errorf("error preparing query GetCodeIndexStats: %w", err)
+	}
 	if q.getFileStmt, err = db.PrepareContext(ctx, getFile); err != nil {
 		return nil, fmt.Errorf("error preparing query GetFile: %w", err)
 	}
@@ -60,6 +81,24 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.getSessionByIDStmt, err = db.PrepareContext(ctx, getSessionByID); err != nil {
 		return nil, fmt.Errorf("error preparing query GetSessionByID: %w", err)
 	}
+	if q.importFileStmt, err = db.PrepareContext(ctx, importFile); err != nil {
+		return nil, fmt.Errorf("error preparing query ImportFile: %w", err)
+	}
+	if q.importMessageStmt, err = db.PrepareContext(ctx, importMessage); err != nil {
+		return nil, fmt.Errorf("error preparing query ImportMessage: %w", err)
+	}
+	if q.importSessionStmt, err = db.PrepareContext(ctx, importSession); err != nil {
+		return nil, fmt.Errorf("error preparing query ImportSession: %w", err)
+	}
+	if q.listChildSessionsStmt, err = db.PrepareContext(ctx, listChildSessions); err != nil {
+		return nil, fmt.Errorf("error preparing query ListChildSessions: %w", err)
+	}
+	if q.listCodeIndexFilesStmt, err = db.PrepareContext(ctx, listCodeIndexFiles); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCodeIndexFiles: %w", err)
+	}
+	if q.listCodeTermChunksStmt, err = db.PrepareContext(ctx, listCodeTermChunks); err != nil {
+		return nil, fmt.Errorf("error preparing query ListCodeTermChunks: %w", err)
+	}
 	if q.listFilesByPathStmt, err = db.PrepareContext(ctx, listFilesByPath); err != nil {
 		return nil, fmt.Errorf("error preparing query ListFilesByPath: %w", err)
 	}
@@ -84,11 +123,24 @@ func Prepare(ctx context.Context, db DBTX) (*Queries, error) {
 	if q.updateSessionStmt, err = db.PrepareContext(ctx, updateSession); err != nil {
 		return nil, fmt.Errorf("error preparing query UpdateSession: %w", err)
 	}
+	if q.upsertCodeIndexFileStmt, err = db.PrepareContext(ctx, upsertCodeIndexFile); err != nil {
+		return nil, fmt.Errorf("error preparing query UpsertCodeIndexFile: %w", err)
+	}
 	return &q, nil
 }
 
 func (q *Queries) Close() error {
 	var err error
+	if q.createCodeChunkStmt != nil {
+		if cerr := q.createCodeChunkStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createCodeChunkStmt: %w", cerr)
+		}
+	}
+	if q.createCodeTermStmt != nil {
+		if cerr := q.createCodeTermStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing createCodeTermStmt: %w", cerr)
+		}
+	}
 	if q.createFileStmt != nil {
 		if cerr := q.createFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing createFileStmt: %w", cerr)
@@ -104,6 +156,21 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing createSessionStmt: %w", cerr)
 		}
 	}
+	if q.deleteCodeChunksByPathStmt != nil {
+		if cerr := q.deleteCodeChunksByPathStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteCodeChunksByPathStmt: %w", cerr)
+		}
+	}
+	if q.deleteCodeIndexFileStmt != nil {
+		if cerr := q.deleteCodeIndexFileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteCodeIndexFileStmt: %w", cerr)
+		}
+	}
+	if q.deleteCodeTermsByPathStmt != nil {
+		if cerr := q.deleteCodeTermsByPathStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing deleteCodeTermsByPathStmt: %w", cerr)
+		}
+	}
 	if q.deleteFileStmt != nil {
 		if cerr := q.deleteFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing deleteFileStmt: %w", cerr)
@@ -129,6 +196,16 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing deleteSessionMessagesStmt: %w", cerr)
 		}
 	}
+	if q.getCodeChunkStmt != nil {
+		if cerr := q.getCodeChunkStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getCodeChunkStmt: %w", cerr)
+		}
+	}
+	if q.getCodeIndexStatsStmt != nil {
+		if cerr := q.getCodeIndexStatsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing getCodeIndexStatsStmt: %w", cerr)
+		}
+	}
 	if q.getFileStmt != nil {
 		if cerr := q.getFileStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing getFileStmt: %w", cerr)
@@ -149,6 +226,36 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing getSessionByIDStmt: %w", cerr)
 		}
 	}
+	if q.importFileStmt != nil {
+		if cerr := q.importFileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing importFileStmt: %w", cerr)
+		}
+	}
+	if q.importMessageStmt != nil {
+		if cerr := q.importMessageStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing importMessageStmt: %w", cerr)
+		}
+	}
+	if q.importSessionStmt != nil {
+		if cerr := q.importSessionStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing importSessionStmt: %w", cerr)
+		}
+	}
+	if q.listChildSessionsStmt != nil {
+		if cerr := q.listChildSessionsStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listChildSessionsStmt: %w", cerr)
+		}
+	}
+	if q.listCodeIndexFilesStmt != nil {
+		if cerr := q.listCodeIndexFilesStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCodeIndexFilesStmt: %w", cerr)
+		}
+	}
+	if q.listCodeTermChunksStmt != nil {
+		if cerr := q.listCodeTermChunksStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing listCodeTermChunksStmt: %w", cerr)
+		}
+	}
 	if q.listFilesByPathStmt != nil {
 		if cerr := q.listFilesByPathStmt.Close(); cerr != nil {
 			err = fmt.Errorf("error closing listFilesByPathStmt: %w", cerr)
@@ -189,6 +296,11 @@ func (q *Queries) Close() error {
 			err = fmt.Errorf("error closing updateSessionStmt: %w", cerr)
 		}
 	}
+	if q.upsertCodeIndexFileStmt != nil {
+		if cerr := q.upsertCodeIndexFileStmt.Close(); cerr != nil {
+			err = fmt.Errorf("error closing upsertCodeIndexFileStmt: %w", cerr)
+		}
+	}
 	return err
 }
 
@@ -228,18 +340,31 @@ func (q *Queries) queryRow(ctx context.Context, stmt *sql.Stmt, query string, ar
 type Queries struct {
 	db                          DBTX
 	tx                          *sql.Tx
+	createCodeChunkStmt         *sql.Stmt
+	createCodeTermStmt          *sql.Stmt
 	createFileStmt              *sql.Stmt
 	createMessageStmt           *sql.Stmt
 	createSessionStmt           *sql.Stmt
+	deleteCodeChunksByPathStmt  *sql.Stmt
+	deleteCodeIndexFileStmt     *sql.Stmt
+	deleteCodeTermsByPathStmt   *sql.Stmt
 	deleteFileStmt              *sql.Stmt
 	deleteMessageStmt           *sql.Stmt
 	deleteSessionStmt           *sql.Stmt
 	deleteSessionFilesStmt      *sql.Stmt
 	deleteSessionMessagesStmt   *sql.Stmt
+	getCodeChunkStmt            *sql.Stmt
+	getCodeIndexStatsStmt       *sql.Stmt
 	getFileStmt                 *sql.Stmt
 	getFileByPathAndSessionStmt *sql.Stmt
 	getMessageStmt              *sql.Stmt
 	getSessionByIDStmt          *sql.Stmt
+	importFileStmt              *sql.Stmt
+	importMessageStmt           *sql.Stmt
+	importSessionStmt           *sql.Stmt
+	listChildSessionsStmt       *sql.Stmt
+	listCodeIndexFilesStmt      *sql.Stmt
+	listCodeTermChunksStmt      *sql.Stmt
 	listFilesByPathStmt         *sql.Stmt
 	listFilesBySessionStmt      *sql.Stmt
 	listLatestSessionFilesStmt  *sql.Stmt
@@ -248,24 +373,38 @@ type Queries struct {
 	listSessionsStmt            *sql.Stmt
 	updateMessageStmt           *sql.Stmt
 	updateSessionStmt           *sql.Stmt
+	upsertCodeIndexFileStmt     *sql.Stmt
 }
 
 func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 	return &Queries{
 		db:                          tx,
 		tx:                          tx,
+		createCodeChunkStmt:         q.createCodeChunkStmt,
+		createCodeTermStmt:          q.createCodeTermStmt,
 		createFileStmt:              q.createFileStmt,
 		createMessageStmt:           q.createMessageStmt,
 		createSessionStmt:           q.createSessionStmt,
+		deleteCodeChunksByPathStmt:  q.deleteCodeChunksByPathStmt,
+		deleteCodeIndexFileStmt:     q.deleteCodeIndexFileStmt,
+		deleteCodeTermsByPathStmt:   q.deleteCodeTermsByPathStmt,
 		deleteFileStmt:              q.deleteFileStmt,
 		deleteMessageStmt:           q.deleteMessageStmt,
 		deleteSessionStmt:           q.deleteSessionStmt,
 		deleteSessionFilesStmt:      q.deleteSessionFilesStmt,
 		deleteSessionMessagesStmt:   q.deleteSessionMessagesStmt,
+		getCodeChunkStmt:            q.getCodeChunkStmt,
+		getCodeIndexStatsStmt:       q.getCodeIndexStatsStmt,
 		getFileStmt:                 q.getFileStmt,
 		getFileByPathAndSessionStmt: q.getFileByPathAndSessionStmt,
 		getMessageStmt:              q.getMessageStmt,
 		getSessionByIDStmt:          q.getSessionByIDStmt,
+		importFileStmt:              q.importFileStmt,
+		importMessageStmt:           q.importMessageStmt,
+		importSessionStmt:           q.importSessionStmt,
+		listChildSessionsStmt:       q.listChildSessionsStmt,
+		listCodeIndexFilesStmt:      q.listCodeIndexFilesStmt,
+		listCodeTermChunksStmt:      q.listCodeTermChunksStmt,
 		listFilesByPathStmt:         q.listFilesByPathStmt,
 		listFilesBySessionStmt:      q.listFilesBySessionStmt,
 		listLatestSessionFilesStmt:  q.listLatestSessionFilesStmt,
@@ -274,5 +413,6 @@ func (q *Queries) WithTx(tx *sql.Tx) *Queries {
 		listSessionsStmt:            q.listSessionsStmt,
 		updateMessageStmt:           q.updateMessageStmt,
 		updateSessionStmt:           q.updateSessionStmt,
+		upsertCodeIndexFileStmt:     q.upsertCodeIndexFileStmt,
 	}
 }
@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Add forked_from_session_id column to sessions table
+ALTER TABLE sessions ADD COLUMN forked_from_session_id TEXT;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Remove forked_from_session_id column from sessions table
+ALTER TABLE sessions DROP COLUMN forked_from_session_id;
+-- +goose StatementEnd
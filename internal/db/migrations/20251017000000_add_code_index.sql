@@ -0,0 +1,42 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Files of the working directory in the code index
+CREATE TABLE IF NOT EXISTS code_index_files (
+    path TEXT PRIMARY KEY,
+    mod_time INTEGER NOT NULL,  -- Unix timestamp in nanoseconds
+    size INTEGER NOT NULL
+);
+
+-- Chunks of the indexed files, one per symbol
+CREATE TABLE IF NOT EXISTS code_chunks (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    path TEXT NOT NULL,
+    symbol TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    start_line INTEGER NOT NULL,
+    end_line INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    length INTEGER NOT NULL  -- Number of terms in the chunk
+);
+
+CREATE INDEX IF NOT EXISTS idx_code_chunks_path ON code_chunks (path);
+
+-- Term frequencies of the chunks, for BM25 ranking
+CREATE TABLE IF NOT EXISTS code_terms (
+    term TEXT NOT NULL,
+    chunk_id INTEGER NOT NULL,
+    frequency INTEGER NOT NULL,
+    PRIMARY KEY (term, chunk_id)
+) WITHOUT ROWID;
+
+CREATE INDEX IF NOT EXISTS idx_code_terms_chunk_id ON code_terms (chunk_id);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX IF EXISTS idx_code_terms_chunk_id;
+DROP INDEX IF EXISTS idx_code_chunks_path;
+DROP TABLE IF EXISTS code_terms;
+DROP TABLE IF EXISTS code_chunks;
+DROP TABLE IF EXISTS code_index_files;
+-- +goose StatementEnd
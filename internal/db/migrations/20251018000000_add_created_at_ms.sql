@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+-- Add millisecond creation times to messages and files, so file versions can
+-- be ordered against the messages of the same second
+ALTER TABLE messages ADD COLUMN created_at_ms INTEGER NOT NULL DEFAULT 0;
+UPDATE messages SET created_at_ms = created_at * 1000;
+ALTER TABLE files ADD COLUMN created_at_ms INTEGER NOT NULL DEFAULT 0;
+UPDATE files SET created_at_ms = created_at * 1000;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+-- Remove millisecond creation times from messages and files
+ALTER TABLE files DROP COLUMN created_at_ms;
+ALTER TABLE messages DROP COLUMN created_at_ms;
+-- +goose StatementEnd
@@ -8,37 +8,63 @@ import (
 	"database/sql"
 )
 
-type File struct {
-	ID        string `json:"id"`
-	SessionID string `json:"session_id"`
+type CodeChunk struct {
+	ID        int64  `json:"id"`
 	Path      string `json:"path"`
+	Symbol    string `json:"symbol"`
+	Kind      string `json:"kind"`
+	StartLine int64  `json:"start_line"`
+	EndLine   int64  `json:"end_line"`
 	Content   string `json:"content"`
-	Version   int64  `json:"version"`
-	CreatedAt int64  `json:"created_at"`
-	UpdatedAt int64  `json:"updated_at"`
+	Length    int64  `json:"length"`
+}
+
+type CodeIndexFile struct {
+	Path    string `json:"path"`
+	ModTime int64  `json:"mod_time"`
+	Size    int64  `json:"size"`
+}
+
+type CodeTerm struct {
+	Term      string `json:"term"`
+	ChunkID   int64  `json:"chunk_id"`
+	Frequency int64  `json:"frequency"`
+}
+
+type File struct {
+	ID          string `json:"id"`
+	SessionID   string `json:"session_id"`
+	Path        string `json:"path"`
+	Content     string `json:"content"`
+	Version     int64  `json:"version"`
+	CreatedAt   int64  `json:"created_at"`
+	UpdatedAt   int64  `json:"updated_at"`
+	CreatedAtMs int64  `json:"created_at_ms"`
 }
 
 type Message struct {
-	ID         string         `json:"id"`
-	SessionID  string         `json:"session_id"`
-	Role       string         `json:"role"`
-	Parts      string         `json:"parts"`
-	Model      sql.NullString `json:"model"`
-	CreatedAt  int64          `json:"created_at"`
-	UpdatedAt  int64          `json:"updated_at"`
-	FinishedAt sql.NullInt64  `json:"finished_at"`
-	Provider   sql.NullString `json:"provider"`
+	ID          string         `json:"id"`
+	SessionID   string         `json:"session_id"`
+	Role        string         `json:"role"`
+	Parts       string         `json:"parts"`
+	Model       sql.NullString `json:"model"`
+	CreatedAt   int64          `json:"created_at"`
+	UpdatedAt   int64          `json:"updated_at"`
+	FinishedAt  sql.NullInt64  `json:"finished_at"`
+	Provider    sql.NullString `json:"provider"`
+	CreatedAtMs int64          `json:"created_at_ms"`
 }
 
 type Session struct {
-	ID               string         `json:"id"`
-	ParentSessionID  sql.NullString `json:"parent_session_id"`
-	Title            string         `json:"title"`
-	MessageCount     int64          `json:"message_count"`
-	PromptTokens     int64          `json:"prompt_tokens"`
-	CompletionTokens int64          `json:"completion_tokens"`
-	Cost             float64        `json:"cost"`
-	UpdatedAt        int64          `json:"updated_at"`
-	CreatedAt        int64          `json:"created_at"`
-	SummaryMessageID sql.NullString `json:"summary_message_id"`
+	ID                  string         `json:"id"`
+	ParentSessionID     sql.NullString `json:"parent_session_id"`
+	Title               string         `json:"title"`
+	MessageCount        int64          `json:"message_count"`
+	PromptTokens        int64          `json:"prompt_tokens"`
+	CompletionTokens    int64          `json:"completion_tokens"`
+	Cost                float64        `json:"cost"`
+	UpdatedAt           int64          `json:"updated_at"`
+	CreatedAt           int64          `json:"created_at"`
+	SummaryMessageID    sql.NullString `json:"summary_message_id"`
+	ForkedFromSessionID sql.NullString `json:"forked_from_session_id"`
 }
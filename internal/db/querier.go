@@ -6,21 +6,35 @@ package db
 
 import (
 	"context"
+	"database/sql"
 )
 
 type Querier interface {
+	CreateCodeChunk(ctx context.Context, arg CreateCodeChunkParams) (int64, error)
+	CreateCodeTerm(ctx context.Context, arg CreateCodeTermParams) error
 	CreateFile(ctx context.Context, arg CreateFileParams) (File, error)
 	CreateMessage(ctx context.Context, arg CreateMessageParams) (Message, error)
 	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
+	DeleteCodeChunksByPath(ctx context.Context, path string) error
+	DeleteCodeIndexFile(ctx context.Context, path string) error
+	DeleteCodeTermsByPath(ctx context.Context, path string) error
 	DeleteFile(ctx context.Context, id string) error
 	DeleteMessage(ctx context.Context, id string) error
 	DeleteSession(ctx context.Context, id string) error
 	DeleteSessionFiles(ctx context.Context, sessionID string) error
 	DeleteSessionMessages(ctx context.Context, sessionID string) error
+	GetCodeChunk(ctx context.Context, id int64) (CodeChunk, error)
+	GetCodeIndexStats(ctx context.Context) (GetCodeIndexStatsRow, error)
 	GetFile(ctx context.Context, id string) (File, error)
 	GetFileByPathAndSession(ctx context.Context, arg GetFileByPathAndSessionParams) (File, error)
 	GetMessage(ctx context.Context, id string) (Message, error)
 	GetSessionByID(ctx context.Context, id string) (Session, error)
+	ImportFile(ctx context.Context, arg ImportFileParams) error
+	ImportMessage(ctx context.Context, arg ImportMessageParams) error
+	ImportSession(ctx context.Context, arg ImportSessionParams) error
+	ListChildSessions(ctx context.Context, parentSessionID sql.NullString) ([]Session, error)
+	ListCodeIndexFiles(ctx context.Context) ([]CodeIndexFile, error)
+	ListCodeTermChunks(ctx context.Context, term string) ([]ListCodeTermChunksRow, error)
 	ListFilesByPath(ctx context.Context, path string) ([]File, error)
 	ListFilesBySession(ctx context.Context, sessionID string) ([]File, error)
 	ListLatestSessionFiles(ctx context.Context, sessionID string) ([]File, error)
@@ -29,6 +43,7 @@ type Querier interface {
 	ListSessions(ctx context.Context) ([]Session, error)
 	UpdateMessage(ctx context.Context, arg UpdateMessageParams) error
 	UpdateSession(ctx context.Context, arg UpdateSessionParams) (Session, error)
+	UpsertCodeIndexFile(ctx context.Context, arg UpsertCodeIndexFileParams) error
 }
 
 var _ Querier = (*Queries)(nil)
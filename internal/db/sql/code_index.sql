@@ -0,0 +1,72 @@
+-- name: ListCodeIndexFiles :many
+SELECT *
+FROM code_index_files
+ORDER BY path ASC;
+
+-- name: UpsertCodeIndexFile :exec
+INSERT INTO code_index_files (
+    path,
+    mod_time,
+    size
+) VALUES (
+    ?, ?, ?
+)
+ON CONFLICT (path) DO UPDATE SET
+    mod_time = excluded.mod_time,
+    size = excluded.size;
+
+-- name: DeleteCodeIndexFile :exec
+DELETE FROM code_index_files
+WHERE path = ?;
+
+-- name: CreateCodeChunk :one
+INSERT INTO code_chunks (
+    path,
+    symbol,
+    kind,
+    start_line,
+    end_line,
+    content,
+    length
+) VALUES (
+    ?, ?, ?, ?, ?, ?, ?
+)
+RETURNING id;
+
+-- name: GetCodeChunk :one
+SELECT *
+FROM code_chunks
+WHERE id = ? LIMIT 1;
+
+-- name: DeleteCodeChunksByPath :exec
+DELETE FROM code_chunks
+WHERE path = ?;
+
+-- name: GetCodeIndexStats :one
+SELECT
+    COUNT(*) AS chunk_count,
+    CAST(COALESCE(AVG(length), 0) AS REAL) AS average_length
+FROM code_chunks;
+
+-- name: CreateCodeTerm :exec
+INSERT INTO code_terms (
+    term,
+    chunk_id,
+    frequency
+) VALUES (
+    ?, ?, ?
+);
+
+-- name: ListCodeTermChunks :many
+SELECT t.chunk_id, t.frequency, c.length
+FROM code_terms t
+INNER JOIN code_chunks c ON c.id = t.chunk_id
+WHERE t.term = ?;
+
+-- name: DeleteCodeTermsByPath :exec
+DELETE FROM code_terms
+WHERE chunk_id IN (
+    SELECT id
+    FROM code_chunks
+    WHERE path = ?
+);
@@ -23,6 +23,9 @@ type File struct {
 	Version   int64
 	CreatedAt int64
 	UpdatedAt int64
+	// CreatedAtMs is the creation time in milliseconds, which orders file
+	// versions against messages created in the same second.
+	CreatedAtMs int64
 }
 
 type Service interface {
@@ -199,12 +202,13 @@ func (s *service) DeleteSessionFiles(ctx context.Context, sessionID string) erro
 
 func (s *service) fromDBItem(item db.File) File {
 	return File{
-		ID:        item.ID,
-		SessionID: item.SessionID,
-		Path:      item.Path,
-		Content:   item.Content,
-		Version:   item.Version,
-		CreatedAt: item.CreatedAt,
-		UpdatedAt: item.UpdatedAt,
+		ID:          item.ID,
+		SessionID:   item.SessionID,
+		Path:        item.Path,
+		Content:     item.Content,
+		Version:     item.Version,
+		CreatedAt:   item.CreatedAt,
+		UpdatedAt:   item.UpdatedAt,
+		CreatedAtMs: item.CreatedAtMs,
 	}
 }
@@ -0,0 +1,199 @@
+// Package hooks runs the user's commands at defined points of the agent loop:
+// before and after tool calls, when a prompt is submitted and when the agent
+// finishes a turn.
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+// Event is a point of the agent loop hooks run at.
+type Event string
+
+const (
+	EventPreToolCall  Event = "pre_tool_call"
+	EventPostToolCall Event = "post_tool_call"
+	EventPromptSubmit Event = "prompt_submit"
+	EventTurnEnd      Event = "turn_end"
+)
+
+// BlockExitCode is the exit code hooks use to veto a tool call, block a
+// prompt or make the agent continue.
+const BlockExitCode = 2
+
+// MaxTurnEndContinuations is how many times in a row turn end hooks can make
+// the agent continue, so a hook that always blocks can't keep it going
+// forever.
+const MaxTurnEndContinuations = 3
+
+const defaultTimeout = 60 * time.Second
+
+// ToolCall is the tool call passed to tool call hooks.
+type ToolCall struct {
+	ID    string          `json:"id"`
+	Name  string          `json:"name"`
+	Input json.RawMessage `json:"input"`
+}
+
+// ToolResponse is the tool response passed to post tool call hooks.
+type ToolResponse struct {
+	Content string `json:"content"`
+	IsError bool   `json:"is_error"`
+}
+
+// Input is written as JSON to the standard input of hooks.
+type Input struct {
+	Event        Event         `json:"event"`
+	SessionID    string        `json:"session_id"`
+	Agent        string        `json:"agent"`
+	WorkingDir   string        `json:"cwd"`
+	ToolCall     *ToolCall     `json:"tool_call,omitempty"`
+	ToolResponse *ToolResponse `json:"tool_response,omitempty"`
+	Prompt       string        `json:"prompt,omitempty"`
+	// Response is the final text of the agent, for turn end hooks.
+	Response string `json:"response,omitempty"`
+	// Continued is set for turn end hooks if the turn was continued by a
+	// turn end hook before, so hooks can avoid keeping the agent going.
+	Continued bool `json:"continued,omitempty"`
+}
+
+// Result combines the results of the hooks run for an event.
+type Result struct {
+	// Blocked is set if a hook exited with BlockExitCode, with its standard
+	// error as the reason.
+	Blocked bool
+	Reason  string
+	// Output is the standard output of the hooks that succeeded, except for
+	// pre tool call hooks.
+	Output string
+	// Input is the tool input as rewritten by pre tool call hooks, or the
+	// original input if no hook rewrote it.
+	Input string
+}
+
+// Runner runs the hooks configured for each event.
+type Runner struct {
+	hooks      config.Hooks
+	workingDir string
+}
+
+// NewRunner creates a runner for the given hooks, which may be nil.
+func NewRunner(hooks *config.Hooks, workingDir string) *Runner {
+	r := &Runner{workingDir: workingDir}
+	if hooks != nil {
+		r.hooks = *hooks
+	}
+	return r
+}
+
+func (r *Runner) forEvent(event Event) []config.Hook {
+	switch event {
+	case EventPreToolCall:
+		return r.hooks.PreToolCall
+	case EventPostToolCall:
+		return r.hooks.PostToolCall
+	case EventPromptSubmit:
+		return r.hooks.PromptSubmit
+	case EventTurnEnd:
+		return r.hooks.TurnEnd
+	default:
+		return nil
+	}
+}
+
+// Has reports whether any hooks are configured for the event.
+func (r *Runner) Has(event Event) bool {
+	return len(r.forEvent(event)) > 0
+}
+
+// Run runs the hooks for an event one after the other, stopping at the first
+// one that blocks. Hooks that fail otherwise are logged and ignored.
+func (r *Runner) Run(ctx context.Context, input Input) Result {
+	input.WorkingDir = r.workingDir
+	var result Result
+	if input.ToolCall != nil {
+		result.Input = string(input.ToolCall.Input)
+	}
+
+	var outputs []string
+	for _, hook := range r.forEvent(input.Event) {
+		if input.ToolCall != nil && !matches(hook.Matcher, input.ToolCall.Name) {
+			continue
+		}
+		stdout, stderr, err := r.exec(ctx, hook, input)
+		switch {
+		case shell.ExitCode(err) == BlockExitCode:
+			result.Blocked = true
+			result.Reason = strings.TrimSpace(stderr)
+			if result.Reason == "" {
+				result.Reason = fmt.Sprintf("Blocked by %s hook: %s", input.Event, hook.Command)
+			}
+			result.Output = strings.Join(outputs, "\n")
+			return result
+		case err != nil:
+			slog.Warn("Hook failed", "event", input.Event, "command", hook.Command, "error", err, "stderr", stderr)
+			continue
+		}
+
+		stdout = strings.TrimSpace(stdout)
+		if stdout == "" {
+			continue
+		}
+		if input.Event == EventPreToolCall {
+			// Pre tool call hooks print the new tool input, if they rewrite it.
+			if !json.Valid([]byte(stdout)) {
+				slog.Warn("Ignoring invalid tool input from hook", "command", hook.Command)
+				continue
+			}
+			result.Input = stdout
+			input.ToolCall.Input = json.RawMessage(stdout)
+			continue
+		}
+		outputs = append(outputs, stdout)
+	}
+	result.Output = strings.Join(outputs, "\n")
+	return result
+}
+
+func (r *Runner) exec(ctx context.Context, hook config.Hook, input Input) (string, string, error) {
+	timeout := defaultTimeout
+	if hook.Timeout > 0 {
+		timeout = time.Duration(hook.Timeout) * time.Second
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	data, err := json.Marshal(input)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to marshal hook input: %w", err)
+	}
+	sh := shell.NewShell(&shell.Options{WorkingDir: r.workingDir})
+	return sh.ExecStdin(ctx, hook.Command, bytes.NewReader(data))
+}
+
+func matches(matcher, toolName string) bool {
+	if matcher == "" {
+		return true
+	}
+	re, err := regexp.Compile("^(?:" + matcher + ")$")
+	if err != nil {
+		slog.Warn("Invalid hook matcher", "matcher", matcher, "error", err)
+		return false
+	}
+	return re.MatchString(toolName)
+}
+
+// Note formats hook output to be added to a prompt or tool result.
+func Note(event Event, output string) string {
+	return fmt.Sprintf("<hook event=%q>\n%s\n</hook>", event, output)
+}
@@ -0,0 +1,85 @@
+package hooks
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner(t *testing.T) {
+	runner := NewRunner(&config.Hooks{
+		PreToolCall: []config.Hook{
+			{Command: `echo "no secrets" >&2; exit 2`, Matcher: "view"},
+			{Command: `echo '{"command":"go test ./..."}'`, Matcher: "bash"},
+		},
+		PostToolCall: []config.Hook{
+			{Command: "echo formatted", Matcher: "edit|write"},
+			{Command: "exit 1"},
+		},
+		PromptSubmit: []config.Hook{
+			{Command: "cat"},
+		},
+		TurnEnd: []config.Hook{
+			{Command: `echo "tests are failing" >&2; exit 2`},
+		},
+	}, t.TempDir())
+
+	toolCall := func(name, input string) *ToolCall {
+		return &ToolCall{ID: "call", Name: name, Input: json.RawMessage(input)}
+	}
+
+	t.Run("pre tool call vetoes", func(t *testing.T) {
+		result := runner.Run(t.Context(), Input{Event: EventPreToolCall, ToolCall: toolCall("view", `{"file_path":".env"}`)})
+		require.True(t, result.Blocked)
+		require.Equal(t, "no secrets", result.Reason)
+	})
+
+	t.Run("pre tool call rewrites input", func(t *testing.T) {
+		result := runner.Run(t.Context(), Input{Event: EventPreToolCall, ToolCall: toolCall("bash", `{"command":"go test"}`)})
+		require.False(t, result.Blocked)
+		require.JSONEq(t, `{"command":"go test ./..."}`, result.Input)
+	})
+
+	t.Run("pre tool call keeps input", func(t *testing.T) {
+		result := runner.Run(t.Context(), Input{Event: EventPreToolCall, ToolCall: toolCall("ls", `{"path":"."}`)})
+		require.False(t, result.Blocked)
+		require.Equal(t, `{"path":"."}`, result.Input)
+	})
+
+	t.Run("post tool call output", func(t *testing.T) {
+		result := runner.Run(t.Context(), Input{
+			Event:        EventPostToolCall,
+			ToolCall:     toolCall("edit", `{}`),
+			ToolResponse: &ToolResponse{Content: "done"},
+		})
+		require.False(t, result.Blocked, "failing hooks should be ignored")
+		require.Equal(t, "formatted", result.Output)
+
+		result = runner.Run(t.Context(), Input{Event: EventPostToolCall, ToolCall: toolCall("view", `{}`)})
+		require.Empty(t, result.Output)
+	})
+
+	t.Run("prompt submit receives the event on stdin", func(t *testing.T) {
+		result := runner.Run(t.Context(), Input{Event: EventPromptSubmit, SessionID: "session", Prompt: "hello"})
+		var input Input
+		require.NoError(t, json.Unmarshal([]byte(result.Output), &input))
+		require.Equal(t, EventPromptSubmit, input.Event)
+		require.Equal(t, "session", input.SessionID)
+		require.Equal(t, "hello", input.Prompt)
+		require.NotEmpty(t, input.WorkingDir)
+	})
+
+	t.Run("turn end continues", func(t *testing.T) {
+		result := runner.Run(t.Context(), Input{Event: EventTurnEnd})
+		require.True(t, result.Blocked)
+		require.Equal(t, "tests are failing", result.Reason)
+	})
+
+	t.Run("no hooks", func(t *testing.T) {
+		empty := NewRunner(nil, t.TempDir())
+		require.False(t, empty.Has(EventTurnEnd))
+		require.Equal(t, Result{}, empty.Run(t.Context(), Input{Event: EventTurnEnd}))
+	})
+}
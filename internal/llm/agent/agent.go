@@ -2,17 +2,21 @@ package agent
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
 	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/codeindex"
 	"github.com/charmbracelet/crush/internal/config"
 	"github.com/charmbracelet/crush/internal/csync"
 	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/hooks"
 	"github.com/charmbracelet/crush/internal/llm/prompt"
 	"github.com/charmbracelet/crush/internal/llm/provider"
 	"github.com/charmbracelet/crush/internal/llm/tools"
@@ -37,6 +41,7 @@ const (
 	AgentEventTypeError     AgentEventType = "error"
 	AgentEventTypeResponse  AgentEventType = "response"
 	AgentEventTypeSummarize AgentEventType = "summarize"
+	AgentEventTypeWarning   AgentEventType = "warning"
 )
 
 type AgentEvent struct {
@@ -66,16 +71,26 @@ type Service interface {
 
 type agent struct {
 	*pubsub.Broker[AgentEvent]
-	agentCfg config.Agent
-	sessions session.Service
-	messages message.Service
-	mcpTools []McpTool
+	agentCfg    config.Agent
+	sessions    session.Service
+	messages    message.Service
+	permissions permission.Service
+	hooks       *hooks.Runner
+	mcpTools    []McpTool
 
 	tools *csync.LazySlice[tools.BaseTool]
 
+	// modelMu guards the provider and the fallbacks, which UpdateModel
+	// replaces while sessions may be running.
+	modelMu    sync.RWMutex
 	provider   provider.Provider
 	providerID string
 
+	// Fallbacks of the agent's model, the index of the one used by a session
+	// is kept in sessionFallbacks.
+	fallbacks        []fallbackProvider
+	sessionFallbacks *csync.Map[string, int]
+
 	titleProvider       provider.Provider
 	summarizeProvider   provider.Provider
 	summarizeProviderID string
@@ -86,8 +101,19 @@ type agent struct {
 }
 
 var agentPromptMap = map[string]prompt.PromptID{
-	"coder": prompt.PromptCoder,
-	"task":  prompt.PromptTask,
+	config.AgentCoder: prompt.PromptCoder,
+	config.AgentTask:  prompt.PromptTask,
+}
+
+func agentSystemPrompt(agentCfg config.Agent, providerID string) (string, error) {
+	if agentCfg.SystemPromptFile != "" {
+		return prompt.CustomPrompt(agentCfg.SystemPromptFile, agentCfg.ContextPaths...)
+	}
+	promptID := agentPromptMap[agentCfg.ID]
+	if promptID == "" {
+		promptID = prompt.PromptDefault
+	}
+	return prompt.GetPrompt(promptID, providerID, agentCfg.ContextPaths...), nil
 }
 
 func NewAgent(
@@ -99,16 +125,17 @@ func NewAgent(
 	messages message.Service,
 	history history.Service,
 	lspClients map[string]*lsp.Client,
+	codeIndex codeindex.Service,
 ) (Service, error) {
 	cfg := config.Get()
 
 	var agentTool tools.BaseTool
-	if agentCfg.ID == "coder" {
-		taskAgentCfg := config.Get().Agents["task"]
-		if taskAgentCfg.ID == "" {
+	if agentCfg.ID != config.AgentTask {
+		taskAgentCfg, ok := cfg.Agent(config.AgentTask)
+		if !ok {
 			return nil, fmt.Errorf("task agent not found in config")
 		}
-		taskAgent, err := NewAgent(ctx, taskAgentCfg, permissions, sessions, messages, history, lspClients)
+		taskAgent, err := NewAgent(ctx, taskAgentCfg, permissions, sessions, messages, history, lspClients, codeIndex)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create task agent: %w", err)
 		}
@@ -126,13 +153,13 @@ func NewAgent(
 		return nil, fmt.Errorf("model not found for agent %s", agentCfg.Name)
 	}
 
-	promptID := agentPromptMap[agentCfg.ID]
-	if promptID == "" {
-		promptID = prompt.PromptDefault
+	systemPrompt, err := agentSystemPrompt(agentCfg, providerCfg.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build system prompt for agent %s: %w", agentCfg.Name, err)
 	}
 	opts := []provider.ProviderClientOption{
 		provider.WithModel(agentCfg.Model),
-		provider.WithSystemMessage(prompt.GetPrompt(promptID, providerCfg.ID, config.Get().Options.ContextPaths...)),
+		provider.WithSystemMessage(systemPrompt),
 	}
 	agentProvider, err := provider.NewProvider(*providerCfg, opts...)
 	if err != nil {
@@ -180,44 +207,54 @@ func NewAgent(
 		}()
 
 		cwd := cfg.WorkingDir()
+		lspClients := allowedLSPClients(agentCfg, lspClients)
+		bashOpts := tools.BashOptions{Sandbox: cfg.Sandbox.ShellSandbox(cwd)}
+		if cfg.Permissions != nil {
+			bashOpts.SafeCommands = cfg.Permissions.SafeCommands
+			bashOpts.BannedCommands = cfg.Permissions.BannedCommands
+		}
 		allTools := []tools.BaseTool{
-			tools.NewBashTool(permissions, cwd),
+			tools.NewBashTool(permissions, cwd, bashOpts),
+			tools.NewCodeSearchTool(codeIndex),
 			tools.NewDownloadTool(permissions, cwd),
 			tools.NewEditTool(lspClients, permissions, history, cwd),
 			tools.NewMultiEditTool(lspClients, permissions, history, cwd),
 			tools.NewFetchTool(permissions, cwd),
 			tools.NewGlobTool(cwd),
 			tools.NewGrepTool(cwd),
+			tools.NewJobOutputTool(),
+			tools.NewJobWaitTool(),
+			tools.NewJobKillTool(),
 			tools.NewLsTool(permissions, cwd),
 			tools.NewSourcegraphTool(),
 			tools.NewViewTool(lspClients, permissions, cwd),
 			tools.NewWriteTool(lspClients, permissions, history, cwd),
 		}
 
+		// MCP tools are added on every request, as servers can change them,
+		// but wait for the servers to start the first time.
 		mcpToolsOnce.Do(func() {
-			mcpTools = doGetMCPTools(ctx, permissions, cfg)
+			startMCPs(ctx, permissions, cfg)
 		})
-		allTools = append(allTools, mcpTools...)
 
 		if len(lspClients) > 0 {
-			allTools = append(allTools, tools.NewDiagnosticsTool(lspClients))
+			allTools = append(allTools,
+				tools.NewDiagnosticsTool(lspClients),
+				tools.NewDefinitionTool(lspClients, cwd),
+				tools.NewImplementationTool(lspClients, cwd),
+				tools.NewReferencesTool(lspClients, cwd),
+				tools.NewHoverTool(lspClients, cwd),
+				tools.NewSymbolsTool(lspClients, cwd),
+				tools.NewCallHierarchyTool(lspClients, cwd),
+				tools.NewRefactorTool(lspClients, permissions, history, cwd),
+			)
 		}
 
 		if agentTool != nil {
 			allTools = append(allTools, agentTool)
 		}
 
-		if agentCfg.AllowedTools == nil {
-			return allTools
-		}
-
-		var filteredTools []tools.BaseTool
-		for _, tool := range allTools {
-			if slices.Contains(agentCfg.AllowedTools, tool.Name()) {
-				filteredTools = append(filteredTools, tool)
-			}
-		}
-		return filteredTools
+		return allowedTools(agentCfg, allTools)
 	}
 
 	return &agent{
@@ -225,8 +262,12 @@ func NewAgent(
 		agentCfg:            agentCfg,
 		provider:            agentProvider,
 		providerID:          string(providerCfg.ID),
+		fallbacks:           newFallbackProviders(agentCfg),
+		sessionFallbacks:    csync.NewMap[string, int](),
 		messages:            messages,
 		sessions:            sessions,
+		permissions:         permissions,
+		hooks:               hooks.NewRunner(cfg.Hooks, cfg.WorkingDir()),
 		titleProvider:       titleProvider,
 		summarizeProvider:   summarizeProvider,
 		summarizeProviderID: string(providerCfg.ID),
@@ -236,6 +277,65 @@ func NewAgent(
 	}, nil
 }
 
+// allowedTools returns the tools the agent is allowed to use.
+func allowedTools(agentCfg config.Agent, allTools []tools.BaseTool) []tools.BaseTool {
+	if agentCfg.AllowedTools == nil {
+		return allTools
+	}
+
+	var filteredTools []tools.BaseTool
+	for _, tool := range allTools {
+		if slices.Contains(agentCfg.AllowedTools, tool.Name()) {
+			filteredTools = append(filteredTools, tool)
+		}
+	}
+	return filteredTools
+}
+
+// availableTools returns the agent's tools along with the current tools of the
+// MCP servers it may use.
+func (a *agent) availableTools() []tools.BaseTool {
+	availableTools := slices.Collect(a.tools.Seq())
+	mcpTools := allowedTools(a.agentCfg, allowedMCPTools(a.agentCfg, getMCPTools()))
+	return append(availableTools, mcpTools...)
+}
+
+// allowedLSPClients returns the LSP clients the agent is allowed to use.
+func allowedLSPClients(agentCfg config.Agent, lspClients map[string]*lsp.Client) map[string]*lsp.Client {
+	if agentCfg.AllowedLSP == nil {
+		return lspClients
+	}
+	filtered := make(map[string]*lsp.Client)
+	for name, client := range lspClients {
+		if slices.Contains(agentCfg.AllowedLSP, name) {
+			filtered[name] = client
+		}
+	}
+	return filtered
+}
+
+// allowedMCPTools returns the MCP tools the agent is allowed to use.
+func allowedMCPTools(agentCfg config.Agent, mcpTools []tools.BaseTool) []tools.BaseTool {
+	if agentCfg.AllowedMCP == nil {
+		return mcpTools
+	}
+	var filtered []tools.BaseTool
+	for _, tool := range mcpTools {
+		mcpTool, ok := tool.(*McpTool)
+		if !ok {
+			continue
+		}
+		allowed, ok := agentCfg.AllowedMCP[mcpTool.mcpName]
+		if !ok {
+			continue
+		}
+		if allowed == nil || slices.Contains(allowed, mcpTool.tool.Name) {
+			filtered = append(filtered, tool)
+		}
+	}
+	return filtered
+}
+
 func (a *agent) Model() catwalk.Model {
 	return *config.Get().GetModelByType(a.agentCfg.Model)
 }
@@ -340,6 +440,7 @@ func (a *agent) err(err error) AgentEvent {
 }
 
 func (a *agent) Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan AgentEvent, error) {
+	content, attachments = inlineTextAttachments(content, attachments)
 	if !a.Model().SupportsImages && attachments != nil {
 		attachments = nil
 	}
@@ -416,6 +517,21 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 		}
 	}
 
+	if a.hooks.Has(hooks.EventPromptSubmit) {
+		result := a.hooks.Run(ctx, hooks.Input{
+			Event:     hooks.EventPromptSubmit,
+			SessionID: sessionID,
+			Agent:     a.agentCfg.ID,
+			Prompt:    content,
+		})
+		if result.Blocked {
+			return a.err(fmt.Errorf("prompt blocked by hook: %s", result.Reason))
+		}
+		if result.Output != "" {
+			content += "\n\n" + hooks.Note(hooks.EventPromptSubmit, result.Output)
+		}
+	}
+
 	userMsg, err := a.createUserMessage(ctx, sessionID, content, attachmentParts)
 	if err != nil {
 		return a.err(fmt.Errorf("failed to create user message: %w", err))
@@ -423,6 +539,7 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 	// Append the new user message to the conversation history.
 	msgHistory := append(msgs, userMsg)
 
+	continuations := 0
 	for {
 		// Check for cancellation before each iteration
 		select {
@@ -477,6 +594,27 @@ func (a *agent) processGeneration(ctx context.Context, sessionID, content string
 				continue
 			}
 		}
+		if agentMessage.FinishReason() == message.FinishReasonEndTurn && a.hooks.Has(hooks.EventTurnEnd) {
+			result := a.hooks.Run(ctx, hooks.Input{
+				Event:     hooks.EventTurnEnd,
+				SessionID: sessionID,
+				Agent:     a.agentCfg.ID,
+				Response:  agentMessage.Content().String(),
+				Continued: continuations > 0,
+			})
+			if result.Blocked && continuations >= hooks.MaxTurnEndContinuations {
+				slog.Warn("Turn end hooks kept the agent going too many times, ending the turn", "continuations", continuations)
+			} else if result.Blocked {
+				// The hook wants the agent to keep going
+				continuations++
+				userMsg, err := a.createUserMessage(ctx, sessionID, hooks.Note(hooks.EventTurnEnd, result.Reason), nil)
+				if err != nil {
+					return a.err(fmt.Errorf("failed to create user message for hook: %w", err))
+				}
+				msgHistory = append(msgHistory, agentMessage, userMsg)
+				continue
+			}
+		}
 		if agentMessage.FinishReason() == "" {
 			// Kujtim: could not track down where this is happening but this means its cancelled
 			agentMessage.AddFinish(message.FinishReasonCanceled, "Request cancelled", "")
@@ -503,25 +641,37 @@ func (a *agent) createUserMessage(ctx context.Context, sessionID, content string
 func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msgHistory []message.Message) (message.Message, *message.Message, error) {
 	ctx = context.WithValue(ctx, tools.SessionIDContextKey, sessionID)
 
+	sessionProvider, providerID := a.sessionProvider(sessionID)
+	model := sessionProvider.Model()
+
 	// Create the assistant message first so the spinner shows immediately
 	assistantMsg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
 		Role:     message.Assistant,
 		Parts:    []message.ContentPart{},
-		Model:    a.Model().ID,
-		Provider: a.providerID,
+		Model:    model.ID,
+		Provider: providerID,
 	})
 	if err != nil {
 		return assistantMsg, nil, fmt.Errorf("failed to create assistant message: %w", err)
 	}
 
 	// Now collect tools (which may block on MCP initialization)
-	eventChan := a.provider.StreamResponse(ctx, msgHistory, slices.Collect(a.tools.Seq()))
+	eventChan := sessionProvider.StreamResponse(ctx, historyFor(msgHistory, providerID), a.availableTools())
 
 	// Add the session and message ID into the context if needed by tools.
 	ctx = context.WithValue(ctx, tools.MessageIDContextKey, assistantMsg.ID)
 
 	// Process each event in the stream.
 	for event := range eventChan {
+		// Transient errors are retried by the provider, errors that another
+		// model may not run into, like an exhausted quota, make the session
+		// switch to the next fallback model.
+		if event.Type == provider.EventError && ctx.Err() == nil && provider.IsFallbackError(event.Error) && a.fallBack(sessionID) {
+			next, nextID := a.sessionProvider(sessionID)
+			slog.Warn("Provider failed, falling back to the next model", "provider", providerID, "model", model.ID, "fallback_provider", nextID, "fallback_model", next.Model().ID, "error", event.Error)
+			a.finishMessage(ctx, &assistantMsg, message.FinishReasonError, "API Error", fmt.Sprintf("%s\n\nFalling back to %s (%s).", event.Error, next.Model().ID, nextID))
+			return a.streamAndHandleEvents(ctx, sessionID, msgHistory)
+		}
 		if processErr := a.processEvent(ctx, sessionID, &assistantMsg, event); processErr != nil {
 			if errors.Is(processErr, context.Canceled) {
 				a.finishMessage(context.Background(), &assistantMsg, message.FinishReasonCanceled, "Request cancelled", "")
@@ -538,6 +688,11 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 
 	toolResults := make([]message.ToolResult, len(assistantMsg.ToolCalls()))
 	toolCalls := assistantMsg.ToolCalls()
+	progress := &toolProgress{
+		messages:  a.messages,
+		sessionID: assistantMsg.SessionID,
+		provider:  providerID,
+	}
 	for i, toolCall := range toolCalls {
 		select {
 		case <-ctx.Done():
@@ -554,7 +709,7 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 		default:
 			// Continue processing
 			var tool tools.BaseTool
-			for availableTool := range a.tools.Seq() {
+			for _, availableTool := range a.availableTools() {
 				if availableTool.Info().Name == toolCall.Name {
 					tool = availableTool
 					break
@@ -571,6 +726,25 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 				continue
 			}
 
+			input := toolCall.Input
+			if a.hooks.Has(hooks.EventPreToolCall) {
+				result := a.hooks.Run(ctx, hooks.Input{
+					Event:     hooks.EventPreToolCall,
+					SessionID: sessionID,
+					Agent:     a.agentCfg.ID,
+					ToolCall:  &hooks.ToolCall{ID: toolCall.ID, Name: toolCall.Name, Input: json.RawMessage(input)},
+				})
+				if result.Blocked {
+					toolResults[i] = message.ToolResult{
+						ToolCallID: toolCall.ID,
+						Content:    result.Reason,
+						IsError:    true,
+					}
+					continue
+				}
+				input = result.Input
+			}
+
 			// Run tool in goroutine to allow cancellation
 			type toolExecResult struct {
 				response tools.ToolResponse
@@ -578,11 +752,12 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 			}
 			resultChan := make(chan toolExecResult, 1)
 
+			toolCtx := context.WithValue(ctx, tools.ProgressContextKey, progress.report(toolCall.ID, toolResults[:i]))
 			go func() {
-				response, err := tool.Run(ctx, tools.ToolCall{
+				response, err := tool.Run(toolCtx, tools.ToolCall{
 					ID:    toolCall.ID,
 					Name:  toolCall.Name,
-					Input: toolCall.Input,
+					Input: input,
 				})
 				resultChan <- toolExecResult{response: response, err: err}
 			}()
@@ -610,6 +785,15 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 			if toolErr != nil {
 				slog.Error("Tool execution error", "toolCall", toolCall.ID, "error", toolErr)
 				if errors.Is(toolErr, permission.ErrorPermissionDenied) {
+					// Refused without asking the user, let the agent carry on
+					if reason, ok := a.permissions.Refusal(toolCall.ID); ok {
+						toolResults[i] = message.ToolResult{
+							ToolCallID: toolCall.ID,
+							Content:    reason,
+							IsError:    true,
+						}
+						continue
+					}
 					toolResults[i] = message.ToolResult{
 						ToolCallID: toolCall.ID,
 						Content:    "Permission denied",
@@ -626,11 +810,32 @@ func (a *agent) streamAndHandleEvents(ctx context.Context, sessionID string, msg
 					break
 				}
 			}
+			if a.hooks.Has(hooks.EventPostToolCall) {
+				result := a.hooks.Run(ctx, hooks.Input{
+					Event:        hooks.EventPostToolCall,
+					SessionID:    sessionID,
+					Agent:        a.agentCfg.ID,
+					ToolCall:     &hooks.ToolCall{ID: toolCall.ID, Name: toolCall.Name, Input: json.RawMessage(input)},
+					ToolResponse: &hooks.ToolResponse{Content: toolResponse.Content, IsError: toolResponse.IsError},
+				})
+				if result.Output != "" {
+					toolResponse.Content += "\n\n" + hooks.Note(hooks.EventPostToolCall, result.Output)
+				}
+				if result.Blocked {
+					toolResponse.Content += "\n\n" + hooks.Note(hooks.EventPostToolCall, result.Reason)
+					toolResponse.IsError = true
+				}
+			}
+			if toolResponse.Type == tools.ToolResponseTypeImage && !model.SupportsImages {
+				toolResponse = tools.NewTextErrorResponse(toolResponse.Content + "\n\nThe current model does not support images, so the image can't be shown.")
+			}
 			toolResults[i] = message.ToolResult{
 				ToolCallID: toolCall.ID,
 				Content:    toolResponse.Content,
 				Metadata:   toolResponse.Metadata,
 				IsError:    toolResponse.IsError,
+				Data:       toolResponse.Data,
+				MIMEType:   toolResponse.MIMEType,
 			}
 		}
 	}
@@ -638,15 +843,7 @@ out:
 	if len(toolResults) == 0 {
 		return assistantMsg, nil, nil
 	}
-	parts := make([]message.ContentPart, 0)
-	for _, tr := range toolResults {
-		parts = append(parts, tr)
-	}
-	msg, err := a.messages.Create(context.Background(), assistantMsg.SessionID, message.CreateMessageParams{
-		Role:     message.Tool,
-		Parts:    parts,
-		Provider: a.providerID,
-	})
+	msg, err := progress.finish(context.Background(), toolResults)
 	if err != nil {
 		return assistantMsg, nil, fmt.Errorf("failed to create cancelled tool message: %w", err)
 	}
@@ -674,6 +871,9 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 	case provider.EventSignatureDelta:
 		assistantMsg.AppendReasoningSignature(event.Signature)
 		return a.messages.Update(ctx, *assistantMsg)
+	case provider.EventReasoningItem:
+		assistantMsg.SetReasoningItem(event.ItemID, event.Signature)
+		return a.messages.Update(ctx, *assistantMsg)
 	case provider.EventContentDelta:
 		assistantMsg.FinishThinking()
 		assistantMsg.AppendContent(event.Content)
@@ -692,6 +892,22 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 		return a.messages.Update(ctx, *assistantMsg)
 	case provider.EventError:
 		return event.Error
+	case provider.EventWarning:
+		var retryErr *provider.RetryError
+		if errors.As(event.Error, &retryErr) {
+			// The response is requested again from scratch, drop the partial one.
+			assistantMsg.Parts = []message.ContentPart{}
+			if err := a.messages.Update(ctx, *assistantMsg); err != nil {
+				return fmt.Errorf("failed to update message: %w", err)
+			}
+		}
+		slog.Warn("Provider warning", "error", event.Error)
+		a.Publish(pubsub.CreatedEvent, AgentEvent{
+			Type:      AgentEventTypeWarning,
+			SessionID: sessionID,
+			Error:     event.Error,
+		})
+		return nil
 	case provider.EventComplete:
 		assistantMsg.FinishThinking()
 		assistantMsg.SetToolCalls(event.Response.ToolCalls)
@@ -699,7 +915,8 @@ func (a *agent) processEvent(ctx context.Context, sessionID string, assistantMsg
 		if err := a.messages.Update(ctx, *assistantMsg); err != nil {
 			return fmt.Errorf("failed to update message: %w", err)
 		}
-		return a.TrackUsage(ctx, sessionID, a.Model(), event.Response.Usage)
+		sessionProvider, _ := a.sessionProvider(sessionID)
+		return a.TrackUsage(ctx, sessionID, sessionProvider.Model(), event.Response.Usage)
 	}
 
 	return nil
@@ -948,14 +1165,14 @@ func (a *agent) UpdateModel() error {
 			return fmt.Errorf("model not found for agent %s", a.agentCfg.Name)
 		}
 
-		promptID := agentPromptMap[a.agentCfg.ID]
-		if promptID == "" {
-			promptID = prompt.PromptDefault
+		systemPrompt, err := agentSystemPrompt(a.agentCfg, currentProviderCfg.ID)
+		if err != nil {
+			return fmt.Errorf("failed to build system prompt for agent %s: %w", a.agentCfg.Name, err)
 		}
 
 		opts := []provider.ProviderClientOption{
 			provider.WithModel(a.agentCfg.Model),
-			provider.WithSystemMessage(prompt.GetPrompt(promptID, currentProviderCfg.ID, cfg.Options.ContextPaths...)),
+			provider.WithSystemMessage(systemPrompt),
 		}
 
 		newProvider, err := provider.NewProvider(*currentProviderCfg, opts...)
@@ -964,8 +1181,10 @@ func (a *agent) UpdateModel() error {
 		}
 
 		// Update the provider and provider ID
+		a.modelMu.Lock()
 		a.provider = newProvider
 		a.providerID = string(currentProviderCfg.ID)
+		a.modelMu.Unlock()
 	}
 
 	// Check if providers have changed for title (small) and summarize (large)
@@ -1023,5 +1242,12 @@ func (a *agent) UpdateModel() error {
 		a.summarizeProviderID = string(largeModelProviderCfg.ID)
 	}
 
+	// Sessions that fell back go back to the newly selected model
+	fallbacks := newFallbackProviders(a.agentCfg)
+	a.modelMu.Lock()
+	a.fallbacks = fallbacks
+	a.sessionFallbacks = csync.NewMap[string, int]()
+	a.modelMu.Unlock()
+
 	return nil
 }
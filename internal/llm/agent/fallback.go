@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"log/slog"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// fallbackProvider is the provider of a fallback model of the agent.
+type fallbackProvider struct {
+	provider   provider.Provider
+	providerID string
+}
+
+// newFallbackProviders creates the providers of the fallback models of the
+// agent's model. Fallbacks that are not configured are skipped.
+func newFallbackProviders(agentCfg config.Agent) []fallbackProvider {
+	cfg := config.Get()
+	var fallbacks []fallbackProvider
+	for _, fallback := range cfg.Models[agentCfg.Model].Fallbacks {
+		providerCfg, ok := cfg.Providers.Get(fallback.Provider)
+		if !ok || providerCfg.Disable || cfg.GetModel(fallback.Provider, fallback.Model) == nil {
+			slog.Warn("Skipping fallback model that is not configured", "agent", agentCfg.ID, "provider", fallback.Provider, "model", fallback.Model)
+			continue
+		}
+		systemPrompt, err := agentSystemPrompt(agentCfg, providerCfg.ID)
+		if err != nil {
+			slog.Warn("Skipping fallback model", "agent", agentCfg.ID, "provider", fallback.Provider, "model", fallback.Model, "error", err)
+			continue
+		}
+		fallbackProv, err := provider.NewProvider(providerCfg,
+			provider.WithModel(agentCfg.Model),
+			provider.WithSelectedModel(fallback),
+			provider.WithSystemMessage(systemPrompt),
+		)
+		if err != nil {
+			slog.Warn("Skipping fallback model", "agent", agentCfg.ID, "provider", fallback.Provider, "model", fallback.Model, "error", err)
+			continue
+		}
+		fallbacks = append(fallbacks, fallbackProvider{provider: fallbackProv, providerID: providerCfg.ID})
+	}
+	return fallbacks
+}
+
+// sessionProvider returns the provider answering in the session, which is the
+// agent's provider unless the session fell back to one of its fallbacks.
+func (a *agent) sessionProvider(sessionID string) (provider.Provider, string) {
+	a.modelMu.RLock()
+	defer a.modelMu.RUnlock()
+	if i, ok := a.sessionFallbacks.Get(sessionID); ok && i < len(a.fallbacks) {
+		return a.fallbacks[i].provider, a.fallbacks[i].providerID
+	}
+	return a.provider, a.providerID
+}
+
+// fallBack switches the session to the next fallback provider. The session
+// keeps using it until the model is changed. It reports false when there is
+// no fallback left.
+func (a *agent) fallBack(sessionID string) bool {
+	a.modelMu.Lock()
+	defer a.modelMu.Unlock()
+	next := 0
+	if i, ok := a.sessionFallbacks.Get(sessionID); ok {
+		next = i + 1
+	}
+	if next >= len(a.fallbacks) {
+		return false
+	}
+	a.sessionFallbacks.Set(sessionID, next)
+	return true
+}
+
+// historyFor prepares the message history for the given provider. Reasoning
+// is signed by the provider that produced it and can't be verified by other
+// providers, so it is dropped from their messages.
+func historyFor(msgs []message.Message, providerID string) []message.Message {
+	history := make([]message.Message, len(msgs))
+	for i, msg := range msgs {
+		if msg.Role == message.Assistant && msg.Provider != "" && msg.Provider != providerID {
+			msg.Parts = slices.DeleteFunc(slices.Clone(msg.Parts), func(part message.ContentPart) bool {
+				_, ok := part.(message.ReasoningContent)
+				return ok
+			})
+		}
+		history[i] = msg
+	}
+	return history
+}
@@ -0,0 +1,106 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/hooks"
+	"github.com/charmbracelet/crush/internal/llm/provider"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProvider answers with the given events and records the history it got.
+type fakeProvider struct {
+	model   string
+	events  []provider.ProviderEvent
+	history []message.Message
+}
+
+func (p *fakeProvider) SendMessages(context.Context, []message.Message, []tools.BaseTool) (*provider.ProviderResponse, error) {
+	return nil, errors.New("not implemented")
+}
+
+func (p *fakeProvider) StreamResponse(_ context.Context, messages []message.Message, _ []tools.BaseTool) <-chan provider.ProviderEvent {
+	p.history = messages
+	events := make(chan provider.ProviderEvent, len(p.events))
+	for _, event := range p.events {
+		events <- event
+	}
+	close(events)
+	return events
+}
+
+func (p *fakeProvider) Model() catwalk.Model {
+	return catwalk.Model{ID: p.model}
+}
+
+func TestAgentFallback(t *testing.T) {
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	sessions := session.NewService(q)
+	messages := message.NewService(q)
+
+	primary := &fakeProvider{model: "primary-model", events: []provider.ProviderEvent{
+		{Type: provider.EventError, Error: errors.New("insufficient_quota")},
+	}}
+	backup := &fakeProvider{model: "backup-model", events: []provider.ProviderEvent{
+		{Type: provider.EventContentDelta, Content: "Hello!"},
+		{Type: provider.EventComplete, Response: &provider.ProviderResponse{FinishReason: message.FinishReasonEndTurn}},
+	}}
+	a := &agent{
+		sessions:         sessions,
+		messages:         messages,
+		hooks:            hooks.NewRunner(nil, t.TempDir()),
+		tools:            csync.NewLazySlice(func() []tools.BaseTool { return nil }),
+		provider:         primary,
+		providerID:       "primary",
+		fallbacks:        []fallbackProvider{{provider: backup, providerID: "backup"}},
+		sessionFallbacks: csync.NewMap[string, int](),
+	}
+
+	sess, err := sessions.Create(t.Context(), "test")
+	require.NoError(t, err)
+	history := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Hi"}}},
+		{Role: message.Assistant, Provider: "primary", Parts: []message.ContentPart{
+			message.ReasoningContent{Thinking: "Greeting", Signature: "signed"},
+			message.TextContent{Text: "Hello"},
+		}},
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Hi again"}}},
+	}
+
+	msg, _, err := a.streamAndHandleEvents(t.Context(), sess.ID, history)
+	require.NoError(t, err)
+	require.Equal(t, "Hello!", msg.Content().Text)
+	require.Equal(t, "backup", msg.Provider)
+	require.Equal(t, "backup-model", msg.Model)
+
+	// The reasoning signed by the primary provider is not sent to the backup.
+	require.Equal(t, "Greeting", primary.history[1].ReasoningContent().Thinking)
+	require.Empty(t, backup.history[1].ReasoningContent().Thinking)
+	require.Equal(t, "Hello", backup.history[1].Content().Text)
+
+	// The failed attempt is kept with the error.
+	msgs, err := messages.List(t.Context(), sess.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	require.Equal(t, "primary", msgs[0].Provider)
+	require.Equal(t, message.FinishReasonError, msgs[0].FinishReason())
+	require.Contains(t, msgs[0].FinishPart().Details, "insufficient_quota")
+
+	// The session keeps using the backup, which has no fallback left.
+	backup.events = primary.events
+	primary.history = nil
+	_, _, err = a.streamAndHandleEvents(t.Context(), sess.ID, history)
+	require.EqualError(t, err, "insufficient_quota")
+	require.Nil(t, primary.history)
+}
@@ -0,0 +1,197 @@
+package agent
+
+import (
+	"cmp"
+	"context"
+	"encoding/base64"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// MCPPrompt is a prompt offered by an MCP server.
+type MCPPrompt struct {
+	MCPName string
+	mcp.Prompt
+}
+
+// MCPResource is a resource offered by an MCP server.
+type MCPResource struct {
+	MCPName string
+	mcp.Resource
+}
+
+var (
+	mcpPrompts   = csync.NewMap[string, []mcp.Prompt]()
+	mcpResources = csync.NewMap[string, []mcp.Resource]()
+)
+
+// getPromptsAndResources caches the prompts and resources of an MCP server,
+// if it offers any.
+func getPromptsAndResources(ctx context.Context, name string, c *client.Client) {
+	capabilities := c.GetServerCapabilities()
+	if capabilities.Prompts != nil {
+		result, err := c.ListPrompts(ctx, mcp.ListPromptsRequest{})
+		if err != nil {
+			slog.Error("error listing prompts", "error", err, "name", name)
+		} else {
+			mcpPrompts.Set(name, result.Prompts)
+		}
+	}
+	if capabilities.Resources != nil {
+		result, err := c.ListResources(ctx, mcp.ListResourcesRequest{})
+		if err != nil {
+			slog.Error("error listing resources", "error", err, "name", name)
+		} else {
+			mcpResources.Set(name, result.Resources)
+		}
+	}
+}
+
+// GetMCPPrompts returns the prompts of all connected MCP servers.
+func GetMCPPrompts() []MCPPrompt {
+	var prompts []MCPPrompt
+	for name, list := range mcpPrompts.Seq2() {
+		for _, p := range list {
+			prompts = append(prompts, MCPPrompt{MCPName: name, Prompt: p})
+		}
+	}
+	slices.SortFunc(prompts, func(a, b MCPPrompt) int {
+		return cmp.Or(cmp.Compare(a.MCPName, b.MCPName), cmp.Compare(a.Name, b.Name))
+	})
+	return prompts
+}
+
+// GetMCPResources returns the resources of all connected MCP servers.
+func GetMCPResources() []MCPResource {
+	var resources []MCPResource
+	for name, list := range mcpResources.Seq2() {
+		for _, r := range list {
+			resources = append(resources, MCPResource{MCPName: name, Resource: r})
+		}
+	}
+	slices.SortFunc(resources, func(a, b MCPResource) int {
+		return cmp.Or(cmp.Compare(a.MCPName, b.MCPName), cmp.Compare(a.Name, b.Name))
+	})
+	return resources
+}
+
+// GetMCPPrompt gets a prompt from an MCP server and returns its messages as
+// text to be sent to the agent.
+func GetMCPPrompt(ctx context.Context, mcpName, promptName string, args map[string]string) (string, error) {
+	c, err := getOrRenewClient(ctx, mcpName)
+	if err != nil {
+		return "", err
+	}
+	ctx, cancel := context.WithTimeout(ctx, mcpTimeout(config.Get().MCP[mcpName]))
+	defer cancel()
+	content, err := getPrompt(ctx, c, promptName, args)
+	if err != nil {
+		return "", fmt.Errorf("failed to get prompt %s from mcp %s: %w", promptName, mcpName, err)
+	}
+	return content, nil
+}
+
+func getPrompt(ctx context.Context, c *client.Client, name string, args map[string]string) (string, error) {
+	result, err := c.GetPrompt(ctx, mcp.GetPromptRequest{
+		Params: mcp.GetPromptParams{
+			Name:      name,
+			Arguments: args,
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+
+	parts := make([]string, 0, len(result.Messages))
+	for _, msg := range result.Messages {
+		if text, ok := mcp.AsTextContent(msg.Content); ok {
+			parts = append(parts, text.Text)
+		} else if res, ok := mcp.AsEmbeddedResource(msg.Content); ok {
+			if text, ok := mcp.AsTextResourceContents(res.Resource); ok {
+				parts = append(parts, resourceText(text.URI, text.Text))
+			}
+		}
+	}
+	if len(parts) == 0 {
+		return "", fmt.Errorf("prompt has no text")
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// ReadMCPResource reads a resource from an MCP server as an attachment. Text
+// resources are added to the prompt when it's sent, images are attached as
+// they are.
+func ReadMCPResource(ctx context.Context, mcpName string, resource mcp.Resource) (message.Attachment, error) {
+	c, err := getOrRenewClient(ctx, mcpName)
+	if err != nil {
+		return message.Attachment{}, err
+	}
+	ctx, cancel := context.WithTimeout(ctx, mcpTimeout(config.Get().MCP[mcpName]))
+	defer cancel()
+	attachment, err := readResource(ctx, c, resource)
+	if err != nil {
+		return message.Attachment{}, fmt.Errorf("failed to read resource %s from mcp %s: %w", resource.URI, mcpName, err)
+	}
+	return attachment, nil
+}
+
+func readResource(ctx context.Context, c *client.Client, resource mcp.Resource) (message.Attachment, error) {
+	result, err := c.ReadResource(ctx, mcp.ReadResourceRequest{
+		Params: mcp.ReadResourceParams{URI: resource.URI},
+	})
+	if err != nil {
+		return message.Attachment{}, err
+	}
+
+	attachment := message.Attachment{
+		FilePath: resource.URI,
+		FileName: resource.Name,
+		MimeType: "text/plain",
+	}
+	var texts []string
+	for _, contents := range result.Contents {
+		if text, ok := mcp.AsTextResourceContents(contents); ok {
+			texts = append(texts, text.Text)
+			continue
+		}
+		blob, ok := mcp.AsBlobResourceContents(contents)
+		if !ok || !strings.HasPrefix(blob.MIMEType, "image/") || len(result.Contents) > 1 {
+			return message.Attachment{}, fmt.Errorf("resource is neither text nor a single image")
+		}
+		data, err := base64.StdEncoding.DecodeString(blob.Blob)
+		if err != nil {
+			return message.Attachment{}, fmt.Errorf("failed to decode image: %w", err)
+		}
+		attachment.MimeType = blob.MIMEType
+		attachment.Content = data
+		return attachment, nil
+	}
+	attachment.Content = []byte(strings.Join(texts, "\n"))
+	return attachment, nil
+}
+
+// inlineTextAttachments adds the content of text attachments, such as MCP
+// resources, to the prompt and returns the remaining attachments.
+func inlineTextAttachments(content string, attachments []message.Attachment) (string, []message.Attachment) {
+	var rest []message.Attachment
+	for _, attachment := range attachments {
+		if strings.HasPrefix(attachment.MimeType, "image/") {
+			rest = append(rest, attachment)
+			continue
+		}
+		content += "\n\n" + resourceText(attachment.FilePath, string(attachment.Content))
+	}
+	return content, rest
+}
+
+func resourceText(uri, text string) string {
+	return fmt.Sprintf("<resource uri=%q>\n%s\n</resource>", uri, text)
+}
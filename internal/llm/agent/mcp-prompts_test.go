@@ -0,0 +1,98 @@
+package agent
+
+import (
+	"context"
+	"encoding/base64"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPPromptsAndResources(t *testing.T) {
+	s := server.NewMCPServer("docs", "1.0.0",
+		server.WithPromptCapabilities(false),
+		server.WithResourceCapabilities(false, false),
+	)
+	s.AddPrompt(mcp.NewPrompt("review",
+		mcp.WithPromptDescription("Review a change"),
+		mcp.WithArgument("focus", mcp.RequiredArgument()),
+	), func(_ context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+		return mcp.NewGetPromptResult("", []mcp.PromptMessage{
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent("Review the change, focusing on "+req.Params.Arguments["focus"]+".")),
+			mcp.NewPromptMessage(mcp.RoleUser, mcp.NewEmbeddedResource(mcp.TextResourceContents{
+				URI:  "docs://style",
+				Text: "Use tabs.",
+			})),
+		}), nil
+	})
+	s.AddResource(mcp.NewResource("docs://style", "style"), func(_ context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return []mcp.ResourceContents{mcp.TextResourceContents{URI: req.Params.URI, Text: "Use tabs."}}, nil
+	})
+	s.AddResource(mcp.NewResource("docs://logo", "logo"), func(_ context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return []mcp.ResourceContents{mcp.BlobResourceContents{
+			URI:      req.Params.URI,
+			MIMEType: "image/png",
+			Blob:     base64.StdEncoding.EncodeToString([]byte("png")),
+		}}, nil
+	})
+
+	c, err := client.NewInProcessClient(s)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	require.NoError(t, c.Start(t.Context()))
+	_, err = c.Initialize(t.Context(), mcpInitRequest)
+	require.NoError(t, err)
+
+	getPromptsAndResources(t.Context(), "docs", c)
+	t.Cleanup(func() {
+		mcpPrompts.Del("docs")
+		mcpResources.Del("docs")
+	})
+
+	t.Run("prompts", func(t *testing.T) {
+		prompts := GetMCPPrompts()
+		require.Len(t, prompts, 1)
+		require.Equal(t, "docs", prompts[0].MCPName)
+		require.Equal(t, "review", prompts[0].Name)
+		require.Len(t, prompts[0].Arguments, 1)
+
+		content, err := getPrompt(t.Context(), c, "review", map[string]string{"focus": "errors"})
+		require.NoError(t, err)
+		require.Equal(t, "Review the change, focusing on errors.\n\n<resource uri=\"docs://style\">\nUse tabs.\n</resource>", content)
+	})
+
+	t.Run("resources", func(t *testing.T) {
+		resources := GetMCPResources()
+		require.Len(t, resources, 2)
+		require.Equal(t, "logo", resources[0].Name)
+		require.Equal(t, "style", resources[1].Name)
+
+		attachment, err := readResource(t.Context(), c, resources[1].Resource)
+		require.NoError(t, err)
+		require.Equal(t, message.Attachment{
+			FilePath: "docs://style",
+			FileName: "style",
+			MimeType: "text/plain",
+			Content:  []byte("Use tabs."),
+		}, attachment)
+
+		attachment, err = readResource(t.Context(), c, resources[0].Resource)
+		require.NoError(t, err)
+		require.Equal(t, "image/png", attachment.MimeType)
+		require.Equal(t, []byte("png"), attachment.Content)
+	})
+
+	t.Run("text attachments are inlined", func(t *testing.T) {
+		image := message.Attachment{FileName: "logo", MimeType: "image/png"}
+		content, attachments := inlineTextAttachments("Fix the style.", []message.Attachment{
+			{FilePath: "docs://style", MimeType: "text/plain", Content: []byte("Use tabs.")},
+			image,
+		})
+		require.Equal(t, "Fix the style.\n\n<resource uri=\"docs://style\">\nUse tabs.\n</resource>", content)
+		require.Equal(t, []message.Attachment{image}, attachments)
+	})
+}
@@ -10,6 +10,7 @@ import (
 	"slices"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/charmbracelet/crush/internal/config"
@@ -75,13 +76,29 @@ type MCPClientInfo struct {
 }
 
 var (
-	mcpToolsOnce sync.Once
-	mcpTools     []tools.BaseTool
-	mcpClients   = csync.NewMap[string, *client.Client]()
-	mcpStates    = csync.NewMap[string, MCPClientInfo]()
-	mcpBroker    = pubsub.NewBroker[MCPEvent]()
+	mcpToolsOnce  sync.Once
+	mcpTools      = csync.NewMap[string, []tools.BaseTool]()
+	mcpToolOpts   atomic.Pointer[mcpToolOptions]
+	mcpClients    = csync.NewMap[string, *client.Client]()
+	mcpStates     = csync.NewMap[string, MCPClientInfo]()
+	mcpReconnects = csync.NewMap[string, *mcpReconnect]()
+	mcpBroker     = pubsub.NewBroker[MCPEvent]()
+	mcpRetryDelay = 2 * time.Second
+	mcpMaxRetries = 5
 )
 
+// mcpToolOptions are what MCP tools are created with, set when the agent's
+// tools are first loaded so servers can be restarted later.
+type mcpToolOptions struct {
+	permissions permission.Service
+	workingDir  string
+}
+
+// mcpReconnect is a running attempt to reconnect to an MCP server.
+type mcpReconnect struct {
+	cancel context.CancelFunc
+}
+
 type McpTool struct {
 	mcpName     string
 	tool        mcp.Tool
@@ -148,23 +165,19 @@ func getOrRenewClient(ctx context.Context, name string) (*client.Client, error)
 	}
 
 	m := config.Get().MCP[name]
-	state, _ := mcpStates.Get(name)
-
 	pingCtx, cancel := context.WithTimeout(ctx, mcpTimeout(m))
 	defer cancel()
 	err := c.Ping(pingCtx)
 	if err == nil {
 		return c, nil
 	}
-	updateMCPState(name, MCPStateError, err, nil, state.ToolCount)
+	slog.Warn("mcp server not responding, restarting it", "name", name, "error", err)
 
-	c, err = createAndInitializeClient(ctx, name, m)
+	c, err = startMCP(ctx, name, m)
 	if err != nil {
+		reconnectMCP(name)
 		return nil, err
 	}
-
-	updateMCPState(name, MCPStateConnected, nil, c, state.ToolCount)
-	mcpClients.Set(name, c)
 	return c, nil
 }
 
@@ -192,25 +205,34 @@ func (b *McpTool) Run(ctx context.Context, params tools.ToolCall) (tools.ToolRes
 	return runTool(ctx, b.mcpName, b.tool.Name, params.Input)
 }
 
-func getTools(ctx context.Context, name string, permissions permission.Service, c *client.Client, workingDir string) []tools.BaseTool {
+func getTools(ctx context.Context, name string, c *client.Client) ([]tools.BaseTool, error) {
 	result, err := c.ListTools(ctx, mcp.ListToolsRequest{})
 	if err != nil {
-		slog.Error("error listing tools", "error", err)
-		updateMCPState(name, MCPStateError, err, nil, 0)
-		c.Close()
-		mcpClients.Del(name)
-		return nil
+		return nil, err
 	}
+	opts := mcpToolOpts.Load()
 	mcpTools := make([]tools.BaseTool, 0, len(result.Tools))
 	for _, tool := range result.Tools {
 		mcpTools = append(mcpTools, &McpTool{
 			mcpName:     name,
 			tool:        tool,
-			permissions: permissions,
-			workingDir:  workingDir,
+			permissions: opts.permissions,
+			workingDir:  opts.workingDir,
 		})
 	}
-	return mcpTools
+	return mcpTools, nil
+}
+
+// getMCPTools returns the tools of all connected MCP servers, ordered by
+// server so the tools sent to the model stay the same between requests.
+func getMCPTools() []tools.BaseTool {
+	names := slices.Sorted(maps.Keys(maps.Collect(mcpTools.Seq2())))
+	var result []tools.BaseTool
+	for _, name := range names {
+		serverTools, _ := mcpTools.Get(name)
+		result = append(result, serverTools...)
+	}
+	return result
 }
 
 // SubscribeMCPEvents returns a channel for MCP events
@@ -270,11 +292,14 @@ var mcpInitRequest = mcp.InitializeRequest{
 	},
 }
 
-func doGetMCPTools(ctx context.Context, permissions permission.Service, cfg *config.Config) []tools.BaseTool {
-	var wg sync.WaitGroup
-	result := csync.NewSlice[tools.BaseTool]()
+// startMCPs connects to all enabled MCP servers and loads their tools.
+func startMCPs(ctx context.Context, permissions permission.Service, cfg *config.Config) {
+	mcpToolOpts.Store(&mcpToolOptions{
+		permissions: permissions,
+		workingDir:  cfg.WorkingDir(),
+	})
 
-	// Initialize states for all configured MCPs
+	var wg sync.WaitGroup
 	for name, m := range cfg.MCP {
 		if m.Disabled {
 			updateMCPState(name, MCPStateDisabled, nil, nil, 0)
@@ -282,43 +307,186 @@ func doGetMCPTools(ctx context.Context, permissions permission.Service, cfg *con
 			continue
 		}
 
-		// Set initial starting state
-		updateMCPState(name, MCPStateStarting, nil, nil, 0)
-
 		wg.Add(1)
 		go func(name string, m config.MCPConfig) {
-			defer func() {
-				wg.Done()
-				if r := recover(); r != nil {
-					var err error
-					switch v := r.(type) {
-					case error:
-						err = v
-					case string:
-						err = fmt.Errorf("panic: %s", v)
-					default:
-						err = fmt.Errorf("panic: %v", v)
-					}
-					updateMCPState(name, MCPStateError, err, nil, 0)
-					slog.Error("panic in mcp client initialization", "error", err, "name", name)
-				}
-			}()
-
-			ctx, cancel := context.WithTimeout(ctx, mcpTimeout(m))
-			defer cancel()
-			c, err := createAndInitializeClient(ctx, name, m)
-			if err != nil {
-				return
+			defer wg.Done()
+			if _, err := startMCP(ctx, name, m); err != nil {
+				reconnectMCP(name)
 			}
-			mcpClients.Set(name, c)
-
-			tools := getTools(ctx, name, permissions, c, cfg.WorkingDir())
-			updateMCPState(name, MCPStateConnected, nil, c, len(tools))
-			result.Append(tools...)
 		}(name, m)
 	}
 	wg.Wait()
-	return slices.Collect(result.Seq())
+}
+
+// startMCP connects to an MCP server, replacing the previous connection if
+// there is one, and loads its tools, prompts and resources.
+func startMCP(ctx context.Context, name string, m config.MCPConfig) (c *client.Client, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			switch v := r.(type) {
+			case error:
+				err = v
+			case string:
+				err = fmt.Errorf("panic: %s", v)
+			default:
+				err = fmt.Errorf("panic: %v", v)
+			}
+			updateMCPState(name, MCPStateError, err, nil, 0)
+			slog.Error("panic in mcp client initialization", "error", err, "name", name)
+		}
+	}()
+
+	if old, ok := mcpClients.Take(name); ok {
+		_ = old.Close()
+	}
+	updateMCPState(name, MCPStateStarting, nil, nil, 0)
+
+	ctx, cancel := context.WithTimeout(ctx, mcpTimeout(m))
+	defer cancel()
+	c, err = createAndInitializeClient(ctx, name, m)
+	if err != nil {
+		return nil, err
+	}
+	serverTools, err := getTools(ctx, name, c)
+	if err != nil {
+		slog.Error("error listing tools", "error", err, "name", name)
+		updateMCPState(name, MCPStateError, err, nil, 0)
+		_ = c.Close()
+		return nil, err
+	}
+	getPromptsAndResources(ctx, name, c)
+
+	watchMCP(name, m, c)
+	mcpClients.Set(name, c)
+	mcpTools.Set(name, serverTools)
+	updateMCPState(name, MCPStateConnected, nil, c, len(serverTools))
+	return c, nil
+}
+
+// watchMCP reloads the tools, prompts and resources of an MCP server when it
+// notifies us they changed, and reconnects if the connection is lost.
+func watchMCP(name string, m config.MCPConfig, c *client.Client) {
+	c.OnNotification(func(n mcp.JSONRPCNotification) {
+		// Requests can't be made from the notification handler, as it runs
+		// on the goroutine reading the responses.
+		go refreshMCP(name, m, c, n.Method)
+	})
+	c.OnConnectionLost(func(err error) {
+		if current, ok := mcpClients.Get(name); !ok || current != c {
+			return
+		}
+		slog.Warn("lost connection to mcp server", "name", name, "error", err)
+		updateMCPState(name, MCPStateError, err, nil, 0)
+		reconnectMCP(name)
+	})
+}
+
+// refreshMCP reloads what an MCP server notified us has changed.
+func refreshMCP(name string, m config.MCPConfig, c *client.Client, method string) {
+	if current, ok := mcpClients.Get(name); !ok || current != c {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), mcpTimeout(m))
+	defer cancel()
+	switch method {
+	case mcp.MethodNotificationToolsListChanged:
+		serverTools, err := getTools(ctx, name, c)
+		if err != nil {
+			slog.Error("error refreshing tools", "error", err, "name", name)
+			return
+		}
+		slog.Info("Refreshed mcp tools", "name", name, "count", len(serverTools))
+		mcpTools.Set(name, serverTools)
+		updateMCPState(name, MCPStateConnected, nil, c, len(serverTools))
+	case mcp.MethodNotificationPromptsListChanged, mcp.MethodNotificationResourcesListChanged:
+		getPromptsAndResources(ctx, name, c)
+	}
+}
+
+// reconnectMCP tries to connect to an MCP server again in the background,
+// backing off between attempts, unless it's already doing so.
+func reconnectMCP(name string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	r := &mcpReconnect{cancel: cancel}
+	if mcpReconnects.GetOrSet(name, func() *mcpReconnect { return r }) != r {
+		cancel()
+		return
+	}
+
+	go func() {
+		defer func() {
+			if current, ok := mcpReconnects.Get(name); ok && current == r {
+				mcpReconnects.Del(name)
+			}
+			cancel()
+		}()
+		delay := mcpRetryDelay
+		for attempt := 1; attempt <= mcpMaxRetries; attempt++ {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			m, ok := config.Get().MCP[name]
+			if !ok {
+				return
+			}
+			slog.Info("Reconnecting to mcp server", "name", name, "attempt", attempt)
+			if _, err := startMCP(ctx, name, m); err == nil {
+				return
+			}
+			delay *= 2
+		}
+		slog.Error("giving up reconnecting to mcp server", "name", name)
+	}()
+}
+
+// stopMCP disconnects from an MCP server and drops its tools, prompts and
+// resources.
+func stopMCP(name string) {
+	if r, ok := mcpReconnects.Take(name); ok {
+		r.cancel()
+	}
+	if c, ok := mcpClients.Take(name); ok {
+		_ = c.Close()
+	}
+	mcpTools.Del(name)
+	mcpPrompts.Del(name)
+	mcpResources.Del(name)
+}
+
+// RestartMCP reconnects to an MCP server and reloads its tools.
+func RestartMCP(ctx context.Context, name string) error {
+	m, ok := config.Get().MCP[name]
+	if !ok {
+		return fmt.Errorf("mcp '%s' not found", name)
+	}
+	if mcpToolOpts.Load() == nil {
+		return fmt.Errorf("mcp servers are still starting")
+	}
+	stopMCP(name)
+	if _, err := startMCP(ctx, name, m); err != nil {
+		return fmt.Errorf("failed to restart mcp '%s': %w", name, err)
+	}
+	return nil
+}
+
+// SetMCPEnabled connects to or disconnects from an MCP server, and saves
+// whether it's disabled so it stays that way.
+func SetMCPEnabled(ctx context.Context, name string, enabled bool) error {
+	cfg := config.Get()
+	if _, ok := cfg.MCP[name]; !ok {
+		return fmt.Errorf("mcp '%s' not found", name)
+	}
+	if err := cfg.SetMCPDisabled(name, !enabled); err != nil {
+		return err
+	}
+	if !enabled {
+		stopMCP(name)
+		updateMCPState(name, MCPStateDisabled, nil, nil, 0)
+		return nil
+	}
+	return RestartMCP(ctx, name)
 }
 
 func createAndInitializeClient(ctx context.Context, name string, m config.MCPConfig) (*client.Client, error) {
@@ -0,0 +1,62 @@
+package agent
+
+import (
+	"context"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMCPToolsRefresh(t *testing.T) {
+	mcpToolOpts.Store(&mcpToolOptions{workingDir: t.TempDir()})
+	t.Cleanup(func() { mcpToolOpts.Store(nil) })
+
+	echo := func(_ context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	}
+	s := server.NewMCPServer("tools", "1.0.0", server.WithToolCapabilities(true))
+	s.AddTool(mcp.NewTool("search"), echo)
+
+	c, err := client.NewInProcessClient(s)
+	require.NoError(t, err)
+	require.NoError(t, c.Start(t.Context()))
+	_, err = c.Initialize(t.Context(), mcpInitRequest)
+	require.NoError(t, err)
+
+	serverTools, err := getTools(t.Context(), "docs", c)
+	require.NoError(t, err)
+	mcpClients.Set("docs", c)
+	mcpTools.Set("docs", serverTools)
+	mcpTools.Set("code", []tools.BaseTool{&McpTool{mcpName: "code", tool: mcp.NewTool("grep")}})
+	t.Cleanup(func() {
+		stopMCP("docs")
+		stopMCP("code")
+	})
+
+	toolNames := func() []string {
+		var names []string
+		for _, tool := range getMCPTools() {
+			names = append(names, tool.Name())
+		}
+		return names
+	}
+	require.Equal(t, []string{"mcp_code_grep", "mcp_docs_search"}, toolNames())
+
+	s.AddTool(mcp.NewTool("read"), echo)
+	refreshMCP("docs", config.MCPConfig{}, c, mcp.MethodNotificationToolsListChanged)
+	require.Equal(t, []string{"mcp_code_grep", "mcp_docs_read", "mcp_docs_search"}, toolNames())
+	state, ok := GetMCPState("docs")
+	require.True(t, ok)
+	require.Equal(t, MCPStateConnected, state.State)
+	require.Equal(t, 2, state.ToolCount)
+
+	stopMCP("docs")
+	require.Equal(t, []string{"mcp_code_grep"}, toolNames())
+	_, ok = mcpClients.Get("docs")
+	require.False(t, ok)
+}
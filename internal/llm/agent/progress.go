@@ -0,0 +1,81 @@
+package agent
+
+import (
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// toolProgress shows the output of the tool calls of a turn while they run.
+// The tool message of the turn is created on the first report, with the
+// results of the tool calls that finished and the output of the running one,
+// and is completed by finish.
+type toolProgress struct {
+	messages  message.Service
+	sessionID string
+	provider  string
+
+	mu     sync.Mutex
+	msg    *message.Message
+	closed bool
+}
+
+// report returns the progress function of a tool call run after the tool
+// calls with the given results.
+func (p *toolProgress) report(toolCallID string, finished []message.ToolResult) tools.ProgressFunc {
+	finished = slices.DeleteFunc(slices.Clone(finished), func(tr message.ToolResult) bool {
+		return tr.ToolCallID == ""
+	})
+	return func(response tools.ToolResponse) {
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if p.closed {
+			return
+		}
+		results := append(slices.Clone(finished), message.ToolResult{
+			ToolCallID: toolCallID,
+			Content:    response.Content,
+			Metadata:   response.Metadata,
+			IsError:    response.IsError,
+			Running:    true,
+		})
+		_, _ = p.save(context.Background(), results)
+	}
+}
+
+// finish saves the results of all tool calls to the tool message. Later
+// reports of tool calls that still run are ignored.
+func (p *toolProgress) finish(ctx context.Context, results []message.ToolResult) (message.Message, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.closed = true
+	return p.save(ctx, results)
+}
+
+// save creates the tool message with the results, or updates it.
+func (p *toolProgress) save(ctx context.Context, results []message.ToolResult) (message.Message, error) {
+	parts := make([]message.ContentPart, 0, len(results)+1)
+	for _, tr := range results {
+		parts = append(parts, tr)
+	}
+	if p.msg == nil {
+		msg, err := p.messages.Create(ctx, p.sessionID, message.CreateMessageParams{
+			Role:     message.Tool,
+			Parts:    parts,
+			Provider: p.provider,
+		})
+		if err != nil {
+			return msg, err
+		}
+		p.msg = &msg
+		return msg, nil
+	}
+	if finish := p.msg.FinishPart(); finish != nil {
+		parts = append(parts, *finish)
+	}
+	p.msg.Parts = parts
+	return *p.msg, p.messages.Update(ctx, *p.msg)
+}
@@ -0,0 +1,31 @@
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/charmbracelet/crush/internal/config"
+)
+
+// CustomPrompt builds a system prompt from a user provided prompt file,
+// adding the same environment and project context as the coder prompt.
+func CustomPrompt(path string, contextFiles ...string) (string, error) {
+	cwd := config.Get().WorkingDir()
+	path = expandPath(path)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(cwd, path)
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read system prompt file: %w", err)
+	}
+
+	basePrompt := fmt.Sprintf("%s\n\n%s\n%s", content, getEnvironmentInfo(), lspInformation())
+
+	contextContent := getContextFromPaths(cwd, contextFiles)
+	if contextContent != "" {
+		return fmt.Sprintf("%s\n\n# Project-Specific Context\n Make sure to follow the instructions in the context below\n%s", basePrompt, contextContent), nil
+	}
+	return basePrompt, nil
+}
@@ -128,7 +128,7 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 			blocks := []anthropic.ContentBlockParamUnion{}
 
 			// Add thinking blocks first if present (required when thinking is enabled with tool use)
-			if reasoningContent := msg.ReasoningContent(); reasoningContent.Thinking != "" {
+			if reasoningContent := msg.ReasoningContent(); reasoningContent.Thinking != "" && reasoningContent.ItemID == "" {
 				thinkingBlock := anthropic.NewThinkingBlock(reasoningContent.Signature, reasoningContent.Thinking)
 				blocks = append(blocks, thinkingBlock)
 			}
@@ -161,6 +161,11 @@ func (a *anthropicClient) convertMessages(messages []message.Message) (anthropic
 			results := make([]anthropic.ContentBlockParamUnion, len(msg.ToolResults()))
 			for i, toolResult := range msg.ToolResults() {
 				results[i] = anthropic.NewToolResultBlock(toolResult.ToolCallID, toolResult.Content, toolResult.IsError)
+				if len(toolResult.Data) > 0 {
+					image := message.BinaryContent{MIMEType: toolResult.MIMEType, Data: toolResult.Data}
+					imageBlock := anthropic.NewImageBlockBase64(image.MIMEType, image.String(catwalk.InferenceProviderAnthropic))
+					results[i].OfToolResult.Content = append(results[i].OfToolResult.Content, anthropic.ToolResultBlockParamContentUnion{OfImage: imageBlock.OfImage})
+				}
 			}
 			anthropicMessages = append(anthropicMessages, anthropic.NewUserMessage(results...))
 		}
@@ -213,22 +218,14 @@ func (a *anthropicClient) finishReason(reason string) message.FinishReason {
 }
 
 func (a *anthropicClient) isThinkingEnabled() bool {
-	cfg := config.Get()
-	modelConfig := cfg.Models[config.SelectedModelTypeLarge]
-	if a.providerOptions.modelType == config.SelectedModelTypeSmall {
-		modelConfig = cfg.Models[config.SelectedModelTypeSmall]
-	}
+	modelConfig := a.providerOptions.selectedModel()
 	return a.Model().CanReason && modelConfig.Think
 }
 
 func (a *anthropicClient) preparedMessages(messages []anthropic.MessageParam, tools []anthropic.ToolUnionParam) anthropic.MessageNewParams {
 	model := a.providerOptions.model(a.providerOptions.modelType)
 	var thinkingParam anthropic.ThinkingConfigParamUnion
-	cfg := config.Get()
-	modelConfig := cfg.Models[config.SelectedModelTypeLarge]
-	if a.providerOptions.modelType == config.SelectedModelTypeSmall {
-		modelConfig = cfg.Models[config.SelectedModelTypeSmall]
-	}
+	modelConfig := a.providerOptions.selectedModel()
 	temperature := anthropic.Float(0)
 
 	maxTokens := model.DefaultMaxTokens
@@ -299,13 +296,11 @@ func (a *anthropicClient) send(ctx context.Context, messages []message.Message,
 				return nil, retryErr
 			}
 			if retry {
-				slog.Warn("Retrying due to rate limit", "attempt", attempts, "max_retries", maxRetries, "error", err)
-				select {
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
+				slog.Warn("Retrying request", "attempt", attempts, "max_retries", a.providerOptions.retry.maxRetries, "after", after, "error", err)
+				if err := a.providerOptions.retry.wait(ctx, after); err != nil {
+					return nil, err
 				}
+				continue
 			}
 			return nil, retryErr
 		}
@@ -347,6 +342,7 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 			accumulatedMessage := anthropic.Message{}
 
 			currentToolCallID := ""
+			completed := false
 			for anthropicStream.Next() {
 				event := anthropicStream.Current()
 				err := accumulatedMessage.Accumulate(event)
@@ -414,6 +410,7 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 					}
 
 				case anthropic.MessageStopEvent:
+					completed = true
 					content := ""
 					for _, block := range accumulatedMessage.Content {
 						if text, ok := block.AsAny().(anthropic.TextBlock); ok {
@@ -435,10 +432,14 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 			}
 
 			err := anthropicStream.Err()
-			if err == nil || errors.Is(err, io.EOF) {
+			if (err == nil || errors.Is(err, io.EOF)) && (completed || ctx.Err() != nil) {
 				close(eventChan)
 				return
 			}
+			if err == nil || errors.Is(err, io.EOF) {
+				// The connection was closed before the message was complete.
+				err = io.ErrUnexpectedEOF
+			}
 
 			// If there is an error we are going to see if we can retry the call
 			retry, after, retryErr := a.shouldRetry(attempts, err)
@@ -448,18 +449,13 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 				return
 			}
 			if retry {
-				slog.Warn("Retrying due to rate limit", "attempt", attempts, "max_retries", maxRetries, "error", err)
-				select {
-				case <-ctx.Done():
-					// context cancelled
-					if ctx.Err() != nil {
-						eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
-					}
+				slog.Warn("Retrying request", "attempt", attempts, "max_retries", a.providerOptions.retry.maxRetries, "after", after, "error", err)
+				if err := a.providerOptions.retry.notifyAndWait(ctx, eventChan, attempts, after, err); err != nil {
+					eventChan <- ProviderEvent{Type: EventError, Error: err}
 					close(eventChan)
 					return
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
 				}
+				continue
 			}
 			if ctx.Err() != nil {
 				eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
@@ -472,51 +468,29 @@ func (a *anthropicClient) stream(ctx context.Context, messages []message.Message
 	return eventChan
 }
 
-func (a *anthropicClient) shouldRetry(attempts int, err error) (bool, int64, error) {
+func (a *anthropicClient) shouldRetry(attempts int, err error) (bool, time.Duration, error) {
 	var apiErr *anthropic.Error
-	if !errors.As(err, &apiErr) {
-		return false, 0, err
-	}
-
-	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
-	}
-
-	if apiErr.StatusCode == 401 {
-		a.providerOptions.apiKey, err = config.Get().Resolve(a.providerOptions.config.APIKey)
-		if err != nil {
-			return false, 0, fmt.Errorf("failed to resolve API key: %w", err)
-		}
-		a.client = createAnthropicClient(a.providerOptions, a.tp)
-		return true, 0, nil
-	}
-
-	// Handle context limit exceeded error (400 Bad Request)
-	if apiErr.StatusCode == 400 {
-		if adjusted, ok := a.handleContextLimitError(apiErr); ok {
-			a.adjustedMaxTokens = adjusted
-			slog.Debug("Adjusted max_tokens due to context limit", "new_max_tokens", adjusted)
+	if errors.As(err, &apiErr) && attempts <= a.providerOptions.retry.maxRetries {
+		if apiErr.StatusCode == 401 {
+			a.providerOptions.apiKey, err = config.Get().Resolve(a.providerOptions.config.APIKey)
+			if err != nil {
+				return false, 0, fmt.Errorf("failed to resolve API key: %w", err)
+			}
+			a.client = createAnthropicClient(a.providerOptions, a.tp)
 			return true, 0, nil
 		}
-	}
-
-	isOverloaded := strings.Contains(apiErr.Error(), "overloaded") || strings.Contains(apiErr.Error(), "rate limit exceeded")
-	if apiErr.StatusCode != 429 && apiErr.StatusCode != 529 && !isOverloaded {
-		return false, 0, err
-	}
 
-	retryMs := 0
-	retryAfterValues := apiErr.Response.Header.Values("Retry-After")
-
-	backoffMs := 2000 * (1 << (attempts - 1))
-	jitterMs := int(float64(backoffMs) * 0.2)
-	retryMs = backoffMs + jitterMs
-	if len(retryAfterValues) > 0 {
-		if _, err := fmt.Sscanf(retryAfterValues[0], "%d", &retryMs); err == nil {
-			retryMs = retryMs * 1000
+		// Handle context limit exceeded error (400 Bad Request)
+		if apiErr.StatusCode == 400 {
+			if adjusted, ok := a.handleContextLimitError(apiErr); ok {
+				a.adjustedMaxTokens = adjusted
+				slog.Debug("Adjusted max_tokens due to context limit", "new_max_tokens", adjusted)
+				return true, 0, nil
+			}
 		}
 	}
-	return true, int64(retryMs), nil
+
+	return a.providerOptions.retry.shouldRetry(attempts, err)
 }
 
 // handleContextLimitError parses context limit error and returns adjusted max_tokens
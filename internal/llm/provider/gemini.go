@@ -129,6 +129,18 @@ func (g *geminiClient) convertMessages(messages []message.Message) []*genai.Cont
 					},
 					Role: genai.RoleModel,
 				})
+				if len(result.Data) > 0 {
+					history = append(history, &genai.Content{
+						Parts: []*genai.Part{
+							{Text: result.Content},
+							{InlineData: &genai.Blob{
+								MIMEType: result.MIMEType,
+								Data:     result.Data,
+							}},
+						},
+						Role: genai.RoleUser,
+					})
+				}
 			}
 		}
 	}
@@ -173,12 +185,7 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 	// Convert messages
 	geminiMessages := g.convertMessages(messages)
 	model := g.providerOptions.model(g.providerOptions.modelType)
-	cfg := config.Get()
-
-	modelConfig := cfg.Models[config.SelectedModelTypeLarge]
-	if g.providerOptions.modelType == config.SelectedModelTypeSmall {
-		modelConfig = cfg.Models[config.SelectedModelTypeSmall]
-	}
+	modelConfig := g.providerOptions.selectedModel()
 
 	maxTokens := model.DefaultMaxTokens
 	if modelConfig.MaxTokens > 0 {
@@ -216,13 +223,11 @@ func (g *geminiClient) send(ctx context.Context, messages []message.Message, too
 				return nil, retryErr
 			}
 			if retry {
-				slog.Warn("Retrying due to rate limit", "attempt", attempts, "max_retries", maxRetries, "error", err)
-				select {
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
+				slog.Warn("Retrying request", "attempt", attempts, "max_retries", g.providerOptions.retry.maxRetries, "after", after, "error", err)
+				if err := g.providerOptions.retry.wait(ctx, after); err != nil {
+					return nil, err
 				}
+				continue
 			}
 			return nil, retryErr
 		}
@@ -269,12 +274,7 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 	geminiMessages := g.convertMessages(messages)
 
 	model := g.providerOptions.model(g.providerOptions.modelType)
-	cfg := config.Get()
-
-	modelConfig := cfg.Models[config.SelectedModelTypeLarge]
-	if g.providerOptions.modelType == config.SelectedModelTypeSmall {
-		modelConfig = cfg.Models[config.SelectedModelTypeSmall]
-	}
+	modelConfig := g.providerOptions.selectedModel()
 	maxTokens := model.DefaultMaxTokens
 	if modelConfig.MaxTokens > 0 {
 		maxTokens = modelConfig.MaxTokens
@@ -305,6 +305,7 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 	go func() {
 		defer close(eventChan)
 
+	retry:
 		for {
 			attempts++
 
@@ -327,17 +328,12 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 						return
 					}
 					if retry {
-						slog.Warn("Retrying due to rate limit", "attempt", attempts, "max_retries", maxRetries, "error", err)
-						select {
-						case <-ctx.Done():
-							if ctx.Err() != nil {
-								eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
-							}
-
+						slog.Warn("Retrying request", "attempt", attempts, "max_retries", g.providerOptions.retry.maxRetries, "after", after, "error", err)
+						if err := g.providerOptions.retry.notifyAndWait(ctx, eventChan, attempts, after, err); err != nil {
+							eventChan <- ProviderEvent{Type: EventError, Error: err}
 							return
-						case <-time.After(time.Duration(after) * time.Millisecond):
-							continue
 						}
+						continue retry
 					} else {
 						eventChan <- ProviderEvent{Type: EventError, Error: err}
 						return
@@ -406,29 +402,32 @@ func (g *geminiClient) stream(ctx context.Context, messages []message.Message, t
 				}
 				return
 			}
+
+			// The stream ended without any response.
+			retry, after, retryErr := g.shouldRetry(attempts, io.ErrUnexpectedEOF)
+			if !retry {
+				eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
+				return
+			}
+			if err := g.providerOptions.retry.notifyAndWait(ctx, eventChan, attempts, after, io.ErrUnexpectedEOF); err != nil {
+				eventChan <- ProviderEvent{Type: EventError, Error: err}
+				return
+			}
 		}
 	}()
 
 	return eventChan
 }
 
-func (g *geminiClient) shouldRetry(attempts int, err error) (bool, int64, error) {
-	// Check if error is a rate limit error
-	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
-	}
-
-	// Gemini doesn't have a standard error type we can check against
-	// So we'll check the error message for rate limit indicators
+func (g *geminiClient) shouldRetry(attempts int, err error) (bool, time.Duration, error) {
 	if errors.Is(err, io.EOF) {
 		return false, 0, err
 	}
 
-	errMsg := err.Error()
-	isRateLimit := contains(errMsg, "rate limit", "quota exceeded", "too many requests")
-
 	// Check for token expiration (401 Unauthorized)
-	if contains(errMsg, "unauthorized", "invalid api key", "api key expired") {
+	var apiErr genai.APIError
+	isUnauthorized := errors.As(err, &apiErr) && apiErr.Code == 401
+	if (isUnauthorized || contains(err.Error(), "unauthorized", "invalid api key", "api key expired")) && attempts <= g.providerOptions.retry.maxRetries {
 		g.providerOptions.apiKey, err = config.Get().Resolve(g.providerOptions.config.APIKey)
 		if err != nil {
 			return false, 0, fmt.Errorf("failed to resolve API key: %w", err)
@@ -440,18 +439,7 @@ func (g *geminiClient) shouldRetry(attempts int, err error) (bool, int64, error)
 		return true, 0, nil
 	}
 
-	// Check for common rate limit error messages
-
-	if !isRateLimit {
-		return false, 0, err
-	}
-
-	// Calculate backoff with jitter
-	backoffMs := 2000 * (1 << (attempts - 1))
-	jitterMs := int(float64(backoffMs) * 0.2)
-	retryMs := backoffMs + jitterMs
-
-	return true, int64(retryMs), nil
+	return g.providerOptions.retry.shouldRetry(attempts, err)
 }
 
 func (g *geminiClient) usage(resp *genai.GenerateContentResponse) TokenUsage {
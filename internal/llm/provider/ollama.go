@@ -0,0 +1,443 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/log"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/ollama"
+	"github.com/google/uuid"
+)
+
+// ollamaClient speaks the native Ollama API. Models without native tool
+// calling are told about the tools in the system prompt and call them with
+// <tool_call> blocks in their response instead.
+type ollamaClient struct {
+	providerOptions providerClientOptions
+	client          *ollama.Client
+	// nativeTools caches whether the models support tool calling.
+	nativeTools *csync.Map[string, bool]
+}
+
+type OllamaClient ProviderClient
+
+func newOllamaClient(opts providerClientOptions) OllamaClient {
+	return &ollamaClient{
+		providerOptions: opts,
+		client:          createOllamaClient(opts),
+		nativeTools:     csync.NewMap[string, bool](),
+	}
+}
+
+func createOllamaClient(opts providerClientOptions) *ollama.Client {
+	baseURL := ""
+	if opts.baseURL != "" {
+		if resolvedBaseURL, err := config.Get().Resolve(opts.baseURL); err == nil {
+			baseURL = resolvedBaseURL
+		}
+	}
+	client := ollama.NewClient(baseURL)
+	client.Headers = make(map[string]string, len(opts.extraHeaders)+1)
+	if opts.apiKey != "" {
+		client.Headers["Authorization"] = "Bearer " + opts.apiKey
+	}
+	for key, value := range opts.extraHeaders {
+		client.Headers[key] = value
+	}
+	if config.Get().Options.Debug {
+		client.HTTPClient = log.NewHTTPClient()
+	}
+	return client
+}
+
+// supportsTools reports whether the model supports native tool calling.
+// Servers that don't report capabilities are assumed to support it.
+func (o *ollamaClient) supportsTools(ctx context.Context, model string) bool {
+	if supported, ok := o.nativeTools.Get(model); ok {
+		return supported
+	}
+	info, err := o.client.Show(ctx, model)
+	if err != nil {
+		slog.Warn("Failed to get Ollama model capabilities", "model", model, "error", err)
+		return true
+	}
+	supported := info.Capabilities == nil || info.HasCapability(ollama.CapabilityTools)
+	o.nativeTools.Set(model, supported)
+	return supported
+}
+
+func (o *ollamaClient) convertMessages(messages []message.Message, tools []tools.BaseTool, nativeTools bool) (ollamaMessages []ollama.Message) {
+	systemMessage := o.providerOptions.systemMessage
+	if o.providerOptions.systemPromptPrefix != "" {
+		systemMessage = o.providerOptions.systemPromptPrefix + "\n" + systemMessage
+	}
+	if !nativeTools && len(tools) > 0 {
+		systemMessage += "\n\n" + toolCallPrompt(tools)
+	}
+	ollamaMessages = append(ollamaMessages, ollama.Message{Role: "system", Content: systemMessage})
+
+	for _, msg := range messages {
+		switch msg.Role {
+		case message.User:
+			userMsg := ollama.Message{Role: "user", Content: msg.Content().String()}
+			for _, binaryContent := range msg.BinaryContent() {
+				userMsg.Images = append(userMsg.Images, binaryContent.Data)
+			}
+			ollamaMessages = append(ollamaMessages, userMsg)
+
+		case message.Assistant:
+			assistantMsg := ollama.Message{Role: "assistant", Content: msg.Content().String()}
+			for _, call := range msg.ToolCalls() {
+				arguments := json.RawMessage(call.Input)
+				if !json.Valid(arguments) {
+					arguments = json.RawMessage("{}")
+				}
+				if nativeTools {
+					assistantMsg.ToolCalls = append(assistantMsg.ToolCalls, ollama.ToolCall{
+						Function: ollama.ToolCallFunction{Name: call.Name, Arguments: arguments},
+					})
+					continue
+				}
+				block, _ := json.Marshal(promptToolCall{Name: call.Name, Arguments: arguments})
+				assistantMsg.Content = strings.TrimSpace(assistantMsg.Content + "\n\n" + toolCallOpen + "\n" + string(block) + "\n" + toolCallClose)
+			}
+			if assistantMsg.Content == "" && len(assistantMsg.ToolCalls) == 0 {
+				continue
+			}
+			ollamaMessages = append(ollamaMessages, assistantMsg)
+
+		case message.Tool:
+			// Without native tool calling the results are sent back in a
+			// user message.
+			results := ollama.Message{Role: "user"}
+			var blocks []string
+			for _, result := range msg.ToolResults() {
+				if nativeTools {
+					toolMsg := ollama.Message{Role: "tool", Content: result.Content, ToolName: result.Name}
+					if len(result.Data) > 0 {
+						toolMsg.Images = [][]byte{result.Data}
+					}
+					ollamaMessages = append(ollamaMessages, toolMsg)
+					continue
+				}
+				attrs := fmt.Sprintf("name=%q", result.Name)
+				if result.IsError {
+					attrs += ` error="true"`
+				}
+				blocks = append(blocks, fmt.Sprintf("<tool_result %s>\n%s\n</tool_result>", attrs, result.Content))
+				if len(result.Data) > 0 {
+					results.Images = append(results.Images, result.Data)
+				}
+			}
+			if !nativeTools {
+				results.Content = strings.Join(blocks, "\n\n")
+				ollamaMessages = append(ollamaMessages, results)
+			}
+		}
+	}
+	return
+}
+
+func (o *ollamaClient) convertTools(tools []tools.BaseTool) []ollama.Tool {
+	ollamaTools := make([]ollama.Tool, len(tools))
+	for i, tool := range tools {
+		info := tool.Info()
+		ollamaTools[i] = ollama.Tool{
+			Type: "function",
+			Function: ollama.ToolFunction{
+				Name:        info.Name,
+				Description: info.Description,
+				Parameters: map[string]any{
+					"type":       "object",
+					"properties": info.Parameters,
+					"required":   info.Required,
+				},
+			},
+		}
+	}
+	return ollamaTools
+}
+
+func (o *ollamaClient) preparedRequest(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (ollama.ChatRequest, bool) {
+	model := o.Model()
+	modelConfig := o.providerOptions.selectedModel()
+
+	nativeTools := len(tools) == 0 || o.supportsTools(ctx, model.ID)
+	req := ollama.ChatRequest{
+		Model:    model.ID,
+		Messages: o.convertMessages(messages, tools, nativeTools),
+		Options:  map[string]any{},
+	}
+	if nativeTools {
+		req.Tools = o.convertTools(tools)
+	}
+	if model.CanReason {
+		think := modelConfig.Think
+		req.Think = &think
+	}
+
+	// Ollama uses a small context window unless told otherwise.
+	if model.ContextWindow > 0 {
+		req.Options["num_ctx"] = model.ContextWindow
+	}
+	maxTokens := model.DefaultMaxTokens
+	if modelConfig.MaxTokens > 0 {
+		maxTokens = modelConfig.MaxTokens
+	}
+	if o.providerOptions.maxTokens > 0 {
+		maxTokens = o.providerOptions.maxTokens
+	}
+	if maxTokens > 0 {
+		req.Options["num_predict"] = maxTokens
+	}
+	return req, nativeTools
+}
+
+// chat sends the request once and reports the streamed deltas to emit.
+func (o *ollamaClient) chat(ctx context.Context, req ollama.ChatRequest, nativeTools bool, emit func(ProviderEvent)) (*ProviderResponse, error) {
+	var content strings.Builder
+	var toolCalls []message.ToolCall
+	var parser toolCallParser
+	response := &ProviderResponse{FinishReason: message.FinishReasonEndTurn}
+
+	addContent := func(text string) {
+		if text != "" {
+			content.WriteString(text)
+			emit(ProviderEvent{Type: EventContentDelta, Content: text})
+		}
+	}
+	addToolCalls := func(calls []message.ToolCall) {
+		for _, call := range calls {
+			emit(ProviderEvent{Type: EventToolUseStart, ToolCall: &call})
+			emit(ProviderEvent{Type: EventToolUseStop, ToolCall: &call})
+			toolCalls = append(toolCalls, call)
+		}
+	}
+
+	err := o.client.Chat(ctx, req, func(chunk ollama.ChatResponse) error {
+		if chunk.Message.Thinking != "" {
+			emit(ProviderEvent{Type: EventThinkingDelta, Thinking: chunk.Message.Thinking})
+		}
+		if nativeTools {
+			addContent(chunk.Message.Content)
+		} else {
+			text, calls := parser.write(chunk.Message.Content)
+			addContent(text)
+			addToolCalls(calls)
+		}
+		for _, call := range chunk.Message.ToolCalls {
+			addToolCalls([]message.ToolCall{newToolCall(call.Function.Name, call.Function.Arguments)})
+		}
+		if chunk.Done {
+			response.Usage = TokenUsage{
+				InputTokens:  chunk.PromptEvalCount,
+				OutputTokens: chunk.EvalCount,
+			}
+			if chunk.DoneReason == "length" {
+				response.FinishReason = message.FinishReasonMaxTokens
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !nativeTools {
+		text, calls := parser.flush()
+		addContent(text)
+		addToolCalls(calls)
+	}
+
+	response.Content = content.String()
+	response.ToolCalls = toolCalls
+	if len(toolCalls) > 0 {
+		response.FinishReason = message.FinishReasonToolUse
+	}
+	return response, nil
+}
+
+func (o *ollamaClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	req, nativeTools := o.preparedRequest(ctx, messages, tools)
+	attempts := 0
+	for {
+		attempts++
+		response, err := o.chat(ctx, req, nativeTools, func(ProviderEvent) {})
+		if err == nil {
+			return response, nil
+		}
+		retry, after, retryErr := o.providerOptions.retry.shouldRetry(attempts, err)
+		if !retry {
+			return nil, retryErr
+		}
+		slog.Warn("Retrying request", "attempt", attempts, "max_retries", o.providerOptions.retry.maxRetries, "after", after, "error", err)
+		if err := o.providerOptions.retry.wait(ctx, after); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (o *ollamaClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	eventChan := make(chan ProviderEvent)
+
+	go func() {
+		defer close(eventChan)
+		req, nativeTools := o.preparedRequest(ctx, messages, tools)
+		req.Stream = true
+		emit := func(event ProviderEvent) {
+			eventChan <- event
+		}
+
+		attempts := 0
+		for {
+			attempts++
+			response, err := o.chat(ctx, req, nativeTools, emit)
+			if err == nil {
+				eventChan <- ProviderEvent{Type: EventComplete, Response: response}
+				return
+			}
+			retry, after, retryErr := o.providerOptions.retry.shouldRetry(attempts, err)
+			if !retry {
+				eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
+				return
+			}
+			slog.Warn("Retrying request", "attempt", attempts, "max_retries", o.providerOptions.retry.maxRetries, "after", after, "error", err)
+			if err := o.providerOptions.retry.notifyAndWait(ctx, eventChan, attempts, after, err); err != nil {
+				eventChan <- ProviderEvent{Type: EventError, Error: err}
+				return
+			}
+		}
+	}()
+
+	return eventChan
+}
+
+func (o *ollamaClient) Model() catwalk.Model {
+	return o.providerOptions.model(o.providerOptions.modelType)
+}
+
+func newToolCall(name string, arguments json.RawMessage) message.ToolCall {
+	input := string(arguments)
+	if len(arguments) == 0 || string(arguments) == "null" {
+		input = "{}"
+	}
+	return message.ToolCall{
+		ID:       uuid.NewString(),
+		Name:     name,
+		Input:    input,
+		Type:     "function",
+		Finished: true,
+	}
+}
+
+const (
+	toolCallOpen  = "<tool_call>"
+	toolCallClose = "</tool_call>"
+)
+
+// promptToolCall is the JSON a model writes in a <tool_call> block.
+type promptToolCall struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// toolCallPrompt describes the tools and how to call them to models without
+// native tool calling.
+func toolCallPrompt(tools []tools.BaseTool) string {
+	var sb strings.Builder
+	sb.WriteString("# Tools\n\n")
+	sb.WriteString("You can call the tools below. To call a tool, write a block with a JSON object holding the name of the tool and its arguments:\n\n")
+	sb.WriteString(toolCallOpen + "\n" + `{"name": "tool_name", "arguments": {"parameter": "value"}}` + "\n" + toolCallClose + "\n\n")
+	sb.WriteString("Write one block per call and stop after your tool calls. ")
+	sb.WriteString("The results are sent back in <tool_result> blocks in the next message.\n")
+	for _, tool := range tools {
+		info := tool.Info()
+		parameters, _ := json.Marshal(map[string]any{
+			"type":       "object",
+			"properties": info.Parameters,
+			"required":   info.Required,
+		})
+		fmt.Fprintf(&sb, "\n## %s\n\n%s\n\nParameters: %s\n", info.Name, strings.TrimSpace(info.Description), parameters)
+	}
+	return sb.String()
+}
+
+// toolCallParser extracts the <tool_call> blocks from the streamed text of
+// models without native tool calling.
+type toolCallParser struct {
+	buf    string
+	inCall bool
+}
+
+// write adds streamed text and returns the text to show and the tool calls
+// completed by it. Text that could be the start of a block is held back.
+func (p *toolCallParser) write(s string) (text string, calls []message.ToolCall) {
+	p.buf += s
+	for {
+		if !p.inCall {
+			if i := strings.Index(p.buf, toolCallOpen); i >= 0 {
+				text += p.buf[:i]
+				p.buf = p.buf[i+len(toolCallOpen):]
+				p.inCall = true
+				continue
+			}
+			keep := partialPrefixLen(p.buf, toolCallOpen)
+			text += p.buf[:len(p.buf)-keep]
+			p.buf = p.buf[len(p.buf)-keep:]
+			return text, calls
+		}
+		i := strings.Index(p.buf, toolCallClose)
+		if i < 0 {
+			return text, calls
+		}
+		block := p.buf[:i]
+		p.buf = p.buf[i+len(toolCallClose):]
+		p.inCall = false
+		if call, ok := parseToolCall(block); ok {
+			calls = append(calls, call)
+		} else {
+			text += toolCallOpen + block + toolCallClose
+		}
+	}
+}
+
+// flush returns what is left at the end of the response. A block the model
+// did not close is still accepted if it holds a valid call.
+func (p *toolCallParser) flush() (string, []message.ToolCall) {
+	rest := p.buf
+	p.buf = ""
+	if !p.inCall {
+		return rest, nil
+	}
+	p.inCall = false
+	if call, ok := parseToolCall(rest); ok {
+		return "", []message.ToolCall{call}
+	}
+	return toolCallOpen + rest, nil
+}
+
+func parseToolCall(block string) (message.ToolCall, bool) {
+	var call promptToolCall
+	if err := json.Unmarshal([]byte(strings.TrimSpace(block)), &call); err != nil || call.Name == "" {
+		return message.ToolCall{}, false
+	}
+	return newToolCall(call.Name, call.Arguments), true
+}
+
+// partialPrefixLen returns the length of the longest suffix of s that is a
+// prefix of tag.
+func partialPrefixLen(s, tag string) int {
+	for n := min(len(s), len(tag)-1); n > 0; n-- {
+		if strings.HasSuffix(s, tag[:n]) {
+			return n
+		}
+	}
+	return 0
+}
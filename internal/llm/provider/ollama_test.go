@@ -0,0 +1,208 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/ollama"
+	"github.com/stretchr/testify/require"
+)
+
+type ollamaTestTool struct{}
+
+func (ollamaTestTool) Name() string { return "view" }
+
+func (ollamaTestTool) Info() tools.ToolInfo {
+	return tools.ToolInfo{
+		Name:        "view",
+		Description: "Reads a file",
+		Parameters:  map[string]any{"file_path": map[string]any{"type": "string"}},
+		Required:    []string{"file_path"},
+	}
+}
+
+func (ollamaTestTool) Run(context.Context, tools.ToolCall) (tools.ToolResponse, error) {
+	return tools.ToolResponse{}, nil
+}
+
+// newOllamaTestClient returns a client for a server that streams the given
+// chunks and reports the capabilities of its model.
+func newOllamaTestClient(t *testing.T, capabilities []string, chunks []map[string]any) (*ollamaClient, *ollama.ChatRequest) {
+	t.Helper()
+	var request ollama.ChatRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/api/show":
+			json.NewEncoder(w).Encode(map[string]any{"capabilities": capabilities})
+		case "/api/chat":
+			require.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+			for _, chunk := range chunks {
+				json.NewEncoder(w).Encode(chunk)
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return &ollamaClient{
+		providerOptions: providerClientOptions{
+			config:        config.ProviderConfig{ID: "ollama"},
+			modelType:     config.SelectedModelTypeLarge,
+			systemMessage: "test",
+			model: func(config.SelectedModelType) catwalk.Model {
+				return catwalk.Model{ID: "test-model", ContextWindow: 32768}
+			},
+		},
+		client:      ollama.NewClient(server.URL),
+		nativeTools: csync.NewMap[string, bool](),
+	}, &request
+}
+
+func collectOllamaStream(t *testing.T, client *ollamaClient, messages []message.Message) (message.Message, *ProviderResponse) {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	msg := message.Message{Role: message.Assistant}
+	var response *ProviderResponse
+	for event := range client.stream(ctx, messages, []tools.BaseTool{ollamaTestTool{}}) {
+		switch event.Type {
+		case EventContentDelta:
+			msg.AppendContent(event.Content)
+		case EventThinkingDelta:
+			msg.AppendReasoningContent(event.Thinking)
+		case EventToolUseStart:
+			msg.AddToolCall(*event.ToolCall)
+		case EventComplete:
+			response = event.Response
+		case EventError:
+			require.NoError(t, event.Error)
+		}
+	}
+	require.NotNil(t, response)
+	return msg, response
+}
+
+func TestOllamaClientStream(t *testing.T) {
+	client, request := newOllamaTestClient(t, []string{"completion", "tools"}, []map[string]any{
+		{"message": map[string]any{"role": "assistant", "thinking": "Reading it"}},
+		{"message": map[string]any{"role": "assistant", "content": "Let me look."}},
+		{"message": map[string]any{"role": "assistant", "tool_calls": []any{
+			map[string]any{"function": map[string]any{"name": "view", "arguments": map[string]any{"file_path": "main.go"}}},
+		}}},
+		{"message": map[string]any{"role": "assistant"}, "done": true, "done_reason": "stop", "prompt_eval_count": 100, "eval_count": 20},
+	})
+
+	messages := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Show main.go"}}},
+	}
+	msg, response := collectOllamaStream(t, client, messages)
+
+	require.True(t, request.Stream)
+	require.Len(t, request.Tools, 1)
+	require.Equal(t, float64(32768), request.Options["num_ctx"])
+	require.Equal(t, "test", request.Messages[0].Content)
+
+	require.Equal(t, "Reading it", msg.ReasoningContent().Thinking)
+	require.Equal(t, "Let me look.", msg.Content().Text)
+	require.Equal(t, message.FinishReasonToolUse, response.FinishReason)
+	require.Len(t, response.ToolCalls, 1)
+	require.Equal(t, "view", response.ToolCalls[0].Name)
+	require.JSONEq(t, `{"file_path":"main.go"}`, response.ToolCalls[0].Input)
+	require.Equal(t, TokenUsage{InputTokens: 100, OutputTokens: 20}, response.Usage)
+}
+
+func TestOllamaClientPromptTools(t *testing.T) {
+	client, request := newOllamaTestClient(t, []string{"completion"}, []map[string]any{
+		{"message": map[string]any{"role": "assistant", "content": "Let me look.\n<tool"}},
+		{"message": map[string]any{"role": "assistant", "content": "_call>\n{\"name\": \"view\", "}},
+		{"message": map[string]any{"role": "assistant", "content": "\"arguments\": {\"file_path\": \"main.go\"}}\n</tool_call>"}},
+		{"message": map[string]any{"role": "assistant"}, "done": true, "done_reason": "stop"},
+	})
+
+	messages := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Show README.md"}}},
+		{Role: message.Assistant, Parts: []message.ContentPart{
+			message.ToolCall{ID: "call_1", Name: "view", Input: `{"file_path":"README.md"}`, Finished: true},
+		}},
+		{Role: message.Tool, Parts: []message.ContentPart{
+			message.ToolResult{ToolCallID: "call_1", Name: "view", Content: "# Hello"},
+		}},
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Show main.go"}}},
+	}
+	msg, response := collectOllamaStream(t, client, messages)
+
+	// The tools are described in the system prompt and the previous call
+	// and its result are sent as text.
+	require.Empty(t, request.Tools)
+	require.Contains(t, request.Messages[0].Content, "## view")
+	require.Equal(t, "assistant", request.Messages[2].Role)
+	require.Equal(t, "<tool_call>\n{\"name\":\"view\",\"arguments\":{\"file_path\":\"README.md\"}}\n</tool_call>", request.Messages[2].Content)
+	require.Equal(t, "user", request.Messages[3].Role)
+	require.Equal(t, "<tool_result name=\"view\">\n# Hello\n</tool_result>", request.Messages[3].Content)
+
+	require.Equal(t, "Let me look.\n", msg.Content().Text)
+	require.Equal(t, message.FinishReasonToolUse, response.FinishReason)
+	require.Len(t, response.ToolCalls, 1)
+	require.Equal(t, "view", response.ToolCalls[0].Name)
+	require.Equal(t, `{"file_path": "main.go"}`, response.ToolCalls[0].Input)
+}
+
+func TestToolCallParser(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		text   string
+		calls  []string
+	}{
+		{
+			name:   "plain text",
+			chunks: []string{"Hello ", "<b>world</b>"},
+			text:   "Hello <b>world</b>",
+		},
+		{
+			name:   "unclosed call",
+			chunks: []string{"<tool_call>{\"name\": \"ls\", \"arguments\": {}}"},
+			calls:  []string{"ls"},
+		},
+		{
+			name:   "several calls",
+			chunks: []string{"<tool_call>{\"name\": \"ls\"}</tool_call><tool_", "call>{\"name\": \"view\"}</tool_call>"},
+			calls:  []string{"ls", "view"},
+		},
+		{
+			name:   "invalid call is kept as text",
+			chunks: []string{"<tool_call>oops</tool_call>"},
+			text:   "<tool_call>oops</tool_call>",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var parser toolCallParser
+			var text string
+			var calls []string
+			for _, chunk := range tt.chunks {
+				chunkText, chunkCalls := parser.write(chunk)
+				text += chunkText
+				for _, call := range chunkCalls {
+					calls = append(calls, call.Name)
+				}
+			}
+			rest, restCalls := parser.flush()
+			text += rest
+			for _, call := range restCalls {
+				calls = append(calls, call.Name)
+			}
+			require.Equal(t, tt.text, text)
+			require.Equal(t, tt.calls, calls)
+		})
+	}
+}
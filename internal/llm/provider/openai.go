@@ -174,10 +174,27 @@ func (o *openaiClient) convertMessages(messages []message.Message) (openaiMessag
 			})
 
 		case message.Tool:
+			// Tool messages can only hold text, so images returned by tools
+			// are sent in a user message following the tool results.
+			var images []openai.ChatCompletionContentPartUnionParam
 			for _, result := range msg.ToolResults() {
 				openaiMessages = append(openaiMessages,
 					openai.ToolMessage(result.Content, result.ToolCallID),
 				)
+				if len(result.Data) > 0 {
+					image := message.BinaryContent{MIMEType: result.MIMEType, Data: result.Data}
+					textBlock := openai.ChatCompletionContentPartTextParam{Text: result.Content}
+					imageBlock := openai.ChatCompletionContentPartImageParam{
+						ImageURL: openai.ChatCompletionContentPartImageImageURLParam{URL: image.String(catwalk.InferenceProviderOpenAI)},
+					}
+					images = append(images,
+						openai.ChatCompletionContentPartUnionParam{OfText: &textBlock},
+						openai.ChatCompletionContentPartUnionParam{OfImageURL: &imageBlock},
+					)
+				}
+			}
+			if len(images) > 0 {
+				openaiMessages = append(openaiMessages, openai.UserMessage(images))
 			}
 		}
 	}
@@ -221,12 +238,7 @@ func (o *openaiClient) finishReason(reason string) message.FinishReason {
 
 func (o *openaiClient) preparedParams(messages []openai.ChatCompletionMessageParamUnion, tools []openai.ChatCompletionToolParam) openai.ChatCompletionNewParams {
 	model := o.providerOptions.model(o.providerOptions.modelType)
-	cfg := config.Get()
-
-	modelConfig := cfg.Models[config.SelectedModelTypeLarge]
-	if o.providerOptions.modelType == config.SelectedModelTypeSmall {
-		modelConfig = cfg.Models[config.SelectedModelTypeSmall]
-	}
+	modelConfig := o.providerOptions.selectedModel()
 
 	reasoningEffort := modelConfig.ReasoningEffort
 
@@ -282,13 +294,11 @@ func (o *openaiClient) send(ctx context.Context, messages []message.Message, too
 				return nil, retryErr
 			}
 			if retry {
-				slog.Warn("Retrying due to rate limit", "attempt", attempts, "max_retries", maxRetries, "error", err)
-				select {
-				case <-ctx.Done():
-					return nil, ctx.Err()
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
+				slog.Warn("Retrying request", "attempt", attempts, "max_retries", o.providerOptions.retry.maxRetries, "after", after, "error", err)
+				if err := o.providerOptions.retry.wait(ctx, after); err != nil {
+					return nil, err
 				}
+				continue
 			}
 			return nil, retryErr
 		}
@@ -463,18 +473,13 @@ func (o *openaiClient) stream(ctx context.Context, messages []message.Message, t
 				return
 			}
 			if retry {
-				slog.Warn("Retrying due to rate limit", "attempt", attempts, "max_retries", maxRetries, "error", err)
-				select {
-				case <-ctx.Done():
-					// context cancelled
-					if ctx.Err() == nil {
-						eventChan <- ProviderEvent{Type: EventError, Error: ctx.Err()}
-					}
+				slog.Warn("Retrying request", "attempt", attempts, "max_retries", o.providerOptions.retry.maxRetries, "after", after, "error", err)
+				if err := o.providerOptions.retry.notifyAndWait(ctx, eventChan, attempts, after, err); err != nil {
+					eventChan <- ProviderEvent{Type: EventError, Error: err}
 					close(eventChan)
 					return
-				case <-time.After(time.Duration(after) * time.Millisecond):
-					continue
 				}
+				continue
 			}
 			eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
 			close(eventChan)
@@ -485,19 +490,11 @@ func (o *openaiClient) stream(ctx context.Context, messages []message.Message, t
 	return eventChan
 }
 
-func (o *openaiClient) shouldRetry(attempts int, err error) (bool, int64, error) {
-	if attempts > maxRetries {
-		return false, 0, fmt.Errorf("maximum retry attempts reached for rate limit: %d retries", maxRetries)
-	}
-	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
-		return false, 0, err
-	}
+func (o *openaiClient) shouldRetry(attempts int, err error) (bool, time.Duration, error) {
 	var apiErr *openai.Error
-	retryMs := 0
-	retryAfterValues := []string{}
 	if errors.As(err, &apiErr) {
 		// Check for token expiration (401 Unauthorized)
-		if apiErr.StatusCode == 401 {
+		if apiErr.StatusCode == 401 && attempts <= o.providerOptions.retry.maxRetries {
 			o.providerOptions.apiKey, err = config.Get().Resolve(o.providerOptions.config.APIKey)
 			if err != nil {
 				return false, 0, fmt.Errorf("failed to resolve API key: %w", err)
@@ -505,32 +502,10 @@ func (o *openaiClient) shouldRetry(attempts int, err error) (bool, int64, error)
 			o.client = createOpenAIClient(o.providerOptions)
 			return true, 0, nil
 		}
-
-		if apiErr.StatusCode != 429 && apiErr.StatusCode != 500 {
-			return false, 0, err
-		}
-
-		retryAfterValues = apiErr.Response.Header.Values("Retry-After")
-	}
-
-	if apiErr != nil {
 		slog.Warn("OpenAI API error", "status_code", apiErr.StatusCode, "message", apiErr.Message, "type", apiErr.Type)
-		if len(retryAfterValues) > 0 {
-			slog.Warn("Retry-After header", "values", retryAfterValues)
-		}
-	} else {
-		slog.Error("OpenAI API error", "error", err.Error(), "attempt", attempts, "max_retries", maxRetries)
 	}
 
-	backoffMs := 2000 * (1 << (attempts - 1))
-	jitterMs := int(float64(backoffMs) * 0.2)
-	retryMs = backoffMs + jitterMs
-	if len(retryAfterValues) > 0 {
-		if _, err := fmt.Sscanf(retryAfterValues[0], "%d", &retryMs); err == nil {
-			retryMs = retryMs * 1000
-		}
-	}
-	return true, int64(retryMs), nil
+	return o.providerOptions.retry.shouldRetry(attempts, err)
 }
 
 func (o *openaiClient) toolCalls(completion openai.ChatCompletion) []message.ToolCall {
@@ -0,0 +1,340 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/responses"
+	"github.com/openai/openai-go/shared"
+)
+
+// openaiResponsesClient speaks the OpenAI Responses API. Responses are not
+// stored by OpenAI, the encrypted reasoning items are passed back with the
+// following requests instead so the model keeps its reasoning across turns.
+type openaiResponsesClient struct {
+	*openaiClient
+}
+
+type OpenAIResponsesClient ProviderClient
+
+func newOpenAIResponsesClient(opts providerClientOptions) OpenAIResponsesClient {
+	return &openaiResponsesClient{
+		openaiClient: &openaiClient{
+			providerOptions: opts,
+			client:          createOpenAIClient(opts),
+		},
+	}
+}
+
+func (o *openaiResponsesClient) convertMessages(messages []message.Message) (input responses.ResponseInputParam) {
+	for _, msg := range messages {
+		switch msg.Role {
+		case message.User:
+			if len(msg.BinaryContent()) == 0 {
+				input = append(input, responses.ResponseInputItemParamOfMessage(msg.Content().String(), responses.EasyInputMessageRoleUser))
+				continue
+			}
+			content := responses.ResponseInputMessageContentListParam{
+				responses.ResponseInputContentParamOfInputText(msg.Content().String()),
+			}
+			for _, binaryContent := range msg.BinaryContent() {
+				content = append(content, inputImage(binaryContent))
+			}
+			input = append(input, responses.ResponseInputItemParamOfMessage(content, responses.EasyInputMessageRoleUser))
+
+		case message.Assistant:
+			// Reasoning items can only be passed back to the provider that
+			// encrypted them.
+			if reasoning := msg.ReasoningContent(); reasoning.ItemID != "" && msg.Provider == o.providerOptions.config.ID {
+				item := responses.ResponseReasoningItemParam{
+					ID:               reasoning.ItemID,
+					Summary:          []responses.ResponseReasoningItemSummaryParam{},
+					EncryptedContent: openai.String(reasoning.Signature),
+				}
+				if reasoning.Thinking != "" {
+					item.Summary = append(item.Summary, responses.ResponseReasoningItemSummaryParam{Text: reasoning.Thinking})
+				}
+				input = append(input, responses.ResponseInputItemUnionParam{OfReasoning: &item})
+			}
+			if text := msg.Content().String(); text != "" {
+				input = append(input, responses.ResponseInputItemParamOfMessage(text, responses.EasyInputMessageRoleAssistant))
+			}
+			for _, call := range msg.ToolCalls() {
+				input = append(input, responses.ResponseInputItemParamOfFunctionCall(call.Input, call.ID, call.Name))
+			}
+
+		case message.Tool:
+			// Function call outputs can only hold text, so images returned by
+			// tools are sent in a user message following the outputs.
+			var images responses.ResponseInputMessageContentListParam
+			for _, result := range msg.ToolResults() {
+				input = append(input, responses.ResponseInputItemParamOfFunctionCallOutput(result.ToolCallID, result.Content))
+				if len(result.Data) > 0 {
+					images = append(images,
+						responses.ResponseInputContentParamOfInputText(result.Content),
+						inputImage(message.BinaryContent{MIMEType: result.MIMEType, Data: result.Data}),
+					)
+				}
+			}
+			if len(images) > 0 {
+				input = append(input, responses.ResponseInputItemParamOfMessage(images, responses.EasyInputMessageRoleUser))
+			}
+		}
+	}
+	return input
+}
+
+func inputImage(content message.BinaryContent) responses.ResponseInputContentUnionParam {
+	return responses.ResponseInputContentUnionParam{
+		OfInputImage: &responses.ResponseInputImageParam{
+			Detail:   responses.ResponseInputImageDetailAuto,
+			ImageURL: openai.String(content.String(catwalk.InferenceProviderOpenAI)),
+		},
+	}
+}
+
+func (o *openaiResponsesClient) convertTools(tools []tools.BaseTool) []responses.ToolUnionParam {
+	responsesTools := make([]responses.ToolUnionParam, len(tools))
+	for i, tool := range tools {
+		info := tool.Info()
+		responsesTools[i] = responses.ToolUnionParam{
+			OfFunction: &responses.FunctionToolParam{
+				Name:        info.Name,
+				Description: openai.String(info.Description),
+				Parameters: map[string]any{
+					"type":       "object",
+					"properties": info.Parameters,
+					"required":   info.Required,
+				},
+				Strict: openai.Bool(false),
+			},
+		}
+	}
+	return responsesTools
+}
+
+func (o *openaiResponsesClient) preparedParams(input responses.ResponseInputParam, tools []responses.ToolUnionParam) responses.ResponseNewParams {
+	model := o.providerOptions.model(o.providerOptions.modelType)
+	modelConfig := o.providerOptions.selectedModel()
+
+	systemMessage := o.providerOptions.systemMessage
+	if o.providerOptions.systemPromptPrefix != "" {
+		systemMessage = o.providerOptions.systemPromptPrefix + "\n" + systemMessage
+	}
+
+	params := responses.ResponseNewParams{
+		Model:        shared.ResponsesModel(model.ID),
+		Instructions: openai.String(systemMessage),
+		Input:        responses.ResponseNewParamsInputUnion{OfInputItemList: input},
+		Tools:        tools,
+		Store:        openai.Bool(false),
+	}
+
+	maxTokens := model.DefaultMaxTokens
+	if modelConfig.MaxTokens > 0 {
+		maxTokens = modelConfig.MaxTokens
+	}
+	// Override max tokens if set in provider options
+	if o.providerOptions.maxTokens > 0 {
+		maxTokens = o.providerOptions.maxTokens
+	}
+	if maxTokens > 0 {
+		params.MaxOutputTokens = openai.Int(maxTokens)
+	}
+
+	if model.CanReason {
+		params.Reasoning = shared.ReasoningParam{
+			Effort:  shared.ReasoningEffort(modelConfig.ReasoningEffort),
+			Summary: shared.ReasoningSummaryAuto,
+		}
+		params.Include = []responses.ResponseIncludable{responses.ResponseIncludableReasoningEncryptedContent}
+	}
+	return params
+}
+
+func (o *openaiResponsesClient) send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error) {
+	params := o.preparedParams(o.convertMessages(messages), o.convertTools(tools))
+	attempts := 0
+	for {
+		attempts++
+		response, err := o.client.Responses.New(ctx, params)
+		if err != nil {
+			retry, after, retryErr := o.shouldRetry(attempts, err)
+			if retryErr != nil {
+				return nil, retryErr
+			}
+			if retry {
+				slog.Warn("Retrying request", "attempt", attempts, "max_retries", o.providerOptions.retry.maxRetries, "after", after, "error", err)
+				if err := o.providerOptions.retry.wait(ctx, after); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			return nil, retryErr
+		}
+		if response.Status == responses.ResponseStatusFailed {
+			return nil, fmt.Errorf("response failed: %s", response.Error.Message)
+		}
+
+		var toolCalls []message.ToolCall
+		for _, item := range response.Output {
+			if item.Type == "function_call" {
+				toolCalls = append(toolCalls, responsesToolCall(item))
+			}
+		}
+		return &ProviderResponse{
+			Content:      response.OutputText(),
+			ToolCalls:    toolCalls,
+			Usage:        o.usage(response.Usage),
+			FinishReason: o.finishReason(*response, toolCalls),
+		}, nil
+	}
+}
+
+func (o *openaiResponsesClient) stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent {
+	params := o.preparedParams(o.convertMessages(messages), o.convertTools(tools))
+	attempts := 0
+	eventChan := make(chan ProviderEvent)
+
+	go func() {
+		defer close(eventChan)
+		for {
+			attempts++
+			stream := o.client.Responses.NewStreaming(ctx, params)
+
+			content := ""
+			var toolCalls []message.ToolCall
+			// Argument deltas refer to the function call item, not to the
+			// call id the results are sent back with.
+			callIDs := make(map[string]string)
+			var completed *responses.Response
+			var streamErr error
+			for stream.Next() {
+				event := stream.Current()
+				switch event.Type {
+				case "response.reasoning_summary_part.added":
+					if event.SummaryIndex > 0 {
+						eventChan <- ProviderEvent{Type: EventThinkingDelta, Thinking: "\n\n"}
+					}
+				case "response.reasoning_summary_text.delta":
+					eventChan <- ProviderEvent{Type: EventThinkingDelta, Thinking: event.Delta.OfString}
+				case "response.output_text.delta":
+					eventChan <- ProviderEvent{Type: EventContentDelta, Content: event.Delta.OfString}
+					content += event.Delta.OfString
+				case "response.output_item.added":
+					if event.Item.Type == "function_call" {
+						callIDs[event.Item.ID] = event.Item.CallID
+						eventChan <- ProviderEvent{
+							Type:     EventToolUseStart,
+							ToolCall: &message.ToolCall{ID: event.Item.CallID, Name: event.Item.Name, Type: "function"},
+						}
+					}
+				case "response.function_call_arguments.delta":
+					eventChan <- ProviderEvent{
+						Type:     EventToolUseDelta,
+						ToolCall: &message.ToolCall{ID: callIDs[event.ItemID], Input: event.Delta.OfString},
+					}
+				case "response.output_item.done":
+					switch event.Item.Type {
+					case "reasoning":
+						if event.Item.EncryptedContent != "" {
+							eventChan <- ProviderEvent{Type: EventReasoningItem, ItemID: event.Item.ID, Signature: event.Item.EncryptedContent}
+						}
+					case "function_call":
+						call := responsesToolCall(event.Item)
+						toolCalls = append(toolCalls, call)
+						eventChan <- ProviderEvent{Type: EventToolUseStop, ToolCall: &call}
+					}
+				case "response.completed", "response.incomplete":
+					completed = &event.Response
+				case "response.failed":
+					streamErr = fmt.Errorf("response failed: %s", event.Response.Error.Message)
+				case "error":
+					streamErr = fmt.Errorf("response failed: %s", event.Message)
+				}
+			}
+
+			err := stream.Err()
+			if err == nil || errors.Is(err, io.EOF) {
+				switch {
+				case streamErr != nil:
+					eventChan <- ProviderEvent{Type: EventError, Error: streamErr}
+				case completed == nil:
+					eventChan <- ProviderEvent{
+						Type:  EventError,
+						Error: errors.New("received incomplete streaming response from OpenAI API - check endpoint configuration"),
+					}
+				default:
+					eventChan <- ProviderEvent{
+						Type: EventComplete,
+						Response: &ProviderResponse{
+							Content:      content,
+							ToolCalls:    toolCalls,
+							Usage:        o.usage(completed.Usage),
+							FinishReason: o.finishReason(*completed, toolCalls),
+						},
+					}
+				}
+				return
+			}
+
+			// If there is an error we are going to see if we can retry the call
+			retry, after, retryErr := o.shouldRetry(attempts, err)
+			if retryErr != nil {
+				eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
+				return
+			}
+			if retry {
+				slog.Warn("Retrying request", "attempt", attempts, "max_retries", o.providerOptions.retry.maxRetries, "after", after, "error", err)
+				if err := o.providerOptions.retry.notifyAndWait(ctx, eventChan, attempts, after, err); err != nil {
+					eventChan <- ProviderEvent{Type: EventError, Error: err}
+					return
+				}
+				continue
+			}
+			eventChan <- ProviderEvent{Type: EventError, Error: retryErr}
+			return
+		}
+	}()
+
+	return eventChan
+}
+
+func responsesToolCall(item responses.ResponseOutputItemUnion) message.ToolCall {
+	return message.ToolCall{
+		ID:       item.CallID,
+		Name:     item.Name,
+		Input:    item.Arguments,
+		Type:     "function",
+		Finished: true,
+	}
+}
+
+func (o *openaiResponsesClient) finishReason(response responses.Response, toolCalls []message.ToolCall) message.FinishReason {
+	switch {
+	case len(toolCalls) > 0:
+		return message.FinishReasonToolUse
+	case response.Status == responses.ResponseStatusIncomplete && response.IncompleteDetails.Reason == "max_output_tokens":
+		return message.FinishReasonMaxTokens
+	case response.Status == responses.ResponseStatusIncomplete:
+		return message.FinishReasonUnknown
+	default:
+		return message.FinishReasonEndTurn
+	}
+}
+
+func (o *openaiResponsesClient) usage(usage responses.ResponseUsage) TokenUsage {
+	cachedTokens := usage.InputTokensDetails.CachedTokens
+	return TokenUsage{
+		InputTokens:     usage.InputTokens - cachedTokens,
+		OutputTokens:    usage.OutputTokens,
+		CacheReadTokens: cachedTokens,
+	}
+}
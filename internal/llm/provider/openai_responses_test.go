@@ -0,0 +1,124 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAIResponsesClientStream(t *testing.T) {
+	var requestBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/responses", r.URL.Path)
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &requestBody))
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.WriteHeader(http.StatusOK)
+		for _, event := range []map[string]any{
+			{"type": "response.reasoning_summary_part.added", "summary_index": 0},
+			{"type": "response.reasoning_summary_text.delta", "delta": "Looking at"},
+			{"type": "response.reasoning_summary_text.delta", "delta": " the file"},
+			{"type": "response.reasoning_summary_part.added", "summary_index": 1},
+			{"type": "response.reasoning_summary_text.delta", "delta": "Reading it"},
+			{"type": "response.output_item.done", "item": map[string]any{"type": "reasoning", "id": "rs_1", "encrypted_content": "secret"}},
+			{"type": "response.output_item.added", "item": map[string]any{"type": "function_call", "id": "fc_1", "call_id": "call_1", "name": "view"}},
+			{"type": "response.function_call_arguments.delta", "item_id": "fc_1", "delta": `{"file_path":`},
+			{"type": "response.function_call_arguments.delta", "item_id": "fc_1", "delta": `"main.go"}`},
+			{"type": "response.output_item.done", "item": map[string]any{"type": "function_call", "id": "fc_1", "call_id": "call_1", "name": "view", "arguments": `{"file_path":"main.go"}`}},
+			{"type": "response.completed", "response": map[string]any{
+				"status": "completed",
+				"usage":  map[string]any{"input_tokens": 100, "input_tokens_details": map[string]any{"cached_tokens": 40}, "output_tokens": 20},
+			}},
+		} {
+			data, _ := json.Marshal(event)
+			w.Write([]byte("event: " + event["type"].(string) + "\ndata: " + string(data) + "\n\n"))
+		}
+	}))
+	defer server.Close()
+
+	client := &openaiResponsesClient{
+		openaiClient: &openaiClient{
+			providerOptions: providerClientOptions{
+				config:        config.ProviderConfig{ID: "openai"},
+				modelType:     config.SelectedModelTypeLarge,
+				systemMessage: "test",
+				model: func(config.SelectedModelType) catwalk.Model {
+					return catwalk.Model{ID: "test-model", CanReason: true}
+				},
+			},
+			client: openai.NewClient(option.WithAPIKey("test-key"), option.WithBaseURL(server.URL)),
+		},
+	}
+
+	previous := message.Message{
+		Role:     message.Assistant,
+		Provider: "openai",
+		Parts: []message.ContentPart{
+			message.ReasoningContent{Thinking: "Thinking", Signature: "previous-secret", ItemID: "rs_0"},
+			message.TextContent{Text: "Hi!"},
+		},
+	}
+	messages := []message.Message{
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Hello"}}},
+		previous,
+		{Role: message.User, Parts: []message.ContentPart{message.TextContent{Text: "Show main.go"}}},
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 5*time.Second)
+	defer cancel()
+
+	msg := message.Message{Role: message.Assistant}
+	var response *ProviderResponse
+	for event := range client.stream(ctx, messages, nil) {
+		switch event.Type {
+		case EventThinkingDelta:
+			msg.AppendReasoningContent(event.Thinking)
+		case EventReasoningItem:
+			msg.SetReasoningItem(event.ItemID, event.Signature)
+		case EventToolUseStart:
+			msg.AddToolCall(*event.ToolCall)
+		case EventToolUseDelta:
+			msg.AppendToolCallInput(event.ToolCall.ID, event.ToolCall.Input)
+		case EventComplete:
+			response = event.Response
+		case EventError:
+			require.NoError(t, event.Error)
+		}
+	}
+
+	// The previous reasoning item is passed back before the message it
+	// belongs to.
+	input := requestBody["input"].([]any)
+	require.Len(t, input, 4)
+	require.Equal(t, map[string]any{
+		"type":              "reasoning",
+		"id":                "rs_0",
+		"encrypted_content": "previous-secret",
+		"summary":           []any{map[string]any{"type": "summary_text", "text": "Thinking"}},
+	}, input[1])
+	require.Equal(t, false, requestBody["store"])
+	require.Equal(t, []any{"reasoning.encrypted_content"}, requestBody["include"])
+
+	reasoning := msg.ReasoningContent()
+	require.Equal(t, "Looking at the file\n\nReading it", reasoning.Thinking)
+	require.Equal(t, "rs_1", reasoning.ItemID)
+	require.Equal(t, "secret", reasoning.Signature)
+	require.Equal(t, `{"file_path":"main.go"}`, msg.ToolCalls()[0].Input)
+
+	require.NotNil(t, response)
+	require.Equal(t, message.FinishReasonToolUse, response.FinishReason)
+	require.Equal(t, []message.ToolCall{{ID: "call_1", Name: "view", Input: `{"file_path":"main.go"}`, Type: "function", Finished: true}}, response.ToolCalls)
+	require.Equal(t, TokenUsage{InputTokens: 60, OutputTokens: 20, CacheReadTokens: 40}, response.Usage)
+}
@@ -27,6 +27,9 @@ const (
 	EventComplete       EventType = "complete"
 	EventError          EventType = "error"
 	EventWarning        EventType = "warning"
+	// EventReasoningItem carries the id of a Responses API reasoning item in
+	// ItemID and its encrypted content in Signature.
+	EventReasoningItem EventType = "reasoning_item"
 )
 
 type TokenUsage struct {
@@ -49,6 +52,7 @@ type ProviderEvent struct {
 	Content   string
 	Thinking  string
 	Signature string
+	ItemID    string
 	Response  *ProviderResponse
 	ToolCall  *message.ToolCall
 	Error     error
@@ -67,6 +71,7 @@ type providerClientOptions struct {
 	apiKey             string
 	modelType          config.SelectedModelType
 	model              func(config.SelectedModelType) catwalk.Model
+	modelConfig        *config.SelectedModel
 	disableCache       bool
 	systemMessage      string
 	systemPromptPrefix string
@@ -74,10 +79,22 @@ type providerClientOptions struct {
 	extraHeaders       map[string]string
 	extraBody          map[string]any
 	extraParams        map[string]string
+	retry              retryPolicy
 }
 
 type ProviderClientOption func(*providerClientOptions)
 
+// selectedModel returns the configuration of the model used by the client.
+func (o providerClientOptions) selectedModel() config.SelectedModel {
+	if o.modelConfig != nil {
+		return *o.modelConfig
+	}
+	if o.modelType == config.SelectedModelTypeSmall {
+		return config.Get().Models[config.SelectedModelTypeSmall]
+	}
+	return config.Get().Models[config.SelectedModelTypeLarge]
+}
+
 type ProviderClient interface {
 	send(ctx context.Context, messages []message.Message, tools []tools.BaseTool) (*ProviderResponse, error)
 	stream(ctx context.Context, messages []message.Message, tools []tools.BaseTool) <-chan ProviderEvent
@@ -173,6 +190,20 @@ func WithMaxTokens(maxTokens int64) ProviderClientOption {
 	}
 }
 
+// WithSelectedModel makes the client use the given model instead of the
+// selected model of its type, e.g. one of its fallbacks.
+func WithSelectedModel(model config.SelectedModel) ProviderClientOption {
+	return func(options *providerClientOptions) {
+		options.modelConfig = &model
+		options.model = func(config.SelectedModelType) catwalk.Model {
+			if m := config.Get().GetModel(model.Provider, model.Model); m != nil {
+				return *m
+			}
+			return catwalk.Model{ID: model.Model, Name: model.Model}
+		}
+	}
+}
+
 func NewProvider(cfg config.ProviderConfig, opts ...ProviderClientOption) (Provider, error) {
 	restore := config.PushPopCrushEnv()
 	defer restore()
@@ -199,6 +230,7 @@ func NewProvider(cfg config.ProviderConfig, opts ...ProviderClientOption) (Provi
 		extraBody:          cfg.ExtraBody,
 		extraParams:        cfg.ExtraParams,
 		systemPromptPrefix: cfg.SystemPromptPrefix,
+		retry:              newRetryPolicy(cfg.Retry),
 		model: func(tp config.SelectedModelType) catwalk.Model {
 			return *config.Get().GetModelByType(tp)
 		},
@@ -224,9 +256,23 @@ func NewProvider(cfg config.ProviderConfig, opts ...ProviderClientOption) (Provi
 			client:  newAnthropicClient(clientOptions, AnthropicClientTypeNormal),
 		}, nil
 	case catwalk.TypeOpenAI:
-		return &baseProvider[OpenAIClient]{
+		switch cfg.API {
+		case "", config.OpenAIAPIChatCompletions:
+			return &baseProvider[OpenAIClient]{
+				options: clientOptions,
+				client:  newOpenAIClient(clientOptions),
+			}, nil
+		case config.OpenAIAPIResponses:
+			return &baseProvider[OpenAIResponsesClient]{
+				options: clientOptions,
+				client:  newOpenAIResponsesClient(clientOptions),
+			}, nil
+		}
+		return nil, fmt.Errorf("api not supported for provider %s: %s", cfg.ID, cfg.API)
+	case config.TypeOllama:
+		return &baseProvider[OllamaClient]{
 			options: clientOptions,
-			client:  newOpenAIClient(clientOptions),
+			client:  newOllamaClient(clientOptions),
 		}, nil
 	case catwalk.TypeGemini:
 		return &baseProvider[GeminiClient]{
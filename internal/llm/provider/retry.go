@@ -0,0 +1,292 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/ollama"
+	"github.com/openai/openai-go"
+	"google.golang.org/genai"
+)
+
+const (
+	defaultInitialBackoff = 2 * time.Second
+	defaultMaxBackoff     = 60 * time.Second
+)
+
+// RetryError is sent as the error of an EventWarning when a request failed
+// with a transient error and is going to be retried.
+type RetryError struct {
+	Attempt    int
+	MaxRetries int
+	Delay      time.Duration
+	Err        error
+}
+
+func (e *RetryError) Error() string {
+	return fmt.Sprintf("%s, retrying in %s (attempt %d/%d)", retryReason(e.Err), e.Delay.Round(time.Second), e.Attempt, e.MaxRetries)
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
+
+// RetriesExhaustedError is returned when a request kept failing with transient
+// errors until it ran out of retries.
+type RetriesExhaustedError struct {
+	Retries int
+	Err     error
+}
+
+func (e *RetriesExhaustedError) Error() string {
+	return fmt.Sprintf("maximum retry attempts reached: %d retries: %s", e.Retries, e.Err)
+}
+
+func (e *RetriesExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// retryPolicy decides whether and when failed requests are retried. It is
+// shared by all provider clients and configured per provider.
+type retryPolicy struct {
+	maxRetries     int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+}
+
+func newRetryPolicy(cfg *config.RetryConfig) retryPolicy {
+	policy := retryPolicy{
+		maxRetries:     maxRetries,
+		initialBackoff: defaultInitialBackoff,
+		maxBackoff:     defaultMaxBackoff,
+	}
+	if cfg == nil {
+		return policy
+	}
+	if cfg.MaxRetries < 0 {
+		policy.maxRetries = 0
+	} else if cfg.MaxRetries > 0 {
+		policy.maxRetries = cfg.MaxRetries
+	}
+	if cfg.InitialBackoff > 0 {
+		policy.initialBackoff = time.Duration(cfg.InitialBackoff) * time.Millisecond
+	}
+	if cfg.MaxBackoff > 0 {
+		policy.maxBackoff = time.Duration(cfg.MaxBackoff) * time.Millisecond
+	}
+	policy.maxBackoff = max(policy.maxBackoff, policy.initialBackoff)
+	return policy
+}
+
+// shouldRetry reports whether a request that failed with err on the given
+// attempt (starting at 1) should be retried and how long to wait before doing
+// so. When the request must not be retried the returned error is the one to
+// report.
+func (p retryPolicy) shouldRetry(attempts int, err error) (bool, time.Duration, error) {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false, 0, err
+	}
+	if !isTransientError(err) {
+		return false, 0, err
+	}
+	if attempts > p.maxRetries {
+		if p.maxRetries == 0 {
+			return false, 0, err
+		}
+		return false, 0, &RetriesExhaustedError{Retries: p.maxRetries, Err: err}
+	}
+	return true, p.backoff(attempts, err), nil
+}
+
+// backoff returns the delay before retrying the given attempt. A Retry-After
+// header sent by the provider takes precedence over the exponential backoff.
+func (p retryPolicy) backoff(attempts int, err error) time.Duration {
+	if _, header := errorStatus(err); header != nil {
+		if delay, ok := parseRetryAfter(header); ok {
+			return min(delay, p.maxBackoff)
+		}
+	}
+
+	delay := p.initialBackoff << (attempts - 1)
+	if delay <= 0 || delay > p.maxBackoff {
+		delay = p.maxBackoff
+	}
+	// Add up to 20% of jitter so concurrent sessions don't retry in lockstep.
+	jitter := time.Duration(rand.Int64N(int64(delay)/5 + 1))
+	return delay + jitter
+}
+
+// wait sleeps for the given delay, returning early with the context error if
+// the context is done.
+func (p retryPolicy) wait(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// notifyAndWait reports an upcoming retry of a stream as an EventWarning, so
+// the consumer can discard the partial response, and waits for the delay.
+func (p retryPolicy) notifyAndWait(ctx context.Context, eventChan chan<- ProviderEvent, attempts int, delay time.Duration, err error) error {
+	if delay > 0 {
+		eventChan <- ProviderEvent{
+			Type: EventWarning,
+			Error: &RetryError{
+				Attempt:    attempts,
+				MaxRetries: p.maxRetries,
+				Delay:      delay,
+				Err:        err,
+			},
+		}
+	}
+	return p.wait(ctx, delay)
+}
+
+// errorStatus extracts the HTTP status code and response headers from the
+// errors returned by the provider SDKs.
+func errorStatus(err error) (int, http.Header) {
+	var anthropicErr *anthropic.Error
+	if errors.As(err, &anthropicErr) {
+		if anthropicErr.Response != nil {
+			return anthropicErr.StatusCode, anthropicErr.Response.Header
+		}
+		return anthropicErr.StatusCode, nil
+	}
+	var openaiErr *openai.Error
+	if errors.As(err, &openaiErr) {
+		if openaiErr.Response != nil {
+			return openaiErr.StatusCode, openaiErr.Response.Header
+		}
+		return openaiErr.StatusCode, nil
+	}
+	var genaiErr genai.APIError
+	if errors.As(err, &genaiErr) {
+		return genaiErr.Code, nil
+	}
+	var ollamaErr *ollama.StatusError
+	if errors.As(err, &ollamaErr) {
+		return ollamaErr.StatusCode, nil
+	}
+	return 0, nil
+}
+
+// isTransientError reports whether err is a rate limit, a server error or a
+// dropped connection, all of which are worth retrying.
+func isTransientError(err error) bool {
+	// Quota errors come as 429s too, but waiting won't fix them.
+	if isQuotaError(err) {
+		return false
+	}
+	if status, _ := errorStatus(err); status != 0 {
+		return status == http.StatusRequestTimeout ||
+			status == http.StatusConflict ||
+			status == http.StatusTooManyRequests ||
+			status >= http.StatusInternalServerError
+	}
+
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+
+	return contains(strings.ToLower(err.Error()),
+		"rate limit",
+		"too many requests",
+		"overloaded",
+		"connection reset",
+		"broken pipe",
+		"stream error",
+		"internal_error",
+		"unexpected eof",
+	)
+}
+
+// isQuotaError reports whether err says the account ran out of quota or
+// credits, which waiting doesn't fix. Per minute quotas, like Gemini's
+// RESOURCE_EXHAUSTED errors, are rate limits and don't count.
+func isQuotaError(err error) bool {
+	if status, _ := errorStatus(err); status == http.StatusPaymentRequired {
+		return true
+	}
+	return contains(strings.ToLower(err.Error()),
+		"insufficient_quota",
+		"credit balance",
+	)
+}
+
+// IsFallbackError reports whether err is a provider error that retrying
+// won't fix but another model may not run into: failed authentication, an
+// exhausted quota, an unavailable model or transient errors that went on
+// until the retries ran out. Bad requests, like a context window overflow,
+// would fail the same way on another model.
+func IsFallbackError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var exhausted *RetriesExhaustedError
+	if isQuotaError(err) || errors.As(err, &exhausted) {
+		return true
+	}
+	status, _ := errorStatus(err)
+	switch status {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+		return true
+	default:
+		return false
+	}
+}
+
+func retryReason(err error) string {
+	status, _ := errorStatus(err)
+	switch {
+	case status == http.StatusTooManyRequests:
+		return "rate limited"
+	case status == 529:
+		return "provider overloaded"
+	case status >= http.StatusInternalServerError:
+		return fmt.Sprintf("provider error %d", status)
+	case status != 0:
+		return fmt.Sprintf("request failed with status %d", status)
+	default:
+		return "connection error"
+	}
+}
+
+// parseRetryAfter reads the delay from the Retry-After header, which can be
+// either a number of seconds or an HTTP date. The non standard
+// retry-after-ms header is also supported.
+func parseRetryAfter(header http.Header) (time.Duration, bool) {
+	if ms := header.Get("Retry-After-Ms"); ms != "" {
+		if v, err := strconv.ParseFloat(ms, 64); err == nil && v >= 0 {
+			return time.Duration(v * float64(time.Millisecond)), true
+		}
+	}
+	value := header.Get("Retry-After")
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && seconds >= 0 {
+		return time.Duration(seconds * float64(time.Second)), true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(0, time.Until(date)), true
+	}
+	return 0, false
+}
@@ -0,0 +1,138 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/openai/openai-go"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genai"
+)
+
+func openaiError(status int, header http.Header) error {
+	return &openai.Error{
+		StatusCode: status,
+		Response:   &http.Response{StatusCode: status, Header: header},
+		Request:    &http.Request{Method: http.MethodPost},
+	}
+}
+
+func TestRetryPolicy_ShouldRetry(t *testing.T) {
+	t.Parallel()
+
+	policy := newRetryPolicy(&config.RetryConfig{
+		MaxRetries:     3,
+		InitialBackoff: 100,
+		MaxBackoff:     1000,
+	})
+
+	tests := []struct {
+		name  string
+		err   error
+		retry bool
+	}{
+		{"rate limit", openaiError(http.StatusTooManyRequests, nil), true},
+		{"server error", openaiError(http.StatusBadGateway, nil), true},
+		{"overloaded", openaiError(529, nil), true},
+		{"bad request", openaiError(http.StatusBadRequest, nil), false},
+		{"quota", fmt.Errorf("insufficient_quota: %w", openaiError(http.StatusTooManyRequests, nil)), false},
+		{"credit balance", errors.New("Your credit balance is too low to access the Anthropic API"), false},
+		{"per minute quota", genai.APIError{Code: http.StatusTooManyRequests, Status: "RESOURCE_EXHAUSTED", Message: "Quota exceeded for quota metric 'Generate Content API requests per minute'"}, true},
+		{"payment required", openaiError(http.StatusPaymentRequired, nil), false},
+		{"dropped stream", fmt.Errorf("reading stream: %w", io.ErrUnexpectedEOF), true},
+		{"other error", errors.New("invalid tool schema"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			retry, after, err := policy.shouldRetry(1, tt.err)
+			require.Equal(t, tt.retry, retry)
+			if tt.retry {
+				require.NoError(t, err)
+				require.GreaterOrEqual(t, after, 100*time.Millisecond)
+				require.LessOrEqual(t, after, 120*time.Millisecond)
+			} else {
+				require.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+
+	retry, _, err := policy.shouldRetry(4, openaiError(http.StatusTooManyRequests, nil))
+	require.False(t, retry)
+	require.ErrorContains(t, err, "maximum retry attempts reached")
+	require.True(t, IsFallbackError(err))
+}
+
+func TestRetryPolicy_Backoff(t *testing.T) {
+	t.Parallel()
+
+	policy := newRetryPolicy(&config.RetryConfig{
+		InitialBackoff: 100,
+		MaxBackoff:     1000,
+	})
+	require.Equal(t, maxRetries, policy.maxRetries)
+
+	after := policy.backoff(3, openaiError(http.StatusTooManyRequests, nil))
+	require.GreaterOrEqual(t, after, 400*time.Millisecond)
+	require.LessOrEqual(t, after, 480*time.Millisecond)
+
+	after = policy.backoff(10, openaiError(http.StatusTooManyRequests, nil))
+	require.GreaterOrEqual(t, after, time.Second)
+	require.LessOrEqual(t, after, 1200*time.Millisecond)
+
+	// Retry-After takes precedence but is capped by the max backoff.
+	after = policy.backoff(1, openaiError(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"0.5"}}))
+	require.Equal(t, 500*time.Millisecond, after)
+	after = policy.backoff(1, openaiError(http.StatusTooManyRequests, http.Header{"Retry-After": []string{"30"}}))
+	require.Equal(t, time.Second, after)
+}
+
+func TestRetryPolicy_Disabled(t *testing.T) {
+	t.Parallel()
+
+	policy := newRetryPolicy(&config.RetryConfig{MaxRetries: -1})
+	rateLimit := openaiError(http.StatusTooManyRequests, nil)
+	retry, _, err := policy.shouldRetry(1, rateLimit)
+	require.False(t, retry)
+	require.Equal(t, rateLimit, err)
+
+	// Resolving the API key again after a 401 is a retry too.
+	client := &openaiClient{providerOptions: providerClientOptions{retry: policy}}
+	unauthorized := openaiError(http.StatusUnauthorized, nil)
+	retry, _, err = client.shouldRetry(1, unauthorized)
+	require.False(t, retry)
+	require.Equal(t, unauthorized, err)
+}
+
+func TestIsFallbackError(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		err      error
+		fallBack bool
+	}{
+		{"unauthorized", openaiError(http.StatusUnauthorized, nil), true},
+		{"payment required", openaiError(http.StatusPaymentRequired, nil), true},
+		{"model not found", openaiError(http.StatusNotFound, nil), true},
+		{"quota", errors.New("insufficient_quota: You exceeded your current quota"), true},
+		{"bad request", openaiError(http.StatusBadRequest, nil), false},
+		{"context overflow", fmt.Errorf("prompt is too long: %w", openaiError(http.StatusBadRequest, nil)), false},
+		{"retries exhausted", &RetriesExhaustedError{Retries: 8, Err: openaiError(http.StatusBadGateway, nil)}, true},
+		{"server error", openaiError(http.StatusBadGateway, nil), false},
+		{"canceled", context.Canceled, false},
+		{"other error", errors.New("invalid tool schema"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tt.fallBack, IsFallbackError(tt.err))
+		})
+	}
+}
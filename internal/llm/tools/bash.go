@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/charmbracelet/crush/internal/permission"
@@ -12,13 +14,15 @@ import (
 )
 
 type BashParams struct {
-	Command string `json:"command"`
-	Timeout int    `json:"timeout"`
+	Command         string `json:"command"`
+	Timeout         int    `json:"timeout"`
+	RunInBackground bool   `json:"run_in_background"`
 }
 
 type BashPermissionsParams struct {
-	Command string `json:"command"`
-	Timeout int    `json:"timeout"`
+	Command         string `json:"command"`
+	Timeout         int    `json:"timeout"`
+	RunInBackground bool   `json:"run_in_background"`
 }
 
 type BashResponseMetadata struct {
@@ -26,10 +30,27 @@ type BashResponseMetadata struct {
 	EndTime          int64  `json:"end_time"`
 	Output           string `json:"output"`
 	WorkingDirectory string `json:"working_directory"`
+	JobID            string `json:"job_id,omitempty"`
 }
 type bashTool struct {
 	permissions permission.Service
 	workingDir  string
+	sandboxed   bool
+	// contained is set when the sandbox is known to contain the commands,
+	// so unlisted commands can run without asking.
+	contained bool
+	policy    *shell.CommandPolicy
+}
+
+// BashOptions configure the bash tool.
+type BashOptions struct {
+	// Sandbox is the sandbox commands run in, nil to run them unsandboxed.
+	Sandbox *shell.Sandbox
+	// SafeCommands are run without asking for permission, in addition to
+	// the built-in read-only commands.
+	SafeCommands []string
+	// BannedCommands are never run, in addition to the built-in ones.
+	BannedCommands []string
 }
 
 const (
@@ -39,6 +60,11 @@ const (
 	MaxTimeout      = 10 * 60 * 1000 // 10 minutes in milliseconds
 	MaxOutputLength = 30000
 	BashNoOutput    = "no output"
+
+	// progressInterval is how often the output of a running command is
+	// reported, and maxProgressOutput how much of its end.
+	progressInterval  = 500 * time.Millisecond
+	maxProgressOutput = 8 * 1024
 )
 
 var bannedCommands = []string{
@@ -114,8 +140,12 @@ var bannedCommands = []string{
 	"ufw",
 }
 
-func bashDescription() string {
+func bashDescription(sandboxed bool) string {
 	bannedCommandsStr := strings.Join(bannedCommands, ", ")
+	sandboxNote := ""
+	if sandboxed {
+		sandboxNote = "\n - Commands run in a sandbox: they can only write to the working directory and the temporary directory, and may not be able to access the network. Don't try to work around it, tell the User if a command fails because of it."
+	}
 	return fmt.Sprintf(`Executes a given bash command in a persistent shell session with optional timeout, ensuring proper handling and security measures.
 
 CROSS-PLATFORM SHELL SUPPORT:
@@ -134,7 +164,7 @@ Before executing the command, please follow these steps:
 
 2. Security Check:
  - For security and to limit the threat of a prompt injection attack, some commands are limited or banned. If you use a disallowed command, you will receive an error message explaining the restriction. Explain the error to the User.
- - Verify that the command is not one of the banned commands: %s.
+ - Verify that the command is not one of the banned commands: %s.%s
 
 3. Command Execution:
  - After ensuring proper quoting, execute the command.
@@ -155,6 +185,7 @@ Usage notes:
 - VERY IMPORTANT: You MUST avoid using search commands like 'find' and 'grep'. Instead use Grep, Glob, or Agent tools to search. You MUST avoid read tools like 'cat', 'head', 'tail', and 'ls', and use FileRead and LS tools to read files.
 - When issuing multiple commands, use the ';' or '&&' operator to separate them. DO NOT use newlines (newlines are ok in quoted strings).
 - IMPORTANT: All commands share the same shell session. Shell state (environment variables, virtual environments, current directory, etc.) persist between commands. For example, if you set an environment variable as part of a command, the environment variable will persist for subsequent commands.
+- Set run_in_background to run commands that don't finish on their own or take longer than the timeout, like dev servers, watchers and long test suites. The command is started as a background job and its job ID is returned right away. Use the job_output tool to read its output, job_wait to wait for it to finish and job_kill to stop it. Background jobs don't change the working directory or environment of the shell session.
 - Try to maintain your current working directory throughout the session by using absolute paths and avoiding usage of 'cd'. You may use 'cd' if the User explicitly requests it.
 <good-example>
 pytest /foo/bar/tests
@@ -269,12 +300,13 @@ EOF
 
 Important:
 - Return an empty response - the user will see the gh output directly
-- Never update git config`, bannedCommandsStr, MaxOutputLength)
+- Never update git config`, bannedCommandsStr, sandboxNote, MaxOutputLength)
 }
 
-func blockFuncs() []shell.BlockFunc {
+func blockFuncs(banned []string) []shell.BlockFunc {
 	return []shell.BlockFunc{
 		shell.CommandsBlocker(bannedCommands),
+		shell.CommandsBlocker(banned),
 
 		// System package managers
 		shell.ArgumentsBlocker("apk", []string{"add"}, nil),
@@ -304,14 +336,27 @@ func blockFuncs() []shell.BlockFunc {
 	}
 }
 
-func NewBashTool(permission permission.Service, workingDir string) BaseTool {
+// NewBashTool creates the bash tool. Commands run in the sandbox unless it
+// is nil. In the built-in sandbox without network access, commands that
+// aren't known to be risky run without asking for permission.
+func NewBashTool(permission permission.Service, workingDir string, opts BashOptions) BaseTool {
+	block := blockFuncs(opts.BannedCommands)
+
 	// Set up command blocking on the persistent shell
 	persistentShell := shell.GetPersistentShell(workingDir)
-	persistentShell.SetBlockFuncs(blockFuncs())
+	persistentShell.SetBlockFuncs(block)
+	persistentShell.SetSandbox(opts.Sandbox)
 
 	return &bashTool{
 		permissions: permission,
 		workingDir:  workingDir,
+		sandboxed:   opts.Sandbox != nil,
+		contained:   opts.Sandbox.Contained(),
+		policy: &shell.CommandPolicy{
+			Allow: append(slices.Clone(safeCommands), opts.SafeCommands...),
+			Ask:   riskyCommands,
+			Block: block,
+		},
 	}
 }
 
@@ -322,7 +367,7 @@ func (b *bashTool) Name() string {
 func (b *bashTool) Info() ToolInfo {
 	return ToolInfo{
 		Name:        BashToolName,
-		Description: bashDescription(),
+		Description: bashDescription(b.sandboxed),
 		Parameters: map[string]any{
 			"command": map[string]any{
 				"type":        "string",
@@ -332,6 +377,10 @@ func (b *bashTool) Info() ToolInfo {
 				"type":        "number",
 				"description": "Optional timeout in milliseconds (max 600000)",
 			},
+			"run_in_background": map[string]any{
+				"type":        "boolean",
+				"description": "Run the command as a background job and return its job ID right away",
+			},
 		},
 		Required: []string{"command"},
 	}
@@ -353,41 +402,42 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse("missing command"), nil
 	}
 
-	isSafeReadOnly := false
-	cmdLower := strings.ToLower(params.Command)
-
-	for _, safe := range safeCommands {
-		if strings.HasPrefix(cmdLower, safe) {
-			if len(cmdLower) == len(safe) || cmdLower[len(safe)] == ' ' || cmdLower[len(safe)] == '-' {
-				isSafeReadOnly = true
-				break
-			}
-		}
+	// Every command of the command line, including those in pipelines and
+	// substitutions, has to be safe for it to run without asking.
+	decision, reason := b.policy.Evaluate(params.Command)
+	if decision == shell.DecisionDeny {
+		return NewTextErrorResponse(fmt.Sprintf("command is not allowed for security reasons: %s", reason)), nil
 	}
+	isSafeReadOnly := decision == shell.DecisionAllow
 
 	sessionID, messageID := GetContextValues(ctx)
 	if sessionID == "" || messageID == "" {
 		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for executing shell command")
 	}
-	if !isSafeReadOnly {
-		p := b.permissions.Request(
-			permission.CreatePermissionRequest{
-				SessionID:   sessionID,
-				Path:        b.workingDir,
-				ToolCallID:  call.ID,
-				ToolName:    BashToolName,
-				Action:      "execute",
-				Description: fmt.Sprintf("Execute command: %s", params.Command),
-				Params: BashPermissionsParams{
-					Command: params.Command,
-				},
-			},
-		)
-		if !p {
-			return ToolResponse{}, permission.ErrorPermissionDenied
-		}
+	request := permission.CreatePermissionRequest{
+		SessionID:   sessionID,
+		Path:        b.workingDir,
+		ToolCallID:  call.ID,
+		ToolName:    BashToolName,
+		Action:      "execute",
+		Description: fmt.Sprintf("Execute command: %s", params.Command),
+		Params: BashPermissionsParams{
+			Command:         params.Command,
+			RunInBackground: params.RunInBackground,
+		},
+		Sandboxed: b.contained && decision == shell.DecisionUnlisted,
+	}
+	// Deny rules apply to safe read-only commands too, e.g. "cat .env".
+	if isSafeReadOnly && b.permissions.Denies(request) {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+	if !isSafeReadOnly && !b.permissions.Request(request) {
+		return ToolResponse{}, permission.ErrorPermissionDenied
 	}
 	startTime := time.Now()
+	if params.RunInBackground {
+		return b.runInBackground(params.Command, startTime)
+	}
 	if params.Timeout > 0 {
 		var cancel context.CancelFunc
 		ctx, cancel = context.WithTimeout(ctx, time.Duration(params.Timeout)*time.Millisecond)
@@ -395,7 +445,13 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	}
 
 	persistentShell := shell.GetPersistentShell(b.workingDir)
-	stdout, stderr, err := persistentShell.Exec(ctx, params.Command)
+	var stdout, stderr string
+	var err error
+	if progress := GetProgressFunc(ctx); progress != nil {
+		stdout, stderr, err = execWithProgress(ctx, persistentShell.Shell, params.Command, startTime, progress)
+	} else {
+		stdout, stderr, err = persistentShell.Exec(ctx, params.Command)
+	}
 
 	// Get the current working directory after command execution
 	currentWorkingDir := persistentShell.GetWorkingDir()
@@ -448,6 +504,87 @@ func (b *bashTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 	return WithResponseMetadata(NewTextResponse(stdout), metadata), nil
 }
 
+// runInBackground starts the command as a background job and returns its ID.
+func (b *bashTool) runInBackground(command string, startTime time.Time) (ToolResponse, error) {
+	persistentShell := shell.GetPersistentShell(b.workingDir)
+	job, err := persistentShell.StartBackground(command)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	metadata := BashResponseMetadata{
+		StartTime:        startTime.UnixMilli(),
+		EndTime:          time.Now().UnixMilli(),
+		WorkingDirectory: job.WorkingDir,
+		JobID:            job.ID,
+	}
+	return WithResponseMetadata(NewTextResponse(fmt.Sprintf(
+		"Started background job %s.\nUse the %s tool to read its output, %s to wait for it to finish and %s to stop it.",
+		job.ID, JobOutputToolName, JobWaitToolName, JobKillToolName,
+	)), metadata), nil
+}
+
+// execWithProgress executes the command, reporting the end of its output
+// while it runs.
+func execWithProgress(ctx context.Context, sh *shell.Shell, command string, startTime time.Time, progress ProgressFunc) (string, string, error) {
+	tail := &outputTail{}
+	workingDir := sh.GetWorkingDir()
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				output, changed := tail.take()
+				if !changed {
+					continue
+				}
+				progress(WithResponseMetadata(NewTextResponse(output), BashResponseMetadata{
+					StartTime:        startTime.UnixMilli(),
+					Output:           output,
+					WorkingDirectory: workingDir,
+				}))
+			}
+		}
+	})
+
+	stdout, stderr, err := sh.ExecStream(ctx, command, tail)
+	close(done)
+	wg.Wait()
+	return stdout, stderr, err
+}
+
+// outputTail keeps the end of the output of a running command.
+type outputTail struct {
+	mu      sync.Mutex
+	buf     []byte
+	changed bool
+}
+
+func (t *outputTail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > maxProgressOutput {
+		t.buf = append(t.buf[:0:0], t.buf[len(t.buf)-maxProgressOutput:]...)
+	}
+	t.changed = true
+	return len(p), nil
+}
+
+// take returns the output kept and whether it changed since the last call.
+func (t *outputTail) take() (string, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	changed := t.changed
+	t.changed = false
+	return strings.ToValidUTF8(string(t.buf), ""), changed
+}
+
 func truncateOutput(content string) string {
 	if len(content) <= MaxOutputLength {
 		return content
@@ -0,0 +1,94 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/shell"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExecWithProgress(t *testing.T) {
+	sh := shell.NewShell(&shell.Options{WorkingDir: t.TempDir()})
+
+	var mu sync.Mutex
+	var reported []string
+	progress := func(response ToolResponse) {
+		mu.Lock()
+		defer mu.Unlock()
+		reported = append(reported, response.Metadata)
+	}
+
+	stdout, stderr, err := execWithProgress(t.Context(), sh, "echo one; echo two >&2; sleep 1; echo three", time.Now(), progress)
+	require.NoError(t, err)
+	require.Equal(t, "one\nthree\n", stdout)
+	require.Equal(t, "two\n", stderr)
+
+	mu.Lock()
+	defer mu.Unlock()
+	require.NotEmpty(t, reported)
+	var meta BashResponseMetadata
+	require.NoError(t, json.Unmarshal([]byte(reported[0]), &meta))
+	require.Equal(t, "one\ntwo\n", meta.Output)
+}
+
+func TestOutputTail(t *testing.T) {
+	tail := &outputTail{}
+	_, changed := tail.take()
+	require.False(t, changed)
+
+	_, err := tail.Write([]byte(strings.Repeat("a", maxProgressOutput)))
+	require.NoError(t, err)
+	_, err = tail.Write([]byte("end"))
+	require.NoError(t, err)
+
+	output, changed := tail.take()
+	require.True(t, changed)
+	require.Len(t, output, maxProgressOutput)
+	require.True(t, strings.HasSuffix(output, "aend"))
+
+	_, changed = tail.take()
+	require.False(t, changed)
+}
+
+func TestBashToolDenyRules(t *testing.T) {
+	workingDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, ".env"), []byte("SECRET=1"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "x"), []byte("x"), 0o644))
+
+	// Requests are skipped, so only deny rules stop commands.
+	permissions := permission.NewPermissionService(workingDir, true, nil, permission.Rules{
+		Deny: []string{`bash:cat .*\.env`, "bash:rm .*"},
+	}, "")
+	tool := NewBashTool(permissions, workingDir, BashOptions{})
+	ctx := context.WithValue(t.Context(), SessionIDContextKey, "session")
+	ctx = context.WithValue(ctx, MessageIDContextKey, "message")
+
+	run := func(command string) (ToolResponse, error) {
+		input, err := json.Marshal(BashParams{Command: command})
+		require.NoError(t, err)
+		return tool.Run(ctx, ToolCall{ID: "call", Name: BashToolName, Input: string(input)})
+	}
+
+	for _, command := range []string{
+		"cat .env",
+		"FOO=1 rm -rf x",
+		`env -S "rm -rf x"`,
+		"/bin/rm x",
+	} {
+		_, err := run(command)
+		require.ErrorIs(t, err, permission.ErrorPermissionDenied, command)
+	}
+	require.FileExists(t, filepath.Join(workingDir, "x"))
+
+	response, err := run("cat x")
+	require.NoError(t, err)
+	require.Contains(t, response.Content, "x")
+}
@@ -0,0 +1,140 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+)
+
+type CallHierarchyParams struct {
+	LSPPositionParams
+	Direction string `json:"direction,omitempty"`
+}
+
+type callHierarchyTool struct {
+	lspClients map[string]*lsp.Client
+	workingDir string
+}
+
+const (
+	CallHierarchyToolName    = "call_hierarchy"
+	callHierarchyDescription = `Find the callers or callees of a function or method using the language server.
+WHEN TO USE THIS TOOL:
+- Use with direction "incoming" to find which functions call a function
+- Use with direction "outgoing" to find which functions a function calls
+HOW TO USE:
+- Provide the path of a file where the function is defined or used
+- Provide the function name, optionally with the line it appears on
+- Alternatively provide the exact line and column (both 1-based)
+- Set direction to "incoming" (default) or "outgoing"
+FEATURES:
+- Lists each caller or callee with its kind, name and location
+LIMITATIONS:
+- Requires a configured LSP server that supports call hierarchy requests
+- Only one level of the hierarchy is returned; call the tool again to go deeper
+- Results are limited to 100 entries
+`
+)
+
+func NewCallHierarchyTool(lspClients map[string]*lsp.Client, workingDir string) BaseTool {
+	return &callHierarchyTool{
+		lspClients: lspClients,
+		workingDir: workingDir,
+	}
+}
+
+func (c *callHierarchyTool) Name() string {
+	return CallHierarchyToolName
+}
+
+func (c *callHierarchyTool) Info() ToolInfo {
+	parameters := make(map[string]any, len(lspPositionParameters)+1)
+	for k, v := range lspPositionParameters {
+		parameters[k] = v
+	}
+	parameters["direction"] = map[string]any{
+		"type":        "string",
+		"description": "Either incoming (callers) or outgoing (callees), defaults to incoming",
+		"enum":        []string{"incoming", "outgoing"},
+	}
+	return ToolInfo{
+		Name:        CallHierarchyToolName,
+		Description: callHierarchyDescription,
+		Parameters:  parameters,
+		Required:    []string{"file_path"},
+	}
+}
+
+func (c *callHierarchyTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params CallHierarchyParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+	if params.Direction == "" {
+		params.Direction = "incoming"
+	}
+	if params.Direction != "incoming" && params.Direction != "outgoing" {
+		return NewTextErrorResponse("direction must be either incoming or outgoing"), nil
+	}
+
+	req, err := prepareLSPRequest(ctx, c.lspClients, c.workingDir, params.LSPPositionParams)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	formatter := newLocationFormatter(c.workingDir)
+	var output strings.Builder
+	count := 0
+	write := func(item protocol.CallHierarchyItem) {
+		count++
+		if count > maxLSPResults {
+			return
+		}
+		fmt.Fprintf(&output, "%s %s %s\n", symbolKindName(item.Kind), item.Name, formatter.format(protocol.Location{
+			URI:   item.URI,
+			Range: item.SelectionRange,
+		}))
+	}
+
+	for _, client := range req.clients {
+		items, err := client.PrepareCallHierarchy(ctx, protocol.CallHierarchyPrepareParams{
+			TextDocumentPositionParams: req.textDocumentPosition(),
+		})
+		if err != nil {
+			continue
+		}
+		for _, item := range items {
+			if params.Direction == "incoming" {
+				calls, err := client.IncomingCalls(ctx, protocol.CallHierarchyIncomingCallsParams{Item: item})
+				if err != nil {
+					continue
+				}
+				for _, call := range calls {
+					write(call.From)
+				}
+			} else {
+				calls, err := client.OutgoingCalls(ctx, protocol.CallHierarchyOutgoingCallsParams{Item: item})
+				if err != nil {
+					continue
+				}
+				for _, call := range calls {
+					write(call.To)
+				}
+			}
+		}
+	}
+	if count == 0 {
+		if params.Direction == "incoming" {
+			return NewTextResponse("No callers found"), nil
+		}
+		return NewTextResponse("No callees found"), nil
+	}
+	if count > maxLSPResults {
+		fmt.Fprintf(&output, "... and %d more\n", count-maxLSPResults)
+	}
+	return NewTextResponse(strings.TrimSuffix(output.String(), "\n")), nil
+}
@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/codeindex"
+)
+
+const (
+	CodeSearchToolName    = "codesearch"
+	codeSearchDescription = `Searches the code of the working directory with a natural language query, returning the functions, types and sections that best match it.
+
+WHEN TO USE THIS TOOL:
+- Use when you don't know the names of what you're looking for, e.g. "where are retries of failed uploads handled"
+- Great for finding your way around a large repository before reading files
+- Useful for finding the code related to a feature, a concept or an error
+
+HOW TO USE:
+- Describe what you're looking for in a few words, including the names you expect if you know them
+- Optionally set the number of results (defaults to 10, at most 50)
+
+FEATURES:
+- Files are split by symbol, so each result is a whole function, type or section
+- Results are ranked by how well their code, symbol name and path match the query
+- The index follows .gitignore and .crushignore, and is updated as files change
+
+LIMITATIONS:
+- Matches words, not meaning: use the words the code likely uses
+- Files larger than 512KB and files that aren't source code or Markdown are not indexed
+- The first search of a session may take a while in a large repository, as the index is brought up to date
+
+TIPS:
+- Use Grep for exact text or regular expressions, and Glob to find files by name
+- Use View to read around a result, starting at its line numbers`
+
+	defaultCodeSearchLimit = 10
+	maxCodeSearchLimit     = 50
+)
+
+type CodeSearchParams struct {
+	Query string `json:"query"`
+	Limit int    `json:"limit"`
+}
+
+type CodeSearchResponseMetadata struct {
+	NumberOfResults int `json:"number_of_results"`
+}
+
+type codeSearchTool struct {
+	index codeindex.Service
+}
+
+func NewCodeSearchTool(index codeindex.Service) BaseTool {
+	return &codeSearchTool{
+		index: index,
+	}
+}
+
+func (c *codeSearchTool) Name() string {
+	return CodeSearchToolName
+}
+
+func (c *codeSearchTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        CodeSearchToolName,
+		Description: codeSearchDescription,
+		Parameters: map[string]any{
+			"query": map[string]any{
+				"type":        "string",
+				"description": "What to look for, in natural language",
+			},
+			"limit": map[string]any{
+				"type":        "number",
+				"description": "The number of results to return (defaults to 10, at most 50)",
+			},
+		},
+		Required: []string{"query"},
+	}
+}
+
+func (c *codeSearchTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params CodeSearchParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	if strings.TrimSpace(params.Query) == "" {
+		return NewTextErrorResponse("query is required"), nil
+	}
+
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultCodeSearchLimit
+	}
+	limit = min(limit, maxCodeSearchLimit)
+
+	results, err := c.index.Search(ctx, params.Query, limit)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error searching code: %w", err)
+	}
+
+	var output string
+	if len(results) == 0 {
+		output = "No results found"
+	} else {
+		var sb strings.Builder
+		for i, result := range results {
+			if i > 0 {
+				sb.WriteString("\n\n")
+			}
+			fmt.Fprintf(&sb, "%s:%d-%d", result.Path, result.StartLine, result.EndLine)
+			if result.Symbol != "" {
+				fmt.Fprintf(&sb, " %s (%s)", result.Symbol, result.Kind)
+			}
+			sb.WriteString("\n")
+			sb.WriteString(result.Content)
+		}
+		output = sb.String()
+	}
+
+	return WithResponseMetadata(
+		NewTextResponse(output),
+		CodeSearchResponseMetadata{
+			NumberOfResults: len(results),
+		},
+	), nil
+}
@@ -0,0 +1,89 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+)
+
+type DefinitionParams = LSPPositionParams
+
+type definitionTool struct {
+	lspClients map[string]*lsp.Client
+	workingDir string
+}
+
+const (
+	DefinitionToolName    = "definition"
+	definitionDescription = `Find where a symbol is defined using the language server.
+WHEN TO USE THIS TOOL:
+- Use when you need to jump to the definition of a function, type, variable or method
+- Prefer this over grep when looking for a definition in a large codebase
+HOW TO USE:
+- Provide the path of a file where the symbol is used
+- Provide the symbol name, optionally with the line it appears on
+- Alternatively provide the exact line and column (both 1-based)
+FEATURES:
+- Resolves definitions across packages and modules, including dependencies
+- Returns compact "path:line:column: code" results
+LIMITATIONS:
+- Requires a configured LSP server for the file's language
+- Without a line, the first occurrence of the symbol in the file is used
+TIPS:
+- Give the line number when the symbol name is common in the file
+- Use the view tool with the returned line to read the full definition
+`
+)
+
+func NewDefinitionTool(lspClients map[string]*lsp.Client, workingDir string) BaseTool {
+	return &definitionTool{
+		lspClients: lspClients,
+		workingDir: workingDir,
+	}
+}
+
+func (d *definitionTool) Name() string {
+	return DefinitionToolName
+}
+
+func (d *definitionTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        DefinitionToolName,
+		Description: definitionDescription,
+		Parameters:  lspPositionParameters,
+		Required:    []string{"file_path"},
+	}
+}
+
+func (d *definitionTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params DefinitionParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	req, err := prepareLSPRequest(ctx, d.lspClients, d.workingDir, params)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	var locations []protocol.Location
+	for _, client := range req.clients {
+		result, err := client.Definition(ctx, protocol.DefinitionParams{
+			TextDocumentPositionParams: req.textDocumentPosition(),
+		})
+		if err != nil {
+			continue
+		}
+		locations = append(locations, locationsFromDefinition(result.Value)...)
+	}
+	if len(locations) == 0 {
+		return NewTextResponse("No definition found"), nil
+	}
+
+	output := newLocationFormatter(d.workingDir).formatAll(locations)
+	return NewTextResponse(strings.TrimSuffix(output, "\n")), nil
+}
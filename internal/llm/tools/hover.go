@@ -0,0 +1,93 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+)
+
+type HoverParams = LSPPositionParams
+
+type hoverTool struct {
+	lspClients map[string]*lsp.Client
+	workingDir string
+}
+
+const (
+	HoverToolName    = "hover"
+	maxHoverLength   = 4000
+	hoverDescription = `Show the type information and documentation of a symbol using the language server.
+WHEN TO USE THIS TOOL:
+- Use when you need the signature or type of a function, method, field or variable
+- Use to read the documentation of a symbol without opening its source file
+HOW TO USE:
+- Provide the path of a file where the symbol is used
+- Provide the symbol name, optionally with the line it appears on
+- Alternatively provide the exact line and column (both 1-based)
+FEATURES:
+- Returns the same information an editor shows when hovering a symbol
+LIMITATIONS:
+- Requires a configured LSP server for the file's language
+- Long documentation is truncated
+TIPS:
+- Cheaper than viewing the definition when you only need a signature
+`
+)
+
+func NewHoverTool(lspClients map[string]*lsp.Client, workingDir string) BaseTool {
+	return &hoverTool{
+		lspClients: lspClients,
+		workingDir: workingDir,
+	}
+}
+
+func (h *hoverTool) Name() string {
+	return HoverToolName
+}
+
+func (h *hoverTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        HoverToolName,
+		Description: hoverDescription,
+		Parameters:  lspPositionParameters,
+		Required:    []string{"file_path"},
+	}
+}
+
+func (h *hoverTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params HoverParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	req, err := prepareLSPRequest(ctx, h.lspClients, h.workingDir, params)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	var contents []string
+	for _, client := range req.clients {
+		result, err := client.Hover(ctx, protocol.HoverParams{
+			TextDocumentPositionParams: req.textDocumentPosition(),
+		})
+		if err != nil {
+			continue
+		}
+		if value := strings.TrimSpace(result.Contents.Value); value != "" {
+			contents = append(contents, value)
+		}
+	}
+	if len(contents) == 0 {
+		return NewTextResponse("No hover information found"), nil
+	}
+
+	output := strings.Join(contents, "\n\n")
+	if len(output) > maxHoverLength {
+		output = output[:maxHoverLength] + "\n... (truncated)"
+	}
+	return NewTextResponse(output), nil
+}
@@ -0,0 +1,87 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+)
+
+type ImplementationParams = LSPPositionParams
+
+type implementationTool struct {
+	lspClients map[string]*lsp.Client
+	workingDir string
+}
+
+const (
+	ImplementationToolName    = "implementation"
+	implementationDescription = `Find the implementations of an interface or abstract method using the language server.
+WHEN TO USE THIS TOOL:
+- Use when you need to know which types implement an interface
+- Use to find the concrete methods behind an interface method
+HOW TO USE:
+- Provide the path of a file where the interface or method is defined or used
+- Provide the symbol name, optionally with the line it appears on
+- Alternatively provide the exact line and column (both 1-based)
+FEATURES:
+- Returns compact "path:line:column: code" results
+LIMITATIONS:
+- Requires a configured LSP server that supports implementation requests
+- Results are limited to 100 locations
+TIPS:
+- Use the definition tool first if you don't know where the interface is declared
+`
+)
+
+func NewImplementationTool(lspClients map[string]*lsp.Client, workingDir string) BaseTool {
+	return &implementationTool{
+		lspClients: lspClients,
+		workingDir: workingDir,
+	}
+}
+
+func (i *implementationTool) Name() string {
+	return ImplementationToolName
+}
+
+func (i *implementationTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        ImplementationToolName,
+		Description: implementationDescription,
+		Parameters:  lspPositionParameters,
+		Required:    []string{"file_path"},
+	}
+}
+
+func (i *implementationTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ImplementationParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	req, err := prepareLSPRequest(ctx, i.lspClients, i.workingDir, params)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	var locations []protocol.Location
+	for _, client := range req.clients {
+		result, err := client.Implementation(ctx, protocol.ImplementationParams{
+			TextDocumentPositionParams: req.textDocumentPosition(),
+		})
+		if err != nil {
+			continue
+		}
+		locations = append(locations, locationsFromDefinition(result.Value)...)
+	}
+	if len(locations) == 0 {
+		return NewTextResponse("No implementations found"), nil
+	}
+
+	output := newLocationFormatter(i.workingDir).formatAll(locations)
+	return NewTextResponse(strings.TrimSuffix(output, "\n")), nil
+}
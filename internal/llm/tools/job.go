@@ -0,0 +1,232 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+type JobOutputParams struct {
+	JobID string `json:"job_id"`
+}
+
+type JobWaitParams struct {
+	JobID   string `json:"job_id"`
+	Timeout int    `json:"timeout"`
+}
+
+type JobKillParams struct {
+	JobID string `json:"job_id"`
+}
+
+type JobResponseMetadata struct {
+	JobID    string `json:"job_id"`
+	Command  string `json:"command"`
+	Output   string `json:"output"`
+	Running  bool   `json:"running"`
+	ExitCode int    `json:"exit_code"`
+}
+
+type jobOutputTool struct{}
+
+type jobWaitTool struct{}
+
+type jobKillTool struct{}
+
+const (
+	JobOutputToolName = "job_output"
+	JobWaitToolName   = "job_wait"
+	JobKillToolName   = "job_kill"
+
+	jobOutputDescription = `Reads the output of a background job started with the bash tool.
+
+WHEN TO USE THIS TOOL:
+- Use to check on a dev server, watcher or long test suite started with run_in_background
+- Use to find out whether a background job is still running
+
+HOW TO USE:
+- Provide the job ID returned by the bash tool
+- Only the output written since the last time it was read is returned, stdout and stderr combined
+- The result tells whether the job is still running or its exit code
+- Once a job has ended and its output was read, its ID is no longer valid
+
+TIPS:
+- Use the job_wait tool instead if you need the job to finish before going on
+`
+
+	jobWaitDescription = `Waits for a background job started with the bash tool to finish.
+
+WHEN TO USE THIS TOOL:
+- Use when you need the result of a long running command started with run_in_background
+- Use to wait for a server to write its startup output
+
+HOW TO USE:
+- Provide the job ID returned by the bash tool
+- Optionally provide a timeout in milliseconds (up to 600000ms / 10 minutes, 1 minute by default)
+- Returns the output written since the last time it was read, and whether the job finished
+- The job keeps running when the timeout is reached
+`
+
+	jobKillDescription = `Stops a background job started with the bash tool.
+
+WHEN TO USE THIS TOOL:
+- Use to stop a dev server or watcher you no longer need
+- Use to stop a job that hangs
+
+HOW TO USE:
+- Provide the job ID returned by the bash tool
+- Returns the output written since the last time it was read
+`
+)
+
+func NewJobOutputTool() BaseTool {
+	return &jobOutputTool{}
+}
+
+func NewJobWaitTool() BaseTool {
+	return &jobWaitTool{}
+}
+
+func NewJobKillTool() BaseTool {
+	return &jobKillTool{}
+}
+
+func (t *jobOutputTool) Name() string {
+	return JobOutputToolName
+}
+
+func (t *jobOutputTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        JobOutputToolName,
+		Description: jobOutputDescription,
+		Parameters: map[string]any{
+			"job_id": map[string]any{
+				"type":        "string",
+				"description": "The ID of the background job",
+			},
+		},
+		Required: []string{"job_id"},
+	}
+}
+
+func (t *jobOutputTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params JobOutputParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse("invalid parameters"), nil
+	}
+	job, ok := shell.GetBackgroundJob(params.JobID)
+	if !ok {
+		return NewTextErrorResponse(fmt.Sprintf("background job not found: %s", params.JobID)), nil
+	}
+	return jobResponse(job), nil
+}
+
+func (t *jobWaitTool) Name() string {
+	return JobWaitToolName
+}
+
+func (t *jobWaitTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        JobWaitToolName,
+		Description: jobWaitDescription,
+		Parameters: map[string]any{
+			"job_id": map[string]any{
+				"type":        "string",
+				"description": "The ID of the background job",
+			},
+			"timeout": map[string]any{
+				"type":        "number",
+				"description": "Optional timeout in milliseconds (max 600000)",
+			},
+		},
+		Required: []string{"job_id"},
+	}
+}
+
+func (t *jobWaitTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params JobWaitParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse("invalid parameters"), nil
+	}
+	if params.Timeout > MaxTimeout {
+		params.Timeout = MaxTimeout
+	} else if params.Timeout <= 0 {
+		params.Timeout = DefaultTimeout
+	}
+
+	job, ok := shell.GetBackgroundJob(params.JobID)
+	if !ok {
+		return NewTextErrorResponse(fmt.Sprintf("background job not found: %s", params.JobID)), nil
+	}
+
+	waitCtx, cancel := context.WithTimeout(ctx, time.Duration(params.Timeout)*time.Millisecond)
+	defer cancel()
+	if err := job.Wait(waitCtx); err != nil && ctx.Err() != nil {
+		return ToolResponse{}, ctx.Err()
+	}
+	return jobResponse(job), nil
+}
+
+func (t *jobKillTool) Name() string {
+	return JobKillToolName
+}
+
+func (t *jobKillTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        JobKillToolName,
+		Description: jobKillDescription,
+		Parameters: map[string]any{
+			"job_id": map[string]any{
+				"type":        "string",
+				"description": "The ID of the background job",
+			},
+		},
+		Required: []string{"job_id"},
+	}
+}
+
+func (t *jobKillTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params JobKillParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse("invalid parameters"), nil
+	}
+	job, ok := shell.GetBackgroundJob(params.JobID)
+	if !ok {
+		return NewTextErrorResponse(fmt.Sprintf("background job not found: %s", params.JobID)), nil
+	}
+	job.Kill()
+	return jobResponse(job), nil
+}
+
+// jobResponse returns the new output of the job along with its status.
+func jobResponse(job *shell.BackgroundJob) ToolResponse {
+	// Check whether the job runs before reading, so the output of a job that
+	// ended is always complete.
+	running := job.Running()
+	output := truncateOutput(job.ReadOutput())
+	metadata := JobResponseMetadata{
+		JobID:    job.ID,
+		Command:  job.Command,
+		Output:   output,
+		Running:  running,
+		ExitCode: shell.ExitCode(job.Err()),
+	}
+
+	var status string
+	switch {
+	case metadata.Running:
+		status = fmt.Sprintf("Job %s is still running.", job.ID)
+	case shell.IsInterrupt(job.Err()):
+		status = fmt.Sprintf("Job %s was killed.", job.ID)
+	default:
+		status = fmt.Sprintf("Job %s exited with code %d.", job.ID, metadata.ExitCode)
+	}
+	if output == "" {
+		return WithResponseMetadata(NewTextResponse(fmt.Sprintf("No new output.\n\n%s", status)), metadata)
+	}
+	return WithResponseMetadata(NewTextResponse(fmt.Sprintf("%s\n\n%s", strings.TrimSuffix(output, "\n"), status)), metadata)
+}
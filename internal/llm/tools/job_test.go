@@ -0,0 +1,56 @@
+package tools
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/shell"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobTools(t *testing.T) {
+	run := func(tool BaseTool, params any) (ToolResponse, JobResponseMetadata) {
+		input, err := json.Marshal(params)
+		require.NoError(t, err)
+		resp, err := tool.Run(t.Context(), ToolCall{ID: "call", Name: tool.Name(), Input: string(input)})
+		require.NoError(t, err)
+		var meta JobResponseMetadata
+		if resp.Metadata != "" {
+			require.NoError(t, json.Unmarshal([]byte(resp.Metadata), &meta))
+		}
+		return resp, meta
+	}
+
+	sh := shell.NewShell(&shell.Options{WorkingDir: t.TempDir()})
+
+	t.Run("wait", func(t *testing.T) {
+		job, err := sh.StartBackground("echo started; exit 2")
+		require.NoError(t, err)
+
+		resp, meta := run(NewJobWaitTool(), JobWaitParams{JobID: job.ID, Timeout: 10000})
+		require.False(t, meta.Running)
+		require.Equal(t, 2, meta.ExitCode)
+		require.Equal(t, "started\n\nJob "+job.ID+" exited with code 2.", resp.Content)
+
+		// The final output was read, so the job is gone.
+		resp, _ = run(NewJobOutputTool(), JobOutputParams{JobID: job.ID})
+		require.True(t, resp.IsError)
+	})
+
+	t.Run("wait timeout and kill", func(t *testing.T) {
+		job, err := sh.StartBackground("sleep 60")
+		require.NoError(t, err)
+
+		_, meta := run(NewJobWaitTool(), JobWaitParams{JobID: job.ID, Timeout: 10})
+		require.True(t, meta.Running)
+
+		resp, meta := run(NewJobKillTool(), JobKillParams{JobID: job.ID})
+		require.False(t, meta.Running)
+		require.Contains(t, resp.Content, "was killed")
+	})
+
+	t.Run("unknown job", func(t *testing.T) {
+		resp, _ := run(NewJobOutputTool(), JobOutputParams{JobID: "job-0"})
+		require.True(t, resp.IsError)
+	})
+}
@@ -0,0 +1,316 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+)
+
+// LSPPositionParams identifies a position in a file, either by line and
+// column or by the name of a symbol.
+type LSPPositionParams struct {
+	FilePath string `json:"file_path"`
+	Symbol   string `json:"symbol,omitempty"`
+	Line     int    `json:"line,omitempty"`
+	Column   int    `json:"column,omitempty"`
+}
+
+const (
+	maxLSPResults      = 100
+	maxLSPSnippetWidth = 200
+)
+
+var lspPositionParameters = map[string]any{
+	"file_path": map[string]any{
+		"type":        "string",
+		"description": "The path to the file containing the symbol",
+	},
+	"symbol": map[string]any{
+		"type":        "string",
+		"description": "The name of the symbol to look up; the first occurrence in the file (or on the given line) is used",
+	},
+	"line": map[string]any{
+		"type":        "integer",
+		"description": "The line number of the symbol (1-based)",
+	},
+	"column": map[string]any{
+		"type":        "integer",
+		"description": "The column of the symbol on the line (1-based), ignored when symbol is given",
+	},
+}
+
+// lspRequest holds the resolved target of an LSP tool call.
+type lspRequest struct {
+	filePath string
+	position protocol.Position
+	clients  []*lsp.Client
+}
+
+func (r lspRequest) textDocumentPosition() protocol.TextDocumentPositionParams {
+	return protocol.TextDocumentPositionParams{
+		TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(r.filePath)},
+		Position:     r.position,
+	}
+}
+
+func resolveToolPath(workingDir, path string) string {
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(workingDir, path)
+	}
+	return path
+}
+
+// lspClientsForFile returns the LSP clients handling the given file, making
+// sure the file is open in each of them.
+func lspClientsForFile(ctx context.Context, lspClients map[string]*lsp.Client, filePath string) []*lsp.Client {
+	var clients []*lsp.Client
+	for _, client := range lspClients {
+		if !client.HandlesFile(filePath) {
+			continue
+		}
+		if err := client.OpenFile(ctx, filePath); err != nil {
+			continue
+		}
+		clients = append(clients, client)
+	}
+	return clients
+}
+
+func prepareLSPRequest(ctx context.Context, lspClients map[string]*lsp.Client, workingDir string, params LSPPositionParams) (lspRequest, error) {
+	if params.FilePath == "" {
+		return lspRequest{}, fmt.Errorf("file_path is required")
+	}
+	if params.Symbol == "" && params.Line <= 0 {
+		return lspRequest{}, fmt.Errorf("either symbol or line is required")
+	}
+
+	filePath := resolveToolPath(workingDir, params.FilePath)
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return lspRequest{}, fmt.Errorf("error reading file: %w", err)
+	}
+
+	position, err := findPosition(string(content), params.Symbol, params.Line, params.Column)
+	if err != nil {
+		return lspRequest{}, err
+	}
+
+	clients := lspClientsForFile(ctx, lspClients, filePath)
+	if len(clients) == 0 {
+		return lspRequest{}, fmt.Errorf("no LSP server available for %s", params.FilePath)
+	}
+
+	return lspRequest{
+		filePath: filePath,
+		position: position,
+		clients:  clients,
+	}, nil
+}
+
+// findPosition converts a 1-based line/column or a symbol name into an LSP
+// position.
+func findPosition(content, symbol string, line, column int) (protocol.Position, error) {
+	lines := strings.Split(content, "\n")
+	if line > len(lines) {
+		return protocol.Position{}, fmt.Errorf("line %d is out of range, the file has %d lines", line, len(lines))
+	}
+
+	if symbol == "" {
+		text := lines[line-1]
+		offset := 0
+		for i := 1; i < column && offset < len(text); i++ {
+			_, size := utf8.DecodeRuneInString(text[offset:])
+			offset += size
+		}
+		return protocol.Position{
+			Line:      uint32(line - 1),
+			Character: utf16Len(text[:offset]),
+		}, nil
+	}
+
+	// For qualified names such as "Client.Rename" look up the last part.
+	if i := strings.LastIndex(symbol, "."); i >= 0 && i < len(symbol)-1 {
+		symbol = symbol[i+1:]
+	}
+	re, err := regexp.Compile(`\b` + regexp.QuoteMeta(symbol) + `\b`)
+	if err != nil {
+		return protocol.Position{}, fmt.Errorf("invalid symbol: %w", err)
+	}
+
+	start, end := 0, len(lines)
+	if line > 0 {
+		start, end = line-1, line
+	}
+	for i := start; i < end; i++ {
+		if loc := re.FindStringIndex(lines[i]); loc != nil {
+			return protocol.Position{
+				Line:      uint32(i),
+				Character: utf16Len(lines[i][:loc[0]]),
+			}, nil
+		}
+	}
+	if line > 0 {
+		return protocol.Position{}, fmt.Errorf("symbol %q not found on line %d", symbol, line)
+	}
+	return protocol.Position{}, fmt.Errorf("symbol %q not found in file", symbol)
+}
+
+func utf16Len(s string) uint32 {
+	return uint32(len(utf16.Encode([]rune(s))))
+}
+
+// locationsFromDefinition flattens the different shapes a definition or
+// implementation result can take into a list of locations.
+func locationsFromDefinition(value any) []protocol.Location {
+	switch v := value.(type) {
+	case protocol.Or_Definition:
+		return locationsFromDefinition(v.Value)
+	case protocol.Location:
+		return []protocol.Location{v}
+	case []protocol.Location:
+		return v
+	case []protocol.DefinitionLink:
+		locations := make([]protocol.Location, 0, len(v))
+		for _, link := range v {
+			locations = append(locations, protocol.Location{
+				URI:   link.TargetURI,
+				Range: link.TargetSelectionRange,
+			})
+		}
+		return locations
+	}
+	return nil
+}
+
+// sortLocations orders locations by file and position.
+func sortLocations(locations []protocol.Location) {
+	sort.SliceStable(locations, func(i, j int) bool {
+		a, b := locations[i], locations[j]
+		if a.URI != b.URI {
+			return a.URI < b.URI
+		}
+		if a.Range.Start.Line != b.Range.Start.Line {
+			return a.Range.Start.Line < b.Range.Start.Line
+		}
+		return a.Range.Start.Character < b.Range.Start.Character
+	})
+}
+
+// locationFormatter renders locations as compact "path:line:col: code"
+// lines, caching the file contents it reads.
+type locationFormatter struct {
+	workingDir string
+	files      map[string][]string
+}
+
+func newLocationFormatter(workingDir string) *locationFormatter {
+	return &locationFormatter{
+		workingDir: workingDir,
+		files:      make(map[string][]string),
+	}
+}
+
+func (f *locationFormatter) path(uri protocol.DocumentURI) string {
+	path, err := uri.Path()
+	if err != nil {
+		return string(uri)
+	}
+	if rel, err := filepath.Rel(f.workingDir, path); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return path
+}
+
+// line returns the text of the given 0-based line of the file, or false if
+// it can't be read.
+func (f *locationFormatter) line(uri protocol.DocumentURI, line uint32) (string, bool) {
+	path, err := uri.Path()
+	if err != nil {
+		return "", false
+	}
+	lines, ok := f.files[path]
+	if !ok {
+		content, err := os.ReadFile(path)
+		if err == nil {
+			lines = strings.Split(string(content), "\n")
+		}
+		f.files[path] = lines
+	}
+	if int(line) >= len(lines) {
+		return "", false
+	}
+	return lines[line], true
+}
+
+// column converts the UTF-16 offset of an LSP position into the 1-based rune
+// column findPosition takes, so reported locations can be passed back as is.
+func (f *locationFormatter) column(uri protocol.DocumentURI, pos protocol.Position) int {
+	text, ok := f.line(uri, pos.Line)
+	if !ok {
+		return int(pos.Character) + 1
+	}
+	column, units := 1, uint32(0)
+	for _, r := range text {
+		if units >= pos.Character {
+			break
+		}
+		units += uint32(utf16.RuneLen(r))
+		column++
+	}
+	return column
+}
+
+func (f *locationFormatter) snippet(uri protocol.DocumentURI, line uint32) string {
+	text, ok := f.line(uri, line)
+	if !ok {
+		return ""
+	}
+	text = strings.TrimSpace(text)
+	if len(text) > maxLSPSnippetWidth {
+		// Cut on a rune boundary so multi-byte characters aren't split.
+		end := maxLSPSnippetWidth
+		for end > 0 && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		text = text[:end] + "..."
+	}
+	return text
+}
+
+func (f *locationFormatter) format(loc protocol.Location) string {
+	return fmt.Sprintf("%s:%d:%d: %s",
+		f.path(loc.URI),
+		loc.Range.Start.Line+1,
+		f.column(loc.URI, loc.Range.Start),
+		f.snippet(loc.URI, loc.Range.Start.Line),
+	)
+}
+
+func (f *locationFormatter) formatAll(locations []protocol.Location) string {
+	var output strings.Builder
+	for i, loc := range locations {
+		if i == maxLSPResults {
+			fmt.Fprintf(&output, "... and %d more\n", len(locations)-maxLSPResults)
+			break
+		}
+		output.WriteString(f.format(loc))
+		output.WriteString("\n")
+	}
+	return output.String()
+}
+
+func symbolKindName(kind protocol.SymbolKind) string {
+	if name, ok := protocol.TableKindMap[kind]; ok {
+		return name
+	}
+	return "Symbol"
+}
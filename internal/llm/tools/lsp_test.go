@@ -0,0 +1,139 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindPosition(t *testing.T) {
+	t.Parallel()
+
+	content := "package main\n\nfunc helper() {}\n\n// héllo calls helper\nfunc héllo() { helper() }\n"
+
+	tests := []struct {
+		name    string
+		symbol  string
+		line    int
+		column  int
+		want    protocol.Position
+		wantErr bool
+	}{
+		{
+			name:   "first occurrence of symbol",
+			symbol: "helper",
+			want:   protocol.Position{Line: 2, Character: 5},
+		},
+		{
+			name:   "symbol on a given line",
+			symbol: "helper",
+			line:   6,
+			want:   protocol.Position{Line: 5, Character: 15},
+		},
+		{
+			name:   "qualified symbol uses the last part",
+			symbol: "main.helper",
+			want:   protocol.Position{Line: 2, Character: 5},
+		},
+		{
+			name:   "whole words only",
+			symbol: "help",
+			// "help" only appears as part of "helper"
+			wantErr: true,
+		},
+		{
+			name:   "line and column",
+			line:   6,
+			column: 12,
+			want:   protocol.Position{Line: 5, Character: 11},
+		},
+		{
+			name:    "symbol missing from line",
+			symbol:  "helper",
+			line:    1,
+			wantErr: true,
+		},
+		{
+			name:    "line out of range",
+			line:    100,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			got, err := findPosition(content, tt.symbol, tt.line, tt.column)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLocationsFromDefinition(t *testing.T) {
+	t.Parallel()
+
+	loc := protocol.Location{URI: "file:///a.go", Range: protocol.Range{Start: protocol.Position{Line: 1}}}
+
+	require.Equal(t, []protocol.Location{loc}, locationsFromDefinition(protocol.Or_Definition{Value: loc}))
+	require.Equal(t, []protocol.Location{loc}, locationsFromDefinition(protocol.Or_Definition{Value: []protocol.Location{loc}}))
+	require.Equal(t, []protocol.Location{loc}, locationsFromDefinition([]protocol.DefinitionLink{{
+		TargetURI:            loc.URI,
+		TargetSelectionRange: loc.Range,
+	}}))
+	require.Empty(t, locationsFromDefinition(nil))
+}
+
+func TestLocationFormatterSnippet(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	// The line starts with three one byte characters, so a byte cut at the
+	// width would split one of the two byte characters that follow.
+	long := strings.Repeat("é", maxLSPSnippetWidth)
+	require.NoError(t, os.WriteFile(path, []byte("package main\n\t// "+long+"\n"), 0o644))
+
+	f := newLocationFormatter(dir)
+	uri := protocol.URIFromPath(path)
+	require.Equal(t, "package main", f.snippet(uri, 0))
+	require.Empty(t, f.snippet(uri, 10))
+
+	snippet := f.snippet(uri, 1)
+	require.True(t, utf8.ValidString(snippet))
+	require.True(t, strings.HasSuffix(snippet, "..."))
+	require.LessOrEqual(t, len(snippet), maxLSPSnippetWidth+len("..."))
+	require.True(t, strings.HasPrefix(long, strings.TrimPrefix(strings.TrimSuffix(snippet, "..."), "// ")))
+}
+
+func TestLocationFormatterColumn(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "main.go")
+	content := "package main\n\nvar greeting = \"😀\" + name\n"
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	// The emoji takes two UTF-16 code units but is a single column.
+	position, err := findPosition(content, "name", 3, 0)
+	require.NoError(t, err)
+	require.Equal(t, uint32(22), position.Character)
+
+	f := newLocationFormatter(dir)
+	loc := protocol.Location{URI: protocol.URIFromPath(path), Range: protocol.Range{Start: position}}
+	require.Equal(t, `main.go:3:22: var greeting = "😀" + name`, f.format(loc))
+
+	// The reported column finds the same position again.
+	again, err := findPosition(content, "", 3, 22)
+	require.NoError(t, err)
+	require.Equal(t, position, again)
+}
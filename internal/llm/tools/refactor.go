@@ -0,0 +1,538 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+	"github.com/charmbracelet/crush/internal/lsp/util"
+	"github.com/charmbracelet/crush/internal/permission"
+)
+
+type RefactorParams struct {
+	LSPPositionParams
+	Operation string `json:"operation"`
+	NewName   string `json:"new_name,omitempty"`
+	Kind      string `json:"kind,omitempty"`
+	Title     string `json:"title,omitempty"`
+}
+
+type RefactorFileChange struct {
+	FilePath   string `json:"file_path"`
+	OldContent string `json:"old_content,omitempty"`
+	NewContent string `json:"new_content,omitempty"`
+	Additions  int    `json:"additions"`
+	Removals   int    `json:"removals"`
+}
+
+type RefactorPermissionsParams struct {
+	Description string               `json:"description"`
+	Files       []RefactorFileChange `json:"files"`
+}
+
+type RefactorResponseMetadata struct {
+	Description string               `json:"description"`
+	Files       []RefactorFileChange `json:"files"`
+	Additions   int                  `json:"additions"`
+	Removals    int                  `json:"removals"`
+}
+
+type refactorTool struct {
+	lspClients  map[string]*lsp.Client
+	permissions permission.Service
+	files       history.Service
+	workingDir  string
+}
+
+const (
+	RefactorToolName = "refactor"
+
+	RefactorOperationRename     = "rename"
+	RefactorOperationCodeAction = "code_action"
+
+	refactorDescription = `Performs semantic refactorings through the language server: renaming symbols and applying code actions such as organize imports or quick fixes.
+WHEN TO USE THIS TOOL:
+- Use operation "rename" to rename a function, type, variable, field or method everywhere it is used
+- Use operation "code_action" to apply fixes and refactorings offered by the language server
+HOW TO USE:
+- Provide the path of a file and the symbol name, optionally with its line
+- Alternatively provide the exact line and column (both 1-based)
+- For "rename", provide new_name
+- For "code_action", optionally filter by kind (e.g. "quickfix", "source.organizeImports", "refactor.extract")
+- Call "code_action" without a title to list the available actions, then call it again with the title of the one to apply
+- For source actions like organize imports the symbol and line can be omitted to target the whole file
+FEATURES:
+- Changes can span multiple files and are shown to the user as a diff for approval
+- Unlike search and replace, only real references to the symbol are changed
+LIMITATIONS:
+- Requires a configured LSP server that supports rename and code actions
+- Code actions that need the server to run a command, and edits that create, rename or delete files, are not supported
+TIPS:
+- Prefer this tool over edit or multiedit when renaming symbols used across several files
+- Use the diagnostics tool to find problems that may have quick fixes available
+`
+)
+
+func NewRefactorTool(lspClients map[string]*lsp.Client, permissions permission.Service, files history.Service, workingDir string) BaseTool {
+	return &refactorTool{
+		lspClients:  lspClients,
+		permissions: permissions,
+		files:       files,
+		workingDir:  workingDir,
+	}
+}
+
+func (r *refactorTool) Name() string {
+	return RefactorToolName
+}
+
+func (r *refactorTool) Info() ToolInfo {
+	parameters := make(map[string]any, len(lspPositionParameters)+4)
+	for k, v := range lspPositionParameters {
+		parameters[k] = v
+	}
+	parameters["operation"] = map[string]any{
+		"type":        "string",
+		"description": "The refactoring to perform",
+		"enum":        []string{RefactorOperationRename, RefactorOperationCodeAction},
+	}
+	parameters["new_name"] = map[string]any{
+		"type":        "string",
+		"description": "The new name of the symbol (rename only)",
+	}
+	parameters["kind"] = map[string]any{
+		"type":        "string",
+		"description": "Only consider code actions of this kind, e.g. quickfix or source.organizeImports (code_action only)",
+	}
+	parameters["title"] = map[string]any{
+		"type":        "string",
+		"description": "The title of the code action to apply, leave empty to list the available actions (code_action only)",
+	}
+	return ToolInfo{
+		Name:        RefactorToolName,
+		Description: refactorDescription,
+		Parameters:  parameters,
+		Required:    []string{"file_path", "operation"},
+	}
+}
+
+func (r *refactorTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params RefactorParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	switch params.Operation {
+	case RefactorOperationRename:
+		return r.rename(ctx, call, params)
+	case RefactorOperationCodeAction:
+		return r.codeAction(ctx, call, params)
+	default:
+		return NewTextErrorResponse("operation must be either rename or code_action"), nil
+	}
+}
+
+func (r *refactorTool) rename(ctx context.Context, call ToolCall, params RefactorParams) (ToolResponse, error) {
+	if params.NewName == "" {
+		return NewTextErrorResponse("new_name is required for rename"), nil
+	}
+
+	req, err := prepareLSPRequest(ctx, r.lspClients, r.workingDir, params.LSPPositionParams)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	var lastErr error
+	for _, client := range req.clients {
+		edit, err := client.Rename(ctx, protocol.RenameParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(req.filePath)},
+			Position:     req.position,
+			NewName:      params.NewName,
+		})
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		if len(edit.Changes) == 0 && len(edit.DocumentChanges) == 0 {
+			continue
+		}
+		description := fmt.Sprintf("Rename %s to %s", renameTarget(params.LSPPositionParams), params.NewName)
+		return r.applyEdit(ctx, call, req.filePath, description, edit)
+	}
+	if lastErr != nil {
+		return NewTextErrorResponse(fmt.Sprintf("rename failed: %s", lastErr)), nil
+	}
+	return NewTextErrorResponse("the language server returned no changes for this rename"), nil
+}
+
+func renameTarget(params LSPPositionParams) string {
+	if params.Symbol != "" {
+		return params.Symbol
+	}
+	return fmt.Sprintf("symbol at %d:%d", params.Line, params.Column)
+}
+
+// codeActionCandidate is a code action together with the client offering it.
+type codeActionCandidate struct {
+	client *lsp.Client
+	action protocol.CodeAction
+}
+
+func (r *refactorTool) codeAction(ctx context.Context, call ToolCall, params RefactorParams) (ToolResponse, error) {
+	filePath, rng, clients, err := r.codeActionTarget(ctx, params.LSPPositionParams)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	uri := protocol.URIFromPath(filePath)
+	var only []protocol.CodeActionKind
+	if params.Kind != "" {
+		only = []protocol.CodeActionKind{protocol.CodeActionKind(params.Kind)}
+	}
+
+	var candidates []codeActionCandidate
+	for _, client := range clients {
+		var diagnostics []protocol.Diagnostic
+		for _, diagnostic := range client.GetDiagnostics()[uri] {
+			if rangesIntersect(diagnostic.Range, rng) {
+				diagnostics = append(diagnostics, diagnostic)
+			}
+		}
+		if diagnostics == nil {
+			diagnostics = []protocol.Diagnostic{}
+		}
+
+		result, err := client.CodeAction(ctx, protocol.CodeActionParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: uri},
+			Range:        rng,
+			Context: protocol.CodeActionContext{
+				Diagnostics: diagnostics,
+				Only:        only,
+			},
+		})
+		if err != nil {
+			continue
+		}
+		for _, item := range result {
+			// Bare commands can't be previewed, so they are not offered.
+			action, ok := item.Value.(protocol.CodeAction)
+			if !ok || action.Disabled != nil {
+				continue
+			}
+			if params.Kind != "" && !matchesCodeActionKind(action.Kind, params.Kind) {
+				continue
+			}
+			candidates = append(candidates, codeActionCandidate{client: client, action: action})
+		}
+	}
+	if len(candidates) == 0 {
+		return NewTextResponse("No code actions available"), nil
+	}
+
+	selected := selectCodeActions(candidates, params.Title)
+	if len(selected) != 1 {
+		var output strings.Builder
+		if params.Title != "" && len(selected) == 0 {
+			fmt.Fprintf(&output, "No code action matches %q. ", params.Title)
+		} else if len(selected) > 1 {
+			candidates = selected
+		}
+		output.WriteString("Available code actions, call again with the title of the one to apply:\n")
+		for _, candidate := range candidates {
+			fmt.Fprintf(&output, "- %s", candidate.action.Title)
+			if candidate.action.Kind != "" {
+				fmt.Fprintf(&output, " (%s)", candidate.action.Kind)
+			}
+			output.WriteString("\n")
+		}
+		return NewTextResponse(strings.TrimSuffix(output.String(), "\n")), nil
+	}
+
+	candidate := selected[0]
+	action := candidate.action
+	if action.Edit == nil && action.Data != nil {
+		resolved, err := candidate.client.ResolveCodeAction(ctx, action)
+		if err != nil {
+			return NewTextErrorResponse(fmt.Sprintf("failed to resolve code action: %s", err)), nil
+		}
+		action = resolved
+	}
+	if action.Edit == nil {
+		return NewTextErrorResponse(fmt.Sprintf("code action %q does not provide edits and can't be applied", action.Title)), nil
+	}
+
+	return r.applyEdit(ctx, call, filePath, action.Title, *action.Edit)
+}
+
+// codeActionTarget resolves the file and range code actions are requested
+// for. Without a symbol or line the whole file is used.
+func (r *refactorTool) codeActionTarget(ctx context.Context, params LSPPositionParams) (string, protocol.Range, []*lsp.Client, error) {
+	if params.Symbol != "" || params.Line > 0 {
+		req, err := prepareLSPRequest(ctx, r.lspClients, r.workingDir, params)
+		if err != nil {
+			return "", protocol.Range{}, nil, err
+		}
+		rng := protocol.Range{Start: req.position, End: req.position}
+		if params.Symbol == "" && params.Column <= 0 {
+			// A whole line was given.
+			rng.End = protocol.Position{Line: req.position.Line + 1}
+		}
+		return req.filePath, rng, req.clients, nil
+	}
+
+	if params.FilePath == "" {
+		return "", protocol.Range{}, nil, fmt.Errorf("file_path is required")
+	}
+	filePath := resolveToolPath(r.workingDir, params.FilePath)
+	content, err := os.ReadFile(filePath)
+	if err != nil {
+		return "", protocol.Range{}, nil, fmt.Errorf("error reading file: %w", err)
+	}
+	clients := lspClientsForFile(ctx, r.lspClients, filePath)
+	if len(clients) == 0 {
+		return "", protocol.Range{}, nil, fmt.Errorf("no LSP server available for %s", params.FilePath)
+	}
+	rng := protocol.Range{
+		End: protocol.Position{Line: uint32(strings.Count(string(content), "\n") + 1)},
+	}
+	return filePath, rng, clients, nil
+}
+
+// matchesCodeActionKind reports whether kind is the wanted kind or one of
+// its sub-kinds, e.g. "refactor.extract.function" matches "refactor".
+func matchesCodeActionKind(kind protocol.CodeActionKind, want string) bool {
+	return string(kind) == want || strings.HasPrefix(string(kind), want+".")
+}
+
+// selectCodeActions returns the candidates matching the title. Without a
+// title the single candidate or the single preferred one is returned.
+func selectCodeActions(candidates []codeActionCandidate, title string) []codeActionCandidate {
+	if title == "" {
+		if len(candidates) == 1 {
+			return candidates
+		}
+		var preferred []codeActionCandidate
+		for _, candidate := range candidates {
+			if candidate.action.IsPreferred {
+				preferred = append(preferred, candidate)
+			}
+		}
+		if len(preferred) == 1 {
+			return preferred
+		}
+		return nil
+	}
+
+	var selected []codeActionCandidate
+	for _, candidate := range candidates {
+		if strings.EqualFold(candidate.action.Title, title) {
+			return []codeActionCandidate{candidate}
+		}
+		if strings.Contains(strings.ToLower(candidate.action.Title), strings.ToLower(title)) {
+			selected = append(selected, candidate)
+		}
+	}
+	return selected
+}
+
+func rangesIntersect(a, b protocol.Range) bool {
+	before := func(p, q protocol.Position) bool {
+		return p.Line < q.Line || (p.Line == q.Line && p.Character < q.Character)
+	}
+	return !before(a.End, b.Start) && !before(b.End, a.Start)
+}
+
+// applyEdit shows the changes of a workspace edit to the user and, once
+// approved, writes them and records every touched file in the history.
+func (r *refactorTool) applyEdit(ctx context.Context, call ToolCall, filePath, description string, edit protocol.WorkspaceEdit) (ToolResponse, error) {
+	sessionID, messageID := GetContextValues(ctx)
+	if sessionID == "" || messageID == "" {
+		return ToolResponse{}, fmt.Errorf("session ID and message ID are required for refactoring")
+	}
+
+	fileEdits, err := util.PreviewWorkspaceEdit(edit)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("can't apply changes: %s", err)), nil
+	}
+
+	var changes []RefactorFileChange
+	totalAdditions, totalRemovals := 0, 0
+	for _, fileEdit := range fileEdits {
+		if fileEdit.OldContent == fileEdit.NewContent {
+			continue
+		}
+		_, additions, removals := diff.GenerateDiff(
+			fileEdit.OldContent,
+			fileEdit.NewContent,
+			strings.TrimPrefix(fileEdit.Path, r.workingDir),
+		)
+		totalAdditions += additions
+		totalRemovals += removals
+		changes = append(changes, RefactorFileChange{
+			FilePath:   fileEdit.Path,
+			OldContent: fileEdit.OldContent,
+			NewContent: fileEdit.NewContent,
+			Additions:  additions,
+			Removals:   removals,
+		})
+	}
+	if len(changes) == 0 {
+		return NewTextResponse("The refactoring doesn't change any file"), nil
+	}
+
+	p := r.permissions.Request(
+		permission.CreatePermissionRequest{
+			SessionID:   sessionID,
+			Path:        fsext.PathOrPrefix(filePath, r.workingDir),
+			ToolCallID:  call.ID,
+			ToolName:    RefactorToolName,
+			Action:      "write",
+			Description: fmt.Sprintf("%s (%d files)", description, len(changes)),
+			Params: RefactorPermissionsParams{
+				Description: description,
+				Files:       changes,
+			},
+		},
+	)
+	if !p {
+		return ToolResponse{}, permission.ErrorPermissionDenied
+	}
+
+	written, err := writeRefactorChanges(changes)
+	var output strings.Builder
+	fmt.Fprintf(&output, "%s\nChanged files:\n", description)
+	for _, change := range written {
+		r.recordHistory(ctx, sessionID, change.FilePath, change.OldContent, change.NewContent)
+		recordFileWrite(change.FilePath)
+		recordFileRead(change.FilePath)
+
+		for _, client := range r.lspClients {
+			if client.IsFileOpen(change.FilePath) {
+				_ = client.NotifyChange(ctx, change.FilePath)
+			}
+		}
+
+		fmt.Fprintf(&output, "- %s (+%d -%d)\n", fsext.PrettyPath(change.FilePath), change.Additions, change.Removals)
+	}
+	if err != nil {
+		if len(written) == 0 {
+			return NewTextErrorResponse(fmt.Sprintf("can't apply changes, no file was changed: %s", err)), nil
+		}
+		return NewTextErrorResponse(fmt.Sprintf("can't apply all changes: %s\n%s", err, strings.TrimSuffix(output.String(), "\n"))), nil
+	}
+
+	// Keep the metadata small, the diffs are only needed for the permission
+	// dialog.
+	for i := range changes {
+		changes[i].OldContent = ""
+		changes[i].NewContent = ""
+	}
+
+	waitForLspDiagnostics(ctx, filePath, r.lspClients)
+	text := strings.TrimSuffix(output.String(), "\n")
+	text += getDiagnostics(filePath, r.lspClients)
+
+	return WithResponseMetadata(
+		NewTextResponse(text),
+		RefactorResponseMetadata{
+			Description: description,
+			Files:       changes,
+			Additions:   totalAdditions,
+			Removals:    totalRemovals,
+		}), nil
+}
+
+// writeRefactorChanges writes the new content of the changed files and
+// returns the files written. Nothing is written if a file changed since the
+// changes were previewed. Every file is written to a temporary file next to
+// it first and then renamed into place, so a failure leaves no file half
+// written.
+func writeRefactorChanges(changes []RefactorFileChange) ([]RefactorFileChange, error) {
+	for _, change := range changes {
+		content, err := os.ReadFile(change.FilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", change.FilePath, err)
+		}
+		if string(content) != change.OldContent {
+			return nil, fmt.Errorf("%s was modified since the changes were previewed", change.FilePath)
+		}
+	}
+
+	temps := make([]string, len(changes))
+	defer func() {
+		for _, temp := range temps {
+			if temp != "" {
+				_ = os.Remove(temp)
+			}
+		}
+	}()
+	for i, change := range changes {
+		temp, err := writeTempFile(change.FilePath, change.NewContent)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write %s: %w", change.FilePath, err)
+		}
+		temps[i] = temp
+	}
+
+	for i, change := range changes {
+		if err := os.Rename(temps[i], change.FilePath); err != nil {
+			return changes[:i], fmt.Errorf("failed to write %s: %w", change.FilePath, err)
+		}
+		temps[i] = ""
+	}
+	return changes, nil
+}
+
+// writeTempFile writes content to a new file in the directory of path, with
+// the permissions of path, and returns its name.
+func writeTempFile(path, content string) (string, error) {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.WriteString(content)
+	if err == nil {
+		err = f.Chmod(mode)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+func (r *refactorTool) recordHistory(ctx context.Context, sessionID, filePath, oldContent, newContent string) {
+	file, err := r.files.GetByPathAndSession(ctx, filePath, sessionID)
+	if err != nil {
+		file, err = r.files.Create(ctx, sessionID, filePath, oldContent)
+		if err != nil {
+			slog.Debug("Error creating file history", "error", err)
+			return
+		}
+	}
+	if file.Content != oldContent {
+		// User manually changed the content, store an intermediate version
+		if _, err := r.files.CreateVersion(ctx, sessionID, filePath, oldContent); err != nil {
+			slog.Debug("Error creating file history version", "error", err)
+		}
+	}
+	if _, err := r.files.CreateVersion(ctx, sessionID, filePath, newContent); err != nil {
+		slog.Debug("Error creating file history version", "error", err)
+	}
+}
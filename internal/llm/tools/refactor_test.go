@@ -0,0 +1,52 @@
+package tools
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteRefactorChanges(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(a, []byte("old a"), 0o600))
+	require.NoError(t, os.WriteFile(b, []byte("old b"), 0o644))
+	changes := []RefactorFileChange{
+		{FilePath: a, OldContent: "old a", NewContent: "new a"},
+		{FilePath: b, OldContent: "old b", NewContent: "new b"},
+	}
+
+	// A file modified after the preview stops every file from being written.
+	require.NoError(t, os.WriteFile(b, []byte("edited b"), 0o644))
+	written, err := writeRefactorChanges(changes)
+	require.ErrorContains(t, err, "was modified since the changes were previewed")
+	require.Empty(t, written)
+	content, err := os.ReadFile(a)
+	require.NoError(t, err)
+	require.Equal(t, "old a", string(content))
+
+	require.NoError(t, os.WriteFile(b, []byte("old b"), 0o644))
+	written, err = writeRefactorChanges(changes)
+	require.NoError(t, err)
+	require.Equal(t, changes, written)
+	for _, change := range changes {
+		content, err := os.ReadFile(change.FilePath)
+		require.NoError(t, err)
+		require.Equal(t, change.NewContent, string(content))
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(a)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	}
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Len(t, entries, 2, "temporary files are left behind")
+}
@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+)
+
+type ReferencesParams struct {
+	LSPPositionParams
+	IncludeDeclaration bool `json:"include_declaration,omitempty"`
+}
+
+type referencesTool struct {
+	lspClients map[string]*lsp.Client
+	workingDir string
+}
+
+const (
+	ReferencesToolName    = "references"
+	referencesDescription = `Find all references to a symbol using the language server.
+WHEN TO USE THIS TOOL:
+- Use when you need to know where a function, type, variable or method is used
+- Prefer this over grep before renaming or changing the signature of a symbol
+HOW TO USE:
+- Provide the path of a file where the symbol is defined or used
+- Provide the symbol name, optionally with the line it appears on
+- Alternatively provide the exact line and column (both 1-based)
+- Set include_declaration to also list the declaration itself
+FEATURES:
+- Only returns real references, not textual matches in comments or strings
+- Returns compact "path:line:column: code" results sorted by file
+LIMITATIONS:
+- Requires a configured LSP server for the file's language
+- Results are limited to 100 locations
+TIPS:
+- Give the line number when the symbol name is common in the file
+`
+)
+
+func NewReferencesTool(lspClients map[string]*lsp.Client, workingDir string) BaseTool {
+	return &referencesTool{
+		lspClients: lspClients,
+		workingDir: workingDir,
+	}
+}
+
+func (r *referencesTool) Name() string {
+	return ReferencesToolName
+}
+
+func (r *referencesTool) Info() ToolInfo {
+	parameters := make(map[string]any, len(lspPositionParameters)+1)
+	for k, v := range lspPositionParameters {
+		parameters[k] = v
+	}
+	parameters["include_declaration"] = map[string]any{
+		"type":        "boolean",
+		"description": "Whether to include the declaration of the symbol in the results (default false)",
+	}
+	return ToolInfo{
+		Name:        ReferencesToolName,
+		Description: referencesDescription,
+		Parameters:  parameters,
+		Required:    []string{"file_path"},
+	}
+}
+
+func (r *referencesTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params ReferencesParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	req, err := prepareLSPRequest(ctx, r.lspClients, r.workingDir, params.LSPPositionParams)
+	if err != nil {
+		return NewTextErrorResponse(err.Error()), nil
+	}
+
+	var locations []protocol.Location
+	for _, client := range req.clients {
+		result, err := client.References(ctx, protocol.ReferenceParams{
+			TextDocumentPositionParams: req.textDocumentPosition(),
+			Context: protocol.ReferenceContext{
+				IncludeDeclaration: params.IncludeDeclaration,
+			},
+		})
+		if err != nil {
+			continue
+		}
+		locations = append(locations, result...)
+	}
+	if len(locations) == 0 {
+		return NewTextResponse("No references found"), nil
+	}
+
+	sortLocations(locations)
+	output := fmt.Sprintf("Found %d references\n", len(locations))
+	output += newLocationFormatter(r.workingDir).formatAll(locations)
+	return NewTextResponse(strings.TrimSuffix(output, "\n")), nil
+}
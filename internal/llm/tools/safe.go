@@ -2,16 +2,27 @@ package tools
 
 import "runtime"
 
+// safeCommands are read-only commands that run without asking for
+// permission. Each one is the leading words of a command.
 var safeCommands = []string{
 	// Bash builtins and core utils
+	"basename",
 	"cal",
+	"cat",
+	"cut",
 	"date",
 	"df",
+	"diff",
+	"dirname",
 	"du",
 	"echo",
 	"env",
+	"false",
+	"file",
 	"free",
+	"grep",
 	"groups",
+	"head",
 	"hostname",
 	"id",
 	"kill",
@@ -20,16 +31,28 @@ var safeCommands = []string{
 	"nice",
 	"nohup",
 	"printenv",
+	"printf",
 	"ps",
 	"pwd",
+	"readlink",
+	"realpath",
+	"rg",
 	"set",
+	"sort",
+	"stat",
+	"tail",
+	"test",
 	"time",
 	"timeout",
 	"top",
+	"tr",
+	"true",
 	"type",
 	"uname",
+	"uniq",
 	"unset",
 	"uptime",
+	"wc",
 	"whatis",
 	"whereis",
 	"which",
@@ -54,6 +77,50 @@ var safeCommands = []string{
 	"git tag",
 }
 
+// riskyCommands always ask for permission, even in the sandbox, as they can
+// delete or overwrite the files of the working directory, or run code that
+// could.
+var riskyCommands = []string{
+	".",
+	"bash",
+	"chmod",
+	"chown",
+	"cp",
+	"dash",
+	"dd",
+	"eval",
+	"exec",
+	"install",
+	"ln",
+	"mv",
+	"node",
+	"perl",
+	"php",
+	"python",
+	"python3",
+	"rm",
+	"rmdir",
+	"rsync",
+	"ruby",
+	"sh",
+	"shred",
+	"source",
+	"tee",
+	"truncate",
+	"unlink",
+	"xargs",
+	"zsh",
+
+	// Git
+	"git checkout",
+	"git clean",
+	"git reset",
+	"git restore",
+	"git rm",
+	"git stash",
+	"git switch",
+}
+
 func init() {
 	if runtime.GOOS == "windows" {
 		safeCommands = append(
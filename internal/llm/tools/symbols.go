@@ -0,0 +1,194 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+)
+
+type SymbolsParams struct {
+	FilePath string `json:"file_path,omitempty"`
+	Query    string `json:"query,omitempty"`
+}
+
+type symbolsTool struct {
+	lspClients map[string]*lsp.Client
+	workingDir string
+}
+
+const (
+	SymbolsToolName       = "symbols"
+	maxWorkspaceSymbols   = 50
+	maxDocumentSymbolRows = 300
+	symbolsDescription    = `List symbols in a file or search symbols across the workspace using the language server.
+WHEN TO USE THIS TOOL:
+- Use with file_path to get an outline of a file (types, functions, methods, fields) without reading it
+- Use with query to find where a type or function is declared when you don't know the file
+HOW TO USE:
+- Provide file_path to list the symbols of that file as a tree with line ranges
+- Provide query to search symbol names in the whole workspace
+FEATURES:
+- File outlines include the kind, name, detail and line range of each symbol
+- Workspace results use the compact "path:line:column: code" format
+LIMITATIONS:
+- Requires a configured LSP server for the file's language
+- Workspace search needs at least one running LSP server and is limited to 50 results
+- Query matching is fuzzy and decided by the language server
+TIPS:
+- Use the line ranges from the outline with the view tool's offset and limit
+`
+)
+
+func NewSymbolsTool(lspClients map[string]*lsp.Client, workingDir string) BaseTool {
+	return &symbolsTool{
+		lspClients: lspClients,
+		workingDir: workingDir,
+	}
+}
+
+func (s *symbolsTool) Name() string {
+	return SymbolsToolName
+}
+
+func (s *symbolsTool) Info() ToolInfo {
+	return ToolInfo{
+		Name:        SymbolsToolName,
+		Description: symbolsDescription,
+		Parameters: map[string]any{
+			"file_path": map[string]any{
+				"type":        "string",
+				"description": "The path to the file to list symbols for",
+			},
+			"query": map[string]any{
+				"type":        "string",
+				"description": "The symbol name to search for in the workspace",
+			},
+		},
+		Required: []string{},
+	}
+}
+
+func (s *symbolsTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error) {
+	var params SymbolsParams
+	if err := json.Unmarshal([]byte(call.Input), &params); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error parsing parameters: %s", err)), nil
+	}
+
+	switch {
+	case params.FilePath != "":
+		return s.documentSymbols(ctx, params.FilePath)
+	case params.Query != "":
+		return s.workspaceSymbols(ctx, params.Query)
+	default:
+		return NewTextErrorResponse("either file_path or query is required"), nil
+	}
+}
+
+func (s *symbolsTool) documentSymbols(ctx context.Context, path string) (ToolResponse, error) {
+	filePath := resolveToolPath(s.workingDir, path)
+	if _, err := os.Stat(filePath); err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("error reading file: %s", err)), nil
+	}
+
+	clients := lspClientsForFile(ctx, s.lspClients, filePath)
+	if len(clients) == 0 {
+		return NewTextErrorResponse(fmt.Sprintf("no LSP server available for %s", path)), nil
+	}
+
+	var output strings.Builder
+	rows := 0
+	for _, client := range clients {
+		result, err := client.DocumentSymbol(ctx, protocol.DocumentSymbolParams{
+			TextDocument: protocol.TextDocumentIdentifier{URI: protocol.URIFromPath(filePath)},
+		})
+		if err != nil {
+			continue
+		}
+		switch v := result.Value.(type) {
+		case []protocol.DocumentSymbol:
+			writeDocumentSymbols(&output, v, 0, &rows)
+		case []protocol.SymbolInformation:
+			for _, symbol := range v {
+				writeSymbolRow(&output, 0, symbol.Kind, symbol.Name, "", symbol.Location.Range, &rows)
+			}
+		}
+		if output.Len() > 0 {
+			break
+		}
+	}
+	if output.Len() == 0 {
+		return NewTextResponse("No symbols found"), nil
+	}
+	if rows > maxDocumentSymbolRows {
+		fmt.Fprintf(&output, "... and %d more\n", rows-maxDocumentSymbolRows)
+	}
+	return NewTextResponse(strings.TrimSuffix(output.String(), "\n")), nil
+}
+
+func writeDocumentSymbols(output *strings.Builder, symbols []protocol.DocumentSymbol, depth int, rows *int) {
+	for _, symbol := range symbols {
+		writeSymbolRow(output, depth, symbol.Kind, symbol.Name, symbol.Detail, symbol.Range, rows)
+		writeDocumentSymbols(output, symbol.Children, depth+1, rows)
+	}
+}
+
+func writeSymbolRow(output *strings.Builder, depth int, kind protocol.SymbolKind, name, detail string, rng protocol.Range, rows *int) {
+	*rows++
+	if *rows > maxDocumentSymbolRows {
+		return
+	}
+	fmt.Fprintf(output, "%s%s %s", strings.Repeat("  ", depth), symbolKindName(kind), name)
+	if detail != "" {
+		fmt.Fprintf(output, " %s", strings.ReplaceAll(detail, "\n", " "))
+	}
+	fmt.Fprintf(output, " (lines %d-%d)\n", rng.Start.Line+1, rng.End.Line+1)
+}
+
+func (s *symbolsTool) workspaceSymbols(ctx context.Context, query string) (ToolResponse, error) {
+	if len(s.lspClients) == 0 {
+		return NewTextErrorResponse("no LSP servers available"), nil
+	}
+
+	formatter := newLocationFormatter(s.workingDir)
+	var output strings.Builder
+	count := 0
+	for _, client := range s.lspClients {
+		result, err := client.Symbol(ctx, protocol.WorkspaceSymbolParams{Query: query})
+		if err != nil {
+			continue
+		}
+		symbols, err := result.Results()
+		if err != nil {
+			continue
+		}
+		for _, symbol := range symbols {
+			count++
+			if count > maxWorkspaceSymbols {
+				continue
+			}
+			fmt.Fprintf(&output, "%s %s %s\n", workspaceSymbolKind(symbol), symbol.GetName(), formatter.format(symbol.GetLocation()))
+		}
+	}
+	if count == 0 {
+		return NewTextResponse("No symbols found"), nil
+	}
+	if count > maxWorkspaceSymbols {
+		fmt.Fprintf(&output, "... and %d more, refine the query to narrow the results\n", count-maxWorkspaceSymbols)
+	}
+	return NewTextResponse(strings.TrimSuffix(output.String(), "\n")), nil
+}
+
+func workspaceSymbolKind(symbol protocol.WorkspaceSymbolResult) string {
+	switch v := symbol.(type) {
+	case *protocol.WorkspaceSymbol:
+		return symbolKindName(v.Kind)
+	case *protocol.SymbolInformation:
+		return symbolKindName(v.Kind)
+	}
+	return "Symbol"
+}
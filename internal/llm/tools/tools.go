@@ -17,6 +17,7 @@ type toolResponseType string
 type (
 	sessionIDContextKey string
 	messageIDContextKey string
+	progressContextKey  string
 )
 
 const (
@@ -25,13 +26,22 @@ const (
 
 	SessionIDContextKey sessionIDContextKey = "session_id"
 	MessageIDContextKey messageIDContextKey = "message_id"
+	ProgressContextKey  progressContextKey  = "progress"
 )
 
+// ProgressFunc reports the response of a tool call so far while it runs.
+type ProgressFunc func(ToolResponse)
+
 type ToolResponse struct {
 	Type     toolResponseType `json:"type"`
 	Content  string           `json:"content"`
 	Metadata string           `json:"metadata,omitempty"`
 	IsError  bool             `json:"is_error"`
+
+	// Data and MIMEType hold the image of an image response, in which case
+	// Content is a short text description of the image.
+	Data     []byte `json:"data,omitempty"`
+	MIMEType string `json:"mime_type,omitempty"`
 }
 
 func NewTextResponse(content string) ToolResponse {
@@ -41,6 +51,15 @@ func NewTextResponse(content string) ToolResponse {
 	}
 }
 
+func NewImageResponse(content string, data []byte, mimeType string) ToolResponse {
+	return ToolResponse{
+		Type:     ToolResponseTypeImage,
+		Content:  content,
+		Data:     data,
+		MIMEType: mimeType,
+	}
+}
+
 func WithResponseMetadata(response ToolResponse, metadata any) ToolResponse {
 	if metadata != nil {
 		metadataBytes, err := json.Marshal(metadata)
@@ -83,3 +102,10 @@ func GetContextValues(ctx context.Context) (string, string) {
 	}
 	return sessionID.(string), messageID.(string)
 }
+
+// GetProgressFunc returns the function tools report their progress to, or
+// nil if progress isn't shown.
+func GetProgressFunc(ctx context.Context) ProgressFunc {
+	progress, _ := ctx.Value(ProgressContextKey).(ProgressFunc)
+	return progress
+}
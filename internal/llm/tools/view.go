@@ -2,9 +2,14 @@ package tools
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
+	"image"
+	_ "image/gif" // register the GIF decoder
+	"image/jpeg"
+	"image/png"
 	"io"
 	"os"
 	"path/filepath"
@@ -13,6 +18,10 @@ import (
 
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/disintegration/imageorient"
+	"github.com/nfnt/resize"
+	_ "golang.org/x/image/bmp"  // register the BMP decoder
+	_ "golang.org/x/image/webp" // register the WebP decoder
 )
 
 type ViewParams struct {
@@ -39,16 +48,20 @@ type ViewResponseMetadata struct {
 }
 
 const (
-	ViewToolName     = "view"
-	MaxReadSize      = 250 * 1024
-	DefaultReadLimit = 2000
-	MaxLineLength    = 2000
-	viewDescription  = `File viewing tool that reads and displays the contents of files with line numbers, allowing you to examine code, logs, or text data.
+	ViewToolName      = "view"
+	MaxReadSize       = 250 * 1024
+	MaxImageReadSize  = 20 * 1024 * 1024
+	MaxImageDimension = 1568
+	MaxImagePixels    = 50_000_000
+	DefaultReadLimit  = 2000
+	MaxLineLength     = 2000
+	viewDescription   = `File viewing tool that reads and displays the contents of files with line numbers, allowing you to examine code, logs, or text data.
 
 WHEN TO USE THIS TOOL:
 - Use when you need to read the contents of a specific file
 - Helpful for examining source code, configuration files, or log files
 - Perfect for looking at text-based file formats
+- Can show images such as screenshots and diagrams, if the model supports images
 
 HOW TO USE:
 - Provide the path to the file you want to view
@@ -62,13 +75,15 @@ FEATURES:
 - Handles large files by limiting the number of lines read
 - Automatically truncates very long lines for better display
 - Suggests similar file names when the requested file isn't found
+- Returns PNG, JPEG, GIF, BMP and WebP files as images, scaled down to at most 1568 pixels per side
 
 LIMITATIONS:
-- Maximum file size is 250KB
+- Maximum file size is 250KB, or 20MB and 50 megapixels for images
 - Default reading limit is 2000 lines
 - Lines longer than 2000 characters are truncated
-- Cannot display binary files or images
-- Images can be identified but not displayed
+- Cannot display binary files
+- Images can only be displayed if the model supports them
+- SVG files are read as text
 
 WINDOWS NOTES:
 - Handles both Windows (CRLF) and Unix (LF) line endings automatically
@@ -205,6 +220,15 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		return NewTextErrorResponse(fmt.Sprintf("Path is a directory, not a file: %s", filePath)), nil
 	}
 
+	// Check if it's an image file, SVG files are read as text
+	if isImage, imageType := isImageFile(filePath); isImage && imageType != "SVG" {
+		if fileInfo.Size() > MaxImageReadSize {
+			return NewTextErrorResponse(fmt.Sprintf("Image is too large (%d bytes). Maximum size is %d bytes",
+				fileInfo.Size(), MaxImageReadSize)), nil
+		}
+		return readImageFile(filePath, imageType)
+	}
+
 	// Check file size
 	if fileInfo.Size() > MaxReadSize {
 		return NewTextErrorResponse(fmt.Sprintf("File is too large (%d bytes). Maximum size is %d bytes",
@@ -216,13 +240,6 @@ func (v *viewTool) Run(ctx context.Context, call ToolCall) (ToolResponse, error)
 		params.Limit = DefaultReadLimit
 	}
 
-	// Check if it's an image file
-	isImage, imageType := isImageFile(filePath)
-	// TODO: handle images
-	if isImage {
-		return NewTextErrorResponse(fmt.Sprintf("This is an image file of type: %s\n", imageType)), nil
-	}
-
 	// Read the file content
 	content, lineCount, err := readTextFile(filePath, params.Offset, params.Limit)
 	isValidUt8 := utf8.ValidString(content)
@@ -331,6 +348,53 @@ func readTextFile(filePath string, offset, limit int) (string, int, error) {
 	return strings.Join(lines, "\n"), lineCount, nil
 }
 
+// readImageFile decodes an image, scales it down to fit within
+// MaxImageDimension and re-encodes it as JPEG for photos or PNG otherwise.
+// Images of more than MaxImagePixels are refused before being decoded, as a
+// small file can claim dimensions that take gigabytes to decode.
+func readImageFile(filePath, imageType string) (ToolResponse, error) {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error opening image: %w", err)
+	}
+	defer f.Close()
+
+	config, _, err := image.DecodeConfig(f)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("This is an image file of type %s, but it could not be decoded: %s", imageType, err)), nil
+	}
+	if pixels := int64(config.Width) * int64(config.Height); pixels > MaxImagePixels {
+		return NewTextErrorResponse(fmt.Sprintf("Image is too large: %dx%d, at most %d pixels can be read", config.Width, config.Height, MaxImagePixels)), nil
+	}
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return ToolResponse{}, fmt.Errorf("error reading image: %w", err)
+	}
+
+	img, format, err := imageorient.Decode(f)
+	if err != nil {
+		return NewTextErrorResponse(fmt.Sprintf("This is an image file of type %s, but it could not be decoded: %s", imageType, err)), nil
+	}
+	bounds := img.Bounds()
+	description := fmt.Sprintf("Image file: %s (%s, %dx%d)", filePath, imageType, bounds.Dx(), bounds.Dy())
+	if bounds.Dx() > MaxImageDimension || bounds.Dy() > MaxImageDimension {
+		img = resize.Thumbnail(MaxImageDimension, MaxImageDimension, img, resize.Lanczos3)
+		description += fmt.Sprintf(", scaled down to %dx%d", img.Bounds().Dx(), img.Bounds().Dy())
+	}
+
+	var buf bytes.Buffer
+	mimeType := "image/png"
+	if format == "jpeg" {
+		mimeType = "image/jpeg"
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 85})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return ToolResponse{}, fmt.Errorf("error encoding image: %w", err)
+	}
+	return NewImageResponse(description, buf.Bytes(), mimeType), nil
+}
+
 func isImageFile(filePath string) (bool, string) {
 	ext := strings.ToLower(filepath.Ext(filePath))
 	switch ext {
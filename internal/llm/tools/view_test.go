@@ -0,0 +1,88 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestViewImage(t *testing.T) {
+	tempDir := t.TempDir()
+
+	writePNG := func(name string, width, height int) {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+		require.NoError(t, os.WriteFile(filepath.Join(tempDir, name), buf.Bytes(), 0o644))
+	}
+	writePNG("small.png", 40, 20)
+	writePNG("large.png", 3136, 1000)
+
+	// A tiny PNG whose header claims 100000x100000 pixels.
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))))
+	huge := buf.Bytes()
+	binary.BigEndian.PutUint32(huge[16:], 100000)
+	binary.BigEndian.PutUint32(huge[20:], 100000)
+	binary.BigEndian.PutUint32(huge[29:], crc32.ChecksumIEEE(huge[12:29]))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "huge.png"), huge, 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "broken.png"), []byte("not an image"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(tempDir, "icon.svg"), []byte("<svg></svg>"), 0o644))
+
+	view := NewViewTool(nil, nil, tempDir)
+	run := func(path string) ToolResponse {
+		input, err := json.Marshal(ViewParams{FilePath: path})
+		require.NoError(t, err)
+		resp, err := view.Run(context.Background(), ToolCall{ID: "call", Name: ViewToolName, Input: string(input)})
+		require.NoError(t, err)
+		return resp
+	}
+	decode := func(resp ToolResponse) image.Image {
+		img, _, err := image.Decode(bytes.NewReader(resp.Data))
+		require.NoError(t, err)
+		return img
+	}
+
+	t.Run("small image", func(t *testing.T) {
+		resp := run("small.png")
+		require.Equal(t, ToolResponseTypeImage, resp.Type)
+		require.False(t, resp.IsError)
+		require.Equal(t, "image/png", resp.MIMEType)
+		require.Contains(t, resp.Content, "(PNG, 40x20)")
+		require.Equal(t, image.Rect(0, 0, 40, 20), decode(resp).Bounds())
+	})
+
+	t.Run("large image is scaled down", func(t *testing.T) {
+		resp := run("large.png")
+		require.Equal(t, ToolResponseTypeImage, resp.Type)
+		require.Contains(t, resp.Content, "scaled down to 1568x500")
+		require.Equal(t, image.Rect(0, 0, 1568, 500), decode(resp).Bounds())
+	})
+
+	t.Run("image with too many pixels", func(t *testing.T) {
+		resp := run("huge.png")
+		require.True(t, resp.IsError)
+		require.Contains(t, resp.Content, "100000x100000")
+		require.Empty(t, resp.Data)
+	})
+
+	t.Run("undecodable image", func(t *testing.T) {
+		resp := run("broken.png")
+		require.True(t, resp.IsError)
+		require.Empty(t, resp.Data)
+	})
+
+	t.Run("svg is read as text", func(t *testing.T) {
+		resp := run("icon.svg")
+		require.Equal(t, ToolResponseTypeText, resp.Type)
+		require.Contains(t, resp.Content, "<svg></svg>")
+	})
+}
@@ -0,0 +1,62 @@
+package util
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/lsp/protocol"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPreviewWorkspaceEdit(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	a := filepath.Join(dir, "a.go")
+	b := filepath.Join(dir, "b.go")
+	require.NoError(t, os.WriteFile(a, []byte("package a\n\nfunc Old() {}\n"), 0o644))
+	require.NoError(t, os.WriteFile(b, []byte("package b\r\n\r\nvar _ = a.Old\r\n"), 0o644))
+
+	rename := func(line, char uint32) protocol.TextEdit {
+		return protocol.TextEdit{
+			Range: protocol.Range{
+				Start: protocol.Position{Line: line, Character: char},
+				End:   protocol.Position{Line: line, Character: char + 3},
+			},
+			NewText: "New",
+		}
+	}
+
+	files, err := PreviewWorkspaceEdit(protocol.WorkspaceEdit{
+		Changes: map[protocol.DocumentURI][]protocol.TextEdit{
+			protocol.URIFromPath(b): {rename(2, 10)},
+			protocol.URIFromPath(a): {rename(2, 5)},
+		},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []FileEdit{
+		{
+			Path:       a,
+			OldContent: "package a\n\nfunc Old() {}\n",
+			NewContent: "package a\n\nfunc New() {}\n",
+		},
+		{
+			Path:       b,
+			OldContent: "package b\r\n\r\nvar _ = a.Old\r\n",
+			NewContent: "package b\r\n\r\nvar _ = a.New\r\n",
+		},
+	}, files)
+
+	// The files on disk are left untouched.
+	content, err := os.ReadFile(a)
+	require.NoError(t, err)
+	require.Equal(t, "package a\n\nfunc Old() {}\n", string(content))
+
+	_, err = PreviewWorkspaceEdit(protocol.WorkspaceEdit{
+		DocumentChanges: []protocol.DocumentChange{
+			{DeleteFile: &protocol.DeleteFile{URI: protocol.URIFromPath(a)}},
+		},
+	})
+	require.Error(t, err)
+}
@@ -8,6 +8,7 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
@@ -16,9 +17,49 @@ import (
 
 	"github.com/charmbracelet/crush/internal/lsp"
 	"github.com/charmbracelet/crush/internal/lsp/protocol"
+	"github.com/charmbracelet/crush/internal/pubsub"
 	"github.com/fsnotify/fsnotify"
 )
 
+// fileBroker publishes the changes to the files of the workspace, whether
+// or not an LSP server handles them.
+var fileBroker = pubsub.NewBroker[string]()
+
+// fileEventsWatcher is the watcher that publishes the changes to files.
+// Every LSP client watches the whole workspace, only one of them publishes so
+// that changes aren't published once per client.
+var fileEventsWatcher atomic.Pointer[WorkspaceWatcher]
+
+// SubscribeFileEvents returns a channel that receives the path of each
+// file of the workspace that is created, written or removed.
+func SubscribeFileEvents(ctx context.Context) <-chan pubsub.Event[string] {
+	return fileBroker.Subscribe(ctx)
+}
+
+// Watching reports whether a watcher is running, so that changes to files
+// are published to SubscribeFileEvents.
+func Watching() bool {
+	return fileEventsWatcher.Load() != nil
+}
+
+// publishesFileEvents reports whether the watcher is the one publishing the
+// changes to files, taking over if none is.
+func (w *WorkspaceWatcher) publishesFileEvents() bool {
+	return fileEventsWatcher.CompareAndSwap(nil, w) || fileEventsWatcher.Load() == w
+}
+
+// publishFileEvent publishes a change to a file.
+func publishFileEvent(event fsnotify.Event) {
+	switch {
+	case event.Op&fsnotify.Create != 0:
+		fileBroker.Publish(pubsub.CreatedEvent, event.Name)
+	case event.Op&fsnotify.Write != 0:
+		fileBroker.Publish(pubsub.UpdatedEvent, event.Name)
+	case event.Op&(fsnotify.Remove|fsnotify.Rename) != 0:
+		fileBroker.Publish(pubsub.DeletedEvent, event.Name)
+	}
+}
+
 // WorkspaceWatcher manages LSP file watching
 type WorkspaceWatcher struct {
 	client        *lsp.Client
@@ -377,6 +418,9 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 		slog.Error("Error walking workspace", "error", err)
 	}
 
+	w.publishesFileEvents()
+	defer fileEventsWatcher.CompareAndSwap(w, nil)
+
 	// Event loop
 	for {
 		select {
@@ -387,6 +431,10 @@ func (w *WorkspaceWatcher) WatchWorkspace(ctx context.Context, workspacePath str
 				return
 			}
 
+			if w.publishesFileEvents() {
+				publishFileEvent(event)
+			}
+
 			if !w.client.HandlesFile(event.Name) {
 				continue // client doesn't handle this filetype
 			}
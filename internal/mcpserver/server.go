@@ -0,0 +1,267 @@
+// Package mcpserver exposes Crush's tools and agent to other agents and
+// editors over the Model Context Protocol.
+package mcpserver
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/codeindex"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/llm/tools"
+	"github.com/charmbracelet/crush/internal/lsp"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	crushserver "github.com/charmbracelet/crush/internal/server"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/charmbracelet/crush/internal/version"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// RunAgentToolName is the name of the tool that runs the agent on a prompt.
+const RunAgentToolName = "run_agent"
+
+const runAgentDescription = `Runs the Crush coding agent on a prompt in the working directory and returns its final answer.
+The agent explores, edits and tests the code on its own with its tools. Give it a complete, self-contained task.
+Pass the session_id returned by a previous run to continue that conversation.`
+
+// Options are the services the server's tools are run with.
+type Options struct {
+	WorkingDir string
+	// SessionID is the session the tools are called in.
+	SessionID   string
+	Permissions permission.Service
+	History     history.Service
+	LSPClients  map[string]*lsp.Client
+	// Bash configures the bash tool.
+	Bash tools.BashOptions
+	// CodeIndex serves the code search tool, which is left out if it is
+	// nil.
+	CodeIndex codeindex.Service
+	// Sessions and Agent serve the run agent tool, which is left out if
+	// Agent is nil.
+	Sessions session.Service
+	Agent    agent.Service
+}
+
+// Server is an MCP server for Crush's tools.
+type Server struct {
+	opts Options
+	mcp  *server.MCPServer
+}
+
+// New creates a server exposing the view, grep, glob, edit, bash and, with
+// a code index or LSPs, code search and diagnostics tools, along with the run
+// agent tool.
+func New(opts Options) *Server {
+	s := &Server{
+		opts: opts,
+		mcp: server.NewMCPServer("crush", version.Version,
+			server.WithToolCapabilities(false),
+			server.WithRecovery(),
+		),
+	}
+
+	builtin := []tools.BaseTool{
+		tools.NewViewTool(opts.LSPClients, opts.Permissions, opts.WorkingDir),
+		tools.NewGrepTool(opts.WorkingDir),
+		tools.NewGlobTool(opts.WorkingDir),
+		tools.NewEditTool(opts.LSPClients, opts.Permissions, opts.History, opts.WorkingDir),
+		tools.NewBashTool(opts.Permissions, opts.WorkingDir, opts.Bash),
+	}
+	if opts.CodeIndex != nil {
+		builtin = append(builtin, tools.NewCodeSearchTool(opts.CodeIndex))
+	}
+	if len(opts.LSPClients) > 0 {
+		builtin = append(builtin, tools.NewDiagnosticsTool(opts.LSPClients))
+	}
+	for _, tool := range builtin {
+		s.mcp.AddTool(mcpTool(tool.Info()), s.toolHandler(tool))
+	}
+
+	if opts.Agent != nil {
+		s.mcp.AddTool(mcp.NewTool(RunAgentToolName,
+			mcp.WithDescription(runAgentDescription),
+			mcp.WithString("prompt", mcp.Required(), mcp.Description("The task for the agent")),
+			mcp.WithString("session_id", mcp.Description("The session to continue, by default a new one is started")),
+		), s.runAgent)
+	}
+	return s
+}
+
+// ServeStdio serves a single client over the given reader and writer until
+// the context is done or the input is closed.
+func (s *Server) ServeStdio(ctx context.Context, in io.Reader, out io.Writer) error {
+	s.denyPermissionRequests(ctx)
+	return server.NewStdioServer(s.mcp).Listen(ctx, in, out)
+}
+
+// ServeHTTP serves clients over streamable HTTP at /mcp on the given address
+// until the context is done. An address without a host listens on 127.0.0.1.
+// Like the HTTP API, only requests carrying the token as a bearer token and
+// addressed to localhost are served.
+func (s *Server) ServeHTTP(ctx context.Context, addr, token string) error {
+	s.denyPermissionRequests(ctx)
+	httpServer := &http.Server{Addr: listenAddr(addr), Handler: s.httpHandler(token)}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- httpServer.ListenAndServe()
+	}()
+	select {
+	case err := <-errc:
+		if errors.Is(err, http.ErrServerClosed) {
+			return nil
+		}
+		return err
+	case <-ctx.Done():
+		return httpServer.Shutdown(context.Background())
+	}
+}
+
+func (s *Server) httpHandler(token string) http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/mcp", crushserver.Protect(server.NewStreamableHTTPServer(s.mcp), token))
+	return mux
+}
+
+// listenAddr keeps the server on the loopback interface unless a host is
+// given.
+func listenAddr(addr string) string {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || host != "" {
+		return addr
+	}
+	return net.JoinHostPort("127.0.0.1", port)
+}
+
+// denyPermissionRequests denies the tool calls that the permission rules don't
+// allow, as there's no one to ask about them, until the context is done. It
+// subscribes before returning so that no request is missed.
+func (s *Server) denyPermissionRequests(ctx context.Context) {
+	requests := s.opts.Permissions.Subscribe(ctx)
+	go func() {
+		for event := range requests {
+			req := event.Payload
+			slog.Info("Denying tool call not allowed by the permission rules", "tool", req.ToolName, "action", req.Action, "path", req.Path)
+			s.opts.Permissions.Deny(req)
+		}
+	}()
+}
+
+func mcpTool(info tools.ToolInfo) mcp.Tool {
+	required := info.Required
+	if required == nil {
+		required = []string{}
+	}
+	schema, _ := json.Marshal(map[string]any{
+		"type":       "object",
+		"properties": info.Parameters,
+		"required":   required,
+	})
+	return mcp.NewToolWithRawSchema(info.Name, info.Description, schema)
+}
+
+func (s *Server) toolHandler(tool tools.BaseTool) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		input, err := json.Marshal(req.GetArguments())
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal arguments: %w", err)
+		}
+		call := tools.ToolCall{
+			ID:    uuid.New().String(),
+			Name:  tool.Name(),
+			Input: string(input),
+		}
+		ctx = context.WithValue(ctx, tools.SessionIDContextKey, s.opts.SessionID)
+		ctx = context.WithValue(ctx, tools.MessageIDContextKey, call.ID)
+
+		response, err := tool.Run(ctx, call)
+		if errors.Is(err, permission.ErrorPermissionDenied) {
+			if reason, ok := s.opts.Permissions.Refusal(call.ID); ok {
+				return mcp.NewToolResultError(reason), nil
+			}
+			return mcp.NewToolResultError("Permission denied: the permission rules don't allow this tool call."), nil
+		}
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+
+		result := &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.NewTextContent(response.Content)},
+			IsError: response.IsError,
+		}
+		if response.Type == tools.ToolResponseTypeImage {
+			result.Content = append(result.Content, mcp.NewImageContent(base64.StdEncoding.EncodeToString(response.Data), response.MIMEType))
+		}
+		return result, nil
+	}
+}
+
+func (s *Server) runAgent(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	prompt, err := req.RequireString("prompt")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	var sess session.Session
+	if id := req.GetString("session_id", ""); id != "" {
+		sess, err = s.opts.Sessions.Get(ctx, id)
+	} else {
+		sess, err = s.opts.Sessions.Create(ctx, "MCP: "+truncate(prompt, 100))
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to get session: %s", err)), nil
+	}
+
+	done, err := s.opts.Agent.Run(ctx, sess.ID, prompt)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if done == nil {
+		return mcp.NewToolResultError(fmt.Sprintf("session %s is busy", sess.ID)), nil
+	}
+
+	var result agent.AgentEvent
+	select {
+	case result = <-done:
+	case <-ctx.Done():
+		s.opts.Agent.Cancel(sess.ID)
+		return nil, ctx.Err()
+	}
+
+	sessionNote := mcp.NewTextContent("session_id: " + sess.ID)
+	switch {
+	case result.Error != nil:
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.NewTextContent("Agent failed: " + result.Error.Error()), sessionNote},
+			IsError: true,
+		}, nil
+	case result.Message.FinishReason() == message.FinishReasonPermissionDenied:
+		return &mcp.CallToolResult{
+			Content: []mcp.Content{mcp.NewTextContent("Agent stopped: a tool call was denied by the permission rules."), sessionNote},
+			IsError: true,
+		}, nil
+	}
+	return &mcp.CallToolResult{
+		Content: []mcp.Content{mcp.NewTextContent(result.Message.Content().Text), sessionNote},
+	}, nil
+}
+
+func truncate(s string, n int) string {
+	s = strings.Join(strings.Fields(s), " ")
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
@@ -0,0 +1,124 @@
+package mcpserver
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/mark3labs/mcp-go/client"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	workingDir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(workingDir, "main.go"), []byte("package main\n"), 0o644))
+
+	permissions := permission.NewPermissionService(workingDir, false, nil, permission.Rules{
+		Allow: []string{"bash:echo .*"},
+	}, "")
+	s := New(Options{
+		WorkingDir:  workingDir,
+		SessionID:   "session",
+		Permissions: permissions,
+	})
+	s.denyPermissionRequests(t.Context())
+
+	c, err := client.NewInProcessClient(s.mcp)
+	require.NoError(t, err)
+	t.Cleanup(func() { c.Close() })
+	require.NoError(t, c.Start(t.Context()))
+	_, err = c.Initialize(t.Context(), mcp.InitializeRequest{})
+	require.NoError(t, err)
+
+	call := func(t *testing.T, name string, args map[string]any) *mcp.CallToolResult {
+		result, err := c.CallTool(t.Context(), mcp.CallToolRequest{
+			Params: mcp.CallToolParams{Name: name, Arguments: args},
+		})
+		require.NoError(t, err)
+		return result
+	}
+	text := func(result *mcp.CallToolResult) string {
+		return result.Content[0].(mcp.TextContent).Text
+	}
+
+	t.Run("lists the tools", func(t *testing.T) {
+		result, err := c.ListTools(t.Context(), mcp.ListToolsRequest{})
+		require.NoError(t, err)
+		var names []string
+		for _, tool := range result.Tools {
+			names = append(names, tool.Name)
+		}
+		require.Equal(t, []string{"bash", "edit", "glob", "grep", "view"}, names)
+	})
+
+	t.Run("runs tools", func(t *testing.T) {
+		result := call(t, "view", map[string]any{"file_path": "main.go"})
+		require.False(t, result.IsError)
+		require.Contains(t, text(result), "package main")
+
+		result = call(t, "bash", map[string]any{"command": "echo hello"})
+		require.False(t, result.IsError)
+		require.Contains(t, text(result), "hello")
+	})
+
+	t.Run("denies tool calls the rules don't allow", func(t *testing.T) {
+		result := call(t, "bash", map[string]any{"command": "touch new.go"})
+		require.True(t, result.IsError)
+		require.Contains(t, text(result), "Permission denied")
+		require.NoFileExists(t, filepath.Join(workingDir, "new.go"))
+	})
+
+	t.Run("refuses tool calls in plan mode", func(t *testing.T) {
+		permissions.SetMode(permission.ModePlan)
+		defer permissions.SetMode(permission.ModeDefault)
+		result := call(t, "edit", map[string]any{"file_path": "new.go", "old_string": "", "new_string": "package main\n"})
+		require.True(t, result.IsError)
+		require.Contains(t, text(result), "plan mode")
+	})
+}
+
+func TestServerHTTP(t *testing.T) {
+	permissions := permission.NewPermissionService(t.TempDir(), false, nil, permission.Rules{}, "")
+	s := New(Options{WorkingDir: t.TempDir(), SessionID: "session", Permissions: permissions})
+	ts := httptest.NewServer(s.httpHandler("secret"))
+	t.Cleanup(ts.Close)
+
+	initialize := func(t *testing.T, url, token string) int {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, url, strings.NewReader(`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	require.Equal(t, http.StatusUnauthorized, initialize(t, ts.URL+"/mcp", ""))
+	require.Equal(t, http.StatusUnauthorized, initialize(t, ts.URL+"/mcp", "wrong"))
+	require.Equal(t, http.StatusOK, initialize(t, ts.URL+"/mcp", "secret"))
+
+	t.Run("refuses other hosts", func(t *testing.T) {
+		req, err := http.NewRequestWithContext(t.Context(), http.MethodPost, ts.URL+"/mcp", nil)
+		require.NoError(t, err)
+		req.Host = "attacker.example"
+		req.Header.Set("Authorization", "Bearer secret")
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, http.StatusForbidden, resp.StatusCode)
+	})
+}
+
+func TestListenAddr(t *testing.T) {
+	require.Equal(t, "127.0.0.1:8080", listenAddr(":8080"))
+	require.Equal(t, "localhost:8080", listenAddr("localhost:8080"))
+	require.Equal(t, "0.0.0.0:8080", listenAddr("0.0.0.0:8080"))
+}
@@ -36,8 +36,11 @@ type ContentPart interface {
 }
 
 type ReasoningContent struct {
-	Thinking   string `json:"thinking"`
-	Signature  string `json:"signature"`
+	Thinking  string `json:"thinking"`
+	Signature string `json:"signature"`
+	// ItemID is the id of an OpenAI Responses API reasoning item, whose
+	// encrypted content is kept in Signature.
+	ItemID     string `json:"item_id,omitempty"`
 	StartedAt  int64  `json:"started_at,omitempty"`
 	FinishedAt int64  `json:"finished_at,omitempty"`
 }
@@ -100,6 +103,12 @@ type ToolResult struct {
 	Content    string `json:"content"`
 	Metadata   string `json:"metadata"`
 	IsError    bool   `json:"is_error"`
+	// Data and MIMEType hold an image returned by the tool, if any.
+	Data     []byte `json:"data,omitempty"`
+	MIMEType string `json:"mime_type,omitempty"`
+	// Running is set while the tool call runs, Content and Metadata then
+	// hold its output so far.
+	Running bool `json:"running,omitempty"`
 }
 
 func (ToolResult) isPart() {}
@@ -122,6 +131,9 @@ type Message struct {
 	Provider  string
 	CreatedAt int64
 	UpdatedAt int64
+	// CreatedAtMs is the creation time in milliseconds, which orders messages
+	// against file versions created in the same second.
+	CreatedAtMs int64
 }
 
 func (m *Message) Content() TextContent {
@@ -233,12 +245,8 @@ func (m *Message) AppendReasoningContent(delta string) {
 	found := false
 	for i, part := range m.Parts {
 		if c, ok := part.(ReasoningContent); ok {
-			m.Parts[i] = ReasoningContent{
-				Thinking:   c.Thinking + delta,
-				Signature:  c.Signature,
-				StartedAt:  c.StartedAt,
-				FinishedAt: c.FinishedAt,
-			}
+			c.Thinking += delta
+			m.Parts[i] = c
 			found = true
 		}
 	}
@@ -253,28 +261,34 @@ func (m *Message) AppendReasoningContent(delta string) {
 func (m *Message) AppendReasoningSignature(signature string) {
 	for i, part := range m.Parts {
 		if c, ok := part.(ReasoningContent); ok {
-			m.Parts[i] = ReasoningContent{
-				Thinking:   c.Thinking,
-				Signature:  c.Signature + signature,
-				StartedAt:  c.StartedAt,
-				FinishedAt: c.FinishedAt,
-			}
+			c.Signature += signature
+			m.Parts[i] = c
 			return
 		}
 	}
 	m.Parts = append(m.Parts, ReasoningContent{Signature: signature})
 }
 
+// SetReasoningItem keeps the id and encrypted content of an OpenAI
+// Responses API reasoning item, to pass it back with the next requests.
+func (m *Message) SetReasoningItem(id, encryptedContent string) {
+	for i, part := range m.Parts {
+		if c, ok := part.(ReasoningContent); ok {
+			c.ItemID = id
+			c.Signature = encryptedContent
+			m.Parts[i] = c
+			return
+		}
+	}
+	m.Parts = append(m.Parts, ReasoningContent{ItemID: id, Signature: encryptedContent})
+}
+
 func (m *Message) FinishThinking() {
 	for i, part := range m.Parts {
 		if c, ok := part.(ReasoningContent); ok {
 			if c.FinishedAt == 0 {
-				m.Parts[i] = ReasoningContent{
-					Thinking:   c.Thinking,
-					Signature:  c.Signature,
-					StartedAt:  c.StartedAt,
-					FinishedAt: time.Now().Unix(),
-				}
+				c.FinishedAt = time.Now().Unix()
+				m.Parts[i] = c
 			}
 			return
 		}
@@ -0,0 +1,261 @@
+// Package ollama is a small client for the native API of Ollama and of
+// servers compatible with it.
+package ollama
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// DefaultBaseURL is the address Ollama listens on by default.
+const DefaultBaseURL = "http://localhost:11434"
+
+// Model capabilities reported by Show.
+const (
+	CapabilityTools    = "tools"
+	CapabilityThinking = "thinking"
+	CapabilityVision   = "vision"
+)
+
+// Client talks to an Ollama server.
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+	// Headers are added to every request, e.g. the authorization of a proxy.
+	Headers map[string]string
+}
+
+// NewClient returns a client for the server at baseURL, or at DefaultBaseURL
+// if it is empty.
+func NewClient(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// StatusError is returned when the server answers with an error status.
+type StatusError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *StatusError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("ollama: %d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("ollama: %d %s: %s", e.StatusCode, http.StatusText(e.StatusCode), e.Message)
+}
+
+// ModelDetails describes the weights of a model.
+type ModelDetails struct {
+	Family            string `json:"family"`
+	ParameterSize     string `json:"parameter_size"`
+	QuantizationLevel string `json:"quantization_level"`
+}
+
+// Model is a model installed on the server.
+type Model struct {
+	Name    string       `json:"name"`
+	Model   string       `json:"model"`
+	Size    int64        `json:"size"`
+	Details ModelDetails `json:"details"`
+}
+
+// Tags lists the models installed on the server.
+func (c *Client) Tags(ctx context.Context) ([]Model, error) {
+	var resp struct {
+		Models []Model `json:"models"`
+	}
+	if err := c.do(ctx, http.MethodGet, "/api/tags", nil, &resp); err != nil {
+		return nil, err
+	}
+	return resp.Models, nil
+}
+
+// ShowResponse holds the information about a model.
+type ShowResponse struct {
+	Details      ModelDetails   `json:"details"`
+	ModelInfo    map[string]any `json:"model_info"`
+	Capabilities []string       `json:"capabilities"`
+}
+
+// ContextLength returns the context window the model was trained with, or 0
+// if the server did not report it.
+func (r *ShowResponse) ContextLength() int64 {
+	arch, _ := r.ModelInfo["general.architecture"].(string)
+	if length, ok := r.ModelInfo[arch+".context_length"].(float64); ok {
+		return int64(length)
+	}
+	return 0
+}
+
+// HasCapability reports whether the model has the given capability. Older
+// servers don't report capabilities at all, Capabilities is nil then.
+func (r *ShowResponse) HasCapability(capability string) bool {
+	return slices.Contains(r.Capabilities, capability)
+}
+
+// Show returns the information about the given model.
+func (c *Client) Show(ctx context.Context, model string) (*ShowResponse, error) {
+	var resp ShowResponse
+	if err := c.do(ctx, http.MethodPost, "/api/show", map[string]string{"model": model}, &resp); err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+// Message is a chat message.
+type Message struct {
+	Role      string     `json:"role"`
+	Content   string     `json:"content"`
+	Thinking  string     `json:"thinking,omitempty"`
+	Images    [][]byte   `json:"images,omitempty"`
+	ToolName  string     `json:"tool_name,omitempty"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+}
+
+// ToolCall is a call of a tool by the model.
+type ToolCall struct {
+	Function ToolCallFunction `json:"function"`
+}
+
+type ToolCallFunction struct {
+	Name      string          `json:"name"`
+	Arguments json.RawMessage `json:"arguments"`
+}
+
+// Tool is a tool the model can call.
+type Tool struct {
+	Type     string       `json:"type"`
+	Function ToolFunction `json:"function"`
+}
+
+type ToolFunction struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters"`
+}
+
+type ChatRequest struct {
+	Model    string         `json:"model"`
+	Messages []Message      `json:"messages"`
+	Tools    []Tool         `json:"tools,omitempty"`
+	Stream   bool           `json:"stream"`
+	Think    *bool          `json:"think,omitempty"`
+	Options  map[string]any `json:"options,omitempty"`
+}
+
+// ChatResponse is a chunk of a chat response. The last one has Done set and
+// carries the token counts.
+type ChatResponse struct {
+	Message         Message `json:"message"`
+	Done            bool    `json:"done"`
+	DoneReason      string  `json:"done_reason"`
+	PromptEvalCount int64   `json:"prompt_eval_count"`
+	EvalCount       int64   `json:"eval_count"`
+	Error           string  `json:"error"`
+}
+
+// Chat sends a chat request and calls fn with every chunk of the response.
+// Without streaming fn is called once with the whole response.
+func (c *Client) Chat(ctx context.Context, req ChatRequest, fn func(ChatResponse) error) error {
+	resp, err := c.request(ctx, http.MethodPost, "/api/chat", req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	done := false
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var chunk ChatResponse
+		if err := json.Unmarshal(line, &chunk); err != nil {
+			return fmt.Errorf("ollama: failed to decode response: %w", err)
+		}
+		if chunk.Error != "" {
+			return errors.New("ollama: " + chunk.Error)
+		}
+		if err := fn(chunk); err != nil {
+			return err
+		}
+		done = chunk.Done
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if !done {
+		return io.ErrUnexpectedEOF
+	}
+	return nil
+}
+
+func (c *Client) do(ctx context.Context, method, path string, body, v any) error {
+	resp, err := c.request(ctx, method, path, body)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return fmt.Errorf("ollama: failed to decode response: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) request(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return nil, err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.BaseURL+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range c.Headers {
+		req.Header.Set(k, v)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		defer resp.Body.Close()
+		statusErr := &StatusError{StatusCode: resp.StatusCode}
+		var errResp struct {
+			Error string `json:"error"`
+		}
+		if data, _ := io.ReadAll(resp.Body); json.Unmarshal(data, &errResp) == nil {
+			statusErr.Message = errResp.Error
+		} else {
+			statusErr.Message = strings.TrimSpace(string(data))
+		}
+		return nil, statusErr
+	}
+	return resp, nil
+}
@@ -0,0 +1,54 @@
+package permission
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Mode controls which tool calls are refused outright.
+type Mode string
+
+const (
+	// ModeDefault asks the user about tool calls that no rule allows.
+	ModeDefault Mode = "default"
+	// ModePlan also refuses tool calls that change the working tree, so the
+	// agent can only explore it and propose a plan.
+	ModePlan Mode = "plan"
+)
+
+// planModeTools are the tools refused in plan mode. The bash tool only asks
+// for permission for commands that aren't known to be read-only, so those are
+// the only ones refused.
+var planModeTools = []string{"bash", "download", "edit", "multiedit", "refactor", "write"}
+
+const (
+	planModeRefusal = "Crush is in plan mode, so the %s tool can't be used to change anything. " +
+		"Keep exploring with read-only tools and propose a plan instead; the user can leave plan mode to carry it out."
+	denyRuleRefusal = "The user's permission rules deny this %s tool call. Don't retry it, try something else or ask the user."
+)
+
+// ParseMode parses a mode name as given on the command line.
+func ParseMode(s string) (Mode, error) {
+	switch mode := Mode(s); mode {
+	case ModeDefault, ModePlan:
+		return mode, nil
+	case "":
+		return ModeDefault, nil
+	default:
+		return "", fmt.Errorf("invalid mode %q, must be %s or %s", s, ModeDefault, ModePlan)
+	}
+}
+
+// refusal returns why a tool call is refused without asking the user, or an
+// empty string if it isn't.
+func (s *permissionService) refusal(target ruleTarget) string {
+	s.rulesMu.RLock()
+	defer s.rulesMu.RUnlock()
+	if s.mode == ModePlan && slices.Contains(planModeTools, target.tool) {
+		return fmt.Sprintf(planModeRefusal, target.tool)
+	}
+	if denies(s.denyRules, target) {
+		return fmt.Sprintf(denyRuleRefusal, target.tool)
+	}
+	return ""
+}
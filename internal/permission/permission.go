@@ -3,6 +3,8 @@ package permission
 import (
 	"context"
 	"errors"
+	"fmt"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"slices"
@@ -23,6 +25,10 @@ type CreatePermissionRequest struct {
 	Action      string `json:"action"`
 	Params      any    `json:"params"`
 	Path        string `json:"path"`
+	// Sandboxed tool calls run where they can't reach the network or
+	// change anything outside of the working directory, and aren't known to
+	// be risky, so they are allowed without asking unless they are refused.
+	Sandboxed bool `json:"sandboxed"`
 }
 
 type PermissionNotification struct {
@@ -45,12 +51,17 @@ type PermissionRequest struct {
 type Service interface {
 	pubsub.Suscriber[PermissionRequest]
 	GrantPersistent(permission PermissionRequest)
+	GrantAlways(permission PermissionRequest)
 	Grant(permission PermissionRequest)
 	Deny(permission PermissionRequest)
 	Request(opts CreatePermissionRequest) bool
+	Denies(opts CreatePermissionRequest) bool
 	AutoApproveSession(sessionID string)
 	SetSkipRequests(skip bool)
 	SkipRequests() bool
+	SetMode(mode Mode)
+	Mode() Mode
+	Refusal(toolCallID string) (string, bool)
 	SubscribeNotifications(ctx context.Context) <-chan pubsub.Event[PermissionNotification]
 }
 
@@ -66,6 +77,12 @@ type permissionService struct {
 	autoApproveSessionsMu sync.RWMutex
 	skip                  bool
 	allowedTools          []string
+	allowRules            []rule
+	denyRules             []rule
+	mode                  Mode
+	rulesMu               sync.RWMutex // guards the rules and the mode
+	rulesFile             string
+	refusals              *csync.Map[string, string]
 
 	// used to make sure we only process one request at a time
 	requestMu     sync.Mutex
@@ -91,6 +108,26 @@ func (s *permissionService) GrantPersistent(permission PermissionRequest) {
 	}
 }
 
+// GrantAlways grants the request and adds a rule allowing the same tool call
+// to the rules file, so it's allowed in every session from now on.
+func (s *permissionService) GrantAlways(permission PermissionRequest) {
+	target := newRuleTarget(s.workingDir, permission.ToolName, permission.Path, permission.Params)
+	r, err := parseRule(target.rule())
+	if err == nil {
+		s.rulesMu.Lock()
+		s.allowRules = append(s.allowRules, r)
+		s.rulesMu.Unlock()
+		if s.rulesFile != "" {
+			if err := addAllowRule(s.rulesFile, target.rule()); err != nil {
+				slog.Error("Failed to save permission rule", "rule", target.rule(), "error", err)
+			}
+		}
+	} else {
+		slog.Error("Failed to create permission rule", "error", err)
+	}
+	s.Grant(permission)
+}
+
 func (s *permissionService) Grant(permission PermissionRequest) {
 	s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
 		ToolCallID: permission.ToolCallID,
@@ -123,7 +160,18 @@ func (s *permissionService) Deny(permission PermissionRequest) {
 }
 
 func (s *permissionService) Request(opts CreatePermissionRequest) bool {
-	if s.skip {
+	// Plan mode and deny rules apply even when requests are skipped
+	target := newRuleTarget(s.workingDir, opts.ToolName, opts.Path, opts.Params)
+	if reason := s.refusal(target); reason != "" {
+		s.refuse(opts.ToolCallID, reason)
+		return false
+	}
+
+	s.rulesMu.RLock()
+	allowed := allows(s.allowRules, target)
+	s.rulesMu.RUnlock()
+	if s.skip || allowed || opts.Sandboxed {
+		s.notifyGranted(opts.ToolCallID)
 		return true
 	}
 
@@ -137,6 +185,7 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 	// Check if the tool/action combination is in the allowlist
 	commandKey := opts.ToolName + ":" + opts.Action
 	if slices.Contains(s.allowedTools, commandKey) || slices.Contains(s.allowedTools, opts.ToolName) {
+		s.notifyGranted(opts.ToolCallID)
 		return true
 	}
 
@@ -145,6 +194,7 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 	s.autoApproveSessionsMu.RUnlock()
 
 	if autoApprove {
+		s.notifyGranted(opts.ToolCallID)
 		return true
 	}
 
@@ -176,15 +226,7 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 	for _, p := range s.sessionPermissions {
 		if p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path {
 			s.sessionPermissionsMu.RUnlock()
-			return true
-		}
-	}
-	s.sessionPermissionsMu.RUnlock()
-
-	s.sessionPermissionsMu.RLock()
-	for _, p := range s.sessionPermissions {
-		if p.ToolName == permission.ToolName && p.Action == permission.Action && p.SessionID == permission.SessionID && p.Path == permission.Path {
-			s.sessionPermissionsMu.RUnlock()
+			s.notifyGranted(opts.ToolCallID)
 			return true
 		}
 	}
@@ -202,6 +244,39 @@ func (s *permissionService) Request(opts CreatePermissionRequest) bool {
 	return <-respCh
 }
 
+// Denies reports whether a deny rule refuses the tool call. Tools check it
+// for calls they run without calling Request, and the refusal is reported
+// the same way.
+func (s *permissionService) Denies(opts CreatePermissionRequest) bool {
+	target := newRuleTarget(s.workingDir, opts.ToolName, opts.Path, opts.Params)
+	s.rulesMu.RLock()
+	denied := denies(s.denyRules, target)
+	s.rulesMu.RUnlock()
+	if denied {
+		s.refuse(opts.ToolCallID, fmt.Sprintf(denyRuleRefusal, target.tool))
+	}
+	return denied
+}
+
+// refuse records why a tool call was refused, see Refusal, and tells
+// subscribers it was denied.
+func (s *permissionService) refuse(toolCallID, reason string) {
+	s.refusals.Set(toolCallID, reason)
+	s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
+		ToolCallID: toolCallID,
+		Denied:     true,
+	})
+}
+
+// notifyGranted tells subscribers that a request was granted without asking
+// the user.
+func (s *permissionService) notifyGranted(toolCallID string) {
+	s.notificationBroker.Publish(pubsub.CreatedEvent, PermissionNotification{
+		ToolCallID: toolCallID,
+		Granted:    true,
+	})
+}
+
 func (s *permissionService) AutoApproveSession(sessionID string) {
 	s.autoApproveSessionsMu.Lock()
 	s.autoApproveSessions[sessionID] = true
@@ -220,7 +295,49 @@ func (s *permissionService) SkipRequests() bool {
 	return s.skip
 }
 
-func NewPermissionService(workingDir string, skip bool, allowedTools []string) Service {
+func (s *permissionService) SetMode(mode Mode) {
+	s.rulesMu.Lock()
+	s.mode = mode
+	s.rulesMu.Unlock()
+}
+
+func (s *permissionService) Mode() Mode {
+	s.rulesMu.RLock()
+	defer s.rulesMu.RUnlock()
+	return s.mode
+}
+
+// Refusal returns the reason a tool call was refused without asking the user,
+// because of plan mode or a deny rule, so it can be reported to the agent.
+func (s *permissionService) Refusal(toolCallID string) (string, bool) {
+	reason, ok := s.refusals.Get(toolCallID)
+	if ok {
+		s.refusals.Del(toolCallID)
+	}
+	return reason, ok
+}
+
+// NewPermissionService creates a permission service. The rules are combined
+// with the ones in rulesFile, which "Allow Always" adds to; invalid rules are
+// logged and ignored. An empty rulesFile disables saving rules.
+func NewPermissionService(workingDir string, skip bool, allowedTools []string, rules Rules, rulesFile string) Service {
+	if rulesFile != "" {
+		fileRules, err := LoadRules(rulesFile)
+		if err != nil {
+			slog.Error("Failed to load permission rules", "error", err)
+		}
+		rules.Allow = append(slices.Clone(rules.Allow), fileRules.Allow...)
+		rules.Deny = append(slices.Clone(rules.Deny), fileRules.Deny...)
+	}
+	allowRules, err := parseRules(rules.Allow)
+	if err != nil {
+		slog.Warn("Ignoring invalid permission rules", "error", err)
+	}
+	denyRules, err := parseRules(rules.Deny)
+	if err != nil {
+		slog.Warn("Ignoring invalid permission rules", "error", err)
+	}
+
 	return &permissionService{
 		Broker:              pubsub.NewBroker[PermissionRequest](),
 		notificationBroker:  pubsub.NewBroker[PermissionNotification](),
@@ -229,6 +346,11 @@ func NewPermissionService(workingDir string, skip bool, allowedTools []string) S
 		autoApproveSessions: make(map[string]bool),
 		skip:                skip,
 		allowedTools:        allowedTools,
+		allowRules:          allowRules,
+		denyRules:           denyRules,
+		mode:                ModeDefault,
+		rulesFile:           rulesFile,
+		refusals:            csync.NewMap[string, string](),
 		pendingRequests:     csync.NewMap[string, chan bool](),
 	}
 }
@@ -54,7 +54,7 @@ func TestPermissionService_AllowedCommands(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			service := NewPermissionService("/tmp", false, tt.allowedTools)
+			service := NewPermissionService("/tmp", false, tt.allowedTools, Rules{}, "")
 
 			// Create a channel to capture the permission request
 			// Since we're testing the allowlist logic, we need to simulate the request
@@ -79,7 +79,7 @@ func TestPermissionService_AllowedCommands(t *testing.T) {
 }
 
 func TestPermissionService_SkipMode(t *testing.T) {
-	service := NewPermissionService("/tmp", true, []string{})
+	service := NewPermissionService("/tmp", true, []string{}, Rules{}, "")
 
 	result := service.Request(CreatePermissionRequest{
 		SessionID:   "test-session",
@@ -96,7 +96,7 @@ func TestPermissionService_SkipMode(t *testing.T) {
 
 func TestPermissionService_SequentialProperties(t *testing.T) {
 	t.Run("Sequential permission requests with persistent grants", func(t *testing.T) {
-		service := NewPermissionService("/tmp", false, []string{})
+		service := NewPermissionService("/tmp", false, []string{}, Rules{}, "")
 
 		req1 := CreatePermissionRequest{
 			SessionID:   "session1",
@@ -140,7 +140,7 @@ func TestPermissionService_SequentialProperties(t *testing.T) {
 		assert.True(t, result2, "Second request should be auto-approved")
 	})
 	t.Run("Sequential requests with temporary grants", func(t *testing.T) {
-		service := NewPermissionService("/tmp", false, []string{})
+		service := NewPermissionService("/tmp", false, []string{}, Rules{}, "")
 
 		req := CreatePermissionRequest{
 			SessionID:   "session2",
@@ -184,7 +184,7 @@ func TestPermissionService_SequentialProperties(t *testing.T) {
 		assert.False(t, result2, "Second request should be denied")
 	})
 	t.Run("Concurrent requests with different outcomes", func(t *testing.T) {
-		service := NewPermissionService("/tmp", false, []string{})
+		service := NewPermissionService("/tmp", false, []string{}, Rules{}, "")
 
 		events := service.Subscribe(t.Context())
 
@@ -249,3 +249,56 @@ func TestPermissionService_SequentialProperties(t *testing.T) {
 		assert.True(t, result, "Repeated request should be auto-approved due to persistent permission")
 	})
 }
+
+func TestPermissionService_PlanMode(t *testing.T) {
+	service := NewPermissionService("/tmp", true, []string{}, Rules{}, "")
+	service.SetMode(ModePlan)
+
+	req := CreatePermissionRequest{
+		SessionID:  "session1",
+		ToolCallID: "call1",
+		ToolName:   "write",
+		Action:     "write",
+		Path:       "/tmp",
+	}
+	assert.False(t, service.Request(req), "Writes should be refused in plan mode, even when skipping requests")
+	reason, ok := service.Refusal("call1")
+	assert.True(t, ok)
+	assert.Contains(t, reason, "plan mode")
+	_, ok = service.Refusal("call1")
+	assert.False(t, ok, "Refusals should only be reported once")
+
+	req.ToolCallID = "call2"
+	req.ToolName = "fetch"
+	assert.True(t, service.Request(req), "Other tools should not be refused in plan mode")
+	_, ok = service.Refusal("call2")
+	assert.False(t, ok)
+
+	service.SetMode(ModeDefault)
+	req.ToolName = "write"
+	assert.True(t, service.Request(req), "Writes should be allowed again outside plan mode")
+}
+
+func TestPermissionService_Sandboxed(t *testing.T) {
+	service := NewPermissionService("/tmp", false, []string{}, Rules{Deny: []string{"bash:rm .*"}}, "")
+
+	req := CreatePermissionRequest{
+		SessionID:  "session1",
+		ToolCallID: "call1",
+		ToolName:   "bash",
+		Action:     "execute",
+		Path:       "/tmp",
+		Params:     map[string]any{"command": "make build"},
+		Sandboxed:  true,
+	}
+	assert.True(t, service.Request(req), "Sandboxed tool calls should be allowed without asking")
+
+	req.ToolCallID = "call2"
+	req.Params = map[string]any{"command": "rm -rf build"}
+	assert.False(t, service.Request(req), "Deny rules should apply to sandboxed tool calls")
+
+	service.SetMode(ModePlan)
+	req.ToolCallID = "call3"
+	req.Params = map[string]any{"command": "make build"}
+	assert.False(t, service.Request(req), "Sandboxed tool calls should be refused in plan mode")
+}
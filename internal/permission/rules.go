@@ -0,0 +1,243 @@
+package permission
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/charmbracelet/crush/internal/shell"
+)
+
+// RulesFileName is the name of the project-local rules file, stored in the
+// data directory, that "Allow Always" writes to.
+const RulesFileName = "permissions.json"
+
+// bashToolName is the name of the bash tool, whose rules match commands
+// instead of paths.
+const bashToolName = "bash"
+
+// Rules lists tool calls that are allowed or denied without asking the user.
+//
+// A rule is a tool name, optionally followed by a colon and a pattern. For
+// bash the pattern is a regular expression matched against each simple
+// command of the command line, for other tools it is a glob matched against
+// the file path, relative to the working directory when the file is inside
+// it. A command line is allowed only if each of its commands matches an allow
+// rule, and denied if any of them matches a deny rule. Deny rules take
+// precedence over allow rules.
+type Rules struct {
+	Allow []string `json:"allow,omitempty"`
+	Deny  []string `json:"deny,omitempty"`
+}
+
+type rule struct {
+	tool    string
+	glob    string
+	command *regexp.Regexp
+	// literal is set when the pattern has no regular expression syntax, as
+	// in the rules added by "Allow Always", so it can stand for a whole
+	// command line.
+	literal bool
+}
+
+func parseRule(s string) (rule, error) {
+	tool, pattern, found := strings.Cut(s, ":")
+	r := rule{tool: strings.TrimSpace(tool)}
+	if r.tool == "" {
+		return rule{}, fmt.Errorf("invalid permission rule %q: missing tool name", s)
+	}
+	if !found {
+		return r, nil
+	}
+	if r.tool == bashToolName {
+		re, err := regexp.Compile("^(?:" + pattern + ")$")
+		if err != nil {
+			return rule{}, fmt.Errorf("invalid permission rule %q: %w", s, err)
+		}
+		r.command = re
+		_, r.literal = re.LiteralPrefix()
+		return r, nil
+	}
+	if !doublestar.ValidatePattern(pattern) {
+		return rule{}, fmt.Errorf("invalid permission rule %q: malformed glob", s)
+	}
+	r.glob = pattern
+	return r, nil
+}
+
+func parseRules(rules []string) ([]rule, error) {
+	var errs []error
+	parsed := make([]rule, 0, len(rules))
+	for _, s := range rules {
+		r, err := parseRule(s)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		parsed = append(parsed, r)
+	}
+	return parsed, errors.Join(errs...)
+}
+
+// allows reports whether the rules allow the target. Each command of a
+// command line has to match a rule, unless a rule without regular expression
+// syntax is the whole command line.
+func allows(rules []rule, target ruleTarget) bool {
+	if slices.ContainsFunc(rules, func(r rule) bool {
+		return r.match(target) && (r.command == nil || r.literal)
+	}) {
+		return true
+	}
+	if target.command == "" || !target.parsed || len(target.commands) == 0 {
+		return false
+	}
+	for _, command := range target.commands {
+		if !slices.ContainsFunc(rules, func(r rule) bool { return r.matchCommand(target.tool, command) }) {
+			return false
+		}
+	}
+	return true
+}
+
+// denies reports whether a rule denies the target. A command line is denied
+// if any of its commands, or the whole of it, matches a rule. Commands are
+// also matched without the variables assigned before them and with the
+// program given by its base name, so "FOO=1 /bin/rm x" matches "rm .*".
+func denies(rules []rule, target ruleTarget) bool {
+	return slices.ContainsFunc(rules, func(r rule) bool {
+		return r.match(target) || slices.ContainsFunc(slices.Concat(target.commands, target.normalized), func(command string) bool {
+			return r.matchCommand(target.tool, command)
+		})
+	})
+}
+
+// match reports whether the rule matches the target, taking the whole
+// command line as the command.
+func (r rule) match(target ruleTarget) bool {
+	if r.tool != target.tool {
+		return false
+	}
+	switch {
+	case r.command != nil:
+		return target.command != "" && r.command.MatchString(target.command)
+	case r.glob != "":
+		if target.path == "" {
+			return false
+		}
+		ok, _ := doublestar.Match(r.glob, target.path)
+		return ok
+	default:
+		return true
+	}
+}
+
+func (r rule) matchCommand(tool, command string) bool {
+	return r.tool == tool && r.command != nil && r.command.MatchString(command)
+}
+
+// ruleTarget is what rules are matched against for a permission request.
+type ruleTarget struct {
+	tool    string
+	command string
+	// commands are the simple commands of the command line, parsed is
+	// false if it couldn't be parsed fully.
+	commands []string
+	parsed   bool
+	// normalized are the commands as returned by shell.NormalizedCommands.
+	normalized []string
+	path       string
+}
+
+func newRuleTarget(workingDir, toolName, path string, params any) ruleTarget {
+	target := ruleTarget{tool: toolName, path: path}
+
+	// Tools describe the command or file they act on in their parameters,
+	// the request path is often just the working directory.
+	var fields struct {
+		Command  string `json:"command"`
+		FilePath string `json:"file_path"`
+		Path     string `json:"path"`
+	}
+	if data, err := json.Marshal(params); err == nil {
+		_ = json.Unmarshal(data, &fields)
+	}
+	target.command = fields.Command
+	if target.command != "" {
+		target.commands, target.parsed = shell.Commands(target.command)
+		target.normalized, _ = shell.NormalizedCommands(target.command)
+	}
+	if fields.FilePath != "" {
+		target.path = fields.FilePath
+	} else if fields.Path != "" {
+		target.path = fields.Path
+	}
+
+	if target.path != "" {
+		if !filepath.IsAbs(target.path) {
+			target.path = filepath.Join(workingDir, target.path)
+		}
+		if rel, err := filepath.Rel(workingDir, target.path); err == nil && !strings.HasPrefix(rel, "..") {
+			target.path = rel
+		}
+		target.path = filepath.ToSlash(target.path)
+	}
+	return target
+}
+
+// rule returns the rule that allows exactly this target.
+func (t ruleTarget) rule() string {
+	switch {
+	case t.command != "":
+		return t.tool + ":" + regexp.QuoteMeta(t.command)
+	case t.path != "" && t.path != ".":
+		return t.tool + ":" + t.path
+	default:
+		return t.tool
+	}
+}
+
+// LoadRules reads a rules file, returning no rules if it doesn't exist.
+func LoadRules(path string) (Rules, error) {
+	var rules Rules
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return rules, nil
+	}
+	if err != nil {
+		return rules, fmt.Errorf("failed to read permission rules: %w", err)
+	}
+	if err := json.Unmarshal(data, &rules); err != nil {
+		return rules, fmt.Errorf("failed to parse permission rules %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// addAllowRule adds an allow rule to the rules file, creating it if needed.
+func addAllowRule(path, rule string) error {
+	rules, err := LoadRules(path)
+	if err != nil {
+		return err
+	}
+	if slices.Contains(rules.Allow, rule) {
+		return nil
+	}
+	rules.Allow = append(rules.Allow, rule)
+
+	data, err := json.MarshalIndent(rules, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal permission rules: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for permission rules: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to write permission rules: %w", err)
+	}
+	return nil
+}
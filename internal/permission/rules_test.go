@@ -0,0 +1,162 @@
+package permission
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testBashParams struct {
+	Command string `json:"command"`
+}
+
+type testWriteParams struct {
+	FilePath string `json:"file_path"`
+}
+
+func TestRules(t *testing.T) {
+	workingDir := t.TempDir()
+	rules := Rules{
+		Allow: []string{"bash:go test ./...", "edit:internal/**", "fetch"},
+		Deny:  []string{"write:**/*.pem", "bash:rm .*", `bash:cat .*\.env`},
+	}
+	service := NewPermissionService(workingDir, false, nil, rules, "")
+
+	request := func(tool string, params any) bool {
+		return service.Request(CreatePermissionRequest{
+			SessionID:  "session",
+			ToolCallID: "call",
+			ToolName:   tool,
+			Action:     "test",
+			Path:       workingDir,
+			Params:     params,
+		})
+	}
+
+	t.Run("allowed by rule", func(t *testing.T) {
+		require.True(t, request("bash", testBashParams{Command: "go test ./..."}))
+		require.True(t, request("edit", testWriteParams{FilePath: filepath.Join(workingDir, "internal/app/app.go")}))
+		require.True(t, request("edit", testWriteParams{FilePath: "internal/app.go"}))
+		require.True(t, request("fetch", nil))
+	})
+
+	t.Run("denied by rule", func(t *testing.T) {
+		require.False(t, request("write", testWriteParams{FilePath: filepath.Join(workingDir, "certs/key.pem")}))
+		require.False(t, request("bash", testBashParams{Command: "rm -rf /"}))
+	})
+
+	t.Run("deny rules apply when skipping requests", func(t *testing.T) {
+		service.SetSkipRequests(true)
+		defer service.SetSkipRequests(false)
+		require.False(t, request("write", testWriteParams{FilePath: "key.pem"}))
+		require.True(t, request("write", testWriteParams{FilePath: "main.go"}))
+	})
+
+	t.Run("commands must match fully", func(t *testing.T) {
+		rule, err := parseRule("bash:go test ./...")
+		require.NoError(t, err)
+		require.False(t, rule.match(ruleTarget{tool: "bash", command: "go test ./... && rm -rf /"}))
+		require.False(t, rule.match(ruleTarget{tool: "bash", command: "sudo go test ./..."}))
+	})
+
+	t.Run("every command must be allowed", func(t *testing.T) {
+		rules, err := parseRules([]string{"bash:go test ./...", "bash:git (status|diff)( .*)?", `bash:cd web && npm test`})
+		require.NoError(t, err)
+		allowed := func(command string) bool {
+			return allows(rules, newRuleTarget(workingDir, "bash", workingDir, testBashParams{Command: command}))
+		}
+		require.True(t, allowed("git status && go test ./..."))
+		require.True(t, allowed("go test ./...; git diff --stat"))
+		require.True(t, allowed("cd web && npm test"))
+		require.False(t, allowed("go test ./...; curl example.com"))
+		require.False(t, allowed("go test ./... && curl example.com"))
+		require.False(t, allowed("git status | sh"))
+		require.False(t, allowed("git diff $(curl example.com)"))
+		require.False(t, allowed("cd web && npm test && curl example.com"))
+		require.False(t, allowed("go test ./... > ~/.bashrc"))
+		require.False(t, allowed("go test ./... ("))
+	})
+
+	t.Run("deny rules apply to every command", func(t *testing.T) {
+		for _, command := range []string{
+			"ls; rm -rf /",
+			"go test ./... && rm -rf /",
+			"git status | rm -rf /",
+			"echo $(rm -rf /)",
+			"timeout 5 rm -rf /",
+			`\rm -rf /`,
+			"bash -c 'rm -rf /'",
+			"FOO=1 rm -rf x",
+			`env -S "rm -rf x"`,
+			"env -S'rm -rf x'",
+			"/bin/rm x",
+			"/usr/bin/env /bin/rm x",
+		} {
+			require.False(t, request("bash", testBashParams{Command: command}), command)
+		}
+	})
+
+	t.Run("deny rules apply to calls allowed without asking", func(t *testing.T) {
+		denied := func(command string) bool {
+			return service.Denies(CreatePermissionRequest{
+				SessionID:  "session",
+				ToolCallID: "call",
+				ToolName:   "bash",
+				Path:       workingDir,
+				Params:     testBashParams{Command: command},
+			})
+		}
+		require.True(t, denied("cat .env"))
+		require.True(t, denied("cat config/.env | head"))
+		require.True(t, denied("/bin/cat .env"))
+		require.False(t, denied("cat main.go"))
+
+		reason, ok := service.Refusal("call")
+		require.True(t, ok)
+		require.Contains(t, reason, "permission rules deny")
+	})
+
+	t.Run("invalid rules", func(t *testing.T) {
+		_, err := parseRule("bash:go test (")
+		require.Error(t, err)
+		_, err = parseRule(":foo")
+		require.Error(t, err)
+		_, err = parseRule("write:[")
+		require.Error(t, err)
+	})
+}
+
+func TestPermissionService_GrantAlways(t *testing.T) {
+	workingDir := t.TempDir()
+	rulesFile := filepath.Join(workingDir, ".crush", RulesFileName)
+	service := NewPermissionService(workingDir, false, nil, Rules{}, rulesFile)
+
+	req := CreatePermissionRequest{
+		SessionID:  "session",
+		ToolCallID: "call",
+		ToolName:   "bash",
+		Action:     "execute",
+		Path:       workingDir,
+		Params:     testBashParams{Command: "make build"},
+	}
+
+	events := service.Subscribe(t.Context())
+	result := make(chan bool, 1)
+	go func() {
+		result <- service.Request(req)
+	}()
+	event := <-events
+	service.GrantAlways(event.Payload)
+	require.True(t, <-result)
+
+	rules, err := LoadRules(rulesFile)
+	require.NoError(t, err)
+	require.Equal(t, []string{`bash:make build`}, rules.Allow)
+
+	// The rule applies to other sessions and survives restarts.
+	req.SessionID = "other"
+	require.True(t, service.Request(req))
+	restarted := NewPermissionService(workingDir, false, nil, Rules{}, rulesFile)
+	require.True(t, restarted.Request(req))
+}
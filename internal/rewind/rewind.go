@@ -0,0 +1,244 @@
+// Package rewind rolls a session back to an earlier user message, removing
+// the messages sent from that point on and restoring the files the agent
+// changed since then to their recorded versions.
+package rewind
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// FileChange is a file restored by a rewind.
+type FileChange struct {
+	Path string
+	// OldContent is the current content of the file.
+	OldContent string
+	// NewContent is the content the file is restored to.
+	NewContent string
+	// Delete is set for files created by the agent, which are removed.
+	Delete bool
+}
+
+// Plan describes what rewinding a session to a message does, so it can be
+// previewed before it is applied.
+type Plan struct {
+	SessionID string
+	MessageID string
+	// Prompt is the text of the message rewound to, so it can be edited and
+	// sent again.
+	Prompt string
+	// Messages are the messages that are removed, starting with the message
+	// rewound to.
+	Messages []message.Message
+	// Files are the files that are restored.
+	Files []FileChange
+
+	versions []history.File
+	children []string
+}
+
+type Service interface {
+	// Plan returns what rewinding the session to the given user message
+	// does, without changing anything.
+	Plan(ctx context.Context, sessionID, messageID string) (Plan, error)
+	// Apply restores the files and removes the messages of a plan.
+	Apply(ctx context.Context, plan Plan) error
+}
+
+type service struct {
+	sessions session.Service
+	messages message.Service
+	files    history.Service
+}
+
+func NewService(sessions session.Service, messages message.Service, files history.Service) Service {
+	return &service{
+		sessions: sessions,
+		messages: messages,
+		files:    files,
+	}
+}
+
+func (s *service) Plan(ctx context.Context, sessionID, messageID string) (Plan, error) {
+	msgs, err := s.messages.List(ctx, sessionID)
+	if err != nil {
+		return Plan{}, fmt.Errorf("failed to list messages: %w", err)
+	}
+	idx := slices.IndexFunc(msgs, func(msg message.Message) bool {
+		return msg.ID == messageID
+	})
+	if idx == -1 {
+		return Plan{}, fmt.Errorf("message %s not found in session %s", messageID, sessionID)
+	}
+	target := msgs[idx]
+	if target.Role != message.User {
+		return Plan{}, errors.New("can only rewind to a user message")
+	}
+
+	plan := Plan{
+		SessionID: sessionID,
+		MessageID: messageID,
+		Prompt:    target.Content().Text,
+		Messages:  msgs[idx:],
+	}
+
+	// Sub-agent sessions use the id of the tool call that started them.
+	children, err := s.sessions.ListChildren(ctx, sessionID)
+	if err != nil {
+		return Plan{}, fmt.Errorf("failed to list sub-agent sessions: %w", err)
+	}
+	for _, msg := range plan.Messages {
+		for _, call := range msg.ToolCalls() {
+			if slices.ContainsFunc(children, func(child session.Session) bool {
+				return child.ID == call.ID
+			}) {
+				plan.children = append(plan.children, call.ID)
+			}
+		}
+	}
+
+	versions, err := s.files.ListBySession(ctx, sessionID)
+	if err != nil {
+		return Plan{}, fmt.Errorf("failed to list file history: %w", err)
+	}
+	var (
+		paths  []string
+		before = make(map[string][]history.File)
+		after  = make(map[string][]history.File)
+	)
+	for _, version := range versions {
+		if version.CreatedAtMs < target.CreatedAtMs {
+			before[version.Path] = append(before[version.Path], version)
+			continue
+		}
+		if len(after[version.Path]) == 0 {
+			paths = append(paths, version.Path)
+		}
+		after[version.Path] = append(after[version.Path], version)
+		plan.versions = append(plan.versions, version)
+	}
+
+	slices.Sort(paths)
+	for _, path := range paths {
+		// Restore the last version recorded before the message. Files first
+		// touched after it are restored to their first version, which holds
+		// their content before the agent changed them.
+		var restored history.File
+		if prev := before[path]; len(prev) > 0 {
+			restored = prev[len(prev)-1]
+		} else {
+			restored = after[path][0]
+		}
+
+		change := FileChange{
+			Path:       path,
+			NewContent: restored.Content,
+			// An empty first version means the agent created the file.
+			Delete: len(before[path]) == 0 && restored.Content == "",
+		}
+		content, err := os.ReadFile(path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			if change.Delete {
+				continue
+			}
+		case err != nil:
+			return Plan{}, fmt.Errorf("failed to read %s: %w", path, err)
+		default:
+			change.OldContent = string(content)
+			if !change.Delete && change.OldContent == change.NewContent {
+				continue
+			}
+		}
+		plan.Files = append(plan.Files, change)
+	}
+	return plan, nil
+}
+
+func (s *service) Apply(ctx context.Context, plan Plan) error {
+	for _, file := range plan.Files {
+		if err := restoreFile(file); err != nil {
+			return err
+		}
+	}
+
+	for _, version := range plan.versions {
+		if err := s.files.Delete(ctx, version.ID); err != nil {
+			return fmt.Errorf("failed to delete file history: %w", err)
+		}
+	}
+	for _, id := range plan.children {
+		if err := DeleteSession(ctx, s.sessions, s.messages, s.files, id); err != nil {
+			return fmt.Errorf("failed to delete sub-agent session: %w", err)
+		}
+	}
+	for _, msg := range slices.Backward(plan.Messages) {
+		if err := s.messages.Delete(ctx, msg.ID); err != nil {
+			return fmt.Errorf("failed to delete message: %w", err)
+		}
+	}
+
+	sess, err := s.sessions.Get(ctx, plan.SessionID)
+	if err != nil {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+	if slices.ContainsFunc(plan.Messages, func(msg message.Message) bool {
+		return msg.ID == sess.SummaryMessageID
+	}) {
+		sess.SummaryMessageID = ""
+		if _, err := s.sessions.Save(ctx, sess); err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteSession removes a session with its messages, file history and child
+// sessions.
+func DeleteSession(ctx context.Context, sessions session.Service, messages message.Service, files history.Service, sessionID string) error {
+	children, err := sessions.ListChildren(ctx, sessionID)
+	if err != nil {
+		return err
+	}
+	for _, child := range children {
+		if err := DeleteSession(ctx, sessions, messages, files, child.ID); err != nil {
+			return err
+		}
+	}
+	if err := messages.DeleteSessionMessages(ctx, sessionID); err != nil {
+		return err
+	}
+	if err := files.DeleteSessionFiles(ctx, sessionID); err != nil {
+		return err
+	}
+	return sessions.Delete(ctx, sessionID)
+}
+
+func restoreFile(file FileChange) error {
+	if file.Delete {
+		if err := os.Remove(file.Path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to remove %s: %w", file.Path, err)
+		}
+		return nil
+	}
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(file.Path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.MkdirAll(filepath.Dir(file.Path), 0o755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", file.Path, err)
+	}
+	if err := os.WriteFile(file.Path, []byte(file.NewContent), mode); err != nil {
+		return fmt.Errorf("failed to restore %s: %w", file.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package rewind
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRewind(t *testing.T) {
+	ctx := t.Context()
+	conn, err := db.Connect(ctx, t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	q := db.New(conn)
+	sessions := session.NewService(q)
+	messages := message.NewService(q)
+	files := history.NewService(q, conn)
+	svc := NewService(sessions, messages, files)
+
+	sess, err := sessions.Create(ctx, "Rewind")
+	require.NoError(t, err)
+
+	// Timestamps are in milliseconds.
+	addMessage := func(id string, role message.MessageRole, createdAt int64, parts ...message.ContentPart) {
+		t.Helper()
+		data, err := message.MarshalParts(parts)
+		require.NoError(t, err)
+		require.NoError(t, q.ImportMessage(ctx, db.ImportMessageParams{
+			ID:          id,
+			SessionID:   sess.ID,
+			Role:        string(role),
+			Parts:       string(data),
+			CreatedAt:   createdAt / 1000,
+			UpdatedAt:   createdAt / 1000,
+			CreatedAtMs: createdAt,
+		}))
+	}
+	addVersion := func(id, path, content string, version, createdAt int64) {
+		t.Helper()
+		require.NoError(t, q.ImportFile(ctx, db.ImportFileParams{
+			ID:          id,
+			SessionID:   sess.ID,
+			Path:        path,
+			Content:     content,
+			Version:     version,
+			CreatedAt:   createdAt / 1000,
+			UpdatedAt:   createdAt / 1000,
+			CreatedAtMs: createdAt,
+		}))
+	}
+
+	dir := t.TempDir()
+	edited := filepath.Join(dir, "edited.go")
+	created := filepath.Join(dir, "created.go")
+	touched := filepath.Join(dir, "touched.go")
+
+	// First turn: edited.go is changed, in the same second the second
+	// turn starts.
+	addMessage("u1", message.User, 100_000, message.TextContent{Text: "first"})
+	addMessage("a1", message.Assistant, 199_000, message.TextContent{Text: "done"})
+	addVersion("f1", edited, "v0", 0, 200_100)
+	addVersion("f2", edited, "v1", 1, 200_200)
+
+	// Second turn: edited.go is changed again, created.go is created,
+	// touched.go is changed, and a sub-agent runs.
+	addMessage("u2", message.User, 200_500, message.TextContent{Text: "second"})
+	addMessage("a2", message.Assistant, 201_000, message.ToolCall{ID: "call-1", Name: "agent", Finished: true})
+	addMessage("t2", message.Tool, 202_000, message.ToolResult{ToolCallID: "call-1", Content: "ok"})
+	addVersion("f3", edited, "v2", 2, 202_000)
+	addVersion("f4", created, "", 0, 202_000)
+	addVersion("f5", created, "new", 1, 202_000)
+	addVersion("f6", touched, "original", 0, 203_000)
+	addVersion("f7", touched, "changed", 1, 203_000)
+	_, err = sessions.CreateTaskSession(ctx, "call-1", sess.ID, "New Agent Session")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(edited, []byte("v2"), 0o644))
+	require.NoError(t, os.WriteFile(created, []byte("new"), 0o644))
+	require.NoError(t, os.WriteFile(touched, []byte("changed"), 0o644))
+
+	_, err = svc.Plan(ctx, sess.ID, "a2")
+	require.Error(t, err)
+
+	plan, err := svc.Plan(ctx, sess.ID, "u2")
+	require.NoError(t, err)
+	require.Equal(t, "second", plan.Prompt)
+	require.Len(t, plan.Messages, 3)
+	require.Equal(t, []FileChange{
+		{Path: created, OldContent: "new", Delete: true},
+		{Path: edited, OldContent: "v2", NewContent: "v1"},
+		{Path: touched, OldContent: "changed", NewContent: "original"},
+	}, plan.Files)
+
+	require.NoError(t, svc.Apply(ctx, plan))
+
+	content, err := os.ReadFile(edited)
+	require.NoError(t, err)
+	require.Equal(t, "v1", string(content))
+	content, err = os.ReadFile(touched)
+	require.NoError(t, err)
+	require.Equal(t, "original", string(content))
+	require.NoFileExists(t, created)
+
+	msgs, err := messages.List(ctx, sess.ID)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+	require.Equal(t, "a1", msgs[1].ID)
+
+	versions, err := files.ListBySession(ctx, sess.ID)
+	require.NoError(t, err)
+	require.Len(t, versions, 2)
+
+	children, err := sessions.ListChildren(ctx, sess.ID)
+	require.NoError(t, err)
+	require.Empty(t, children)
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// keepAliveInterval is how often a comment is sent on idle event streams so
+// that proxies don't close them.
+const keepAliveInterval = 30 * time.Second
+
+// messageResponse is a message with its parts in the JSON format they are
+// stored in, see message.MarshalParts.
+type messageResponse struct {
+	ID        string          `json:"id"`
+	SessionID string          `json:"session_id"`
+	Role      string          `json:"role"`
+	Model     string          `json:"model,omitempty"`
+	Provider  string          `json:"provider,omitempty"`
+	Parts     json.RawMessage `json:"parts"`
+	CreatedAt int64           `json:"created_at"`
+	UpdatedAt int64           `json:"updated_at"`
+}
+
+func newMessageResponse(msg message.Message) messageResponse {
+	parts, err := message.MarshalParts(msg.Parts)
+	if err != nil {
+		slog.Error("Failed to marshal message parts", "message_id", msg.ID, "error", err)
+		parts = json.RawMessage("[]")
+	}
+	return messageResponse{
+		ID:        msg.ID,
+		SessionID: msg.SessionID,
+		Role:      string(msg.Role),
+		Model:     msg.Model,
+		Provider:  msg.Provider,
+		Parts:     parts,
+		CreatedAt: msg.CreatedAt,
+		UpdatedAt: msg.UpdatedAt,
+	}
+}
+
+// agentEventResponse is an agent event: the end of a run with its final
+// message or error, a warning, or the progress of a summary.
+type agentEventResponse struct {
+	Type      string           `json:"type"`
+	SessionID string           `json:"session_id"`
+	Message   *messageResponse `json:"message,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	Progress  string           `json:"progress,omitempty"`
+	Done      bool             `json:"done,omitempty"`
+}
+
+func newAgentEventResponse(event agent.AgentEvent) agentEventResponse {
+	resp := agentEventResponse{
+		Type:      string(event.Type),
+		SessionID: cmp.Or(event.SessionID, event.Message.SessionID),
+		Progress:  event.Progress,
+		Done:      event.Done,
+	}
+	if event.Message.ID != "" {
+		msg := newMessageResponse(event.Message)
+		resp.Message = &msg
+	}
+	if event.Error != nil {
+		resp.Error = event.Error.Error()
+	}
+	return resp
+}
+
+// eventFilter keeps the events of a session and its sub-agent sessions.
+// Permission notifications only carry the tool call, so the filter keeps
+// track of the tool calls of the sessions.
+type eventFilter struct {
+	sessions  map[string]bool
+	toolCalls map[string]bool
+}
+
+func newEventFilter(sessionID string) *eventFilter {
+	if sessionID == "" {
+		return nil
+	}
+	return &eventFilter{
+		sessions:  map[string]bool{sessionID: true},
+		toolCalls: make(map[string]bool),
+	}
+}
+
+func (f *eventFilter) session(s session.Session) bool {
+	if f == nil {
+		return true
+	}
+	if s.ParentSessionID != "" && f.sessions[s.ParentSessionID] {
+		f.sessions[s.ID] = true
+	}
+	return f.sessions[s.ID]
+}
+
+func (f *eventFilter) message(msg message.Message) bool {
+	if f == nil {
+		return true
+	}
+	if !f.sessions[msg.SessionID] {
+		return false
+	}
+	for _, call := range msg.ToolCalls() {
+		f.toolCalls[call.ID] = true
+	}
+	return true
+}
+
+func (f *eventFilter) permissionRequest(req permission.PermissionRequest) bool {
+	if f == nil {
+		return true
+	}
+	if !f.sessions[req.SessionID] {
+		return false
+	}
+	f.toolCalls[req.ToolCallID] = true
+	return true
+}
+
+func (f *eventFilter) permission(n permission.PermissionNotification) bool {
+	return f == nil || f.toolCalls[n.ToolCallID]
+}
+
+func (f *eventFilter) agent(event agentEventResponse) bool {
+	return f == nil || event.SessionID == "" || f.sessions[event.SessionID]
+}
+
+// events streams the session, message, permission and agent events as
+// server-sent events, only those of the session_id query parameter's session
+// if given. The event name is the kind of the event, and its data holds the
+// type of the event, created, updated or deleted, and its payload.
+func (s *Server) events(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	filter := newEventFilter(r.URL.Query().Get("session_id"))
+
+	// Subscribe before writing anything so that no event is missed by a
+	// client that starts a run once connected.
+	sessionEvents := s.opts.Sessions.Subscribe(ctx)
+	messageEvents := s.opts.Messages.Subscribe(ctx)
+	permissionRequests := s.opts.Permissions.Subscribe(ctx)
+	permissionEvents := s.opts.Permissions.SubscribeNotifications(ctx)
+	var agentEvents <-chan pubsub.Event[agent.AgentEvent]
+	if s.opts.Agent != nil {
+		agentEvents = s.opts.Agent.Subscribe(ctx)
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		slog.Error("Failed to start event stream", "error", err)
+		return
+	}
+
+	send := func(kind string, typ pubsub.EventType, payload any) error {
+		data, err := json.Marshal(map[string]any{"type": typ, "payload": payload})
+		if err != nil {
+			return err
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", kind, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	}
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		var err error
+		select {
+		case event := <-sessionEvents:
+			if filter.session(event.Payload) {
+				err = send("session", event.Type, event.Payload)
+			}
+		case event := <-messageEvents:
+			if filter.message(event.Payload) {
+				err = send("message", event.Type, newMessageResponse(event.Payload))
+			}
+		case event := <-permissionRequests:
+			if filter.permissionRequest(event.Payload) {
+				err = send("permission_request", event.Type, event.Payload)
+			}
+		case event := <-permissionEvents:
+			if filter.permission(event.Payload) {
+				err = send("permission", event.Type, event.Payload)
+			}
+		case event := <-agentEvents:
+			payload := newAgentEventResponse(event.Payload)
+			if filter.agent(payload) {
+				err = send("agent", event.Type, payload)
+			}
+		case <-keepAlive.C:
+			if _, err = fmt.Fprint(w, ": keep-alive\n\n"); err == nil {
+				err = rc.Flush()
+			}
+		case <-ctx.Done():
+			return
+		case <-s.ctx.Done():
+			return
+		}
+		if err != nil {
+			slog.Debug("Event stream closed", "error", err)
+			return
+		}
+	}
+}
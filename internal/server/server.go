@@ -0,0 +1,407 @@
+// Package server serves Crush's sessions and agent over a local HTTP API,
+// for web UIs and editor plugins to build on.
+package server
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/session"
+)
+
+// Options are the services the API is served from.
+type Options struct {
+	Sessions    session.Service
+	Messages    message.Service
+	Permissions permission.Service
+	// Agent runs the prompts. Prompts are refused if it is nil.
+	Agent agent.Service
+	// Token, if set, must be given as a bearer token with every request.
+	Token string
+}
+
+// Server is the HTTP API. It implements http.Handler.
+type Server struct {
+	ctx     context.Context
+	opts    Options
+	handler http.Handler
+
+	// pending holds the permission requests waiting for an answer, by id.
+	pending *csync.Map[string, permission.PermissionRequest]
+}
+
+// New creates a server. Agent runs started through it, and its tracking of
+// pending permission requests, last until the context is done.
+func New(ctx context.Context, opts Options) *Server {
+	s := &Server{
+		ctx:     ctx,
+		opts:    opts,
+		pending: csync.NewMap[string, permission.PermissionRequest](),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v1/sessions", s.listSessions)
+	mux.HandleFunc("POST /v1/sessions", s.createSession)
+	mux.HandleFunc("GET /v1/sessions/{id}", s.getSession)
+	mux.HandleFunc("DELETE /v1/sessions/{id}", s.deleteSession)
+	mux.HandleFunc("GET /v1/sessions/{id}/messages", s.listMessages)
+	mux.HandleFunc("POST /v1/sessions/{id}/prompt", s.prompt)
+	mux.HandleFunc("POST /v1/sessions/{id}/cancel", s.cancel)
+	mux.HandleFunc("GET /v1/permissions", s.listPermissions)
+	mux.HandleFunc("POST /v1/permissions/{id}", s.answerPermission)
+	mux.HandleFunc("GET /v1/events", s.events)
+	s.handler = Protect(mux, opts.Token)
+
+	s.trackPermissions()
+	return s
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.handler.ServeHTTP(w, r)
+}
+
+// ListenAndServe serves the API on the given address until the context is
+// done.
+func (s *Server) ListenAndServe(ctx context.Context, addr string) error {
+	httpServer := &http.Server{Addr: addr, Handler: s}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- httpServer.ListenAndServe()
+	}()
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		return httpServer.Shutdown(context.Background())
+	}
+}
+
+// Protect wraps a local API so that it only serves the requests carrying the
+// token as a bearer token, if it is set, and addressed to localhost. Browsers
+// must not be able to drive the API from other sites, neither directly nor
+// through a domain of theirs resolving to this machine.
+func Protect(next http.Handler, token string) http.Handler {
+	return checkHost(http.NewCrossOriginProtection().Handler(authenticate(next, token)))
+}
+
+func authenticate(next http.Handler, token string) http.Handler {
+	if token == "" {
+		return next
+	}
+	want := []byte("Bearer " + token)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if subtle.ConstantTimeCompare([]byte(r.Header.Get("Authorization")), want) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("missing or invalid token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// GenerateToken returns a random token, for when the API is served without
+// one given.
+func GenerateToken() string {
+	return rand.Text()
+}
+
+// checkHost refuses the requests whose Host header isn't localhost or a
+// loopback address. Origin checks don't stop DNS rebinding, where the page
+// and the API share the attacker's host name.
+func checkHost(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !isLoopbackHost(r.Host) {
+			writeError(w, http.StatusForbidden, fmt.Errorf("host %q is not allowed", r.Host))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isLoopbackHost(hostport string) bool {
+	host, _, err := net.SplitHostPort(hostport)
+	if err != nil {
+		host = strings.Trim(hostport, "[]")
+	}
+	if strings.EqualFold(strings.TrimSuffix(host, "."), "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// trackPermissions keeps the pending permission requests until they are
+// answered, here or elsewhere. It subscribes before returning so that no
+// request is missed.
+func (s *Server) trackPermissions() {
+	requests := s.opts.Permissions.Subscribe(s.ctx)
+	notifications := s.opts.Permissions.SubscribeNotifications(s.ctx)
+	go func() {
+		for {
+			select {
+			case event, ok := <-requests:
+				if !ok {
+					return
+				}
+				s.pending.Set(event.Payload.ID, event.Payload)
+			case event, ok := <-notifications:
+				if !ok {
+					return
+				}
+				n := event.Payload
+				if !n.Granted && !n.Denied {
+					continue
+				}
+				for id, req := range s.pending.Seq2() {
+					if req.ToolCallID == n.ToolCallID {
+						s.pending.Del(id)
+					}
+				}
+			}
+		}
+	}()
+}
+
+// sessionResponse is a session along with the state of its agent run.
+type sessionResponse struct {
+	session.Session
+	Busy          bool `json:"busy"`
+	QueuedPrompts int  `json:"queued_prompts"`
+}
+
+func (s *Server) sessionResponse(sess session.Session) sessionResponse {
+	resp := sessionResponse{Session: sess}
+	if s.opts.Agent != nil {
+		resp.Busy = s.opts.Agent.IsSessionBusy(sess.ID)
+		resp.QueuedPrompts = s.opts.Agent.QueuedPrompts(sess.ID)
+	}
+	return resp
+}
+
+func (s *Server) listSessions(w http.ResponseWriter, r *http.Request) {
+	sessions, err := s.opts.Sessions.List(r.Context())
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to list sessions: %w", err))
+		return
+	}
+	resp := make([]sessionResponse, len(sessions))
+	for i, sess := range sessions {
+		resp[i] = s.sessionResponse(sess)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+func (s *Server) createSession(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Title string `json:"title"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Title == "" {
+		req.Title = "New Session"
+	}
+	sess, err := s.opts.Sessions.Create(r.Context(), req.Title)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to create session: %w", err))
+		return
+	}
+	writeJSON(w, http.StatusCreated, s.sessionResponse(sess))
+}
+
+// session gets the session of the request path, writing an error if there
+// is none.
+func (s *Server) session(w http.ResponseWriter, r *http.Request) (session.Session, bool) {
+	id := r.PathValue("id")
+	sess, err := s.opts.Sessions.Get(r.Context(), id)
+	if errors.Is(err, sql.ErrNoRows) {
+		writeError(w, http.StatusNotFound, fmt.Errorf("session %s not found", id))
+		return session.Session{}, false
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to get session: %w", err))
+		return session.Session{}, false
+	}
+	return sess, true
+}
+
+func (s *Server) getSession(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, s.sessionResponse(sess))
+}
+
+func (s *Server) deleteSession(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	if s.opts.Agent != nil {
+		s.opts.Agent.Cancel(sess.ID)
+	}
+	if err := s.opts.Sessions.Delete(r.Context(), sess.ID); err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to delete session: %w", err))
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listMessages(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	msgs, err := s.opts.Messages.List(r.Context(), sess.ID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to list messages: %w", err))
+		return
+	}
+	resp := make([]messageResponse, len(msgs))
+	for i, msg := range msgs {
+		resp[i] = newMessageResponse(msg)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// prompt starts an agent run on the prompt, or queues the prompt if the
+// session is busy. The run is followed through the events.
+func (s *Server) prompt(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Prompt string `json:"prompt"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	if req.Prompt == "" {
+		writeError(w, http.StatusBadRequest, errors.New("prompt is required"))
+		return
+	}
+	if s.opts.Agent == nil {
+		writeError(w, http.StatusServiceUnavailable, errors.New("no providers configured"))
+		return
+	}
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+
+	done, err := s.opts.Agent.Run(s.ctx, sess.ID, req.Prompt)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, fmt.Errorf("failed to run agent: %w", err))
+		return
+	}
+	if done != nil {
+		go func() {
+			result := <-done
+			if result.Error != nil {
+				slog.Warn("Agent run failed", "session_id", sess.ID, "error", result.Error)
+			}
+		}()
+	}
+	writeJSON(w, http.StatusAccepted, map[string]any{
+		"session_id": sess.ID,
+		"queued":     done == nil,
+	})
+}
+
+func (s *Server) cancel(w http.ResponseWriter, r *http.Request) {
+	sess, ok := s.session(w, r)
+	if !ok {
+		return
+	}
+	if s.opts.Agent != nil {
+		s.opts.Agent.Cancel(sess.ID)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) listPermissions(w http.ResponseWriter, r *http.Request) {
+	requests := slices.Collect(s.pending.Seq())
+	if requests == nil {
+		requests = []permission.PermissionRequest{}
+	}
+	slices.SortFunc(requests, func(a, b permission.PermissionRequest) int {
+		return cmp.Or(cmp.Compare(a.SessionID, b.SessionID), cmp.Compare(a.ToolCallID, b.ToolCallID))
+	})
+	writeJSON(w, http.StatusOK, requests)
+}
+
+// Answers to permission requests, as in the permissions dialog.
+const (
+	answerAllow        = "allow"
+	answerAllowSession = "allow_session"
+	answerAllowAlways  = "allow_always"
+	answerDeny         = "deny"
+)
+
+func (s *Server) answerPermission(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Answer string `json:"answer"`
+	}
+	if !readJSON(w, r, &req) {
+		return
+	}
+	var answer func(permission.PermissionRequest)
+	switch req.Answer {
+	case answerAllow:
+		answer = s.opts.Permissions.Grant
+	case answerAllowSession:
+		answer = s.opts.Permissions.GrantPersistent
+	case answerAllowAlways:
+		answer = s.opts.Permissions.GrantAlways
+	case answerDeny:
+		answer = s.opts.Permissions.Deny
+	default:
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid answer %q, must be %s, %s, %s or %s", req.Answer, answerAllow, answerAllowSession, answerAllowAlways, answerDeny))
+		return
+	}
+
+	id := r.PathValue("id")
+	permissionReq, ok := s.pending.Take(id)
+	if !ok {
+		writeError(w, http.StatusNotFound, fmt.Errorf("permission request %s not found", id))
+		return
+	}
+	answer(permissionReq)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// readJSON decodes the request body into v, which is left as is if the body
+// is empty.
+func readJSON(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Failed to write response", "error", err)
+	}
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,225 @@
+package server
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/charmbracelet/catwalk/pkg/catwalk"
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAgent answers every prompt with an assistant message echoing it.
+type fakeAgent struct {
+	*pubsub.Broker[agent.AgentEvent]
+	messages message.Service
+}
+
+func (a *fakeAgent) Model() catwalk.Model { return catwalk.Model{} }
+
+func (a *fakeAgent) Run(ctx context.Context, sessionID string, content string, attachments ...message.Attachment) (<-chan agent.AgentEvent, error) {
+	msg, err := a.messages.Create(ctx, sessionID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.TextContent{Text: "You said: " + content}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	done := make(chan agent.AgentEvent, 1)
+	result := agent.AgentEvent{Type: agent.AgentEventTypeResponse, Message: msg}
+	a.Publish(pubsub.CreatedEvent, result)
+	done <- result
+	return done, nil
+}
+
+func (a *fakeAgent) Cancel(string)                           {}
+func (a *fakeAgent) CancelAll()                              {}
+func (a *fakeAgent) IsSessionBusy(string) bool               { return false }
+func (a *fakeAgent) IsBusy() bool                            { return false }
+func (a *fakeAgent) Summarize(context.Context, string) error { return nil }
+func (a *fakeAgent) UpdateModel() error                      { return nil }
+func (a *fakeAgent) QueuedPrompts(string) int                { return 0 }
+func (a *fakeAgent) ClearQueue(string)                       {}
+
+func newTestServer(t *testing.T, token string) (*httptest.Server, permission.Service) {
+	t.Helper()
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	q := db.New(conn)
+	messages := message.NewService(q)
+	permissions := permission.NewPermissionService(t.TempDir(), false, nil, permission.Rules{}, "")
+
+	s := New(t.Context(), Options{
+		Sessions:    session.NewService(q),
+		Messages:    messages,
+		Permissions: permissions,
+		Agent:       &fakeAgent{Broker: pubsub.NewBroker[agent.AgentEvent](), messages: messages},
+		Token:       token,
+	})
+	ts := httptest.NewServer(s)
+	t.Cleanup(ts.Close)
+	return ts, permissions
+}
+
+func do(t *testing.T, method, url, body string, v any) int {
+	t.Helper()
+	req, err := http.NewRequestWithContext(t.Context(), method, url, strings.NewReader(body))
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	if v != nil {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+type sseEvent struct {
+	Name string
+	Data struct {
+		Type    string          `json:"type"`
+		Payload json.RawMessage `json:"payload"`
+	}
+}
+
+// readEvents reads the server-sent events of the stream until the one with
+// the given name.
+func readEvents(t *testing.T, r *bufio.Reader, until string) []sseEvent {
+	t.Helper()
+	var events []sseEvent
+	var event sseEvent
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "event: "):
+			event.Name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &event.Data))
+		case line == "" && event.Name != "":
+			events = append(events, event)
+			if event.Name == until {
+				return events
+			}
+			event = sseEvent{}
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	ts, permissions := newTestServer(t, "")
+
+	var sess sessionResponse
+	require.Equal(t, http.StatusCreated, do(t, "POST", ts.URL+"/v1/sessions", `{"title":"Hello"}`, &sess))
+	require.Equal(t, "Hello", sess.Title)
+	require.Equal(t, http.StatusNotFound, do(t, "GET", ts.URL+"/v1/sessions/missing", "", nil))
+
+	t.Run("streams the events of a prompt", func(t *testing.T) {
+		resp, err := http.Get(ts.URL + "/v1/events?session_id=" + sess.ID)
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+		var started map[string]any
+		require.Equal(t, http.StatusAccepted, do(t, "POST", ts.URL+"/v1/sessions/"+sess.ID+"/prompt", `{"prompt":"hi"}`, &started))
+		require.Equal(t, false, started["queued"])
+
+		events := readEvents(t, bufio.NewReader(resp.Body), "agent")
+		require.Equal(t, "message", events[0].Name)
+		require.Equal(t, "created", events[0].Data.Type)
+		var result agentEventResponse
+		require.NoError(t, json.Unmarshal(events[len(events)-1].Data.Payload, &result))
+		require.Equal(t, "response", result.Type)
+		require.Equal(t, sess.ID, result.SessionID)
+
+		var msgs []messageResponse
+		require.Equal(t, http.StatusOK, do(t, "GET", ts.URL+"/v1/sessions/"+sess.ID+"/messages", "", &msgs))
+		require.Len(t, msgs, 1)
+		require.Contains(t, string(msgs[0].Parts), "You said: hi")
+	})
+
+	t.Run("answers permission requests", func(t *testing.T) {
+		granted := make(chan bool)
+		go func() {
+			granted <- permissions.Request(permission.CreatePermissionRequest{
+				SessionID:  sess.ID,
+				ToolCallID: "call",
+				ToolName:   "bash",
+				Action:     "execute",
+				Path:       ".",
+			})
+		}()
+
+		var pending []permission.PermissionRequest
+		require.Eventually(t, func() bool {
+			do(t, "GET", ts.URL+"/v1/permissions", "", &pending)
+			return len(pending) == 1
+		}, 5*time.Second, 10*time.Millisecond)
+		require.Equal(t, "call", pending[0].ToolCallID)
+
+		url := ts.URL + "/v1/permissions/" + pending[0].ID
+		require.Equal(t, http.StatusBadRequest, do(t, "POST", url, `{"answer":"maybe"}`, nil))
+		require.Equal(t, http.StatusNoContent, do(t, "POST", url, `{"answer":"allow"}`, nil))
+		require.True(t, <-granted)
+		require.Equal(t, http.StatusNotFound, do(t, "POST", url, `{"answer":"allow"}`, nil))
+
+		do(t, "GET", ts.URL+"/v1/permissions", "", &pending)
+		require.Empty(t, pending)
+	})
+
+	t.Run("deletes sessions", func(t *testing.T) {
+		require.Equal(t, http.StatusNoContent, do(t, "DELETE", ts.URL+"/v1/sessions/"+sess.ID, "", nil))
+		var sessions []sessionResponse
+		require.Equal(t, http.StatusOK, do(t, "GET", ts.URL+"/v1/sessions", "", &sessions))
+		require.Empty(t, sessions)
+	})
+}
+
+func TestServerToken(t *testing.T) {
+	ts, _ := newTestServer(t, "secret")
+
+	require.Equal(t, http.StatusUnauthorized, do(t, "GET", ts.URL+"/v1/sessions", "", nil))
+
+	req, err := http.NewRequestWithContext(t.Context(), "GET", ts.URL+"/v1/sessions", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer secret")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+}
+
+func TestServerHost(t *testing.T) {
+	ts, _ := newTestServer(t, "")
+
+	for host, status := range map[string]int{
+		"localhost:4096":      http.StatusOK,
+		"127.0.0.1:4096":      http.StatusOK,
+		"[::1]:4096":          http.StatusOK,
+		"localhost":           http.StatusOK,
+		"attacker.example":    http.StatusForbidden,
+		"attacker.example:80": http.StatusForbidden,
+		"192.168.1.10:4096":   http.StatusForbidden,
+	} {
+		req, err := http.NewRequestWithContext(t.Context(), "GET", ts.URL+"/v1/sessions", nil)
+		require.NoError(t, err)
+		req.Host = host
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		resp.Body.Close()
+		require.Equal(t, status, resp.StatusCode, host)
+	}
+}
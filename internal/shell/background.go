@@ -0,0 +1,221 @@
+package shell
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/csync"
+	"github.com/charmbracelet/crush/internal/pubsub"
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// maxJobOutput is how much output of a background job is kept. Older output
+// is dropped once a job has written more. The buffer grows up to twice as
+// much before it is trimmed, so that chatty jobs don't move it on each write.
+const maxJobOutput = 1024 * 1024
+
+var (
+	jobs      = csync.NewMap[string, *BackgroundJob]()
+	jobBroker = pubsub.NewBroker[*BackgroundJob]()
+	jobSeq    atomic.Int64
+)
+
+// BackgroundJob is a command running in the background. Its stdout and
+// stderr are combined and can be read while it runs.
+type BackgroundJob struct {
+	ID         string
+	Command    string
+	WorkingDir string
+	StartedAt  time.Time
+
+	cancel context.CancelFunc
+	done   chan struct{}
+
+	mu      sync.Mutex
+	output  []byte
+	dropped int // bytes dropped from the start of output
+	read    int // bytes returned by ReadOutput, counted from the first byte written
+	err     error
+	endedAt time.Time
+}
+
+// StartBackground starts the command in the background and returns right
+// away. The command runs in a copy of the shell, so changes it makes to the
+// working directory or environment don't affect the shell. On Unix, each
+// program it runs gets its own process group, which is killed as a whole
+// with the job, so that the programs they start, like the server started by
+// "npm run dev", don't outlive it.
+func (s *Shell) StartBackground(command string) (*BackgroundJob, error) {
+	if _, err := syntax.NewParser().Parse(strings.NewReader(command), ""); err != nil {
+		return nil, fmt.Errorf("could not parse command: %w", err)
+	}
+
+	s.mu.Lock()
+	sub := &Shell{
+		cwd:        s.cwd,
+		env:        slices.Clone(s.env),
+		logger:     s.logger,
+		blockFuncs: s.blockFuncs,
+		sandbox:    s.sandbox,
+	}
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	job := &BackgroundJob{
+		ID:         fmt.Sprintf("job-%d", jobSeq.Add(1)),
+		Command:    command,
+		WorkingDir: sub.cwd,
+		StartedAt:  time.Now(),
+		cancel:     cancel,
+		done:       make(chan struct{}),
+	}
+	jobs.Set(job.ID, job)
+	jobBroker.Publish(pubsub.CreatedEvent, job)
+
+	go func() {
+		defer cancel()
+		err := sub.execPOSIXTo(ctx, command, nil, job, job)
+
+		job.mu.Lock()
+		job.err = err
+		job.endedAt = time.Now()
+		job.mu.Unlock()
+		close(job.done)
+		jobBroker.Publish(pubsub.UpdatedEvent, job)
+	}()
+	return job, nil
+}
+
+// Write appends to the output of the job.
+func (j *BackgroundJob) Write(p []byte) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.output = append(j.output, p...)
+	if len(j.output) > 2*maxJobOutput {
+		j.trimOutput()
+	}
+	return len(p), nil
+}
+
+// trimOutput drops the output beyond the last maxJobOutput bytes.
+func (j *BackgroundJob) trimOutput() {
+	if over := len(j.output) - maxJobOutput; over > 0 {
+		j.output = slices.Delete(j.output, 0, over)
+		j.dropped += over
+	}
+}
+
+// ReadOutput returns the output written since the last call. Once the job
+// has ended, reading its output removes it from the background jobs.
+func (j *BackgroundJob) ReadOutput() string {
+	// Whatever the job writes is written before it ends, so this read gets
+	// all of it.
+	if !j.Running() {
+		defer jobs.Del(j.ID)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.trimOutput()
+	var sb strings.Builder
+	if j.read < j.dropped {
+		fmt.Fprintf(&sb, "... [%d bytes of output dropped] ...\n", j.dropped-j.read)
+		j.read = j.dropped
+	}
+	sb.Write(j.output[j.read-j.dropped:])
+	j.read = j.dropped + len(j.output)
+	return sb.String()
+}
+
+// Done returns a channel that is closed when the job ends.
+func (j *BackgroundJob) Done() <-chan struct{} {
+	return j.done
+}
+
+// Running reports whether the job is still running.
+func (j *BackgroundJob) Running() bool {
+	select {
+	case <-j.done:
+		return false
+	default:
+		return true
+	}
+}
+
+// Err returns the error the job ended with, or nil while it runs.
+func (j *BackgroundJob) Err() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.err
+}
+
+// EndedAt returns when the job ended, or the zero time while it runs.
+func (j *BackgroundJob) EndedAt() time.Time {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.endedAt
+}
+
+// Wait waits until the job ends or ctx is done.
+func (j *BackgroundJob) Wait(ctx context.Context) error {
+	select {
+	case <-j.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Kill interrupts the job, along with the programs it started, and waits for
+// it to end.
+func (j *BackgroundJob) Kill() {
+	j.cancel()
+	<-j.done
+}
+
+// GetBackgroundJob returns the background job with the given ID, until its
+// output is read after it ended.
+func GetBackgroundJob(id string) (*BackgroundJob, bool) {
+	return jobs.Get(id)
+}
+
+// ListBackgroundJobs returns the background jobs that are running or whose
+// final output wasn't read yet, oldest first.
+func ListBackgroundJobs() []*BackgroundJob {
+	list := slices.Collect(jobs.Seq())
+	slices.SortFunc(list, func(a, b *BackgroundJob) int {
+		return cmp.Compare(jobNumber(a.ID), jobNumber(b.ID))
+	})
+	return list
+}
+
+// KillBackgroundJobs kills all background jobs that are still running.
+func KillBackgroundJobs() {
+	var wg sync.WaitGroup
+	for job := range jobs.Seq() {
+		if job.Running() {
+			wg.Go(job.Kill)
+		}
+	}
+	wg.Wait()
+}
+
+// SubscribeJobEvents returns a channel that receives an event when a
+// background job starts or ends.
+func SubscribeJobEvents(ctx context.Context) <-chan pubsub.Event[*BackgroundJob] {
+	return jobBroker.Subscribe(ctx)
+}
+
+func jobNumber(id string) int {
+	n, _ := strconv.Atoi(strings.TrimPrefix(id, "job-"))
+	return n
+}
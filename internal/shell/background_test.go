@@ -0,0 +1,99 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackgroundJob(t *testing.T) {
+	t.Run("output and exit code", func(t *testing.T) {
+		shell := NewShell(&Options{WorkingDir: t.TempDir()})
+		job, err := shell.StartBackground("echo hello; echo oops >&2; exit 3")
+		require.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Second)
+		defer cancel()
+		require.NoError(t, job.Wait(ctx))
+		require.False(t, job.Running())
+		require.Equal(t, 3, ExitCode(job.Err()))
+
+		found, ok := GetBackgroundJob(job.ID)
+		require.True(t, ok)
+		require.Same(t, job, found)
+
+		require.Equal(t, "hello\noops\n", job.ReadOutput())
+		require.Empty(t, job.ReadOutput())
+
+		// The job is forgotten once its final output was read.
+		_, ok = GetBackgroundJob(job.ID)
+		require.False(t, ok)
+		require.NotContains(t, ListBackgroundJobs(), job)
+	})
+
+	t.Run("is kept until its output is read", func(t *testing.T) {
+		shell := NewShell(&Options{WorkingDir: t.TempDir()})
+		job, err := shell.StartBackground("echo hello")
+		require.NoError(t, err)
+		<-job.Done()
+
+		_, ok := GetBackgroundJob(job.ID)
+		require.True(t, ok)
+		require.Equal(t, "hello\n", job.ReadOutput())
+		_, ok = GetBackgroundJob(job.ID)
+		require.False(t, ok)
+	})
+
+	t.Run("does not change the shell", func(t *testing.T) {
+		dir := t.TempDir()
+		shell := NewShell(&Options{WorkingDir: dir})
+		job, err := shell.StartBackground("cd ..; export FOO=bar")
+		require.NoError(t, err)
+		<-job.Done()
+		require.NoError(t, job.Err())
+		require.Equal(t, dir, shell.GetWorkingDir())
+		require.NotContains(t, shell.GetEnv(), "FOO=bar")
+	})
+
+	t.Run("kill", func(t *testing.T) {
+		shell := NewShell(&Options{WorkingDir: t.TempDir()})
+		job, err := shell.StartBackground("sleep 60")
+		require.NoError(t, err)
+		require.True(t, job.Running())
+		require.Contains(t, ListBackgroundJobs(), job)
+
+		KillBackgroundJobs()
+		require.False(t, job.Running())
+		require.True(t, IsInterrupt(job.Err()))
+	})
+
+	t.Run("invalid command", func(t *testing.T) {
+		shell := NewShell(&Options{WorkingDir: t.TempDir()})
+		_, err := shell.StartBackground("echo 'unclosed")
+		require.ErrorContains(t, err, "could not parse command")
+	})
+}
+
+func TestBackgroundJobOutputLimit(t *testing.T) {
+	job := &BackgroundJob{}
+	job.Write(make([]byte, maxJobOutput))
+	job.Write([]byte("tail"))
+	// The buffer isn't trimmed on every write past the limit.
+	require.Len(t, job.output, maxJobOutput+4)
+
+	output := job.ReadOutput()
+	require.Contains(t, output, "[4 bytes of output dropped]")
+	require.Len(t, job.output, maxJobOutput)
+	require.Greater(t, len(output), maxJobOutput)
+
+	job.Write([]byte("more"))
+	require.Equal(t, "more", job.ReadOutput())
+
+	// Writes trim it once it doubles.
+	job.Write(make([]byte, 2*maxJobOutput))
+	require.Len(t, job.output, maxJobOutput)
+	require.Contains(t, job.ReadOutput(), fmt.Sprintf("[%d bytes of output dropped]", maxJobOutput))
+}
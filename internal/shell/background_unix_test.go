@@ -0,0 +1,33 @@
+//go:build !windows
+
+package shell
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBackgroundJobKillsProcessGroup(t *testing.T) {
+	shell := NewShell(&Options{WorkingDir: t.TempDir()})
+	// The shell ignores interrupts and its child keeps the output open, so
+	// the job only ends if the whole group is killed.
+	job, err := shell.StartBackground(`sh -c 'trap "" INT; sleep 60 & echo started; wait'`)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return job.ReadOutput() == "started\n"
+	}, 10*time.Second, 10*time.Millisecond)
+
+	killed := make(chan struct{})
+	go func() {
+		job.Kill()
+		close(killed)
+	}()
+	select {
+	case <-killed:
+	case <-time.After(10 * time.Second):
+		t.Fatal("the job's programs weren't killed")
+	}
+	require.False(t, job.Running())
+}
@@ -0,0 +1,518 @@
+package shell
+
+import (
+	"fmt"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+
+	"mvdan.cc/sh/v3/syntax"
+)
+
+// Decision is what a CommandPolicy decides about a command line.
+type Decision int
+
+const (
+	// DecisionAsk means the user has to be asked before running it.
+	DecisionAsk Decision = iota
+	// DecisionAllow means it can run without asking.
+	DecisionAllow
+	// DecisionDeny means it must not run.
+	DecisionDeny
+	// DecisionUnlisted means it is neither allowed nor known to be risky.
+	// It needs asking like DecisionAsk, unless it runs where it can't do
+	// harm.
+	DecisionUnlisted
+)
+
+func (d Decision) String() string {
+	switch d {
+	case DecisionAllow:
+		return "allow"
+	case DecisionDeny:
+		return "deny"
+	case DecisionUnlisted:
+		return "unlisted"
+	default:
+		return "ask"
+	}
+}
+
+// severity orders the decisions, the decision about a command line is the
+// most severe one about its parts.
+func (d Decision) severity() int {
+	switch d {
+	case DecisionAllow:
+		return 0
+	case DecisionUnlisted:
+		return 1
+	case DecisionAsk:
+		return 2
+	default:
+		return 3
+	}
+}
+
+// maxPolicyDepth limits how deep scripts run with "sh -c" are looked into.
+const maxPolicyDepth = 3
+
+// unsafeOptions are the options that make a command run other programs or
+// write files, so it needs asking even when it is allowed.
+var unsafeOptions = map[string][]string{
+	"file": {"-C", "--compile"},
+	"find": {"-delete", "-exec", "-execdir", "-ok", "-okdir", "-fprint", "-fprint0", "-fprintf", "-fls"},
+	"git":  {"--output"},
+	"perl": {"-i"},
+	"rg":   {"--pre", "--pre-glob"},
+	"sed":  {"-i", "--in-place"},
+	"sort": {"-o", "--output", "--compress-program"},
+}
+
+// CommandPolicy decides about a command line by looking at every simple
+// command in it, including those in pipelines, lists, subshells and command
+// substitutions. A command line is denied if any of its commands is blocked,
+// and allowed only if all of them are allowed and it writes no files. It
+// needs asking if any of its commands is known to be risky, and is unlisted
+// otherwise.
+//
+// Commands run through env, nice, nohup, time, timeout and command, command
+// lines split by "env -S", and scripts run with "sh -c", are looked at in
+// place of the wrapper. Allowed
+// commands given options that run programs or write files, like "sort -o",
+// need asking.
+type CommandPolicy struct {
+	// Allow lists the commands that can run without asking. A rule is the
+	// leading words of a command, e.g. "git status" allows "git status -s"
+	// but not "git stash".
+	Allow []string
+	// Ask lists the commands that always need asking, as they can do harm
+	// even where unlisted commands can run without asking, e.g. "rm". Rules
+	// are like those of Allow.
+	Ask []string
+	// Block decides which commands are denied.
+	Block []BlockFunc
+}
+
+// Evaluate decides about the command line. Unless it is allowed, it also
+// returns the command the decision was made for.
+func (p *CommandPolicy) Evaluate(command string) (Decision, string) {
+	return p.evaluateScript(command, 0)
+}
+
+// Commands returns the simple commands of a command line, including those in
+// pipelines, lists, subshells, command substitutions and scripts run with
+// "sh -c", each followed by its redirections. Quotes and escapes are removed
+// from literal words. Commands run through wrappers like env and timeout are
+// also returned without the wrapper. It returns false if the command line
+// can't be parsed, along with the commands found until then.
+func Commands(command string) ([]string, bool) {
+	var commands []string
+	ok := appendCommands(&commands, command, 0, false)
+	return commands, ok
+}
+
+// NormalizedCommands returns the commands of a command line like Commands,
+// but without the variable assignments before them and with the names of
+// the programs they run reduced to their base names, so "FOO=1 /bin/rm x"
+// gives "rm x".
+func NormalizedCommands(command string) ([]string, bool) {
+	var commands []string
+	ok := appendCommands(&commands, command, 0, true)
+	return commands, ok
+}
+
+func appendCommands(commands *[]string, script string, depth int, normalize bool) bool {
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	if err != nil {
+		return false
+	}
+
+	ok := true
+	syntax.Walk(file, func(node syntax.Node) bool {
+		stmt, isStmt := node.(*syntax.Stmt)
+		if !isStmt {
+			return true
+		}
+		redirects := make([]string, len(stmt.Redirs))
+		for i, redirect := range stmt.Redirs {
+			if redirect.N != nil {
+				redirects[i] = redirect.N.Value
+			}
+			redirects[i] += redirect.Op.String() + wordString(redirect.Word)
+		}
+
+		switch cmd := stmt.Cmd.(type) {
+		case *syntax.CallExpr:
+			var words []string
+			if !normalize {
+				for _, assign := range cmd.Assigns {
+					words = append(words, nodeString(assign))
+				}
+			}
+			args := make([]string, len(cmd.Args))
+			literal := make([]bool, len(cmd.Args))
+			for i, word := range cmd.Args {
+				args[i], literal[i] = wordLiteral(word)
+				if !literal[i] {
+					args[i] = wordString(word)
+				}
+			}
+			start, split := unwrapCommand(args)
+			if normalize {
+				// With -S, the program is in the split command line.
+				for _, i := range []int{0, start} {
+					if i < len(args) && literal[i] && (i == 0 || !split) {
+						args[i] = programName(args[i])
+					}
+				}
+			}
+			*commands = append(*commands, strings.Join(slices.Concat(words, args, redirects), " "))
+
+			if split {
+				script, isScript := splitCommand(args[start:], literal[start:])
+				if !isScript || depth >= maxPolicyDepth || !appendCommands(commands, script, depth+1, normalize) {
+					ok = false
+				}
+				break
+			}
+			if start > 0 && start < len(args) {
+				*commands = append(*commands, strings.Join(slices.Concat(args[start:], redirects), " "))
+			}
+			if start < len(args) {
+				if script, isScript := shellScript(args[start:], literal[start:]); isScript {
+					if depth >= maxPolicyDepth || !appendCommands(commands, script, depth+1, normalize) {
+						ok = false
+					}
+				}
+			}
+		case *syntax.DeclClause, *syntax.TestClause, *syntax.ArithmCmd, *syntax.LetClause:
+			*commands = append(*commands, strings.Join(slices.Concat([]string{nodeString(cmd)}, redirects), " "))
+		default:
+			// The statements of compound commands are walked, their
+			// redirections apply to all of them.
+			if len(redirects) > 0 {
+				*commands = append(*commands, strings.Join(redirects, " "))
+			}
+		}
+		return true
+	})
+	return ok
+}
+
+func (p *CommandPolicy) evaluateScript(script string, depth int) (Decision, string) {
+	file, err := syntax.NewParser().Parse(strings.NewReader(script), "")
+	if err != nil {
+		return DecisionAsk, script
+	}
+
+	decision, reason := DecisionAllow, ""
+	syntax.Walk(file, func(node syntax.Node) bool {
+		d, r := DecisionAllow, ""
+		switch node := node.(type) {
+		case *syntax.CallExpr:
+			d, r = p.evaluateCall(node, depth)
+		case *syntax.Redirect:
+			if writesFile(node) {
+				d, r = DecisionAsk, "redirection to "+wordString(node.Word)
+			}
+		case *syntax.DeclClause:
+			d, r = DecisionAsk, node.Variant.Value
+		case *syntax.CoprocClause:
+			d, r = DecisionAsk, "coproc"
+		}
+		// Deny wins over ask, which wins over unlisted and allow.
+		if d.severity() > decision.severity() {
+			decision, reason = d, r
+		}
+		return decision != DecisionDeny
+	})
+	return decision, reason
+}
+
+func (p *CommandPolicy) evaluateCall(call *syntax.CallExpr, depth int) (Decision, string) {
+	if len(call.Assigns) > 0 {
+		// Variables like PATH change what later commands run.
+		return DecisionAsk, call.Assigns[0].Name.Value + "="
+	}
+	if len(call.Args) == 0 {
+		return DecisionAllow, ""
+	}
+
+	args := make([]string, len(call.Args))
+	literal := make([]bool, len(call.Args))
+	for i, word := range call.Args {
+		args[i], literal[i] = wordLiteral(word)
+		if !literal[i] {
+			args[i] = wordString(word)
+		}
+	}
+	if !literal[0] {
+		// Nothing is known about a command whose name is computed.
+		return DecisionAsk, wordString(call.Args[0])
+	}
+
+	start, split := unwrapCommand(args)
+	if split {
+		script, ok := splitCommand(args[start:], literal[start:])
+		if !ok || depth >= maxPolicyDepth {
+			return DecisionAsk, strings.Join(args, " ")
+		}
+		return p.evaluateScript(script, depth+1)
+	}
+	if start < len(args) && !literal[start] {
+		return DecisionAsk, wordString(call.Args[start])
+	}
+	args, literal = args[start:], literal[start:]
+	if len(args) == 0 {
+		return DecisionAllow, ""
+	}
+	display := strings.Join(args, " ")
+
+	for _, block := range p.Block {
+		if block(args) {
+			return DecisionDeny, display
+		}
+	}
+
+	if script, ok := shellScript(args, literal); ok {
+		if depth >= maxPolicyDepth {
+			return DecisionAsk, display
+		}
+		return p.evaluateScript(script, depth+1)
+	}
+
+	if hasUnsafeOption(args, literal) || matchesRule(p.Ask, args, literal) {
+		return DecisionAsk, display
+	}
+	if matchesRule(p.Allow, args, literal) {
+		return DecisionAllow, ""
+	}
+	return DecisionUnlisted, display
+}
+
+// matchesRule reports whether the leading words of the command are one of
+// the rules.
+func matchesRule(rules []string, args []string, literal []bool) bool {
+	return slices.ContainsFunc(rules, func(rule string) bool {
+		words := strings.Fields(rule)
+		return len(words) > 0 && len(words) <= len(args) &&
+			slices.Equal(words, args[:len(words)]) && !slices.Contains(literal[:len(words)], false)
+	})
+}
+
+// hasUnsafeOption reports whether the command is given one of its
+// unsafeOptions, or arguments that could expand to one.
+func hasUnsafeOption(args []string, literal []bool) bool {
+	options, ok := unsafeOptions[args[0]]
+	if !ok {
+		return false
+	}
+	for i, arg := range args[1:] {
+		if !literal[i+1] {
+			return true
+		}
+		if arg == "--" {
+			return false
+		}
+		for _, option := range options {
+			if strings.HasPrefix(option, "--") {
+				if arg == option || strings.HasPrefix(arg, option+"=") {
+					return true
+				}
+			} else if !strings.HasPrefix(arg, "--") && strings.HasPrefix(arg, "-") &&
+				strings.Contains(arg[1:], option[1:]) {
+				// Short options can be grouped, as in "sort -ro out".
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unwrapCommand returns the index of the command run by wrappers like env
+// and timeout, or 0 if the command isn't a wrapper. When env is given a
+// command line to split with -S, it returns the index of that option and
+// true instead, see splitCommand.
+func unwrapCommand(args []string) (int, bool) {
+	i := 0
+	for i < len(args) {
+		wrapper, name := i, programName(args[i])
+		switch name {
+		case "env", "nice", "nohup", "time", "timeout", "command":
+		default:
+			return i, false
+		}
+		i++
+		for i < len(args) && strings.HasPrefix(args[i], "-") {
+			if name == "env" && isSplitString(args[i]) {
+				return i, true
+			}
+			// Options of these wrappers that take a value.
+			if (name == "env" && (args[i] == "-u" || args[i] == "-C")) ||
+				(name == "nice" && args[i] == "-n") ||
+				(name == "timeout" && (args[i] == "-s" || args[i] == "-k")) {
+				i++
+			}
+			i++
+		}
+		switch name {
+		case "env":
+			for i < len(args) && strings.Contains(args[i], "=") {
+				i++
+			}
+		case "timeout":
+			i++ // the duration
+		}
+		if i >= len(args) {
+			// The wrapper runs nothing, so it is the command.
+			return wrapper, false
+		}
+	}
+	return i, false
+}
+
+// isSplitString reports whether the option of env is -S or --split-string,
+// possibly abbreviated or grouped with other options.
+func isSplitString(arg string) bool {
+	if name, ok := strings.CutPrefix(arg, "--"); ok {
+		name, _, _ = strings.Cut(name, "=")
+		return name != "" && strings.HasPrefix("split-string", name)
+	}
+	for _, c := range arg[1:] {
+		switch c {
+		case 'S':
+			return true
+		case 'u', 'C':
+			// The rest of the option is their value.
+			return false
+		}
+	}
+	return false
+}
+
+// splitCommand returns the command line run by env when given -S, whose
+// value env splits into words itself: env followed by the value and the
+// arguments after it. args starts with the -S option. It returns false if the
+// value or an argument isn't a literal.
+func splitCommand(args []string, literal []bool) (string, bool) {
+	option, rest := args[0], args[1:]
+	var value string
+	if name, v, ok := strings.Cut(option, "="); ok && strings.HasPrefix(name, "--") {
+		value = v
+	} else if i := strings.IndexByte(option, 'S'); !strings.HasPrefix(option, "--") && i+1 < len(option) {
+		value = option[i+1:]
+	} else if len(rest) > 0 {
+		value, rest, literal = rest[0], rest[1:], literal[1:]
+	} else {
+		return "", false
+	}
+	if slices.Contains(literal, false) {
+		return "", false
+	}
+
+	words := []string{"env", value}
+	for _, arg := range rest {
+		quoted, err := syntax.Quote(arg, syntax.LangBash)
+		if err != nil {
+			return "", false
+		}
+		words = append(words, quoted)
+	}
+	return strings.Join(words, " "), true
+}
+
+// programName returns the name of the program run by a command, without the
+// directory it was given with, as in "/bin/rm".
+func programName(arg string) string {
+	if !strings.Contains(arg, "/") {
+		return arg
+	}
+	return path.Base(arg)
+}
+
+// shellScript returns the script run by a shell with -c.
+func shellScript(args []string, literal []bool) (string, bool) {
+	switch programName(args[0]) {
+	case "sh", "bash", "dash", "zsh":
+	default:
+		return "", false
+	}
+	i := slices.Index(args, "-c")
+	if i < 0 || i+1 >= len(args) || !literal[i+1] {
+		return "", false
+	}
+	return args[i+1], true
+}
+
+// writesFile reports whether the redirection writes to a file.
+func writesFile(redirect *syntax.Redirect) bool {
+	switch redirect.Op {
+	case syntax.RdrOut, syntax.AppOut, syntax.RdrInOut, syntax.ClbOut, syntax.RdrAll, syntax.AppAll:
+		target, _ := wordLiteral(redirect.Word)
+		return target != "/dev/null"
+	case syntax.DplOut:
+		// >&2 duplicates a file descriptor, >&file writes to a file.
+		target, _ := wordLiteral(redirect.Word)
+		_, err := strconv.Atoi(target)
+		return err != nil && target != "-"
+	default:
+		return false
+	}
+}
+
+// wordLiteral returns the value of a word made only of literals and quoted
+// literals, and whether it is one.
+func wordLiteral(word *syntax.Word) (string, bool) {
+	var sb strings.Builder
+	for _, part := range word.Parts {
+		switch part := part.(type) {
+		case *syntax.Lit:
+			sb.WriteString(unescape(part.Value))
+		case *syntax.SglQuoted:
+			sb.WriteString(part.Value)
+		case *syntax.DblQuoted:
+			for _, part := range part.Parts {
+				lit, ok := part.(*syntax.Lit)
+				if !ok {
+					return "", false
+				}
+				sb.WriteString(lit.Value)
+			}
+		default:
+			return "", false
+		}
+	}
+	return sb.String(), true
+}
+
+// unescape removes the backslashes of an unquoted literal.
+func unescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var sb strings.Builder
+	escaped := false
+	for _, r := range s {
+		if r == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// wordString prints a word as it was written.
+func wordString(word *syntax.Word) string {
+	return nodeString(word)
+}
+
+func nodeString(node syntax.Node) string {
+	var sb strings.Builder
+	if err := syntax.NewPrinter().Print(&sb, node); err != nil {
+		return fmt.Sprint(node)
+	}
+	return sb.String()
+}
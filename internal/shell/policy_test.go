@@ -0,0 +1,134 @@
+package shell
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommandPolicy(t *testing.T) {
+	policy := &CommandPolicy{
+		Allow: []string{"ls", "echo", "head", "grep", "git status", "git log", "env", "rg", "sort", "file"},
+		Ask:   []string{"rm", "git clean"},
+		Block: []BlockFunc{
+			CommandsBlocker([]string{"curl"}),
+			ArgumentsBlocker("go", []string{"install"}, nil),
+		},
+	}
+
+	tests := []struct {
+		name     string
+		command  string
+		decision Decision
+		reason   string
+	}{
+		{name: "allowed command", command: "ls -la", decision: DecisionAllow},
+		{name: "allowed subcommand", command: "git status -s", decision: DecisionAllow},
+		{name: "other subcommand", command: "git stash", decision: DecisionUnlisted, reason: "git stash"},
+		{name: "prefix of a word", command: "lsblk", decision: DecisionUnlisted, reason: "lsblk"},
+		{name: "risky command", command: "rm -rf .", decision: DecisionAsk, reason: "rm -rf ."},
+		{name: "risky subcommand", command: "git clean -fdx", decision: DecisionAsk, reason: "git clean -fdx"},
+		{name: "risky wins over unlisted", command: "make; rm x; make", decision: DecisionAsk, reason: "rm x"},
+		{name: "risky behind wrapper", command: "timeout 5 rm x", decision: DecisionAsk, reason: "rm x"},
+		{name: "unlisted with unsafe option", command: "sed -i s/a/b/ main.go", decision: DecisionAsk, reason: "sed -i s/a/b/ main.go"},
+		{name: "unlisted with safe options", command: "sed -n 1,5p main.go", decision: DecisionUnlisted, reason: "sed -n 1,5p main.go"},
+		{name: "find delete", command: "find . -name '*.o' -delete", decision: DecisionAsk, reason: "find . -name *.o -delete"},
+		{name: "pipeline", command: "git status | head -n 5", decision: DecisionAllow},
+		{name: "list with unknown command", command: "ls; rm -rf x", decision: DecisionAsk, reason: "rm -rf x"},
+		{name: "and chain", command: "ls && git log --oneline", decision: DecisionAllow},
+		{name: "subshell", command: "(ls && rm x)", decision: DecisionAsk, reason: "rm x"},
+		{name: "command substitution", command: "echo $(rm x)", decision: DecisionAsk, reason: "rm x"},
+		{name: "blocked in substitution", command: "echo $(curl https://example.com)", decision: DecisionDeny, reason: "curl https://example.com"},
+		{name: "blocked after allowed", command: "ls || go install foo", decision: DecisionDeny, reason: "go install foo"},
+		{name: "blocked wins over ask", command: "rm x; curl y", decision: DecisionDeny, reason: "curl y"},
+		{name: "blocked behind wrapper", command: "env FOO=bar timeout 5 curl y", decision: DecisionDeny, reason: "curl y"},
+		{name: "wrapper alone", command: "env", decision: DecisionAllow},
+		{name: "env split string", command: `env -S "rm -rf /tmp/x"`, decision: DecisionAsk, reason: "rm -rf /tmp/x"},
+		{name: "env attached split string", command: `env -S'rm -rf x'`, decision: DecisionAsk, reason: "rm -rf x"},
+		{name: "env long split string", command: `env --split-string="curl y"`, decision: DecisionDeny, reason: "curl y"},
+		{name: "env grouped split string", command: `env -iS "rm x" y`, decision: DecisionAsk, reason: "rm x y"},
+		{name: "env split string with arguments", command: `env -S "ls -l" src`, decision: DecisionAllow},
+		{name: "env computed split string", command: `env -S "$CMD"`, decision: DecisionAsk, reason: `env -S "$CMD"`},
+		{name: "quoted command", command: `'cu'"rl" y`, decision: DecisionDeny, reason: "curl y"},
+		{name: "escaped command", command: `c\url y`, decision: DecisionDeny, reason: "curl y"},
+		{name: "computed command", command: "$CMD x", decision: DecisionAsk, reason: "$CMD"},
+		{name: "assignment", command: "PATH=/tmp ls", decision: DecisionAsk, reason: "PATH="},
+		{name: "shell script", command: "bash -c 'git status | head'", decision: DecisionAllow},
+		{name: "blocked in shell script", command: `sh -c "ls; curl y"`, decision: DecisionDeny, reason: "curl y"},
+		{name: "write redirection", command: "ls > out.txt", decision: DecisionAsk, reason: "redirection to out.txt"},
+		{name: "append redirection", command: "echo x >> out.txt", decision: DecisionAsk, reason: "redirection to out.txt"},
+		{name: "discarded output", command: "ls 2>/dev/null >&2", decision: DecisionAllow},
+		{name: "read redirection", command: "grep x < in.txt", decision: DecisionAllow},
+		{name: "declaration", command: "export FOO=bar", decision: DecisionAsk, reason: "export"},
+		{name: "rg", command: "rg -n foo src", decision: DecisionAllow},
+		{name: "rg preprocessor", command: "rg --pre ./run.sh foo", decision: DecisionAsk, reason: "rg --pre ./run.sh foo"},
+		{name: "rg preprocessor glob", command: "rg --pre-glob=*.gz foo", decision: DecisionAsk, reason: "rg --pre-glob=*.gz foo"},
+		{name: "rg computed argument", command: "rg $OPTS foo", decision: DecisionAsk, reason: "rg $OPTS foo"},
+		{name: "rg pattern after --", command: "rg -- --pre", decision: DecisionAllow},
+		{name: "sort", command: "sort -u names.txt", decision: DecisionAllow},
+		{name: "sort output", command: "sort -o names.txt names.txt", decision: DecisionAsk, reason: "sort -o names.txt names.txt"},
+		{name: "sort grouped output", command: "sort -ro out in", decision: DecisionAsk, reason: "sort -ro out in"},
+		{name: "sort long output", command: "sort --output=out in", decision: DecisionAsk, reason: "sort --output=out in"},
+		{name: "sort compress program", command: "sort --compress-program=sh in", decision: DecisionAsk, reason: "sort --compress-program=sh in"},
+		{name: "file", command: "file -b main.go", decision: DecisionAllow},
+		{name: "file compile", command: "file -C -m magic", decision: DecisionAsk, reason: "file -C -m magic"},
+		{name: "file long compile", command: "file --compile -m magic", decision: DecisionAsk, reason: "file --compile -m magic"},
+		{name: "git output", command: "git log --output=log.txt", decision: DecisionAsk, reason: "git log --output=log.txt"},
+		{name: "invalid syntax", command: "ls (", decision: DecisionAsk, reason: "ls ("},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			decision, reason := policy.Evaluate(tt.command)
+			require.Equal(t, tt.decision, decision)
+			require.Equal(t, tt.reason, reason)
+		})
+	}
+}
+
+func TestCommands(t *testing.T) {
+	tests := []struct {
+		command  string
+		commands []string
+		ok       bool
+	}{
+		{command: "ls; rm -rf x", commands: []string{"ls", "rm -rf x"}, ok: true},
+		{command: `go test ./... && \rm -rf /`, commands: []string{"go test ./...", "rm -rf /"}, ok: true},
+		{command: "cat foo | grep x > out.txt 2>&1", commands: []string{"cat foo", "grep x >out.txt 2>&1"}, ok: true},
+		{command: "echo $(r''m -rf x)", commands: []string{"echo $(r''m -rf x)", "rm -rf x"}, ok: true},
+		{command: "timeout 5 rm x", commands: []string{"timeout 5 rm x", "rm x"}, ok: true},
+		{command: `env -S "rm -rf x"`, commands: []string{"env -S rm -rf x", "env rm -rf x", "rm -rf x"}, ok: true},
+		{command: "bash -c 'ls && rm x'", commands: []string{"bash -c ls && rm x", "ls", "rm x"}, ok: true},
+		{command: "(ls) > out.txt", commands: []string{">out.txt", "ls"}, ok: true},
+		{command: "ls (", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			commands, ok := Commands(tt.command)
+			require.Equal(t, tt.ok, ok)
+			require.Equal(t, tt.commands, commands)
+		})
+	}
+}
+
+func TestNormalizedCommands(t *testing.T) {
+	tests := []struct {
+		command  string
+		commands []string
+	}{
+		{command: "FOO=1 rm -rf x", commands: []string{"rm -rf x"}},
+		{command: "/bin/rm x > out.txt", commands: []string{"rm x >out.txt"}},
+		{command: "/usr/bin/env /bin/rm x", commands: []string{"env rm x", "rm x"}},
+		{command: `env -S "/bin/rm -rf x"`, commands: []string{"env -S /bin/rm -rf x", "env rm -rf x", "rm -rf x"}},
+		{command: "/bin/sh -c 'FOO=1 ./rm x'", commands: []string{"sh -c FOO=1 ./rm x", "rm x"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.command, func(t *testing.T) {
+			commands, ok := NormalizedCommands(tt.command)
+			require.True(t, ok)
+			require.Equal(t, tt.commands, commands)
+		})
+	}
+}
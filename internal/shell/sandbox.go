@@ -0,0 +1,114 @@
+package shell
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"mvdan.cc/sh/v3/interp"
+)
+
+// Sandbox restricts what the commands run by a shell can change. Commands
+// can only write to the writable paths and, unless AllowNetwork is set,
+// can't access the network.
+//
+// The built-in sandbox runs every command in new user, mount, PID and
+// network namespaces, where it can only see its own processes, and refuses
+// to create Unix domain sockets, so it can't reach host services through
+// their sockets. It is only available on Linux. Setting Command wraps every
+// command with it instead, e.g. with bubblewrap, on any platform.
+//
+// The shell builtins run in-process and the core utilities that are
+// otherwise emulated are run from the system instead, so they are sandboxed
+// too. Redirections to files outside of the writable paths are refused.
+type Sandbox struct {
+	WritablePaths []string
+	AllowNetwork  bool
+	Command       []string
+}
+
+// Contained reports whether the sandbox is known to keep commands off the
+// network, away from host services and processes, and from writing outside
+// of the writable paths. That is only the case of the built-in sandbox
+// without network access, on the platforms where it refuses Unix domain
+// sockets. Wrapper commands and the network can't be vouched for.
+func (sb *Sandbox) Contained() bool {
+	return sb != nil && !sb.AllowNetwork && len(sb.Command) == 0 && refusesUnixSockets()
+}
+
+// errNotWritable is returned when a redirection writes outside of the
+// writable paths of the sandbox.
+var errNotWritable = errors.New("not writable in the sandbox")
+
+// sandboxDevices can always be written to by redirections.
+var sandboxDevices = []string{"/dev/null", "/dev/stdout", "/dev/stderr", "/dev/tty"}
+
+// writable reports whether path is inside one of the writable paths. Symbolic
+// links are resolved, so a link can't point out of the writable paths.
+func (sb *Sandbox) writable(path string) bool {
+	if slices.Contains(sandboxDevices, path) {
+		return true
+	}
+	path = resolvePath(path)
+	return slices.ContainsFunc(sb.WritablePaths, func(dir string) bool {
+		dir = resolvePath(dir)
+		return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+	})
+}
+
+// resolvePath resolves the symbolic links of the part of path that exists.
+func resolvePath(path string) string {
+	path = filepath.Clean(path)
+	var rest []string
+	for {
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			return filepath.Join(append([]string{resolved}, rest...)...)
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return filepath.Join(append([]string{path}, rest...)...)
+		}
+		rest = append([]string{filepath.Base(path)}, rest...)
+		path = parent
+	}
+}
+
+// openHandler refuses redirections that write outside of the writable paths.
+func (sb *Sandbox) openHandler() interp.OpenHandlerFunc {
+	open := interp.DefaultOpenHandler()
+	return func(ctx context.Context, path string, flag int, perm os.FileMode) (io.ReadWriteCloser, error) {
+		if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND) != 0 {
+			abs := path
+			if !filepath.IsAbs(abs) {
+				abs = filepath.Join(interp.HandlerCtx(ctx).Dir, abs)
+			}
+			if !sb.writable(abs) {
+				return nil, &os.PathError{Op: "open", Path: path, Err: errNotWritable}
+			}
+		}
+		return open(ctx, path, flag, perm)
+	}
+}
+
+// execHandler runs the commands in the sandbox.
+func (sb *Sandbox) execHandler() func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+	return func(next interp.ExecHandlerFunc) interp.ExecHandlerFunc {
+		return func(ctx context.Context, args []string) error {
+			if len(sb.Command) > 0 {
+				return next(ctx, append(slices.Clone(sb.Command), args...))
+			}
+			return sb.exec(ctx, args)
+		}
+	}
+}
+
+// sandboxError reports that the sandbox could not run a command.
+func sandboxError(ctx context.Context, err error) error {
+	fmt.Fprintf(interp.HandlerCtx(ctx).Stderr, "sandbox: %v\n", err)
+	return interp.ExitStatus(126)
+}
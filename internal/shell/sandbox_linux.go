@@ -0,0 +1,278 @@
+package shell
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/signal"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+	"mvdan.cc/sh/v3/expand"
+	"mvdan.cc/sh/v3/interp"
+)
+
+// sandboxHelperName is the program name the executable is run with to set
+// up the sandbox of a command. The helper runs in the new namespaces, makes
+// everything but the writable paths read-only and then runs the command,
+// staying around as the init process of the PID namespace.
+const sandboxHelperName = "crush-sandbox"
+
+// sandboxKillTimeout is how long a command has to exit after it was
+// interrupted before it is killed.
+const sandboxKillTimeout = 2 * time.Second
+
+// sandboxHelperConfig is passed to the helper as its first argument.
+type sandboxHelperConfig struct {
+	Path          string   `json:"path"`
+	Dir           string   `json:"dir"`
+	WritablePaths []string `json:"writable_paths"`
+}
+
+func init() {
+	if len(os.Args) > 1 && os.Args[0] == sandboxHelperName {
+		runSandboxHelper()
+	}
+}
+
+// seccompArches are the audit architectures of the platforms the sandbox
+// refuses Unix domain sockets on, see socketFilter. Their system call
+// arguments are little endian.
+var seccompArches = map[string]uint32{
+	"amd64":   unix.AUDIT_ARCH_X86_64,
+	"arm64":   unix.AUDIT_ARCH_AARCH64,
+	"loong64": unix.AUDIT_ARCH_LOONGARCH64,
+	"riscv64": unix.AUDIT_ARCH_RISCV64,
+}
+
+func refusesUnixSockets() bool {
+	_, ok := seccompArches[runtime.GOARCH]
+	return ok
+}
+
+// exec runs the command through the sandbox helper, in new user, mount and
+// PID namespaces and, unless the network is allowed, a new network
+// namespace.
+func (sb *Sandbox) exec(ctx context.Context, args []string) error {
+	hc := interp.HandlerCtx(ctx)
+	path, err := interp.LookPathDir(hc.Dir, hc.Env, args[0])
+	if err != nil {
+		fmt.Fprintln(hc.Stderr, err)
+		return interp.ExitStatus(127)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return sandboxError(ctx, err)
+	}
+	helperConfig, err := json.Marshal(sandboxHelperConfig{
+		Path:          path,
+		Dir:           hc.Dir,
+		WritablePaths: sb.WritablePaths,
+	})
+	if err != nil {
+		return sandboxError(ctx, err)
+	}
+
+	cloneflags := uintptr(unix.CLONE_NEWUSER | unix.CLONE_NEWNS | unix.CLONE_NEWPID)
+	if !sb.AllowNetwork {
+		cloneflags |= unix.CLONE_NEWNET
+	}
+	cmd := exec.Cmd{
+		Path:   self,
+		Args:   append([]string{sandboxHelperName, string(helperConfig)}, args...),
+		Env:    execEnv(hc.Env),
+		Dir:    hc.Dir,
+		Stdin:  hc.Stdin,
+		Stdout: hc.Stdout,
+		Stderr: hc.Stderr,
+		SysProcAttr: &syscall.SysProcAttr{
+			Setpgid:     true,
+			Cloneflags:  cloneflags,
+			UidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}},
+			GidMappings: []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}},
+		},
+	}
+	if err := cmd.Start(); err != nil {
+		return sandboxError(ctx, fmt.Errorf("could not create namespaces: %w", err))
+	}
+	stop := context.AfterFunc(ctx, func() {
+		_ = unix.Kill(-cmd.Process.Pid, unix.SIGINT)
+		time.Sleep(sandboxKillTimeout)
+		_ = unix.Kill(-cmd.Process.Pid, unix.SIGKILL)
+	})
+	defer stop()
+
+	err = cmd.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		// The helper exits with 128 plus the signal that killed the
+		// command, like a shell.
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			return interp.ExitStatus(128 + status.Signal())
+		}
+		return interp.ExitStatus(exitErr.ExitCode())
+	}
+	return err
+}
+
+// execEnv returns the exported variables of env.
+func execEnv(env expand.Environ) []string {
+	var list []string
+	for name, vr := range env.Each {
+		if vr.Exported && vr.IsSet() {
+			list = append(list, name+"="+vr.String())
+		}
+	}
+	return list
+}
+
+// runSandboxHelper sets up the sandbox and runs the command. It runs in the
+// new namespaces, where it has all capabilities. The writable paths are
+// cloned before everything is made read-only and mounted back on top, and
+// /proc is mounted again so only the processes of the sandbox are visible.
+// The capabilities are dropped and Unix domain sockets refused before the
+// command is run, so it can't undo any of it or talk to host services.
+//
+// The helper is the init process of the PID namespace: it reaps the
+// processes left behind by the command and exits like it, which kills them.
+func runSandboxHelper() {
+	// Capabilities and the no_new_privs flag are set per thread.
+	runtime.LockOSThread()
+	err := func() error {
+		var cfg sandboxHelperConfig
+		if err := json.Unmarshal([]byte(os.Args[1]), &cfg); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+
+		if err := unix.Mount("", "/", "", unix.MS_REC|unix.MS_PRIVATE, ""); err != nil {
+			return fmt.Errorf("could not make mounts private: %w", err)
+		}
+		var paths []string
+		var trees []int
+		for _, path := range append([]string{"/dev"}, cfg.WritablePaths...) {
+			tree, err := unix.OpenTree(unix.AT_FDCWD, path, unix.OPEN_TREE_CLONE|unix.O_CLOEXEC|unix.AT_RECURSIVE)
+			if errors.Is(err, unix.ENOENT) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("could not clone %s: %w", path, err)
+			}
+			paths = append(paths, path)
+			trees = append(trees, tree)
+		}
+		if err := unix.Mount("proc", "/proc", "proc", unix.MS_NOSUID|unix.MS_NODEV|unix.MS_NOEXEC, ""); err != nil {
+			return fmt.Errorf("could not mount /proc: %w", err)
+		}
+		if err := unix.MountSetattr(unix.AT_FDCWD, "/", unix.AT_RECURSIVE, &unix.MountAttr{Attr_set: unix.MOUNT_ATTR_RDONLY}); err != nil {
+			return fmt.Errorf("could not make mounts read-only: %w", err)
+		}
+		for i, tree := range trees {
+			if err := unix.MoveMount(tree, "", unix.AT_FDCWD, paths[i], unix.MOVE_MOUNT_F_EMPTY_PATH); err != nil {
+				return fmt.Errorf("could not mount %s: %w", paths[i], err)
+			}
+		}
+		// The working directory may have been mounted over.
+		if err := os.Chdir(cfg.Dir); err != nil {
+			return err
+		}
+
+		for c := 0; c <= unix.CAP_LAST_CAP; c++ {
+			if err := unix.Prctl(unix.PR_CAPBSET_DROP, uintptr(c), 0, 0, 0); err != nil && !errors.Is(err, unix.EINVAL) {
+				return fmt.Errorf("could not drop capabilities: %w", err)
+			}
+		}
+		if err := unix.Prctl(unix.PR_SET_NO_NEW_PRIVS, 1, 0, 0, 0); err != nil {
+			return fmt.Errorf("could not drop privileges: %w", err)
+		}
+		if arch, ok := seccompArches[runtime.GOARCH]; ok {
+			if err := installSocketFilter(arch); err != nil {
+				return fmt.Errorf("could not refuse Unix domain sockets: %w", err)
+			}
+		}
+
+		// Interrupts are sent to the whole process group, the command
+		// handles them, the helper waits for it to exit.
+		signal.Notify(make(chan os.Signal, 1), unix.SIGINT, unix.SIGTERM, unix.SIGQUIT)
+		proc, err := os.StartProcess(cfg.Path, os.Args[2:], &os.ProcAttr{
+			Env:   os.Environ(),
+			Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+		})
+		if err != nil {
+			return err
+		}
+		for {
+			var status unix.WaitStatus
+			pid, err := unix.Wait4(-1, &status, 0, nil)
+			if errors.Is(err, unix.EINTR) {
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if pid != proc.Pid {
+				continue
+			}
+			if status.Signaled() {
+				os.Exit(128 + int(status.Signal()))
+			}
+			os.Exit(status.ExitStatus())
+		}
+	}()
+	fmt.Fprintf(os.Stderr, "sandbox: %v\n", err)
+	os.Exit(126)
+}
+
+// installSocketFilter installs socketFilter on every thread of the process.
+func installSocketFilter(arch uint32) error {
+	filter := socketFilter(arch)
+	prog := unix.SockFprog{Len: uint16(len(filter)), Filter: &filter[0]}
+	_, _, errno := unix.Syscall(unix.SYS_SECCOMP, unix.SECCOMP_SET_MODE_FILTER, unix.SECCOMP_FILTER_FLAG_TSYNC, uintptr(unsafe.Pointer(&prog)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// socketFilter returns a seccomp filter refusing to create Unix domain
+// sockets, through which commands could reach host services like the Docker
+// daemon or the SSH agent. io_uring, which can create sockets too, is
+// refused, and so are system calls of other architectures, which the filter
+// can't check.
+func socketFilter(arch uint32) []unix.SockFilter {
+	const (
+		archOffset = 4  // seccomp_data.arch
+		nrOffset   = 0  // seccomp_data.nr
+		argOffset  = 16 // the low half of seccomp_data.args[0]
+		// x32 system calls of x86-64 have this bit set.
+		x32SyscallBit = 0x40000000
+	)
+	stmt := func(code uint16, k uint32) unix.SockFilter {
+		return unix.SockFilter{Code: code, K: k}
+	}
+	jump := func(code uint16, k uint32, jt, jf uint8) unix.SockFilter {
+		return unix.SockFilter{Code: code, Jt: jt, Jf: jf, K: k}
+	}
+	return []unix.SockFilter{
+		/* 0 */ stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, archOffset),
+		/* 1 */ jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, arch, 1, 0),
+		/* 2 */ stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_KILL_PROCESS),
+		/* 3 */ stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, nrOffset),
+		/* 4 */ jump(unix.BPF_JMP|unix.BPF_JGE|unix.BPF_K, x32SyscallBit, 5, 0),
+		/* 5 */ jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.SYS_IO_URING_SETUP, 4, 0),
+		/* 6 */ jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.SYS_SOCKET, 0, 4),
+		/* 7 */ stmt(unix.BPF_LD|unix.BPF_W|unix.BPF_ABS, argOffset),
+		/* 8 */ jump(unix.BPF_JMP|unix.BPF_JEQ|unix.BPF_K, unix.AF_UNIX, 0, 2),
+		/* 9 */ stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ERRNO|uint32(unix.EACCES)),
+		/* 10 */ stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ERRNO|uint32(unix.ENOSYS)),
+		/* 11 */ stmt(unix.BPF_RET|unix.BPF_K, unix.SECCOMP_RET_ALLOW),
+	}
+}
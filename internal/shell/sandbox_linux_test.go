@@ -0,0 +1,124 @@
+package shell
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newSandboxedShell(t *testing.T, sandbox *Sandbox) *Shell {
+	t.Helper()
+	dir := t.TempDir()
+	sandbox.WritablePaths = append(sandbox.WritablePaths, dir)
+	shell := NewShell(&Options{WorkingDir: dir, Sandbox: sandbox})
+	// Some systems don't allow unprivileged user namespaces.
+	if _, stderr, err := shell.Exec(t.Context(), "true"); err != nil {
+		t.Skipf("sandbox is not available: %s", stderr)
+	}
+	return shell
+}
+
+func TestSandbox(t *testing.T) {
+	outside := t.TempDir()
+	shell := newSandboxedShell(t, &Sandbox{})
+
+	t.Run("writes to the working directory", func(t *testing.T) {
+		_, _, err := shell.Exec(t.Context(), "touch inside && sh -c 'echo hello > inside'")
+		require.NoError(t, err)
+		data, err := os.ReadFile(filepath.Join(shell.GetWorkingDir(), "inside"))
+		require.NoError(t, err)
+		require.Equal(t, "hello\n", string(data))
+	})
+
+	t.Run("does not write elsewhere", func(t *testing.T) {
+		_, stderr, err := shell.Exec(t.Context(), "sh -c 'touch "+outside+"/file'")
+		require.Error(t, err)
+		require.Contains(t, stderr, "Read-only file system")
+		require.NoFileExists(t, filepath.Join(outside, "file"))
+	})
+
+	t.Run("refuses redirections elsewhere", func(t *testing.T) {
+		_, stderr, err := shell.Exec(t.Context(), "echo hello > "+outside+"/file")
+		require.Error(t, err)
+		require.Contains(t, stderr, "not writable in the sandbox")
+
+		require.NoError(t, os.Symlink(outside, filepath.Join(shell.GetWorkingDir(), "link")))
+		_, stderr, err = shell.Exec(t.Context(), "echo hello > link/file")
+		require.Error(t, err)
+		require.Contains(t, stderr, "not writable in the sandbox")
+		require.NoFileExists(t, filepath.Join(outside, "file"))
+
+		_, _, err = shell.Exec(t.Context(), "echo hello > /dev/null")
+		require.NoError(t, err)
+	})
+
+	t.Run("has no network", func(t *testing.T) {
+		stdout, _, err := shell.Exec(t.Context(), "cat /proc/net/dev")
+		require.NoError(t, err)
+		// Only the loopback interface is there, after the two header lines.
+		lines := strings.Split(strings.TrimSpace(stdout), "\n")
+		require.Len(t, lines, 3)
+		require.Contains(t, lines[2], "lo:")
+	})
+
+	t.Run("keeps the exit code", func(t *testing.T) {
+		_, _, err := shell.Exec(t.Context(), "sh -c 'exit 3'")
+		require.Equal(t, 3, ExitCode(err))
+	})
+
+	t.Run("does not connect to host sockets", func(t *testing.T) {
+		if _, err := exec.LookPath("curl"); err != nil {
+			t.Skip("curl is not installed")
+		}
+		socket := filepath.Join(outside, "host.sock")
+		listener, err := net.Listen("unix", socket)
+		require.NoError(t, err)
+		defer listener.Close()
+		var accepted atomic.Bool
+		go func() {
+			if conn, err := listener.Accept(); err == nil {
+				accepted.Store(true)
+				conn.Close()
+			}
+		}()
+
+		_, _, err = shell.Exec(t.Context(), "curl -s --unix-socket "+socket+" http://localhost/")
+		require.Error(t, err)
+		require.False(t, accepted.Load())
+	})
+
+	t.Run("does not see host processes", func(t *testing.T) {
+		_, _, err := shell.Exec(t.Context(), fmt.Sprintf("kill -0 %d", os.Getpid()))
+		require.Error(t, err)
+		_, _, err = shell.Exec(t.Context(), fmt.Sprintf("ls /proc/%d", os.Getpid()))
+		require.Error(t, err)
+	})
+
+	t.Run("is interrupted", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+		defer cancel()
+		start := time.Now()
+		_, _, err := shell.Exec(ctx, "sleep 10")
+		require.Error(t, err)
+		require.Less(t, time.Since(start), sandboxKillTimeout)
+	})
+}
+
+func TestSandboxWrapperCommand(t *testing.T) {
+	shell := NewShell(&Options{
+		WorkingDir: t.TempDir(),
+		Sandbox:    &Sandbox{Command: []string{"env", "SANDBOXED=1"}},
+	})
+	stdout, _, err := shell.Exec(t.Context(), "sh -c 'echo $SANDBOXED'")
+	require.NoError(t, err)
+	require.Equal(t, "1\n", stdout)
+}
@@ -0,0 +1,18 @@
+//go:build !linux
+
+package shell
+
+import (
+	"context"
+	"errors"
+)
+
+// exec refuses to run the command, the built-in sandbox needs Linux
+// namespaces.
+func (sb *Sandbox) exec(ctx context.Context, args []string) error {
+	return sandboxError(ctx, errors.New("the built-in sandbox is only available on Linux, configure a wrapper command instead"))
+}
+
+func refusesUnixSockets() bool {
+	return false
+}
@@ -0,0 +1,80 @@
+package transcript
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/google/uuid"
+)
+
+// Fork copies a session up to and including the given message into a new
+// session, together with the file history recorded until then, and returns
+// the id of the new session.
+//
+// The tool results of a forked assistant message are copied too, so the new
+// session can be continued. Sub-agent sessions are not copied, as their ids
+// are the ids of the tool calls that started them.
+func Fork(ctx context.Context, conn *sql.DB, sessionID, messageID string) (string, error) {
+	t, err := Export(ctx, db.New(conn), sessionID)
+	if err != nil {
+		return "", err
+	}
+	src := t.Root()
+
+	idx := slices.IndexFunc(src.Messages, func(m Message) bool {
+		return m.ID == messageID
+	})
+	if idx == -1 {
+		return "", fmt.Errorf("message %s not found in session %s", messageID, sessionID)
+	}
+	end := idx + 1
+	for end < len(src.Messages) && src.Messages[end].Role == string(message.Tool) {
+		end++
+	}
+	// Files changed after the last copied message belong to later turns.
+	cutoff := int64(math.MaxInt64)
+	if end < len(src.Messages) {
+		cutoff = src.Messages[end].CreatedAtMs
+	}
+
+	now := time.Now().Unix()
+	fork := Session{
+		ID:                  uuid.New().String(),
+		ForkedFromSessionID: src.ID,
+		Title:               src.Title + " (fork)",
+		PromptTokens:        src.PromptTokens,
+		CompletionTokens:    src.CompletionTokens,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}
+	ids := make(map[string]string, end)
+	for _, m := range src.Messages[:end] {
+		ids[m.ID] = uuid.New().String()
+		m.ID = ids[m.ID]
+		fork.Messages = append(fork.Messages, m)
+	}
+	fork.SummaryMessageID = ids[src.SummaryMessageID]
+	for _, f := range src.Files {
+		if f.CreatedAtMs >= cutoff {
+			continue
+		}
+		f.ID = uuid.New().String()
+		fork.Files = append(fork.Files, f)
+	}
+
+	err = Import(ctx, conn, &Transcript{
+		Version:    Version,
+		ExportedAt: now,
+		Sessions:   []Session{fork},
+	})
+	if err != nil {
+		return "", err
+	}
+	return fork.ID, nil
+}
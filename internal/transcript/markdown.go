@@ -0,0 +1,183 @@
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/message"
+)
+
+// maxResultLines is the number of lines of a tool result included in the
+// Markdown rendering. The full output is kept in the JSON export.
+const maxResultLines = 50
+
+// WriteMarkdown renders the conversation of the transcript, including tool
+// calls and the conversations of the sub-agents they started, as Markdown.
+func WriteMarkdown(w io.Writer, t *Transcript) error {
+	var b strings.Builder
+	root := t.Root()
+
+	fmt.Fprintf(&b, "# %s\n\n", title(root))
+	fmt.Fprintf(&b, "- **Session:** `%s`\n", root.ID)
+	fmt.Fprintf(&b, "- **Created:** %s\n", formatTime(root.CreatedAt))
+	fmt.Fprintf(&b, "- **Tokens:** %d in, %d out\n", root.PromptTokens, root.CompletionTokens)
+	fmt.Fprintf(&b, "- **Cost:** $%.4f\n", root.Cost)
+	if files := modifiedFiles(t); len(files) > 0 {
+		fmt.Fprintf(&b, "- **Modified files:** %s\n", strings.Join(files, ", "))
+	}
+	b.WriteString("\n")
+
+	if err := writeConversation(&b, t, root, 2); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeConversation(b *strings.Builder, t *Transcript, s Session, level int) error {
+	sessions := make(map[string]Session)
+	for _, child := range t.Children(s.ID) {
+		sessions[child.ID] = child
+	}
+
+	results := make(map[string]message.ToolResult)
+	messages := make([][]message.ContentPart, len(s.Messages))
+	for i, m := range s.Messages {
+		parts, err := message.UnmarshalParts(m.Parts)
+		if err != nil {
+			return fmt.Errorf("failed to decode message %s: %w", m.ID, err)
+		}
+		messages[i] = parts
+		for _, part := range parts {
+			if r, ok := part.(message.ToolResult); ok {
+				results[r.ToolCallID] = r
+			}
+		}
+	}
+
+	for i, m := range s.Messages {
+		switch message.MessageRole(m.Role) {
+		case message.User:
+			heading(b, level, "User")
+		case message.Assistant:
+			if m.Model != "" {
+				heading(b, level, fmt.Sprintf("Assistant (%s)", m.Model))
+			} else {
+				heading(b, level, "Assistant")
+			}
+		default:
+			// Tool results are rendered with the call they belong to.
+			continue
+		}
+
+		for _, part := range messages[i] {
+			switch p := part.(type) {
+			case message.ReasoningContent:
+				if strings.TrimSpace(p.Thinking) == "" {
+					continue
+				}
+				b.WriteString("<details>\n<summary>Thinking</summary>\n\n")
+				b.WriteString(strings.TrimSpace(p.Thinking))
+				b.WriteString("\n\n</details>\n\n")
+			case message.TextContent:
+				if strings.TrimSpace(p.Text) == "" {
+					continue
+				}
+				b.WriteString(strings.TrimSpace(p.Text))
+				b.WriteString("\n\n")
+			case message.BinaryContent:
+				fmt.Fprintf(b, "_Attached `%s` (%s)_\n\n", p.Path, p.MIMEType)
+			case message.ToolCall:
+				writeToolCall(b, p, results, level+1)
+				if child, ok := sessions[p.ID]; ok {
+					if err := writeConversation(b, t, child, level+2); err != nil {
+						return err
+					}
+				}
+			case message.Finish:
+				switch p.Reason {
+				case message.FinishReasonCanceled:
+					b.WriteString("_Canceled_\n\n")
+				case message.FinishReasonError:
+					fmt.Fprintf(b, "**Error:** %s\n\n", strings.TrimSpace(p.Message+" "+p.Details))
+				case message.FinishReasonPermissionDenied:
+					b.WriteString("_Permission denied_\n\n")
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func writeToolCall(b *strings.Builder, call message.ToolCall, results map[string]message.ToolResult, level int) {
+	heading(b, level, fmt.Sprintf("Tool: `%s`", call.Name))
+
+	input := call.Input
+	var indented bytes.Buffer
+	if json.Indent(&indented, []byte(input), "", "  ") == nil {
+		input = indented.String()
+	}
+	codeBlock(b, "json", input)
+
+	result, ok := results[call.ID]
+	if !ok {
+		b.WriteString("_No result_\n\n")
+		return
+	}
+	if result.IsError {
+		b.WriteString("**Error:**\n\n")
+	} else {
+		b.WriteString("**Result:**\n\n")
+	}
+	content := strings.TrimRight(result.Content, "\n")
+	if lines := strings.Split(content, "\n"); len(lines) > maxResultLines {
+		content = strings.Join(lines[:maxResultLines], "\n") +
+			fmt.Sprintf("\n... (%d more lines)", len(lines)-maxResultLines)
+	}
+	codeBlock(b, "", content)
+}
+
+func heading(b *strings.Builder, level int, text string) {
+	fmt.Fprintf(b, "%s %s\n\n", strings.Repeat("#", min(level, 6)), text)
+}
+
+// codeBlock writes a fenced code block, using a fence longer than any run of
+// backticks in the content so it cannot be closed early.
+func codeBlock(b *strings.Builder, lang, content string) {
+	fence := "```"
+	for strings.Contains(content, fence) {
+		fence += "`"
+	}
+	fmt.Fprintf(b, "%s%s\n%s\n%s\n\n", fence, lang, content, fence)
+}
+
+func title(s Session) string {
+	if s.Title == "" {
+		return "Untitled Session"
+	}
+	return s.Title
+}
+
+func formatTime(unix int64) string {
+	return time.Unix(unix, 0).UTC().Format("2006-01-02 15:04 MST")
+}
+
+// modifiedFiles returns the paths of all files with a recorded history in
+// the transcript, in the order they were first touched.
+func modifiedFiles(t *Transcript) []string {
+	var paths []string
+	seen := make(map[string]bool)
+	for _, s := range t.Sessions {
+		for _, f := range s.Files {
+			if !seen[f.Path] {
+				seen[f.Path] = true
+				paths = append(paths, "`"+f.Path+"`")
+			}
+		}
+	}
+	return paths
+}
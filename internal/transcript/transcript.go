@@ -0,0 +1,312 @@
+// Package transcript exports sessions, together with their messages, child
+// task sessions and file history, to a self-contained file that can be
+// imported into another crush database or rendered as Markdown.
+package transcript
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/charmbracelet/crush/internal/db"
+)
+
+// Version is the version of the transcript format written by Export.
+const Version = 1
+
+type Transcript struct {
+	Version    int   `json:"version"`
+	ExportedAt int64 `json:"exported_at"`
+	// Sessions holds the exported session first, followed by its
+	// descendants. Parents always come before their children.
+	Sessions []Session `json:"sessions"`
+}
+
+type Session struct {
+	ID                  string    `json:"id"`
+	ParentSessionID     string    `json:"parent_session_id,omitempty"`
+	ForkedFromSessionID string    `json:"forked_from_session_id,omitempty"`
+	Title               string    `json:"title"`
+	PromptTokens        int64     `json:"prompt_tokens"`
+	CompletionTokens    int64     `json:"completion_tokens"`
+	Cost                float64   `json:"cost"`
+	SummaryMessageID    string    `json:"summary_message_id,omitempty"`
+	CreatedAt           int64     `json:"created_at"`
+	UpdatedAt           int64     `json:"updated_at"`
+	Messages            []Message `json:"messages"`
+	Files               []File    `json:"files"`
+}
+
+type Message struct {
+	ID       string `json:"id"`
+	Role     string `json:"role"`
+	Model    string `json:"model,omitempty"`
+	Provider string `json:"provider,omitempty"`
+	// Parts are kept exactly as stored in the database, see
+	// message.UnmarshalParts.
+	Parts       json.RawMessage `json:"parts"`
+	CreatedAt   int64           `json:"created_at"`
+	UpdatedAt   int64           `json:"updated_at"`
+	FinishedAt  int64           `json:"finished_at,omitempty"`
+	CreatedAtMs int64           `json:"created_at_ms,omitempty"`
+}
+
+type File struct {
+	ID          string `json:"id"`
+	Path        string `json:"path"`
+	Content     string `json:"content"`
+	Version     int64  `json:"version"`
+	CreatedAt   int64  `json:"created_at"`
+	UpdatedAt   int64  `json:"updated_at"`
+	CreatedAtMs int64  `json:"created_at_ms,omitempty"`
+}
+
+// Root returns the exported session.
+func (t *Transcript) Root() Session {
+	return t.Sessions[0]
+}
+
+// Children returns the direct child sessions of the given session.
+func (t *Transcript) Children(sessionID string) []Session {
+	var children []Session
+	for _, s := range t.Sessions {
+		if s.ParentSessionID == sessionID {
+			children = append(children, s)
+		}
+	}
+	return children
+}
+
+// Export reads the given session and all of its descendants from the
+// database. The exported session is detached from its own parent, if any, so
+// the transcript is self-contained.
+func Export(ctx context.Context, q db.Querier, sessionID string) (*Transcript, error) {
+	root, err := q.GetSessionByID(ctx, sessionID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("session %s not found", sessionID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	root.ParentSessionID = sql.NullString{}
+
+	t := &Transcript{
+		Version:    Version,
+		ExportedAt: time.Now().Unix(),
+	}
+	queue := []db.Session{root}
+	for len(queue) > 0 {
+		dbSession := queue[0]
+		queue = queue[1:]
+
+		session, err := exportSession(ctx, q, dbSession)
+		if err != nil {
+			return nil, err
+		}
+		t.Sessions = append(t.Sessions, session)
+
+		children, err := q.ListChildSessions(ctx, sql.NullString{String: dbSession.ID, Valid: true})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list child sessions of %s: %w", dbSession.ID, err)
+		}
+		queue = append(queue, children...)
+	}
+	return t, nil
+}
+
+func exportSession(ctx context.Context, q db.Querier, item db.Session) (Session, error) {
+	session := Session{
+		ID:                  item.ID,
+		ParentSessionID:     item.ParentSessionID.String,
+		ForkedFromSessionID: item.ForkedFromSessionID.String,
+		Title:               item.Title,
+		PromptTokens:        item.PromptTokens,
+		CompletionTokens:    item.CompletionTokens,
+		Cost:                item.Cost,
+		SummaryMessageID:    item.SummaryMessageID.String,
+		CreatedAt:           item.CreatedAt,
+		UpdatedAt:           item.UpdatedAt,
+		Messages:            []Message{},
+		Files:               []File{},
+	}
+
+	messages, err := q.ListMessagesBySession(ctx, item.ID)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to list messages of session %s: %w", item.ID, err)
+	}
+	for _, m := range messages {
+		session.Messages = append(session.Messages, Message{
+			ID:          m.ID,
+			Role:        m.Role,
+			Model:       m.Model.String,
+			Provider:    m.Provider.String,
+			Parts:       json.RawMessage(m.Parts),
+			CreatedAt:   m.CreatedAt,
+			UpdatedAt:   m.UpdatedAt,
+			FinishedAt:  m.FinishedAt.Int64,
+			CreatedAtMs: m.CreatedAtMs,
+		})
+	}
+
+	files, err := q.ListFilesBySession(ctx, item.ID)
+	if err != nil {
+		return Session{}, fmt.Errorf("failed to list files of session %s: %w", item.ID, err)
+	}
+	for _, f := range files {
+		session.Files = append(session.Files, File{
+			ID:          f.ID,
+			Path:        f.Path,
+			Content:     f.Content,
+			Version:     f.Version,
+			CreatedAt:   f.CreatedAt,
+			UpdatedAt:   f.UpdatedAt,
+			CreatedAtMs: f.CreatedAtMs,
+		})
+	}
+	return session, nil
+}
+
+// Read decodes and validates a transcript written with Write.
+func Read(r io.Reader) (*Transcript, error) {
+	var t Transcript
+	if err := json.NewDecoder(r).Decode(&t); err != nil {
+		return nil, fmt.Errorf("failed to decode transcript: %w", err)
+	}
+	if t.Version < 1 || t.Version > Version {
+		return nil, fmt.Errorf("unsupported transcript version %d", t.Version)
+	}
+	if len(t.Sessions) == 0 {
+		return nil, fmt.Errorf("transcript contains no sessions")
+	}
+	seen := make(map[string]bool, len(t.Sessions))
+	for i, s := range t.Sessions {
+		if s.ID == "" {
+			return nil, fmt.Errorf("session %d has no id", i)
+		}
+		if i == 0 && s.ParentSessionID != "" {
+			return nil, fmt.Errorf("exported session %s must not have a parent", s.ID)
+		}
+		if i > 0 && !seen[s.ParentSessionID] {
+			return nil, fmt.Errorf("parent of session %s is not part of the transcript", s.ID)
+		}
+		if seen[s.ID] {
+			return nil, fmt.Errorf("duplicate session %s", s.ID)
+		}
+		seen[s.ID] = true
+	}
+	return &t, nil
+}
+
+// Write encodes the transcript as indented JSON.
+func Write(w io.Writer, t *Transcript) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(t)
+}
+
+// Import inserts all sessions of the transcript into the database in a
+// single transaction, keeping their original ids and timestamps. It fails
+// without changing anything if any of the sessions already exists.
+func Import(ctx context.Context, conn *sql.DB, t *Transcript) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck
+
+	qtx := db.New(conn).WithTx(tx)
+	for _, s := range t.Sessions {
+		if err := importSession(ctx, qtx, s); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func importSession(ctx context.Context, q db.Querier, s Session) error {
+	_, err := q.GetSessionByID(ctx, s.ID)
+	if err == nil {
+		return fmt.Errorf("session %s already exists", s.ID)
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to get session: %w", err)
+	}
+
+	err = q.ImportSession(ctx, db.ImportSessionParams{
+		ID:                  s.ID,
+		ParentSessionID:     sql.NullString{String: s.ParentSessionID, Valid: s.ParentSessionID != ""},
+		ForkedFromSessionID: sql.NullString{String: s.ForkedFromSessionID, Valid: s.ForkedFromSessionID != ""},
+		Title:               s.Title,
+		PromptTokens:        s.PromptTokens,
+		CompletionTokens:    s.CompletionTokens,
+		Cost:                s.Cost,
+		SummaryMessageID:    sql.NullString{String: s.SummaryMessageID, Valid: s.SummaryMessageID != ""},
+		UpdatedAt:           s.UpdatedAt,
+		CreatedAt:           s.CreatedAt,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to import session %s: %w", s.ID, err)
+	}
+
+	for _, m := range s.Messages {
+		// Parts are stored compacted, as written by the message service.
+		parts := []byte("[]")
+		if len(m.Parts) > 0 {
+			var compacted bytes.Buffer
+			if err := json.Compact(&compacted, m.Parts); err != nil {
+				return fmt.Errorf("invalid parts in message %s: %w", m.ID, err)
+			}
+			parts = compacted.Bytes()
+		}
+		err := q.ImportMessage(ctx, db.ImportMessageParams{
+			ID:          m.ID,
+			SessionID:   s.ID,
+			Role:        m.Role,
+			Parts:       string(parts),
+			Model:       sql.NullString{String: m.Model, Valid: m.Model != ""},
+			Provider:    sql.NullString{String: m.Provider, Valid: m.Provider != ""},
+			CreatedAt:   m.CreatedAt,
+			UpdatedAt:   m.UpdatedAt,
+			FinishedAt:  sql.NullInt64{Int64: m.FinishedAt, Valid: m.FinishedAt != 0},
+			CreatedAtMs: createdAtMs(m.CreatedAt, m.CreatedAtMs),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to import message %s: %w", m.ID, err)
+		}
+	}
+
+	for _, f := range s.Files {
+		err := q.ImportFile(ctx, db.ImportFileParams{
+			ID:          f.ID,
+			SessionID:   s.ID,
+			Path:        f.Path,
+			Content:     f.Content,
+			Version:     f.Version,
+			CreatedAt:   f.CreatedAt,
+			UpdatedAt:   f.UpdatedAt,
+			CreatedAtMs: createdAtMs(f.CreatedAt, f.CreatedAtMs),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to import file %s: %w", f.Path, err)
+		}
+	}
+	return nil
+}
+
+// createdAtMs returns the creation time in milliseconds, falling back to the
+// one in seconds for transcripts that don't have it.
+func createdAtMs(createdAt, createdAtMs int64) int64 {
+	if createdAtMs == 0 {
+		return createdAt * 1000
+	}
+	return createdAtMs
+}
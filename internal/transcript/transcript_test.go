@@ -0,0 +1,197 @@
+package transcript
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"testing"
+
+	"github.com/charmbracelet/crush/internal/db"
+	"github.com/charmbracelet/crush/internal/history"
+	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/session"
+	"github.com/stretchr/testify/require"
+)
+
+func connect(t *testing.T) *sql.DB {
+	t.Helper()
+	conn, err := db.Connect(t.Context(), t.TempDir())
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestExportImport(t *testing.T) {
+	ctx := t.Context()
+	src := connect(t)
+	q := db.New(src)
+	sessions := session.NewService(q)
+	messages := message.NewService(q)
+	files := history.NewService(q, src)
+
+	root, err := sessions.Create(ctx, "Fix the parser")
+	require.NoError(t, err)
+	_, err = messages.Create(ctx, root.ID, message.CreateMessageParams{
+		Role:  message.User,
+		Parts: []message.ContentPart{message.TextContent{Text: "Please fix the parser"}},
+	})
+	require.NoError(t, err)
+	_, err = messages.Create(ctx, root.ID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Model: "test-model",
+		Parts: []message.ContentPart{
+			message.TextContent{Text: "Let me look around."},
+			message.ToolCall{ID: "call-1", Name: "agent", Input: `{"prompt":"find the parser"}`, Finished: true},
+		},
+	})
+	require.NoError(t, err)
+	_, err = messages.Create(ctx, root.ID, message.CreateMessageParams{
+		Role:  message.Tool,
+		Parts: []message.ContentPart{message.ToolResult{ToolCallID: "call-1", Name: "agent", Content: "parser.go"}},
+	})
+	require.NoError(t, err)
+
+	task, err := sessions.CreateTaskSession(ctx, "call-1", root.ID, "New Agent Session")
+	require.NoError(t, err)
+	_, err = messages.Create(ctx, task.ID, message.CreateMessageParams{
+		Role:  message.Assistant,
+		Parts: []message.ContentPart{message.TextContent{Text: "It is in parser.go"}},
+	})
+	require.NoError(t, err)
+
+	_, err = files.Create(ctx, root.ID, "/tmp/parser.go", "package parser")
+	require.NoError(t, err)
+	_, err = files.CreateVersion(ctx, root.ID, "/tmp/parser.go", "package parser\n\nfunc Parse() {}")
+	require.NoError(t, err)
+
+	exported, err := Export(ctx, q, root.ID)
+	require.NoError(t, err)
+	require.Len(t, exported.Sessions, 2)
+	require.Equal(t, root.ID, exported.Root().ID)
+	require.Len(t, exported.Root().Messages, 3)
+	require.Len(t, exported.Root().Files, 2)
+	require.Equal(t, []Session{exported.Sessions[1]}, exported.Children(root.ID))
+
+	var buf bytes.Buffer
+	require.NoError(t, Write(&buf, exported))
+	read, err := Read(&buf)
+	require.NoError(t, err)
+
+	dst := connect(t)
+	require.NoError(t, Import(ctx, dst, read))
+	require.ErrorContains(t, Import(ctx, dst, read), "already exists")
+
+	imported, err := Export(ctx, db.New(dst), root.ID)
+	require.NoError(t, err)
+	for i := range imported.Sessions {
+		// The database bumps updated_at when the message count changes.
+		imported.Sessions[i].UpdatedAt = exported.Sessions[i].UpdatedAt
+	}
+	imported.ExportedAt = exported.ExportedAt
+	require.Equal(t, exported, imported)
+
+	importedRoot, err := session.NewService(db.New(dst)).Get(ctx, root.ID)
+	require.NoError(t, err)
+	require.Equal(t, int64(3), importedRoot.MessageCount)
+
+	buf.Reset()
+	require.NoError(t, WriteMarkdown(&buf, imported))
+	md := buf.String()
+	require.Contains(t, md, "# Fix the parser")
+	require.Contains(t, md, "## User\n\nPlease fix the parser")
+	require.Contains(t, md, "## Assistant (test-model)")
+	require.Contains(t, md, "### Tool: `agent`")
+	require.Contains(t, md, "**Result:**\n\n```\nparser.go\n```")
+	require.Contains(t, md, "#### Assistant\n\nIt is in parser.go")
+	require.Contains(t, md, "`/tmp/parser.go`")
+}
+
+func TestRead(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"invalid json", `{`, "failed to decode transcript"},
+		{"unsupported version", `{"version": 99, "sessions": [{"id": "a"}]}`, "unsupported transcript version"},
+		{"no sessions", `{"version": 1, "sessions": []}`, "no sessions"},
+		{"orphan", `{"version": 1, "sessions": [{"id": "a"}, {"id": "b", "parent_session_id": "c"}]}`, "not part of the transcript"},
+		{"duplicate", `{"version": 1, "sessions": [{"id": "a"}, {"id": "a", "parent_session_id": "a"}]}`, "duplicate session"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+			_, err := Read(bytes.NewBufferString(tt.input))
+			require.ErrorContains(t, err, tt.err)
+		})
+	}
+}
+
+func TestFork(t *testing.T) {
+	ctx := t.Context()
+	conn := connect(t)
+	q := db.New(conn)
+	sessions := session.NewService(q)
+
+	src, err := sessions.Create(ctx, "Refactor")
+	require.NoError(t, err)
+	// Timestamps are in milliseconds.
+	addMessage := func(id string, role message.MessageRole, createdAt int64, parts ...message.ContentPart) {
+		t.Helper()
+		data, err := message.MarshalParts(parts)
+		require.NoError(t, err)
+		require.NoError(t, q.ImportMessage(ctx, db.ImportMessageParams{
+			ID:          id,
+			SessionID:   src.ID,
+			Role:        string(role),
+			Parts:       string(data),
+			CreatedAt:   createdAt / 1000,
+			UpdatedAt:   createdAt / 1000,
+			CreatedAtMs: createdAt,
+		}))
+	}
+	// The first version is recorded in the same second the second turn
+	// starts.
+	addMessage("u1", message.User, 100_000, message.TextContent{Text: "first"})
+	addMessage("a1", message.Assistant, 199_000, message.ToolCall{ID: "call-1", Name: "edit", Finished: true})
+	addMessage("t1", message.Tool, 200_200, message.ToolResult{ToolCallID: "call-1", Content: "ok"})
+	addMessage("u2", message.User, 200_600, message.TextContent{Text: "second"})
+	for i, createdAt := range []int64{200_100, 201_000} {
+		require.NoError(t, q.ImportFile(ctx, db.ImportFileParams{
+			ID:          fmt.Sprintf("f%d", i),
+			SessionID:   src.ID,
+			Path:        "/tmp/main.go",
+			Content:     fmt.Sprintf("v%d", i),
+			Version:     int64(i),
+			CreatedAt:   createdAt / 1000,
+			UpdatedAt:   createdAt / 1000,
+			CreatedAtMs: createdAt,
+		}))
+	}
+
+	_, err = Fork(ctx, conn, src.ID, "missing")
+	require.Error(t, err)
+
+	// Forking at a tool call keeps its result.
+	id, err := Fork(ctx, conn, src.ID, "a1")
+	require.NoError(t, err)
+
+	fork, err := Export(ctx, q, id)
+	require.NoError(t, err)
+	root := fork.Root()
+	require.Equal(t, src.ID, root.ForkedFromSessionID)
+	require.Equal(t, "Refactor (fork)", root.Title)
+	require.Len(t, root.Messages, 3)
+	require.Equal(t, "tool", root.Messages[2].Role)
+	require.NotEqual(t, "t1", root.Messages[2].ID)
+	require.Len(t, root.Files, 1)
+	require.Equal(t, "v0", root.Files[0].Content)
+
+	// The original session is left untouched.
+	orig, err := Export(ctx, q, src.ID)
+	require.NoError(t, err)
+	require.Len(t, orig.Root().Messages, 4)
+	require.Len(t, orig.Root().Files, 2)
+}
@@ -103,8 +103,8 @@ func (m *messageListCmp) Init() tea.Cmd {
 // Update handles incoming messages and updates the component state.
 func (m *messageListCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmds []tea.Cmd
-	if m.session.ID != "" && m.app.CoderAgent != nil {
-		queueSize := m.app.CoderAgent.QueuedPrompts(m.session.ID)
+	if m.session.ID != "" && m.app.CoderAgent() != nil {
+		queueSize := m.app.CoderAgent().QueuedPrompts(m.session.ID)
 		if queueSize != m.promptQueue {
 			m.promptQueue = queueSize
 			cmds = append(cmds, m.SetSize(m.width, m.height))
@@ -235,7 +235,7 @@ func (m *messageListCmp) View() string {
 				m.listCmp.View(),
 			),
 	}
-	if m.app.CoderAgent != nil && m.promptQueue > 0 {
+	if m.app.CoderAgent() != nil && m.promptQueue > 0 {
 		queuePill := queuePill(m.promptQueue, t)
 		view = append(view, t.S().Base.PaddingLeft(4).PaddingTop(1).Render(queuePill))
 	}
@@ -337,10 +337,25 @@ func (m *messageListCmp) handleMessageEvent(event pubsub.Event[message.Message])
 		case message.Tool:
 			return m.handleToolMessage(event.Payload)
 		}
+	case pubsub.DeletedEvent:
+		if event.Payload.SessionID != m.session.ID {
+			return nil
+		}
+		return m.handleDeletedMessage(event.Payload)
 	}
 	return nil
 }
 
+// handleDeletedMessage removes a deleted message and its tool calls from
+// the list.
+func (m *messageListCmp) handleDeletedMessage(msg message.Message) tea.Cmd {
+	cmds := []tea.Cmd{m.listCmp.DeleteItem(msg.ID)}
+	for _, tc := range msg.ToolCalls() {
+		cmds = append(cmds, m.listCmp.DeleteItem(tc.ID))
+	}
+	return tea.Batch(cmds...)
+}
+
 // messageExists checks if a message with the given ID already exists in the list.
 func (m *messageListCmp) messageExists(messageID string) bool {
 	items := m.listCmp.Items()
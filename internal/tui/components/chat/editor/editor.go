@@ -18,7 +18,9 @@ import (
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/app"
 	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/llm/agent"
 	"github.com/charmbracelet/crush/internal/message"
+	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/session"
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/completions"
@@ -30,6 +32,7 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
 	"github.com/charmbracelet/lipgloss/v2"
+	"github.com/mark3labs/mcp-go/mcp"
 )
 
 type Editor interface {
@@ -49,6 +52,11 @@ type FileCompletionItem struct {
 	Path string // The file path
 }
 
+type ResourceCompletionItem struct {
+	MCPName  string
+	Resource mcp.Resource
+}
+
 type editorCmp struct {
 	width              int
 	height             int
@@ -209,9 +217,26 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.completionsStartIndex = 0
 			}
 		}
+		if item, ok := msg.Value.(ResourceCompletionItem); ok && !msg.Insert {
+			// Replace the query with the resource, attached once it's read.
+			word := m.textarea.Word()
+			value := m.textarea.Value()
+			m.textarea.SetValue(value[:m.completionsStartIndex] + value[m.completionsStartIndex+len(word):])
+			m.textarea.MoveToEnd()
+			m.isCompletionsOpen = false
+			m.currentQuery = ""
+			m.completionsStartIndex = 0
+			return m, func() tea.Msg {
+				attachment, err := agent.ReadMCPResource(context.Background(), item.MCPName, item.Resource)
+				if err != nil {
+					return util.ReportError(err)()
+				}
+				return filepicker.FilePickedMsg{Attachment: attachment}
+			}
+		}
 
 	case commands.OpenExternalEditorMsg:
-		if m.app.CoderAgent.IsSessionBusy(m.session.ID) {
+		if m.app.CoderAgent().IsSessionBusy(m.session.ID) {
 			return m, util.ReportWarn("Agent is working, please wait...")
 		}
 		return m, m.openEditor(m.textarea.Value())
@@ -271,6 +296,12 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.currentQuery = ""
 			m.completionsStartIndex = curIdx
 			cmds = append(cmds, m.startCompletions)
+		case msg.String() == "@" && !m.isCompletionsOpen &&
+			(len(m.textarea.Value()) == 0 || unicode.IsSpace(rune(m.textarea.Value()[len(m.textarea.Value())-1]))):
+			m.isCompletionsOpen = true
+			m.currentQuery = ""
+			m.completionsStartIndex = curIdx
+			cmds = append(cmds, m.startResourceCompletions)
 		case m.isCompletionsOpen && curIdx <= m.completionsStartIndex:
 			cmds = append(cmds, util.CmdHandler(completions.CloseCompletionsMsg{}))
 		}
@@ -297,7 +328,7 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 		}
 		if key.Matches(msg, m.keyMap.OpenEditor) {
-			if m.app.CoderAgent.IsSessionBusy(m.session.ID) {
+			if m.app.CoderAgent().IsSessionBusy(m.session.ID) {
 				return m, util.ReportWarn("Agent is working, please wait...")
 			}
 			return m, m.openEditor(m.textarea.Value())
@@ -336,7 +367,7 @@ func (m *editorCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				cmds = append(cmds, util.CmdHandler(completions.CloseCompletionsMsg{}))
 			} else {
 				word := m.textarea.Word()
-				if strings.HasPrefix(word, "/") {
+				if strings.HasPrefix(word, "/") || strings.HasPrefix(word, "@") {
 					// XXX: wont' work if editing in the middle of the field.
 					m.completionsStartIndex = strings.LastIndex(m.textarea.Value(), word)
 					m.currentQuery = word[1:]
@@ -415,7 +446,7 @@ func (m *editorCmp) randomizePlaceholders() {
 func (m *editorCmp) View() string {
 	t := styles.CurrentTheme()
 	// Update placeholder
-	if m.app.CoderAgent != nil && m.app.CoderAgent.IsBusy() {
+	if m.app.CoderAgent() != nil && m.app.CoderAgent().IsBusy() {
 		m.textarea.Placeholder = m.workingPlaceholder
 	} else {
 		m.textarea.Placeholder = m.readyPlaceholder
@@ -423,6 +454,9 @@ func (m *editorCmp) View() string {
 	if m.app.Permissions.SkipRequests() {
 		m.textarea.Placeholder = "Yolo mode!"
 	}
+	if m.app.Permissions.Mode() == permission.ModePlan {
+		m.textarea.Placeholder = "Plan mode: explore and plan, no changes"
+	}
 	if len(m.attachments) == 0 {
 		content := t.S().Base.Padding(1).Render(
 			m.textarea.View(),
@@ -501,6 +535,27 @@ func (m *editorCmp) startCompletions() tea.Msg {
 	}
 }
 
+func (m *editorCmp) startResourceCompletions() tea.Msg {
+	resources := agent.GetMCPResources()
+	completionItems := make([]completions.Completion, 0, len(resources))
+	for _, r := range resources {
+		completionItems = append(completionItems, completions.Completion{
+			Title: r.MCPName + ":" + r.Name,
+			Value: ResourceCompletionItem{
+				MCPName:  r.MCPName,
+				Resource: r.Resource,
+			},
+		})
+	}
+
+	x, y := m.completionsPosition()
+	return completions.OpenCompletionsMsg{
+		Completions: completionItems,
+		X:           x,
+		Y:           y,
+	}
+}
+
 // Blur implements Container.
 func (c *editorCmp) Blur() tea.Cmd {
 	c.textarea.Blur()
@@ -28,6 +28,22 @@ import (
 // CopyKey is the key binding for copying message content to the clipboard.
 var CopyKey = key.NewBinding(key.WithKeys("c", "y", "C", "Y"), key.WithHelp("c/y", "copy"))
 
+// RewindKey is the key binding for rewinding the session to a user message.
+var RewindKey = key.NewBinding(key.WithKeys("r", "R"), key.WithHelp("r", "rewind to here"))
+
+// RewindMsg is sent to rewind the session to the given user message.
+type RewindMsg struct {
+	Message message.Message
+}
+
+// ForkKey is the key binding for forking the session at a message.
+var ForkKey = key.NewBinding(key.WithKeys("F"), key.WithHelp("F", "fork from here"))
+
+// ForkMsg is sent to fork the session at the given message.
+type ForkMsg struct {
+	Message message.Message
+}
+
 // ClearSelectionKey is the key binding for clearing the current selection in the chat interface.
 var ClearSelectionKey = key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "clear selection"))
 
@@ -113,6 +129,12 @@ func (m *messageCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				util.ReportInfo("Message copied to clipboard"),
 			)
 		}
+		if key.Matches(msg, RewindKey) && m.message.Role == message.User {
+			return m, util.CmdHandler(RewindMsg{Message: m.message})
+		}
+		if key.Matches(msg, ForkKey) {
+			return m, util.CmdHandler(ForkMsg{Message: m.message})
+		}
 	}
 	return m, nil
 }
@@ -116,7 +116,7 @@ func (br baseRenderer) unmarshalParams(input string, target any) error {
 func (br baseRenderer) makeNestedHeader(v *toolCallCmp, tool string, width int, params ...string) string {
 	t := styles.CurrentTheme()
 	icon := t.S().Base.Foreground(t.GreenDark).Render(styles.ToolPending)
-	if v.result.ToolCallID != "" {
+	if v.result.ToolCallID != "" && !v.result.Running {
 		if v.result.IsError {
 			icon = t.S().Base.Foreground(t.RedDark).Render(styles.ToolError)
 		} else {
@@ -137,7 +137,7 @@ func (br baseRenderer) makeHeader(v *toolCallCmp, tool string, width int, params
 	}
 	t := styles.CurrentTheme()
 	icon := t.S().Base.Foreground(t.GreenDark).Render(styles.ToolPending)
-	if v.result.ToolCallID != "" {
+	if v.result.ToolCallID != "" && !v.result.Running {
 		if v.result.IsError {
 			icon = t.S().Base.Foreground(t.RedDark).Render(styles.ToolError)
 		} else {
@@ -163,6 +163,10 @@ func (br baseRenderer) renderError(v *toolCallCmp, message string) string {
 // Register tool renderers
 func init() {
 	registry.register(tools.BashToolName, func() renderer { return bashRenderer{} })
+	registry.register(tools.CodeSearchToolName, func() renderer { return codeSearchRenderer{} })
+	registry.register(tools.JobOutputToolName, func() renderer { return jobRenderer{} })
+	registry.register(tools.JobWaitToolName, func() renderer { return jobRenderer{} })
+	registry.register(tools.JobKillToolName, func() renderer { return jobRenderer{} })
 	registry.register(tools.DownloadToolName, func() renderer { return downloadRenderer{} })
 	registry.register(tools.ViewToolName, func() renderer { return viewRenderer{} })
 	registry.register(tools.EditToolName, func() renderer { return editRenderer{} })
@@ -174,6 +178,13 @@ func init() {
 	registry.register(tools.LSToolName, func() renderer { return lsRenderer{} })
 	registry.register(tools.SourcegraphToolName, func() renderer { return sourcegraphRenderer{} })
 	registry.register(tools.DiagnosticsToolName, func() renderer { return diagnosticsRenderer{} })
+	registry.register(tools.DefinitionToolName, func() renderer { return lspRenderer{} })
+	registry.register(tools.ImplementationToolName, func() renderer { return lspRenderer{} })
+	registry.register(tools.ReferencesToolName, func() renderer { return lspRenderer{} })
+	registry.register(tools.HoverToolName, func() renderer { return lspRenderer{} })
+	registry.register(tools.CallHierarchyToolName, func() renderer { return lspRenderer{} })
+	registry.register(tools.SymbolsToolName, func() renderer { return symbolsRenderer{} })
+	registry.register(tools.RefactorToolName, func() renderer { return refactorRenderer{} })
 	registry.register(agent.AgentToolName, func() renderer { return agentRenderer{} })
 }
 
@@ -226,6 +237,39 @@ func (br bashRenderer) Render(v *toolCallCmp) string {
 		if meta.Output == "" {
 			return ""
 		}
+		if v.result.Running {
+			return renderPlainTail(v, meta.Output)
+		}
+		return renderPlainContent(v, meta.Output)
+	})
+}
+
+// -----------------------------------------------------------------------------
+//  Background job renderer
+// -----------------------------------------------------------------------------
+
+// jobRenderer handles reading, waiting for and killing background jobs
+type jobRenderer struct {
+	baseRenderer
+}
+
+// Render displays the job ID and the output read from the job
+func (jr jobRenderer) Render(v *toolCallCmp) string {
+	var params tools.JobWaitParams
+	if err := jr.unmarshalParams(v.call.Input, &params); err != nil {
+		return jr.renderError(v, "Invalid job parameters")
+	}
+
+	args := newParamBuilder().
+		addMain(params.JobID).
+		addKeyValue("timeout", formatTimeoutMillis(params.Timeout)).
+		build()
+
+	return jr.renderWithParams(v, prettifyToolName(v.call.Name), args, func() string {
+		var meta tools.JobResponseMetadata
+		if err := jr.unmarshalParams(v.result.Metadata, &meta); err != nil || meta.Output == "" {
+			return renderPlainContent(v, v.result.Content)
+		}
 		return renderPlainContent(v, meta.Output)
 	})
 }
@@ -439,6 +483,14 @@ func formatTimeout(timeout int) string {
 	return (time.Duration(timeout) * time.Second).String()
 }
 
+// formatTimeoutMillis converts timeout milliseconds to duration string
+func formatTimeoutMillis(timeout int) string {
+	if timeout == 0 {
+		return ""
+	}
+	return (time.Duration(timeout) * time.Millisecond).String()
+}
+
 // -----------------------------------------------------------------------------
 //  Download renderer
 // -----------------------------------------------------------------------------
@@ -517,6 +569,31 @@ func (gr grepRenderer) Render(v *toolCallCmp) string {
 	})
 }
 
+// -----------------------------------------------------------------------------
+//  Code search renderer
+// -----------------------------------------------------------------------------
+
+// codeSearchRenderer handles natural language searches of the code index
+type codeSearchRenderer struct {
+	baseRenderer
+}
+
+// Render displays the search query with the optional limit
+func (cr codeSearchRenderer) Render(v *toolCallCmp) string {
+	var params tools.CodeSearchParams
+	var args []string
+	if err := cr.unmarshalParams(v.call.Input, &params); err == nil {
+		args = newParamBuilder().
+			addMain(params.Query).
+			addKeyValue("limit", formatNonZero(params.Limit)).
+			build()
+	}
+
+	return cr.renderWithParams(v, "Code Search", args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
 // -----------------------------------------------------------------------------
 //  LS renderer
 // -----------------------------------------------------------------------------
@@ -589,6 +666,92 @@ func (dr diagnosticsRenderer) Render(v *toolCallCmp) string {
 	})
 }
 
+// -----------------------------------------------------------------------------
+//  LSP renderers
+// -----------------------------------------------------------------------------
+
+// lspRenderer handles the LSP navigation tools that target a symbol position
+type lspRenderer struct {
+	baseRenderer
+}
+
+// Render displays the target symbol and file with plain content output
+func (lr lspRenderer) Render(v *toolCallCmp) string {
+	var params struct {
+		tools.LSPPositionParams
+		Direction string `json:"direction"`
+	}
+	var args []string
+	if err := lr.unmarshalParams(v.call.Input, &params); err == nil {
+		file := fsext.PrettyPath(params.FilePath)
+		if params.Line > 0 {
+			file = fmt.Sprintf("%s:%d", file, params.Line)
+		}
+		builder := newParamBuilder()
+		if params.Symbol != "" {
+			builder.addMain(params.Symbol).addKeyValue("file", file)
+		} else {
+			builder.addMain(file)
+		}
+		args = builder.addKeyValue("direction", params.Direction).build()
+	}
+
+	return lr.renderWithParams(v, prettifyToolName(v.call.Name), args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
+// symbolsRenderer handles document outlines and workspace symbol searches
+type symbolsRenderer struct {
+	baseRenderer
+}
+
+// Render displays the file or query with plain content output
+func (sr symbolsRenderer) Render(v *toolCallCmp) string {
+	var params tools.SymbolsParams
+	var args []string
+	if err := sr.unmarshalParams(v.call.Input, &params); err == nil {
+		main := params.Query
+		if params.FilePath != "" {
+			main = fsext.PrettyPath(params.FilePath)
+		}
+		args = newParamBuilder().addMain(main).build()
+	}
+
+	return sr.renderWithParams(v, "Symbols", args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
+// refactorRenderer handles LSP renames and code actions
+type refactorRenderer struct {
+	baseRenderer
+}
+
+// Render displays the refactoring and the list of changed files
+func (rr refactorRenderer) Render(v *toolCallCmp) string {
+	var params tools.RefactorParams
+	var args []string
+	if err := rr.unmarshalParams(v.call.Input, &params); err == nil {
+		builder := newParamBuilder()
+		switch params.Operation {
+		case tools.RefactorOperationRename:
+			builder.addMain(fmt.Sprintf("%s → %s", params.Symbol, params.NewName))
+		default:
+			action := params.Title
+			if action == "" {
+				action = params.Kind
+			}
+			builder.addMain(action)
+		}
+		args = builder.addKeyValue("file", fsext.PrettyPath(params.FilePath)).build()
+	}
+
+	return rr.renderWithParams(v, "Refactor", args, func() string {
+		return renderPlainContent(v, v.result.Content)
+	})
+}
+
 // -----------------------------------------------------------------------------
 //  Task renderer
 // -----------------------------------------------------------------------------
@@ -780,6 +943,38 @@ func renderPlainContent(v *toolCallCmp, content string) string {
 	return strings.Join(out, "\n")
 }
 
+// renderPlainTail renders the last lines of the output of a running tool.
+func renderPlainTail(v *toolCallCmp, content string) string {
+	t := styles.CurrentTheme()
+	content = strings.ReplaceAll(content, "\r\n", "\n") // Normalize line endings
+	content = strings.ReplaceAll(content, "\t", "    ") // Replace tabs with spaces
+	content = strings.TrimSpace(content)
+	lines := strings.Split(content, "\n")
+
+	width := v.textWidth() - 2 // -2 for left padding
+	var out []string
+	if len(lines) > responseContextHeight {
+		out = append(out, t.S().Muted.
+			Background(t.BgBaseLighter).
+			Width(width).
+			Render(fmt.Sprintf("… (%d lines)", len(lines)-responseContextHeight)))
+		lines = lines[len(lines)-responseContextHeight:]
+	}
+	for _, ln := range lines {
+		ln = ansiext.Escape(ln)
+		ln = " " + ln // left padding
+		if len(ln) > width {
+			ln = v.fit(ln, width)
+		}
+		out = append(out, t.S().Muted.
+			Width(width).
+			Background(t.BgBaseLighter).
+			Render(ln))
+	}
+
+	return strings.Join(out, "\n")
+}
+
 func getDigits(n int) int {
 	if n == 0 {
 		return 1
@@ -866,6 +1061,14 @@ func prettifyToolName(name string) string {
 		return "Agent"
 	case tools.BashToolName:
 		return "Bash"
+	case tools.JobOutputToolName:
+		return "Job Output"
+	case tools.JobWaitToolName:
+		return "Job Wait"
+	case tools.JobKillToolName:
+		return "Job Kill"
+	case tools.CodeSearchToolName:
+		return "Code Search"
 	case tools.DownloadToolName:
 		return "Download"
 	case tools.EditToolName:
@@ -886,6 +1089,20 @@ func prettifyToolName(name string) string {
 		return "View"
 	case tools.WriteToolName:
 		return "Write"
+	case tools.DefinitionToolName:
+		return "Definition"
+	case tools.ImplementationToolName:
+		return "Implementation"
+	case tools.ReferencesToolName:
+		return "References"
+	case tools.HoverToolName:
+		return "Hover"
+	case tools.SymbolsToolName:
+		return "Symbols"
+	case tools.CallHierarchyToolName:
+		return "Call Hierarchy"
+	case tools.RefactorToolName:
+		return "Refactor"
 	default:
 		return name
 	}
@@ -21,6 +21,7 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
 	"github.com/charmbracelet/crush/internal/tui/components/files"
+	jobscomponent "github.com/charmbracelet/crush/internal/tui/components/jobs"
 	"github.com/charmbracelet/crush/internal/tui/components/logo"
 	lspcomponent "github.com/charmbracelet/crush/internal/tui/components/lsp"
 	"github.com/charmbracelet/crush/internal/tui/components/mcp"
@@ -44,6 +45,7 @@ const (
 	DefaultMaxFilesShown = 10
 	DefaultMaxLSPsShown  = 8
 	DefaultMaxMCPsShown  = 8
+	DefaultMaxJobsShown  = 5
 	MinItemsPerSection   = 2 // Minimum items to show per section
 )
 
@@ -100,6 +102,11 @@ func (m *sidebarCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case chat.SessionClearedMsg:
 		m.session = session.Session{}
 	case pubsub.Event[history.File]:
+		if msg.Type == pubsub.DeletedEvent {
+			// Versions are deleted when a session is rewound, start over
+			// from the remaining ones.
+			return m, m.loadSessionFiles
+		}
 		return m, m.handleFileHistoryEvent(msg)
 	case pubsub.Event[session.Session]:
 		if msg.Type == pubsub.UpdatedEvent {
@@ -162,6 +169,9 @@ func (m *sidebarCmp) View() string {
 		if m.session.ID != "" {
 			parts = append(parts, "", m.filesBlock())
 		}
+		if jobsBlock := m.jobsBlock(); jobsBlock != "" {
+			parts = append(parts, "", jobsBlock)
+		}
 		parts = append(parts,
 			"",
 			m.lspBlock(),
@@ -320,6 +330,10 @@ func (m *sidebarCmp) calculateAvailableHeight() int {
 
 	usedHeight += 6 // 3 sections × 2 lines each (header + empty line)
 
+	if jobs := len(jobscomponent.RunningJobs()); jobs > 0 {
+		usedHeight += 3 + min(jobs, DefaultMaxJobsShown) // Jobs section, shown only while jobs run
+	}
+
 	// Base padding
 	usedHeight += 2 // Top and bottom padding
 
@@ -505,6 +519,21 @@ func (m *sidebarCmp) mcpBlock() string {
 	}, true)
 }
 
+// jobsBlock renders the running background jobs, or nothing if there are none.
+func (m *sidebarCmp) jobsBlock() string {
+	jobs := jobscomponent.RunningJobs()
+	if len(jobs) == 0 {
+		return ""
+	}
+
+	return jobscomponent.RenderJobBlock(jobs, jobscomponent.RenderOptions{
+		MaxWidth:    m.getMaxWidth(),
+		MaxItems:    DefaultMaxJobsShown,
+		ShowSection: true,
+		SectionName: core.Section("Background Jobs", m.getMaxWidth()),
+	}, true)
+}
+
 func formatTokensAndCost(tokens, contextWindow int64, cost float64) string {
 	t := styles.CurrentTheme()
 	// Format tokens in human-readable format (e.g., 110K, 1.2M)
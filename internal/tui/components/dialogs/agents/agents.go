@@ -0,0 +1,193 @@
+package agents
+
+import (
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const AgentsDialogID dialogs.DialogID = "agents"
+
+// AgentSelectedMsg is sent when an agent is selected in the dialog.
+type AgentSelectedMsg struct {
+	ID string
+}
+
+// AgentDialog interface for the agent switching dialog
+type AgentDialog interface {
+	dialogs.DialogModel
+}
+
+type AgentsList = list.FilterableList[list.CompletionItem[config.Agent]]
+
+type agentDialogCmp struct {
+	wWidth          int
+	wHeight         int
+	width           int
+	selectedAgentID string
+	keyMap          KeyMap
+	agentsList      AgentsList
+	help            help.Model
+}
+
+// SelectableAgents returns the agents that can be chosen as the main agent.
+func SelectableAgents() []config.Agent {
+	var agents []config.Agent
+	for _, agent := range config.Get().Agents.Sorted() {
+		if agent.ID == config.AgentTask {
+			continue
+		}
+		agents = append(agents, agent)
+	}
+	return agents
+}
+
+// NewAgentDialogCmp creates a new agent switching dialog
+func NewAgentDialogCmp(selectedID string) AgentDialog {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	agents := SelectableAgents()
+	items := make([]list.CompletionItem[config.Agent], len(agents))
+	for i, agent := range agents {
+		title := agent.Name
+		if agent.Description != "" {
+			title += " - " + agent.Description
+		}
+		items[i] = list.NewCompletionItem(title, agent, list.WithCompletionID(agent.ID))
+	}
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	agentsList := list.NewFilterableList(
+		items,
+		list.WithFilterPlaceholder("Enter an agent name"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &agentDialogCmp{
+		selectedAgentID: selectedID,
+		keyMap:          DefaultKeyMap(),
+		agentsList:      agentsList,
+		help:            help,
+	}
+}
+
+func (a *agentDialogCmp) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	cmds = append(cmds, a.agentsList.Init())
+	cmds = append(cmds, a.agentsList.Focus())
+	return tea.Sequence(cmds...)
+}
+
+func (a *agentDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		var cmds []tea.Cmd
+		a.wWidth = msg.Width
+		a.wHeight = msg.Height
+		a.width = min(90, a.wWidth-8)
+		a.agentsList.SetInputWidth(a.listWidth() - 2)
+		cmds = append(cmds, a.agentsList.SetSize(a.listWidth(), a.listHeight()))
+		if a.selectedAgentID != "" {
+			cmds = append(cmds, a.agentsList.SetSelected(a.selectedAgentID))
+		}
+		return a, tea.Batch(cmds...)
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, a.keyMap.Select):
+			selectedItem := a.agentsList.SelectedItem()
+			if selectedItem != nil {
+				selected := *selectedItem
+				return a, tea.Sequence(
+					util.CmdHandler(dialogs.CloseDialogMsg{}),
+					util.CmdHandler(AgentSelectedMsg{ID: selected.Value().ID}),
+				)
+			}
+		case key.Matches(msg, a.keyMap.Close):
+			return a, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := a.agentsList.Update(msg)
+			a.agentsList = u.(AgentsList)
+			return a, cmd
+		}
+	}
+	return a, nil
+}
+
+func (a *agentDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	listView := a.agentsList.View()
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("Switch Agent", a.width-4)),
+		listView,
+		"",
+		t.S().Base.Width(a.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(a.help.View(a.keyMap)),
+	)
+
+	return a.style().Render(content)
+}
+
+func (a *agentDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := a.agentsList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			cursor = a.moveCursor(cursor)
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (a *agentDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(a.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (a *agentDialogCmp) listHeight() int {
+	return min(len(a.agentsList.Items())+2, a.wHeight/2-6) // 6 for the border, title and help
+}
+
+func (a *agentDialogCmp) listWidth() int {
+	return a.width - 2 // 2 for the border
+}
+
+func (a *agentDialogCmp) Position() (int, int) {
+	row := a.wHeight/4 - 2 // just a bit above the center
+	col := a.wWidth / 2
+	col -= a.width / 2
+	return row, col
+}
+
+func (a *agentDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
+	row, col := a.Position()
+	offset := row + 3 // Border + title
+	cursor.Y += offset
+	cursor.X = cursor.X + col + 2
+	return cursor
+}
+
+// ID implements AgentDialog.
+func (a *agentDialogCmp) ID() dialogs.DialogID {
+	return AgentsDialogID
+}
@@ -14,6 +14,7 @@ import (
 	"github.com/charmbracelet/crush/internal/tui/components/chat"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/agents"
 	"github.com/charmbracelet/crush/internal/tui/exp/list"
 	"github.com/charmbracelet/crush/internal/tui/styles"
 	"github.com/charmbracelet/crush/internal/tui/util"
@@ -63,6 +64,8 @@ type (
 	SwitchSessionsMsg     struct{}
 	NewSessionsMsg        struct{}
 	SwitchModelMsg        struct{}
+	SwitchAgentMsg        struct{}
+	ManageMCPsMsg         struct{}
 	QuitMsg               struct{}
 	OpenFilePickerMsg     struct{}
 	ToggleHelpMsg         struct{}
@@ -70,6 +73,7 @@ type (
 	ToggleThinkingMsg     struct{}
 	OpenExternalEditorMsg struct{}
 	ToggleYoloModeMsg     struct{}
+	TogglePlanModeMsg     struct{}
 	CompactMsg            struct {
 		SessionID string
 	}
@@ -111,7 +115,7 @@ func (c *commandDialogCmp) Init() tea.Cmd {
 	if err != nil {
 		return util.ReportError(err)
 	}
-	c.userCommands = commands
+	c.userCommands = append(commands, loadMCPPrompts()...)
 	return c.SetCommandType(c.commandType)
 }
 
@@ -286,6 +290,29 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 		},
 	}
 
+	// Only show agent switching if there are agents to switch between
+	if len(agents.SelectableAgents()) > 1 {
+		commands = append(commands, Command{
+			ID:          "switch_agent",
+			Title:       "Switch Agent",
+			Description: "Switch to a different agent",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(SwitchAgentMsg{})
+			},
+		})
+	}
+
+	if len(config.Get().MCP) > 0 {
+		commands = append(commands, Command{
+			ID:          "manage_mcps",
+			Title:       "Manage MCP Servers",
+			Description: "Restart, enable or disable MCP servers",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(ManageMCPsMsg{})
+			},
+		})
+	}
+
 	// Only show compact command if there's an active session
 	if c.sessionID != "" {
 		commands = append(commands, Command{
@@ -371,6 +398,14 @@ func (c *commandDialogCmp) defaultCommands() []Command {
 				return util.CmdHandler(ToggleYoloModeMsg{})
 			},
 		},
+		{
+			ID:          "toggle_plan",
+			Title:       "Toggle Plan Mode",
+			Description: "Refuse tool calls that change files, so the agent can only explore and plan",
+			Handler: func(cmd Command) tea.Cmd {
+				return util.CmdHandler(TogglePlanModeMsg{})
+			},
+		},
 		{
 			ID:          "toggle_help",
 			Title:       "Toggle Help",
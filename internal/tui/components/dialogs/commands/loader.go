@@ -1,8 +1,11 @@
 package commands
 
 import (
+	"cmp"
+	"context"
 	"fmt"
 	"io/fs"
+	"maps"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -10,12 +13,14 @@ import (
 
 	tea "github.com/charmbracelet/bubbletea/v2"
 	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/agent"
 	"github.com/charmbracelet/crush/internal/tui/util"
 )
 
 const (
 	UserCommandPrefix    = "user:"
 	ProjectCommandPrefix = "project:"
+	MCPPromptPrefix      = "mcp:"
 )
 
 var namedArgPattern = regexp.MustCompile(`\$([A-Z][A-Z0-9_]*)`)
@@ -166,6 +171,52 @@ func createCommandHandler(id string, content string) func(Command) tea.Cmd {
 	}
 }
 
+// loadMCPPrompts returns commands for the prompts of the connected MCP servers.
+func loadMCPPrompts() []Command {
+	prompts := agent.GetMCPPrompts()
+	commands := make([]Command, 0, len(prompts))
+	for _, p := range prompts {
+		id := MCPPromptPrefix + p.MCPName + ":" + p.Name
+		commands = append(commands, Command{
+			ID:          id,
+			Title:       id,
+			Description: cmp.Or(p.Description, fmt.Sprintf("Prompt from %s", p.MCPName)),
+			Handler:     createMCPPromptHandler(id, p),
+		})
+	}
+	return commands
+}
+
+func createMCPPromptHandler(id string, p agent.MCPPrompt) func(Command) tea.Cmd {
+	run := func(args map[string]string) tea.Cmd {
+		return func() tea.Msg {
+			// Leave out optional arguments that weren't given.
+			maps.DeleteFunc(args, func(_, value string) bool { return value == "" })
+			content, err := agent.GetMCPPrompt(context.Background(), p.MCPName, p.Name, args)
+			if err != nil {
+				return util.ReportError(err)()
+			}
+			return CommandRunCustomMsg{
+				Content: content,
+			}
+		}
+	}
+	return func(cmd Command) tea.Cmd {
+		if len(p.Arguments) > 0 {
+			args := make([]string, 0, len(p.Arguments))
+			for _, arg := range p.Arguments {
+				args = append(args, arg.Name)
+			}
+			return util.CmdHandler(ShowArgumentsDialogMsg{
+				CommandID: id,
+				ArgNames:  args,
+				OnSubmit:  run,
+			})
+		}
+		return run(map[string]string{})
+	}
+}
+
 func extractArgNames(content string) []string {
 	matches := namedArgPattern.FindAllStringSubmatch(content, -1)
 	if len(matches) == 0 {
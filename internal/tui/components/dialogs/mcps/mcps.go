@@ -0,0 +1,199 @@
+package mcps
+
+import (
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/crush/internal/config"
+	"github.com/charmbracelet/crush/internal/llm/agent"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/exp/list"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+	"github.com/charmbracelet/lipgloss/v2"
+)
+
+const MCPsDialogID dialogs.DialogID = "mcps"
+
+// RestartMCPMsg is sent to restart an MCP server.
+type RestartMCPMsg struct {
+	Name string
+}
+
+// ToggleMCPMsg is sent to enable or disable an MCP server.
+type ToggleMCPMsg struct {
+	Name   string
+	Enable bool
+}
+
+// MCPDialog interface for the MCP servers dialog
+type MCPDialog interface {
+	dialogs.DialogModel
+}
+
+type mcpItem struct {
+	name    string
+	enabled bool
+}
+
+type MCPsList = list.FilterableList[list.CompletionItem[mcpItem]]
+
+type mcpDialogCmp struct {
+	wWidth   int
+	wHeight  int
+	width    int
+	keyMap   KeyMap
+	mcpsList MCPsList
+	help     help.Model
+}
+
+// NewMCPDialogCmp creates a new dialog to restart, enable and disable MCP
+// servers.
+func NewMCPDialogCmp() MCPDialog {
+	t := styles.CurrentTheme()
+	listKeyMap := list.DefaultKeyMap()
+	keyMap := DefaultKeyMap()
+	listKeyMap.Down.SetEnabled(false)
+	listKeyMap.Up.SetEnabled(false)
+	listKeyMap.DownOneItem = keyMap.Next
+	listKeyMap.UpOneItem = keyMap.Previous
+
+	states := agent.GetMCPStates()
+	mcps := config.Get().MCP.Sorted()
+	items := make([]list.CompletionItem[mcpItem], len(mcps))
+	for i, m := range mcps {
+		enabled := !m.MCP.Disabled
+		status := "disabled"
+		if state, ok := states[m.Name]; ok {
+			enabled = state.State != agent.MCPStateDisabled
+			status = state.State.String()
+		}
+		items[i] = list.NewCompletionItem(m.Name+" - "+status, mcpItem{name: m.Name, enabled: enabled}, list.WithCompletionID(m.Name))
+	}
+
+	inputStyle := t.S().Base.PaddingLeft(1).PaddingBottom(1)
+	mcpsList := list.NewFilterableList(
+		items,
+		list.WithFilterPlaceholder("Enter an MCP server name"),
+		list.WithFilterInputStyle(inputStyle),
+		list.WithFilterListOptions(
+			list.WithKeyMap(listKeyMap),
+			list.WithWrapNavigation(),
+		),
+	)
+	help := help.New()
+	help.Styles = t.S().Help
+	return &mcpDialogCmp{
+		keyMap:   keyMap,
+		mcpsList: mcpsList,
+		help:     help,
+	}
+}
+
+func (m *mcpDialogCmp) Init() tea.Cmd {
+	var cmds []tea.Cmd
+	cmds = append(cmds, m.mcpsList.Init())
+	cmds = append(cmds, m.mcpsList.Focus())
+	return tea.Sequence(cmds...)
+}
+
+func (m *mcpDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.wWidth = msg.Width
+		m.wHeight = msg.Height
+		m.width = min(90, m.wWidth-8)
+		m.mcpsList.SetInputWidth(m.listWidth() - 2)
+		return m, m.mcpsList.SetSize(m.listWidth(), m.listHeight())
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, m.keyMap.Restart):
+			selectedItem := m.mcpsList.SelectedItem()
+			if selectedItem != nil {
+				selected := (*selectedItem).Value()
+				return m, tea.Sequence(
+					util.CmdHandler(dialogs.CloseDialogMsg{}),
+					util.CmdHandler(RestartMCPMsg{Name: selected.name}),
+				)
+			}
+		case key.Matches(msg, m.keyMap.Toggle):
+			selectedItem := m.mcpsList.SelectedItem()
+			if selectedItem != nil {
+				selected := (*selectedItem).Value()
+				return m, tea.Sequence(
+					util.CmdHandler(dialogs.CloseDialogMsg{}),
+					util.CmdHandler(ToggleMCPMsg{Name: selected.name, Enable: !selected.enabled}),
+				)
+			}
+		case key.Matches(msg, m.keyMap.Close):
+			return m, util.CmdHandler(dialogs.CloseDialogMsg{})
+		default:
+			u, cmd := m.mcpsList.Update(msg)
+			m.mcpsList = u.(MCPsList)
+			return m, cmd
+		}
+	}
+	return m, nil
+}
+
+func (m *mcpDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	listView := m.mcpsList.View()
+	content := lipgloss.JoinVertical(
+		lipgloss.Left,
+		t.S().Base.Padding(0, 1, 1, 1).Render(core.Title("MCP Servers", m.width-4)),
+		listView,
+		"",
+		t.S().Base.Width(m.width-2).PaddingLeft(1).AlignHorizontal(lipgloss.Left).Render(m.help.View(m.keyMap)),
+	)
+
+	return m.style().Render(content)
+}
+
+func (m *mcpDialogCmp) Cursor() *tea.Cursor {
+	if cursor, ok := m.mcpsList.(util.Cursor); ok {
+		cursor := cursor.Cursor()
+		if cursor != nil {
+			cursor = m.moveCursor(cursor)
+		}
+		return cursor
+	}
+	return nil
+}
+
+func (m *mcpDialogCmp) style() lipgloss.Style {
+	t := styles.CurrentTheme()
+	return t.S().Base.
+		Width(m.width).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus)
+}
+
+func (m *mcpDialogCmp) listHeight() int {
+	return min(len(m.mcpsList.Items())+2, m.wHeight/2-6) // 6 for the border, title and help
+}
+
+func (m *mcpDialogCmp) listWidth() int {
+	return m.width - 2 // 2 for the border
+}
+
+func (m *mcpDialogCmp) Position() (int, int) {
+	row := m.wHeight/4 - 2 // just a bit above the center
+	col := m.wWidth / 2
+	col -= m.width / 2
+	return row, col
+}
+
+func (m *mcpDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
+	row, col := m.Position()
+	offset := row + 3 // Border + title
+	cursor.Y += offset
+	cursor.X = cursor.X + col + 2
+	return cursor
+}
+
+// ID implements MCPDialog.
+func (m *mcpDialogCmp) ID() dialogs.DialogID {
+	return MCPsDialogID
+}
@@ -0,0 +1,85 @@
+package rewind
+
+import (
+	"github.com/charmbracelet/bubbles/v2/key"
+)
+
+// KeyMap defines the keyboard bindings for the rewind dialog.
+type KeyMap struct {
+	LeftRight,
+	Tab,
+	Select,
+	Yes,
+	No,
+	ScrollDown,
+	ScrollUp key.Binding
+}
+
+func DefaultKeyMap() KeyMap {
+	return KeyMap{
+		LeftRight: key.NewBinding(
+			key.WithKeys("left", "right", "h", "l"),
+			key.WithHelp("←/→", "switch options"),
+		),
+		Tab: key.NewBinding(
+			key.WithKeys("tab"),
+			key.WithHelp("tab", "switch options"),
+		),
+		Select: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "confirm"),
+		),
+		Yes: key.NewBinding(
+			key.WithKeys("y", "Y"),
+			key.WithHelp("y", "rewind"),
+		),
+		No: key.NewBinding(
+			key.WithKeys("n", "N", "esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+		ScrollDown: key.NewBinding(
+			key.WithKeys("shift+down", "J", "down", "j"),
+			key.WithHelp("↓", "scroll down"),
+		),
+		ScrollUp: key.NewBinding(
+			key.WithKeys("shift+up", "K", "up", "k"),
+			key.WithHelp("↑", "scroll up"),
+		),
+	}
+}
+
+// KeyBindings implements layout.KeyMapProvider
+func (k KeyMap) KeyBindings() []key.Binding {
+	return []key.Binding{
+		k.LeftRight,
+		k.Tab,
+		k.Select,
+		k.Yes,
+		k.No,
+		k.ScrollDown,
+		k.ScrollUp,
+	}
+}
+
+// FullHelp implements help.KeyMap.
+func (k KeyMap) FullHelp() [][]key.Binding {
+	m := [][]key.Binding{}
+	slice := k.KeyBindings()
+	for i := 0; i < len(slice); i += 4 {
+		end := min(i+4, len(slice))
+		m = append(m, slice[i:end])
+	}
+	return m
+}
+
+// ShortHelp implements help.KeyMap.
+func (k KeyMap) ShortHelp() []key.Binding {
+	return []key.Binding{
+		key.NewBinding(
+			key.WithKeys("down", "up"),
+			key.WithHelp("↓↑", "scroll"),
+		),
+		k.Yes,
+		k.No,
+	}
+}
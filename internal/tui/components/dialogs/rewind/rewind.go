@@ -0,0 +1,234 @@
+package rewind
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/v2/help"
+	"github.com/charmbracelet/bubbles/v2/key"
+	tea "github.com/charmbracelet/bubbletea/v2"
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/charmbracelet/crush/internal/diff"
+	"github.com/charmbracelet/crush/internal/fsext"
+	"github.com/charmbracelet/crush/internal/rewind"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+	"github.com/charmbracelet/crush/internal/tui/util"
+)
+
+const RewindDialogID dialogs.DialogID = "rewind"
+
+// RewoundMsg is sent once a session was rewound, with the prompt of the
+// message it was rewound to.
+type RewoundMsg struct {
+	SessionID string
+	Prompt    string
+}
+
+// RewindDialog interface for the session rewind dialog
+type RewindDialog interface {
+	dialogs.DialogModel
+}
+
+type rewindDialogCmp struct {
+	wWidth, wHeight int
+	width, height   int
+	selected        int // 0: Rewind, 1: Cancel
+	keyMap          KeyMap
+	service         rewind.Service
+	plan            rewind.Plan
+
+	// Preview of the restored files
+	lines   []string
+	yOffset int
+}
+
+// NewRewindDialogCmp creates a new dialog previewing a rewind plan, which
+// is applied once confirmed.
+func NewRewindDialogCmp(service rewind.Service, plan rewind.Plan) RewindDialog {
+	return &rewindDialogCmp{
+		keyMap:  DefaultKeyMap(),
+		service: service,
+		plan:    plan,
+	}
+}
+
+func (r *rewindDialogCmp) Init() tea.Cmd {
+	return nil
+}
+
+func (r *rewindDialogCmp) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		r.wWidth = msg.Width
+		r.wHeight = msg.Height
+		return r, r.SetSize()
+	case tea.KeyPressMsg:
+		switch {
+		case key.Matches(msg, r.keyMap.LeftRight, r.keyMap.Tab):
+			r.selected = (r.selected + 1) % 2
+		case key.Matches(msg, r.keyMap.Select):
+			if r.selected == 0 {
+				return r, r.apply()
+			}
+			return r, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case key.Matches(msg, r.keyMap.Yes):
+			return r, r.apply()
+		case key.Matches(msg, r.keyMap.No):
+			return r, util.CmdHandler(dialogs.CloseDialogMsg{})
+		case key.Matches(msg, r.keyMap.ScrollDown):
+			r.yOffset = min(r.yOffset+1, max(0, len(r.lines)-r.contentHeight()))
+		case key.Matches(msg, r.keyMap.ScrollUp):
+			r.yOffset = max(0, r.yOffset-1)
+		}
+	case tea.MouseWheelMsg:
+		switch msg.Button {
+		case tea.MouseWheelDown:
+			r.yOffset = min(r.yOffset+1, max(0, len(r.lines)-r.contentHeight()))
+		case tea.MouseWheelUp:
+			r.yOffset = max(0, r.yOffset-1)
+		}
+	}
+	return r, nil
+}
+
+func (r *rewindDialogCmp) apply() tea.Cmd {
+	plan := r.plan
+	return tea.Sequence(
+		util.CmdHandler(dialogs.CloseDialogMsg{}),
+		func() tea.Msg {
+			if err := r.service.Apply(context.Background(), plan); err != nil {
+				return util.InfoMsg{
+					Type: util.InfoTypeError,
+					Msg:  fmt.Sprintf("failed to rewind session: %v", err),
+				}
+			}
+			return RewoundMsg{SessionID: plan.SessionID, Prompt: plan.Prompt}
+		},
+	)
+}
+
+func (r *rewindDialogCmp) renderButtons() string {
+	t := styles.CurrentTheme()
+	baseStyle := t.S().Base
+
+	buttons := []core.ButtonOpts{
+		{
+			Text:           "Rewind",
+			UnderlineIndex: -1,
+			Selected:       r.selected == 0,
+		},
+		{
+			Text:           "Cancel",
+			UnderlineIndex: -1,
+			Selected:       r.selected == 1,
+		},
+	}
+
+	content := core.SelectableButtons(buttons, "  ")
+
+	return baseStyle.AlignHorizontal(lipgloss.Right).Width(r.width - 4).Render(content)
+}
+
+func (r *rewindDialogCmp) renderSummary() string {
+	t := styles.CurrentTheme()
+
+	summary := fmt.Sprintf("This removes %s from the session", pluralize(len(r.plan.Messages), "message"))
+	if len(r.plan.Files) > 0 {
+		summary += fmt.Sprintf(" and restores %s to how they were before the message was sent:", pluralize(len(r.plan.Files), "file"))
+	} else {
+		summary += ". No files need to be restored."
+	}
+	return t.S().Text.Width(r.width - 4).Render(summary)
+}
+
+// renderFiles renders the diff of every restored file, as a single list of
+// lines that is scrolled through as a whole.
+func (r *rewindDialogCmp) renderFiles() []string {
+	t := styles.CurrentTheme()
+	var parts []string
+	for _, file := range r.plan.Files {
+		path := fsext.PrettyPath(file.Path)
+		_, additions, removals := diff.GenerateDiff(file.OldContent, file.NewContent, path)
+		header := fmt.Sprintf("%s +%d -%d", path, additions, removals)
+		if file.Delete {
+			header = fmt.Sprintf("%s (deleted)", path)
+		}
+		formatter := core.DiffFormatter().
+			Before(path, file.OldContent).
+			After(path, file.NewContent).
+			Width(r.width - 4).
+			Unified()
+		parts = append(parts, t.S().Muted.Bold(true).Render(header), formatter.String(), "")
+	}
+	if len(parts) == 0 {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(strings.Join(parts, "\n"), "\n"), "\n")
+}
+
+func (r *rewindDialogCmp) contentHeight() int {
+	// Leave room for the title, summary, buttons and help.
+	return max(1, r.height-12)
+}
+
+func (r *rewindDialogCmp) View() string {
+	t := styles.CurrentTheme()
+	baseStyle := t.S().Base
+
+	strs := []string{
+		core.Title("Rewind Session", r.width-4),
+		"",
+		r.renderSummary(),
+		"",
+	}
+	if len(r.lines) > 0 {
+		end := min(len(r.lines), r.yOffset+r.contentHeight())
+		strs = append(strs, strings.Join(r.lines[r.yOffset:end], "\n"), "")
+	}
+	strs = append(strs, r.renderButtons(), "")
+	if len(r.lines) > r.contentHeight() {
+		strs = append(strs, help.New().View(r.keyMap))
+	}
+
+	return baseStyle.
+		Padding(0, 1).
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(t.BorderFocus).
+		Width(r.width).
+		Render(lipgloss.JoinVertical(lipgloss.Top, strs...))
+}
+
+// SetSize sets the size of the component.
+func (r *rewindDialogCmp) SetSize() tea.Cmd {
+	width := min(140, int(float64(r.wWidth)*0.8))
+	if width != r.width {
+		r.width = width
+		r.lines = r.renderFiles()
+	}
+	r.height = int(float64(r.wHeight) * 0.8)
+	r.yOffset = min(r.yOffset, max(0, len(r.lines)-r.contentHeight()))
+	return nil
+}
+
+func (r *rewindDialogCmp) Position() (int, int) {
+	height := min(r.height, len(r.lines)+12)
+	row := (r.wHeight / 2) - (height / 2)
+	col := (r.wWidth / 2) - (r.width / 2)
+	return row, col
+}
+
+// ID implements RewindDialog.
+func (r *rewindDialogCmp) ID() dialogs.DialogID {
+	return RewindDialogID
+}
+
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
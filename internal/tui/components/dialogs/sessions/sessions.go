@@ -1,6 +1,8 @@
 package sessions
 
 import (
+	"strings"
+
 	"github.com/charmbracelet/bubbles/v2/help"
 	"github.com/charmbracelet/bubbles/v2/key"
 	tea "github.com/charmbracelet/bubbletea/v2"
@@ -44,10 +46,15 @@ func NewSessionDialogCmp(sessions []session.Session, selectedID string) SessionD
 	listKeyMap.DownOneItem = keyMap.Next
 	listKeyMap.UpOneItem = keyMap.Previous
 
+	sessions, depths := sessionTree(sessions)
 	items := make([]list.CompletionItem[session.Session], len(sessions))
 	if len(sessions) > 0 {
 		for i, session := range sessions {
-			items[i] = list.NewCompletionItem(session.Title, session, list.WithCompletionID(session.ID))
+			title := session.Title
+			if depths[i] > 0 {
+				title = strings.Repeat("  ", depths[i]-1) + "└ " + title
+			}
+			items[i] = list.NewCompletionItem(title, session, list.WithCompletionID(session.ID))
 		}
 	}
 
@@ -177,3 +184,36 @@ func (s *sessionDialogCmp) moveCursor(cursor *tea.Cursor) *tea.Cursor {
 func (s *sessionDialogCmp) ID() dialogs.DialogID {
 	return SessionsDialogID
 }
+
+// sessionTree orders the sessions so forks come right after the session they
+// were forked from, and returns the depth of every session in the tree.
+func sessionTree(sessions []session.Session) ([]session.Session, []int) {
+	ids := make(map[string]bool, len(sessions))
+	for _, s := range sessions {
+		ids[s.ID] = true
+	}
+	forks := make(map[string][]session.Session)
+	var roots []session.Session
+	for _, s := range sessions {
+		if s.ForkedFromSessionID != "" && ids[s.ForkedFromSessionID] {
+			forks[s.ForkedFromSessionID] = append(forks[s.ForkedFromSessionID], s)
+			continue
+		}
+		roots = append(roots, s)
+	}
+
+	ordered := make([]session.Session, 0, len(sessions))
+	depths := make([]int, 0, len(sessions))
+	var walk func(s session.Session, depth int)
+	walk = func(s session.Session, depth int) {
+		ordered = append(ordered, s)
+		depths = append(depths, depth)
+		for _, fork := range forks[s.ID] {
+			walk(fork, depth+1)
+		}
+	}
+	for _, s := range roots {
+		walk(s, 0)
+	}
+	return ordered, depths
+}
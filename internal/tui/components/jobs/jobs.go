@@ -0,0 +1,97 @@
+package jobs
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/charmbracelet/lipgloss/v2"
+
+	"github.com/charmbracelet/crush/internal/shell"
+	"github.com/charmbracelet/crush/internal/tui/components/core"
+	"github.com/charmbracelet/crush/internal/tui/styles"
+)
+
+// RenderOptions contains options for rendering background job lists.
+type RenderOptions struct {
+	MaxWidth    int
+	MaxItems    int
+	ShowSection bool
+	SectionName string
+}
+
+// RunningJobs returns the background jobs that are still running, oldest
+// first.
+func RunningJobs() []*shell.BackgroundJob {
+	return slices.DeleteFunc(shell.ListBackgroundJobs(), func(job *shell.BackgroundJob) bool {
+		return !job.Running()
+	})
+}
+
+// RenderJobList renders a list of running background jobs with the given
+// options.
+func RenderJobList(jobs []*shell.BackgroundJob, opts RenderOptions) []string {
+	t := styles.CurrentTheme()
+	jobList := []string{}
+
+	if opts.ShowSection {
+		sectionName := opts.SectionName
+		if sectionName == "" {
+			sectionName = "Jobs"
+		}
+		section := t.S().Subtle.Render(sectionName)
+		jobList = append(jobList, section, "")
+	}
+
+	if len(jobs) == 0 {
+		jobList = append(jobList, t.S().Base.Foreground(t.Border).Render("None"))
+		return jobList
+	}
+
+	// Determine how many items to show
+	maxItems := len(jobs)
+	if opts.MaxItems > 0 {
+		maxItems = min(opts.MaxItems, len(jobs))
+	}
+
+	for _, job := range jobs[:maxItems] {
+		command := strings.ReplaceAll(job.Command, "\n", " ")
+		jobList = append(jobList,
+			core.Status(
+				core.StatusOpts{
+					Icon:        t.ItemBusyIcon.String(),
+					Title:       job.ID,
+					Description: command,
+				},
+				opts.MaxWidth,
+			),
+		)
+	}
+
+	return jobList
+}
+
+// RenderJobBlock renders a complete background job block with optional
+// truncation indicator.
+func RenderJobBlock(jobs []*shell.BackgroundJob, opts RenderOptions, showTruncationIndicator bool) string {
+	t := styles.CurrentTheme()
+	jobList := RenderJobList(jobs, opts)
+
+	// Add truncation indicator if needed
+	if showTruncationIndicator && opts.MaxItems > 0 && len(jobs) > opts.MaxItems {
+		remaining := len(jobs) - opts.MaxItems
+		if remaining == 1 {
+			jobList = append(jobList, t.S().Base.Foreground(t.FgMuted).Render("…"))
+		} else {
+			jobList = append(jobList,
+				t.S().Base.Foreground(t.FgSubtle).Render(fmt.Sprintf("…and %d more", remaining)),
+			)
+		}
+	}
+
+	content := lipgloss.JoinVertical(lipgloss.Left, jobList...)
+	if opts.MaxWidth > 0 {
+		return lipgloss.NewStyle().Width(opts.MaxWidth).Render(content)
+	}
+	return content
+}
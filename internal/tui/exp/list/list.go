@@ -942,6 +942,7 @@ func (l *list[T]) DeleteItem(id string) tea.Cmd {
 		return nil
 	}
 	l.items.Delete(inx)
+	l.indexMap.Del(id)
 	l.renderedItems.Del(id)
 	for inx, item := range slices.Collect(l.items.Seq()) {
 		l.indexMap.Set(item.ID(), inx)
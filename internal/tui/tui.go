@@ -13,19 +13,24 @@ import (
 	"github.com/charmbracelet/crush/internal/llm/agent"
 	"github.com/charmbracelet/crush/internal/permission"
 	"github.com/charmbracelet/crush/internal/pubsub"
+	"github.com/charmbracelet/crush/internal/session"
 	cmpChat "github.com/charmbracelet/crush/internal/tui/components/chat"
+	"github.com/charmbracelet/crush/internal/tui/components/chat/messages"
 	"github.com/charmbracelet/crush/internal/tui/components/chat/splash"
 	"github.com/charmbracelet/crush/internal/tui/components/completions"
 	"github.com/charmbracelet/crush/internal/tui/components/core"
 	"github.com/charmbracelet/crush/internal/tui/components/core/layout"
 	"github.com/charmbracelet/crush/internal/tui/components/core/status"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/agents"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/commands"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/compact"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/filepicker"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/mcps"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/models"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/permissions"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/quit"
+	"github.com/charmbracelet/crush/internal/tui/components/dialogs/rewind"
 	"github.com/charmbracelet/crush/internal/tui/components/dialogs/sessions"
 	"github.com/charmbracelet/crush/internal/tui/page"
 	"github.com/charmbracelet/crush/internal/tui/page/chat"
@@ -71,7 +76,8 @@ type appModel struct {
 	isConfigured bool
 
 	// Chat Page Specific
-	selectedSessionID string // The ID of the currently selected session
+	selectedSessionID string          // The ID of the currently selected session
+	initialSession    session.Session // The session to open on startup, if any
 }
 
 // Init initializes the application model and returns initial commands.
@@ -86,6 +92,10 @@ func (a appModel) Init() tea.Cmd {
 
 	cmds = append(cmds, tea.EnableMouseAllMotion)
 
+	if a.initialSession.ID != "" {
+		cmds = append(cmds, util.CmdHandler(cmpChat.SessionSelectedMsg(a.initialSession)))
+	}
+
 	return tea.Batch(cmds...)
 }
 
@@ -131,6 +141,7 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					msg.CommandID,
 					msg.Content,
 					msg.ArgNames,
+					msg.OnSubmit,
 				),
 			},
 		)
@@ -165,24 +176,103 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				Model: models.NewModelDialogCmp(),
 			},
 		)
+	case commands.SwitchAgentMsg:
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: agents.NewAgentDialogCmp(a.app.AgentID()),
+			},
+		)
+	case agents.AgentSelectedMsg:
+		if msg.ID == a.app.AgentID() {
+			return a, nil
+		}
+		if err := a.app.SwitchAgent(msg.ID); err != nil {
+			return a, util.ReportError(fmt.Errorf("failed to switch agent: %w", err))
+		}
+		agentCfg, _ := config.Get().Agent(msg.ID)
+		return a, util.ReportInfo(fmt.Sprintf("agent changed to %s", agentCfg.Name))
+	// MCP servers
+	case commands.ManageMCPsMsg:
+		return a, util.CmdHandler(
+			dialogs.OpenDialogMsg{
+				Model: mcps.NewMCPDialogCmp(),
+			},
+		)
+	case mcps.RestartMCPMsg:
+		return a, func() tea.Msg {
+			if err := agent.RestartMCP(context.Background(), msg.Name); err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: fmt.Sprintf("MCP %s restarted", msg.Name)}
+		}
+	case mcps.ToggleMCPMsg:
+		return a, func() tea.Msg {
+			if err := agent.SetMCPEnabled(context.Background(), msg.Name, msg.Enable); err != nil {
+				return util.InfoMsg{Type: util.InfoTypeError, Msg: err.Error()}
+			}
+			status := "disabled"
+			if msg.Enable {
+				status = "enabled"
+			}
+			return util.InfoMsg{Type: util.InfoTypeInfo, Msg: fmt.Sprintf("MCP %s %s", msg.Name, status)}
+		}
 	// Compact
 	case commands.CompactMsg:
 		return a, util.CmdHandler(dialogs.OpenDialogMsg{
-			Model: compact.NewCompactDialogCmp(a.app.CoderAgent, msg.SessionID, true),
+			Model: compact.NewCompactDialogCmp(a.app.CoderAgent(), msg.SessionID, true),
 		})
+	// Rewind
+	case messages.RewindMsg:
+		if a.app.CoderAgent() != nil && a.app.CoderAgent().IsSessionBusy(msg.Message.SessionID) {
+			return a, util.ReportWarn("Agent is busy, please wait...")
+		}
+		return a, func() tea.Msg {
+			plan, err := a.app.Rewind.Plan(context.Background(), msg.Message.SessionID, msg.Message.ID)
+			if err != nil {
+				return util.InfoMsg{
+					Type: util.InfoTypeError,
+					Msg:  fmt.Sprintf("failed to rewind session: %v", err),
+				}
+			}
+			return dialogs.OpenDialogMsg{
+				Model: rewind.NewRewindDialogCmp(a.app.Rewind, plan),
+			}
+		}
+	// Fork
+	case messages.ForkMsg:
+		if a.app.CoderAgent() != nil && a.app.CoderAgent().IsSessionBusy(msg.Message.SessionID) {
+			return a, util.ReportWarn("Agent is busy, please wait...")
+		}
+		return a, func() tea.Msg {
+			fork, err := a.app.ForkSession(context.Background(), msg.Message.SessionID, msg.Message.ID)
+			if err != nil {
+				return util.InfoMsg{
+					Type: util.InfoTypeError,
+					Msg:  err.Error(),
+				}
+			}
+			return cmpChat.SessionSelectedMsg(fork)
+		}
 	case commands.QuitMsg:
 		return a, util.CmdHandler(dialogs.OpenDialogMsg{
 			Model: quit.NewQuitDialog(),
 		})
 	case commands.ToggleYoloModeMsg:
 		a.app.Permissions.SetSkipRequests(!a.app.Permissions.SkipRequests())
+	case commands.TogglePlanModeMsg:
+		if a.app.Permissions.Mode() == permission.ModePlan {
+			a.app.Permissions.SetMode(permission.ModeDefault)
+			return a, util.ReportInfo("Plan mode off")
+		}
+		a.app.Permissions.SetMode(permission.ModePlan)
+		return a, util.ReportInfo("Plan mode on: tool calls that change files will be refused")
 	case commands.ToggleHelpMsg:
 		a.status.ToggleFullHelp()
 		a.showingFullHelp = !a.showingFullHelp
 		return a, a.handleWindowResize(a.wWidth, a.wHeight)
 	// Model Switch
 	case models.ModelSelectedMsg:
-		if a.app.CoderAgent.IsBusy() {
+		if a.app.CoderAgent().IsBusy() {
 			return a, util.ReportWarn("Agent is busy, please wait...")
 		}
 		config.Get().UpdatePreferredModel(msg.ModelType, msg.Model)
@@ -230,6 +320,8 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			a.app.Permissions.Grant(msg.Permission)
 		case permissions.PermissionAllowForSession:
 			a.app.Permissions.GrantPersistent(msg.Permission)
+		case permissions.PermissionAllowAlways:
+			a.app.Permissions.GrantAlways(msg.Permission)
 		case permissions.PermissionDeny:
 			a.app.Permissions.Deny(msg.Permission)
 		}
@@ -245,17 +337,21 @@ func (a *appModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			cmds = append(cmds, dialogCmd)
 		}
 
+		if payload.Type == agent.AgentEventTypeWarning && payload.Error != nil {
+			cmds = append(cmds, util.ReportWarn(payload.Error.Error()))
+		}
+
 		// Handle auto-compact logic
 		if payload.Done && payload.Type == agent.AgentEventTypeResponse && a.selectedSessionID != "" {
 			// Get current session to check token usage
 			session, err := a.app.Sessions.Get(context.Background(), a.selectedSessionID)
 			if err == nil {
-				model := a.app.CoderAgent.Model()
+				model := a.app.CoderAgent().Model()
 				contextWindow := model.ContextWindow
 				tokens := session.CompletionTokens + session.PromptTokens
 				if (tokens >= int64(float64(contextWindow)*0.95)) && !config.Get().Options.DisableAutoSummarize { // Show compact confirmation dialog
 					cmds = append(cmds, util.CmdHandler(dialogs.OpenDialogMsg{
-						Model: compact.NewCompactDialogCmp(a.app.CoderAgent, a.selectedSessionID, false),
+						Model: compact.NewCompactDialogCmp(a.app.CoderAgent(), a.selectedSessionID, false),
 					}))
 				}
 			}
@@ -432,7 +528,7 @@ func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 		)
 		return tea.Sequence(cmds...)
 	case key.Matches(msg, a.keyMap.Suspend):
-		if a.app.CoderAgent != nil && a.app.CoderAgent.IsBusy() {
+		if a.app.CoderAgent() != nil && a.app.CoderAgent().IsBusy() {
 			return util.ReportWarn("Agent is busy, please wait...")
 		}
 		return tea.Suspend
@@ -450,7 +546,7 @@ func (a *appModel) handleKeyPressMsg(msg tea.KeyPressMsg) tea.Cmd {
 
 // moveToPage handles navigation between different pages in the application.
 func (a *appModel) moveToPage(pageID page.PageID) tea.Cmd {
-	if a.app.CoderAgent.IsBusy() {
+	if a.app.CoderAgent().IsBusy() {
 		// TODO: maybe remove this :  For now we don't move to any page if the agent is busy
 		return util.ReportWarn("Agent is busy, please wait...")
 	}
@@ -551,7 +647,7 @@ func (a *appModel) View() tea.View {
 }
 
 // New creates and initializes a new TUI application model.
-func New(app *app.App) tea.Model {
+func New(app *app.App, initialSession session.Session) tea.Model {
 	chatPage := chat.New(app)
 	keyMap := DefaultKeyMap()
 	keyMap.pageBindings = chatPage.Bindings()
@@ -569,6 +665,8 @@ func New(app *app.App) tea.Model {
 
 		dialog:      dialogs.NewDialogCmp(),
 		completions: completions.New(),
+
+		initialSession: initialSession,
 	}
 
 	return model